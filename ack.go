@@ -0,0 +1,26 @@
+package asyncx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ConfirmFunc verifies the side effects of a task after its handler returned
+// nil, e.g. by checking that a payment was captured. A nil error confirms completion.
+type ConfirmFunc func(ctx context.Context, t *asynq.Task) error
+
+// Ack confirms a task left in StatusAwaitingAck and marks it completed.
+// Use it when confirmation arrives out of band (webhook, reconciliation job).
+func Ack(ctx context.Context, store Store, taskID string) error {
+	rec, err := store.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if rec.Status != StatusAwaitingAck && rec.Status != StatusNeedsReview {
+		return fmt.Errorf("task %s is %s, not awaiting ack", taskID, rec.Status)
+	}
+	return store.MarkCompleted(ctx, taskID, rec.ResultJSON, time.Now().UTC())
+}
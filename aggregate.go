@@ -0,0 +1,288 @@
+package asyncx
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AggregateField is a column Aggregate can group by.
+type AggregateField string
+
+const (
+	GroupByType   AggregateField = "type"
+	GroupByQueue  AggregateField = "queue"
+	GroupByStatus AggregateField = "status"
+)
+
+// AggregateRow is one group of an Aggregate result. Fields not grouped by
+// are empty. P50 and P95 are handler durations (finished_at - started_at of
+// the last attempt) of the group's finished records, zero if none finished.
+// They are exact on Postgres and otherwise within 2%; see durationHistogram.
+type AggregateRow struct {
+	Type   string
+	Queue  string
+	Status Status
+	Count  int
+	P50    time.Duration
+	P95    time.Duration
+}
+
+// AggregateStore is implemented by stores that can aggregate records
+// without loading them. Use Aggregate to work with any Store.
+type AggregateStore interface {
+	Aggregate(ctx context.Context, groupBy []AggregateField, window time.Duration) ([]AggregateRow, error)
+}
+
+// Aggregate returns counts and duration percentiles of the records created
+// within window (all records if zero), grouped by groupBy and sorted by
+// group. It uses AggregateStore when available and falls back to List.
+func Aggregate(ctx context.Context, store Store, groupBy []AggregateField, window time.Duration) ([]AggregateRow, error) {
+	if err := validateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+	if as, ok := storeAs[AggregateStore](store); ok {
+		return as.Aggregate(ctx, groupBy, window)
+	}
+	f := TaskFilter{}
+	if window > 0 {
+		f.CreatedAfter = time.Now().Add(-window)
+	}
+	recs, err := listRecords(ctx, store, f)
+	if err != nil {
+		return nil, err
+	}
+	agg := newAggregator(groupBy)
+	for _, rec := range recs {
+		row := agg.add(rec.Type, rec.Queue, rec.Status)
+		row.Count++
+		if rec.StartedAt != nil && rec.FinishedAt != nil {
+			agg.addDuration(row, rec.FinishedAt.Sub(*rec.StartedAt))
+		}
+	}
+	return agg.rows(), nil
+}
+
+func validateGroupBy(groupBy []AggregateField) error {
+	for _, g := range groupBy {
+		switch g {
+		case GroupByType, GroupByQueue, GroupByStatus:
+		default:
+			return fmt.Errorf("asyncx: cannot group by %q", g)
+		}
+	}
+	return nil
+}
+
+// aggregator collects AggregateRows keyed by their group.
+type aggregator struct {
+	groupBy   []AggregateField
+	groups    map[AggregateRow]*AggregateRow
+	durations map[*AggregateRow]durationHistogram
+}
+
+func newAggregator(groupBy []AggregateField) *aggregator {
+	return &aggregator{groupBy: groupBy, groups: map[AggregateRow]*AggregateRow{}, durations: map[*AggregateRow]durationHistogram{}}
+}
+
+// addDuration counts a finished record of row.
+func (a *aggregator) addDuration(row *AggregateRow, d time.Duration) {
+	h, ok := a.durations[row]
+	if !ok {
+		h = durationHistogram{}
+		a.durations[row] = h
+	}
+	h.add(d)
+}
+
+// add returns the row of the group a record with these fields belongs to.
+func (a *aggregator) add(taskType, queue string, status Status) *AggregateRow {
+	var key AggregateRow
+	for _, g := range a.groupBy {
+		switch g {
+		case GroupByType:
+			key.Type = taskType
+		case GroupByQueue:
+			key.Queue = queue
+		case GroupByStatus:
+			key.Status = status
+		}
+	}
+	row, ok := a.groups[key]
+	if !ok {
+		row = &key
+		a.groups[key] = row
+	}
+	return row
+}
+
+// rows returns the groups sorted by type, queue and status, with the
+// percentiles of the groups with durations filled in.
+func (a *aggregator) rows() []AggregateRow {
+	out := make([]AggregateRow, 0, len(a.groups))
+	for _, row := range a.groups {
+		if h, ok := a.durations[row]; ok {
+			row.P50, row.P95 = h.percentile(0.50), h.percentile(0.95)
+		}
+		out = append(out, *row)
+	}
+	slices.SortFunc(out, func(x, y AggregateRow) int {
+		return cmp.Or(strings.Compare(x.Type, y.Type), strings.Compare(x.Queue, y.Queue), strings.Compare(string(x.Status), string(y.Status)))
+	})
+	return out
+}
+
+// durationHistogram counts durations in buckets 2% wide, keeping the
+// longest duration of each, so that percentiles over any number of records
+// take bounded memory: under a thousand buckets span a millisecond to a day.
+type durationHistogram map[int]*durationBucket
+
+type durationBucket struct {
+	count int
+	max   time.Duration
+}
+
+// bucketGrowth is the ratio of the bounds of consecutive buckets.
+const bucketGrowth = 1.02
+
+func (h durationHistogram) add(d time.Duration) {
+	i := 0
+	if d > time.Millisecond {
+		i = int(math.Ceil(math.Log(float64(d)/float64(time.Millisecond)) / math.Log(bucketGrowth)))
+	}
+	b, ok := h[i]
+	if !ok {
+		b = &durationBucket{max: d}
+		h[i] = b
+	}
+	b.count++
+	b.max = max(b.max, d)
+}
+
+// percentile returns the longest duration of the bucket holding the
+// nearest-rank percentile p, which is exact when the bucket holds a single
+// value.
+func (h durationHistogram) percentile(p float64) time.Duration {
+	keys := make([]int, 0, len(h))
+	n := 0
+	for i, b := range h {
+		keys = append(keys, i)
+		n += b.count
+	}
+	slices.Sort(keys)
+	rank := max(1, int(math.Ceil(float64(n)*p)))
+	for _, i := range keys {
+		if rank -= h[i].count; rank <= 0 {
+			return h[i].max
+		}
+	}
+	return 0
+}
+
+// Aggregate counts with GROUP BY. On Postgres the percentiles come from
+// percentile_disc in the same query. Elsewhere, with no portable percentile
+// function, the finished records' start and finish times are streamed in a
+// second query into a durationHistogram per group.
+func (s *SQLStore) Aggregate(ctx context.Context, groupBy []AggregateField, window time.Duration) ([]AggregateRow, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	if err := validateGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+	where, args := ` WHERE 1 = 1`, []any{}
+	if window > 0 {
+		where, args = ` WHERE created_at >= ?`, []any{time.Now().Add(-window).UTC()}
+	}
+	// Every group column is selected so that rows scan the same way;
+	// ungrouped ones are constant.
+	cols := map[AggregateField]string{GroupByType: "''", GroupByQueue: "''", GroupByStatus: "''"}
+	var group []string
+	for _, g := range groupBy {
+		cols[g] = string(g)
+		group = append(group, string(g))
+	}
+	sel := cols[GroupByType] + ", " + cols[GroupByQueue] + ", " + cols[GroupByStatus]
+	agg := newAggregator(groupBy)
+	pg := s.dialect == DialectPostgres
+
+	q := `SELECT ` + sel + `, COUNT(*)`
+	if pg {
+		finished := `EXTRACT(EPOCH FROM finished_at - started_at)`
+		q += `, percentile_disc(0.5) WITHIN GROUP (ORDER BY ` + finished + `), percentile_disc(0.95) WITHIN GROUP (ORDER BY ` + finished + `)`
+	}
+	q += ` FROM asyncx_tasks` + where
+	if len(group) > 0 {
+		q += ` GROUP BY ` + strings.Join(group, ", ")
+	}
+	err := s.queryRows(ctx, q, args, func(rows *sql.Rows) error {
+		var taskType, queue, status string
+		var n int
+		// NULL for groups without finished records, since the durations
+		// of the others are NULL and ignored.
+		var p50, p95 sql.NullFloat64
+		dest := []any{&taskType, &queue, &status, &n}
+		if pg {
+			dest = append(dest, &p50, &p95)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if n == 0 {
+			// COUNT without GROUP BY over no rows.
+			return nil
+		}
+		row := agg.add(taskType, queue, Status(status))
+		row.Count = n
+		row.P50 = time.Duration(p50.Float64 * float64(time.Second))
+		row.P95 = time.Duration(p95.Float64 * float64(time.Second))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pg {
+		return agg.rows(), nil
+	}
+
+	q = `SELECT ` + sel + `, started_at, finished_at FROM asyncx_tasks` + where + ` AND started_at IS NOT NULL AND finished_at IS NOT NULL`
+	err = s.queryRows(ctx, q, args, func(rows *sql.Rows) error {
+		var taskType, queue, status string
+		var started, finished time.Time
+		if err := rows.Scan(&taskType, &queue, &status, &started, &finished); err != nil {
+			return err
+		}
+		agg.addDuration(agg.add(taskType, queue, Status(status)), finished.Sub(started))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return agg.rows(), nil
+}
+
+// queryRows runs q, with '?' placeholders rewritten for Postgres if the
+// driver needs them, and calls fn for each row.
+func (s *SQLStore) queryRows(ctx context.Context, q string, args []any, fn func(*sql.Rows) error) error {
+	st, err := s.stmt(ctx, q, dollarPlaceholders(q))
+	if err != nil {
+		return err
+	}
+	rows, err := st.QueryContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
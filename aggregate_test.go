@@ -0,0 +1,90 @@
+package asyncx
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAggregate(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "aggregate.db"), BoltStoreOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer bolt.Close()
+	ctx := context.Background()
+
+	for name, store := range map[string]Store{"sql": NewSQLStore(db), "bolt": bolt} {
+		start := time.Now().Add(-time.Minute).UTC()
+		add := func(id, typ, queue string, took time.Duration, fail bool) {
+			if err := store.InsertCreated(ctx, TaskRecord{ID: name + id, Type: typ, Queue: queue, PayloadJSON: `{}`, CreatedAt: start}); err != nil {
+				t.Fatalf("%s: InsertCreated: %v", name, err)
+			}
+			_ = store.MarkStarted(ctx, name+id, start)
+			if fail {
+				_ = store.MarkFailed(ctx, name+id, "boom", start.Add(took))
+			} else {
+				_ = store.MarkCompleted(ctx, name+id, nil, start.Add(took))
+			}
+		}
+		for i, took := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 10 * time.Second} {
+			add(string(rune('a'+i)), "email:send", "default", took, false)
+		}
+		add("f", "email:send", "default", time.Second, true)
+		add("r", "report:build", "low", 5*time.Second, false)
+
+		rows, err := Aggregate(ctx, store, []AggregateField{GroupByType, GroupByStatus}, time.Hour)
+		if err != nil {
+			t.Fatalf("%s: Aggregate: %v", name, err)
+		}
+		want := []AggregateRow{
+			{Type: "email:send", Status: StatusCompleted, Count: 4, P50: 2 * time.Second, P95: 10 * time.Second},
+			{Type: "email:send", Status: StatusFailed, Count: 1, P50: time.Second, P95: time.Second},
+			{Type: "report:build", Status: StatusCompleted, Count: 1, P50: 5 * time.Second, P95: 5 * time.Second},
+		}
+		if len(rows) != len(want) {
+			t.Fatalf("%s: got %+v", name, rows)
+		}
+		for i := range want {
+			if rows[i] != want[i] {
+				t.Errorf("%s: row %d = %+v, want %+v", name, i, rows[i], want[i])
+			}
+		}
+
+		byQueue, err := Aggregate(ctx, store, []AggregateField{GroupByQueue}, 0)
+		if err != nil || len(byQueue) != 2 || byQueue[0].Queue != "default" || byQueue[0].Count != 5 {
+			t.Fatalf("%s: by queue: %+v %v", name, byQueue, err)
+		}
+		if _, err := Aggregate(ctx, store, []AggregateField{"payload_json"}, 0); err == nil {
+			t.Errorf("%s: unknown group field must be rejected", name)
+		}
+	}
+}
+
+func TestDurationHistogram_BoundedAndClose(t *testing.T) {
+	h := durationHistogram{}
+	const n = 100000
+	for i := 1; i <= n; i++ {
+		h.add(time.Duration(i) * time.Millisecond)
+	}
+	if len(h) > 1000 {
+		t.Fatalf("%d buckets for %d durations", len(h), n)
+	}
+	for _, c := range []struct {
+		p    float64
+		want time.Duration
+	}{{0.50, 50 * time.Second}, {0.95, 95 * time.Second}} {
+		got := h.percentile(c.p)
+		if got < c.want || float64(got) > float64(c.want)*bucketGrowth {
+			t.Errorf("p%v = %v, want within 2%% above %v", c.p*100, got, c.want)
+		}
+	}
+	exact := durationHistogram{}
+	exact.add(4 * time.Second)
+	if got := exact.percentile(0.95); got != 4*time.Second {
+		t.Errorf("single value: %v", got)
+	}
+}
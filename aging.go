@@ -0,0 +1,205 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// AgingRule promotes tasks that waited in From for longer than After to To.
+type AgingRule struct {
+	From  string
+	To    string
+	After time.Duration
+}
+
+type PriorityAgerConfig struct {
+	// Interval between passes. Defaults to one minute.
+	Interval time.Duration
+	// Rules are applied in order on every pass. A task promoted by one rule
+	// may be promoted again by a later rule once it is old enough.
+	Rules []AgingRule
+	// BatchSize is the most tasks promoted per rule and pass. Defaults to
+	// 100.
+	BatchSize int
+	// Leader, if set, limits passes to the elected replica.
+	Leader Leader
+}
+
+// PromotionStore is implemented by stores that record a PriorityAger
+// promotion as one step, including in the task's history.
+type PromotionStore interface {
+	MarkPromoted(ctx context.Context, taskID, from, to string, at time.Time) error
+}
+
+// PriorityAger keeps tasks in low-priority queues from starving: it moves
+// pending tasks that waited past an AgingRule's age to the rule's higher
+// queue. A promoted task keeps its ID, payload, retry limit, timeout,
+// deadline and retention; its retry count starts over.
+type PriorityAger struct {
+	client *Client
+	cfg    PriorityAgerConfig
+}
+
+// NewPriorityAger panics if client has no store, which is where task ages
+// are read from.
+func NewPriorityAger(client *Client, cfg PriorityAgerConfig) *PriorityAger {
+	if client.store == nil {
+		panic("asyncx: NewPriorityAger: client has no store")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &PriorityAger{client: client, cfg: cfg}
+}
+
+// Run promotes every Interval until ctx is cancelled.
+func (a *PriorityAger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_, _ = a.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies every rule once and returns how many tasks it promoted,
+// or does nothing unless a leads.
+func (a *PriorityAger) RunOnce(ctx context.Context) (int, error) {
+	if !leads(a.cfg.Leader) {
+		return 0, nil
+	}
+	promoted := 0
+	for _, r := range a.cfg.Rules {
+		recs, err := a.candidates(ctx, r)
+		if err != nil {
+			return promoted, err
+		}
+		for _, rec := range recs {
+			ok, err := a.client.promote(ctx, rec, r.To)
+			if err != nil {
+				return promoted, fmt.Errorf("asyncx: promote %s: %w", rec.ID, err)
+			}
+			if ok {
+				promoted++
+			}
+		}
+	}
+	return promoted, nil
+}
+
+// errBatchFull stops the walk over candidates once a batch is collected.
+var errBatchFull = errors.New("asyncx: batch full")
+
+// candidates returns up to BatchSize records of tasks pending in r.From for
+// longer than r.After, oldest first. Records of scheduled tasks, or of
+// tasks no longer on Redis, are passed over so that they cannot hold up the
+// tasks behind them. It streams from a RecordStreamer and falls back to
+// List.
+func (a *PriorityAger) candidates(ctx context.Context, r AgingRule) ([]*TaskRecord, error) {
+	f := TaskFilter{Status: StatusCreated, Queue: r.From, CreatedBefore: time.Now().Add(-r.After), Enqueued: true}
+	var out []*TaskRecord
+	visit := func(rec *TaskRecord) error {
+		info, err := a.client.inspector.GetTaskInfo(r.From, rec.ID)
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.State == asynq.TaskStatePending {
+			out = append(out, rec)
+		}
+		if len(out) == a.cfg.BatchSize {
+			return errBatchFull
+		}
+		return nil
+	}
+	var err error
+	if rs, ok := storeAs[RecordStreamer](a.client.store); ok {
+		err = rs.Each(ctx, f, visit)
+	} else {
+		var recs []*TaskRecord
+		recs, err = listRecords(ctx, a.client.store, f)
+		for _, rec := range recs {
+			if err = visit(rec); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil && !errors.Is(err, errBatchFull) {
+		return nil, err
+	}
+	return out, nil
+}
+
+// promoteHold is how long a promoted copy stays scheduled while the task
+// leaves its old queue; a crash midway delays the task by at most this.
+const promoteHold = time.Minute
+
+// promote moves the task of rec to queue if it is still pending. The task
+// is first copied to queue as scheduled, so that it is on Redis throughout
+// and no worker takes the copy before the original is deleted, and then
+// made pending. If a worker took the original in the meantime, the copy is
+// deleted again.
+func (c *Client) promote(ctx context.Context, rec *TaskRecord, queue string) (bool, error) {
+	info, err := c.inspector.GetTaskInfo(rec.Queue, rec.ID)
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) || (err == nil && info.State != asynq.TaskStatePending) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	opts := []asynq.Option{asynq.TaskID(rec.ID), asynq.Queue(queue), asynq.MaxRetry(info.MaxRetry), asynq.ProcessIn(promoteHold)}
+	if info.Timeout > 0 {
+		opts = append(opts, asynq.Timeout(info.Timeout))
+	}
+	if !info.Deadline.IsZero() {
+		opts = append(opts, asynq.Deadline(info.Deadline))
+	}
+	if info.Retention > 0 {
+		opts = append(opts, asynq.Retention(info.Retention))
+	}
+	// A conflict means a copy left by an interrupted promotion.
+	if _, err := c.client.EnqueueContext(ctx, asynq.NewTask(info.Type, info.Payload), opts...); err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return false, err
+	}
+	if err := c.inspector.DeleteTask(rec.Queue, rec.ID); err != nil {
+		// A worker took the task in the meantime.
+		_ = c.inspector.DeleteTask(queue, rec.ID)
+		return false, nil
+	}
+	// Should this fail, the copy still runs once promoteHold is over.
+	runErr := c.inspector.RunTask(queue, rec.ID)
+	now := time.Now().UTC()
+	if ps, ok := storeAs[PromotionStore](c.store); ok {
+		err = ps.MarkPromoted(ctx, rec.ID, rec.Queue, queue, now)
+	} else {
+		err = c.store.MarkEnqueued(ctx, rec.ID, queue, now)
+	}
+	return true, errors.Join(runErr, err)
+}
+
+// MarkPromoted moves the record to queue and, with SQLStoreOptions.History,
+// records a StatusPromoted transition whose message names both queues. The
+// record's status is unchanged.
+func (s *SQLStore) MarkPromoted(ctx context.Context, taskID, from, to string, at time.Time) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET queue = ?, priority = ?, updated_at = ? WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET queue = $1, priority = $2, updated_at = $3 WHERE id = $4`
+	return s.withHistory(ctx, taskID, StatusPromoted, from+" -> "+to, at, func(s *SQLStore) error {
+		return s.exec(ctx, q, qpg, to, optional(string(priorityOf(to))), at.UTC(), taskID)
+	})
+}
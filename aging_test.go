@@ -0,0 +1,80 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestPriorityAger_PromotesOldPendingTasks(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_aging_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db, SQLStoreOptions{History: true})
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	// Older than the pending task, and never promoted: they must not take
+	// up the batch.
+	scheduled, err := client.EnqueueLow(ctx, "report:build", nil, asynq.ProcessIn(time.Hour))
+	if err != nil {
+		t.Fatalf("EnqueueLow: %v", err)
+	}
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "orphan", Type: "report:build", Queue: "low", PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	old, err := client.EnqueueLow(ctx, "report:build", map[string]int{"n": 1}, asynq.MaxRetry(7), asynq.Timeout(time.Minute))
+	if err != nil {
+		t.Fatalf("EnqueueLow: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	fresh, err := client.EnqueueLow(ctx, "report:build", nil)
+	if err != nil {
+		t.Fatalf("EnqueueLow: %v", err)
+	}
+
+	ager := NewPriorityAger(client, PriorityAgerConfig{Rules: []AgingRule{{From: "low", To: "critical", After: 10 * time.Millisecond}}, BatchSize: 1})
+	n, err := ager.RunOnce(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("RunOnce = %d, %v; want 1", n, err)
+	}
+
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+	info, err := inspector.GetTaskInfo("critical", old.ID)
+	if err != nil || info.State != asynq.TaskStatePending || info.MaxRetry != 7 || info.Timeout != time.Minute || string(info.Payload) != `{"n":1}` {
+		t.Fatalf("promoted task: %+v %v", info, err)
+	}
+	if _, err := inspector.GetTaskInfo("low", old.ID); err == nil {
+		t.Fatal("promoted task left in low")
+	}
+	for _, id := range []string{scheduled.ID, fresh.ID} {
+		if _, err := inspector.GetTaskInfo("low", id); err != nil {
+			t.Fatalf("task %s must stay in low: %v", id, err)
+		}
+	}
+	rec, err := store.GetByID(ctx, old.ID)
+	if err != nil || rec.Queue != "critical" || rec.Priority != PriorityCritical || rec.Status != StatusCreated {
+		t.Fatalf("promoted record: %+v %v", rec, err)
+	}
+	history, err := store.History(ctx, old.ID)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	last := history[len(history)-1]
+	if last.Status != StatusPromoted || last.ErrorMsg != "low -> critical" {
+		t.Fatalf("history: %+v", history)
+	}
+}
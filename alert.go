@@ -0,0 +1,262 @@
+package asyncx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// AlertKind is what an Alert measures.
+type AlertKind string
+
+const (
+	// AlertQueueDepth is the number of pending tasks in a queue.
+	AlertQueueDepth AlertKind = "queue_depth"
+	// AlertFailureRate is the share of a task type's finished records
+	// created within AlerterConfig.Window that failed or timed out. Failed
+	// attempts that asynq will retry are not finished.
+	AlertFailureRate AlertKind = "failure_rate"
+)
+
+// Alert reports a threshold being crossed. Firing is true when Value
+// reached Threshold.Fire and false when it fell back to Threshold.Clear.
+type Alert struct {
+	Kind AlertKind `json:"kind"`
+	// Subject is the queue for AlertQueueDepth and the task type for
+	// AlertFailureRate.
+	Subject   string    `json:"subject"`
+	Value     float64   `json:"value"`
+	Threshold Threshold `json:"threshold"`
+	Firing    bool      `json:"firing"`
+	At        time.Time `json:"at"`
+}
+
+// Threshold fires an alert when a value reaches Fire and resolves it only
+// once the value drops to Clear, so a value hovering around Fire does not
+// alert on every check. Clear defaults to 80% of Fire.
+type Threshold struct {
+	Fire  float64 `json:"fire"`
+	Clear float64 `json:"clear,omitempty"`
+}
+
+func (t Threshold) clear() float64 {
+	if t.Clear > 0 {
+		return t.Clear
+	}
+	return t.Fire * 0.8
+}
+
+type AlerterConfig struct {
+	// Interval between checks. Defaults to 30 seconds.
+	Interval time.Duration
+	// QueueDepth maps a queue to the threshold on its pending tasks.
+	QueueDepth map[string]Threshold
+	// FailureRate maps a task type to the threshold on its failure rate,
+	// between 0 and 1. Requires a store.
+	FailureRate map[string]Threshold
+	// Window is how far back failure rates look. Defaults to five minutes.
+	Window time.Duration
+	// MinSamples is the fewest finished records in Window for a failure
+	// rate to be evaluated. Defaults to 10.
+	MinSamples int
+	// OnAlert is called for every alert fired or resolved, and again on
+	// the next check while the webhook fails to deliver it.
+	OnAlert func(Alert)
+	// WebhookURL, if set, receives every alert as a JSON POST.
+	WebhookURL string
+	// HTTPClient sends webhooks. Defaults to a client with a 10 second
+	// timeout.
+	HTTPClient *http.Client
+	// Leader, if set, limits checks to the elected replica.
+	Leader Leader
+}
+
+// Alerter watches queue depths through asynq and failure rates through
+// Aggregate, and notifies when thresholds are crossed.
+type Alerter struct {
+	inspector *asynq.Inspector
+	store     Store
+	cfg       AlerterConfig
+
+	mu     sync.Mutex
+	firing map[AlertKind]map[string]bool
+}
+
+// NewAlerter creates an Alerter. store may be nil without FailureRate
+// thresholds.
+func NewAlerter(redisOpt asynq.RedisConnOpt, store Store, cfg AlerterConfig) *Alerter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 5 * time.Minute
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = 10
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Alerter{
+		inspector: newInspector(redisOpt),
+		store:     store,
+		cfg:       cfg,
+		firing:    map[AlertKind]map[string]bool{AlertQueueDepth: {}, AlertFailureRate: {}},
+	}
+}
+
+// Run checks every Interval until ctx is cancelled.
+func (a *Alerter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = a.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce checks every threshold once, or nothing unless a leads.
+func (a *Alerter) RunOnce(ctx context.Context) error {
+	if !leads(a.cfg.Leader) {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	if len(a.cfg.QueueDepth) > 0 {
+		errs = append(errs, a.checkQueues(ctx))
+	}
+	if len(a.cfg.FailureRate) > 0 {
+		errs = append(errs, a.checkFailureRates(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Alerter) checkQueues(ctx context.Context) error {
+	queues, err := a.inspector.Queues()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for queue, t := range a.cfg.QueueDepth {
+		depth := 0
+		// GetQueueInfo does not fail for unknown queues.
+		if slices.Contains(queues, queue) {
+			info, err := a.inspector.GetQueueInfo(queue)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			depth = info.Pending
+		}
+		errs = append(errs, a.evaluate(ctx, AlertQueueDepth, queue, float64(depth), t))
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Alerter) checkFailureRates(ctx context.Context) error {
+	if a.store == nil {
+		return errors.New("asyncx: Alerter FailureRate thresholds need a store")
+	}
+	rows, err := Aggregate(ctx, a.store, []AggregateField{GroupByType, GroupByStatus}, a.cfg.Window)
+	if err != nil {
+		return err
+	}
+	failed, finished := map[string]int{}, map[string]int{}
+	for _, r := range rows {
+		switch r.Status {
+		case StatusFailed, StatusTimedOut:
+			failed[r.Type] += r.Count
+			finished[r.Type] += r.Count
+		case StatusCompleted:
+			finished[r.Type] += r.Count
+		}
+	}
+	var errs []error
+	for taskType, t := range a.cfg.FailureRate {
+		if failed[taskType] > 0 {
+			// Failed attempts asynq will retry have not finished yet.
+			f := TaskFilter{Type: taskType, Status: StatusFailed, RetryScheduled: true}
+			if a.cfg.Window > 0 {
+				f.CreatedAfter = time.Now().Add(-a.cfg.Window).UTC()
+			}
+			stats, err := CountByStatus(ctx, a.store, f)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			failed[taskType] -= stats[StatusFailed]
+			finished[taskType] -= stats[StatusFailed]
+		}
+		if finished[taskType] < a.cfg.MinSamples {
+			continue
+		}
+		rate := float64(failed[taskType]) / float64(finished[taskType])
+		errs = append(errs, a.evaluate(ctx, AlertFailureRate, taskType, rate, t))
+	}
+	return errors.Join(errs...)
+}
+
+// evaluate fires or resolves the alert for subject when value crosses t.
+// The state only changes once the notification is delivered, so a failed
+// webhook is retried on the next check.
+func (a *Alerter) evaluate(ctx context.Context, kind AlertKind, subject string, value float64, t Threshold) error {
+	firing := a.firing[kind][subject]
+	switch {
+	case !firing && value >= t.Fire:
+		firing = true
+	case firing && value <= t.clear():
+		firing = false
+	default:
+		return nil
+	}
+	if err := a.notify(ctx, Alert{Kind: kind, Subject: subject, Value: value, Threshold: t, Firing: firing, At: time.Now().UTC()}); err != nil {
+		return err
+	}
+	a.firing[kind][subject] = firing
+	return nil
+}
+
+func (a *Alerter) notify(ctx context.Context, alert Alert) error {
+	if a.cfg.OnAlert != nil {
+		a.cfg.OnAlert(alert)
+	}
+	if a.cfg.WebhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("asyncx: alert webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Close closes the Alerter's Redis connection.
+func (a *Alerter) Close() error {
+	return a.inspector.Close()
+}
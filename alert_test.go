@@ -0,0 +1,175 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestAlerter_QueueDepthWithHysteresis(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, nil, ClientOptions{})
+	defer client.Close()
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+
+	var mu sync.Mutex
+	var hooked []Alert
+	var posted []Alert
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a Alert
+		_ = json.NewDecoder(r.Body).Decode(&a)
+		mu.Lock()
+		posted = append(posted, a)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	alerter := NewAlerter(redis, nil, AlerterConfig{
+		QueueDepth: map[string]Threshold{"mail": {Fire: 3, Clear: 1}},
+		OnAlert:    func(a Alert) { hooked = append(hooked, a) },
+		WebhookURL: hook.URL,
+	})
+	defer alerter.Close()
+
+	var ids []string
+	setDepth := func(n int) {
+		t.Helper()
+		for len(ids) < n {
+			info, err := client.Enqueue(ctx, "mail:send", nil, asynq.Queue("mail"))
+			if err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+			ids = append(ids, info.ID)
+		}
+		for len(ids) > n {
+			if err := inspector.DeleteTask("mail", ids[0]); err != nil {
+				t.Fatalf("DeleteTask: %v", err)
+			}
+			ids = ids[1:]
+		}
+		if err := alerter.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+
+	for _, depth := range []int{2, 3, 4, 2, 3, 1, 2} {
+		setDepth(depth)
+	}
+	// Fires at 3, stays firing at 2 (above Clear) and 3, resolves at 1.
+	if len(hooked) != 2 || !hooked[0].Firing || hooked[0].Value != 3 || hooked[1].Firing || hooked[1].Value != 1 {
+		t.Fatalf("unexpected alerts %+v", hooked)
+	}
+	if hooked[0].Kind != AlertQueueDepth || hooked[0].Subject != "mail" {
+		t.Fatalf("unexpected alert %+v", hooked[0])
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posted) != 2 || posted[0] != hooked[0] {
+		t.Fatalf("webhook got %+v", posted)
+	}
+}
+
+func TestAlerter_FailureRate(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+	var alerts []Alert
+	alerter := NewAlerter(asynq.RedisClientOpt{Addr: s.Addr()}, store, AlerterConfig{
+		FailureRate: map[string]Threshold{"charge:card": {Fire: 0.5}},
+		MinSamples:  4,
+		OnAlert:     func(a Alert) { alerts = append(alerts, a) },
+	})
+	defer alerter.Close()
+
+	n := 0
+	finish := func(fail bool) {
+		n++
+		id := fmt.Sprintf("charge-%d", n)
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "charge:card", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		if fail {
+			_ = store.MarkFailed(ctx, id, "declined", time.Now())
+		} else {
+			_ = store.MarkCompleted(ctx, id, nil, time.Now())
+		}
+	}
+	finish(true)
+	finish(true)
+	finish(false)
+	// Failed attempts that asynq will retry are not counted.
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("charge-retry-%d", i)
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "charge:card", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		_ = store.MarkRetry(ctx, id, "declined", time.Now().Add(time.Minute))
+	}
+	if err := alerter.RunOnce(ctx); err != nil || len(alerts) != 0 {
+		t.Fatalf("too few samples must not alert: %+v %v", alerts, err)
+	}
+	finish(false)
+	if err := alerter.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(alerts) != 1 || !alerts[0].Firing || alerts[0].Value != 0.5 || alerts[0].Kind != AlertFailureRate {
+		t.Fatalf("unexpected alerts %+v", alerts)
+	}
+}
+
+func TestAlerter_ResendsAfterWebhookFailure(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, nil, ClientOptions{})
+	defer client.Close()
+
+	var mu sync.Mutex
+	calls := 0
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer hook.Close()
+	alerter := NewAlerter(redis, nil, AlerterConfig{
+		QueueDepth: map[string]Threshold{"mail": {Fire: 1}},
+		WebhookURL: hook.URL,
+	})
+	defer alerter.Close()
+	if _, err := client.Enqueue(ctx, "mail:send", nil, asynq.Queue("mail")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if err := alerter.RunOnce(ctx); err == nil {
+		t.Fatal("want the webhook error")
+	}
+	for range 2 {
+		if err := alerter.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Resent once after the failure, then left alone while firing.
+	if calls != 2 {
+		t.Fatalf("webhook called %d times", calls)
+	}
+}
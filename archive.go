@@ -0,0 +1,74 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// Archive is a read-only view of records that were moved out of the live
+// store, typically an index over archived objects in S3 or similar. Any Store
+// that implements ListStore satisfies it. GetByID must return ErrNotFound for unknown IDs.
+type Archive interface {
+	GetByID(ctx context.Context, taskID string) (*TaskRecord, error)
+	List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error)
+}
+
+// ArchivedStore reads through to an Archive so that old tasks can be looked up
+// without a manual restore. Writes go to the live store only.
+type ArchivedStore struct {
+	Store
+	archive Archive
+}
+
+// WithArchive wraps live so that GetByID and List also consult archive.
+func WithArchive(live Store, archive Archive) *ArchivedStore {
+	return &ArchivedStore{Store: live, archive: archive}
+}
+
+// Unwrap returns the live store.
+func (s *ArchivedStore) Unwrap() Store {
+	return s.Store
+}
+
+// GetByID returns the live record, falling back to the archive when the live
+// store does not know taskID.
+func (s *ArchivedStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	rec, err := s.Store.GetByID(ctx, taskID)
+	if errors.Is(err, ErrNotFound) {
+		return s.archive.GetByID(ctx, taskID)
+	}
+	return rec, err
+}
+
+// List merges live and archived records in creation order. A record present
+// in both is reported once, from the live store. The archive is skipped when
+// the live store alone satisfies f.Limit.
+func (s *ArchivedStore) List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error) {
+	live, err := listRecords(ctx, s.Store, f)
+	if err != nil {
+		return nil, err
+	}
+	if f.Limit > 0 && len(live) >= f.Limit {
+		return live, nil
+	}
+	archived, err := s.archive.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(live))
+	for _, rec := range live {
+		seen[rec.ID] = true
+	}
+	out := live
+	for _, rec := range archived {
+		if !seen[rec.ID] {
+			out = append(out, rec)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchivedStore_ReadThrough(t *testing.T) {
+	live, err := NewBoltStore(filepath.Join(t.TempDir(), "live.db"), BoltStoreOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer live.Close()
+	archive, err := NewBoltStore(filepath.Join(t.TempDir(), "archive.db"), BoltStoreOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer archive.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"old-1", "old-2"} {
+		if err := archive.InsertCreated(ctx, TaskRecord{ID: id, Type: "report:build", Queue: "default"}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	store := WithArchive(live, archive)
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "new-1", Type: "report:build", Queue: "default"}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+
+	if rec, err := store.GetByID(ctx, "old-1"); err != nil || rec.ID != "old-1" {
+		t.Fatalf("GetByID from archive: rec=%#v err=%v", rec, err)
+	}
+	if _, err := store.GetByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	all, err := store.List(ctx, TaskFilter{Type: "report:build"})
+	if err != nil || len(all) != 3 || all[0].ID != "old-1" || all[2].ID != "new-1" {
+		t.Fatalf("List should merge in creation order: %v %v", all, err)
+	}
+	if recs, _ := store.List(ctx, TaskFilter{Limit: 1}); len(recs) != 1 || recs[0].ID != "new-1" {
+		t.Fatalf("live results satisfying Limit should skip the archive: %v", recs)
+	}
+	if _, err := live.GetByID(ctx, "old-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("read-through must not copy records into the live store")
+	}
+}
@@ -0,0 +1,100 @@
+package asyncx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BlobStore keeps large payloads and results outside Redis and the
+// database, typically in S3 or GCS; implement it over the object store's
+// SDK. asyncx chooses the keys, which may contain slashes. DeleteBlob of a
+// missing key must succeed.
+type BlobStore interface {
+	PutBlob(ctx context.Context, key string, data []byte) error
+	GetBlob(ctx context.Context, key string) ([]byte, error)
+	DeleteBlob(ctx context.Context, key string) error
+}
+
+// DirBlobStore is a BlobStore over a directory, for tests and for volumes
+// shared by every Client and Processor.
+type DirBlobStore struct {
+	Dir string
+}
+
+func (s DirBlobStore) PutBlob(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (s DirBlobStore) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(key)))
+}
+
+func (s DirBlobStore) DeleteBlob(ctx context.Context, key string) error {
+	err := os.Remove(filepath.Join(s.Dir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// blobRef is the stand-in for a payload kept in a BlobStore.
+func blobRef(key string) []byte {
+	return append(append([]byte(nil), payloadRef...), "blob:"+key...)
+}
+
+// blobKey returns the key of the blob payload stands in for.
+func blobKey(payload []byte) (string, bool) {
+	key, ok := bytes.CutPrefix(payload, blobRef(""))
+	return string(key), ok
+}
+
+// discardBlob deletes the blob prepare uploaded for a task that was not
+// enqueued after all.
+func (c *Client) discardBlob(ctx context.Context, onRedis []byte) {
+	if key, ok := blobKey(onRedis); ok {
+		_ = c.blobs.DeleteBlob(ctx, key)
+	}
+}
+
+// blobResult is the result_json stand-in for a result kept in a BlobStore.
+type blobResult struct {
+	Blob string `json:"$blob"`
+}
+
+// offloadResult moves a result over ProcessorConfig.BlobThreshold to the
+// BlobStore and returns its stand-in. The result stays inline if the upload
+// fails.
+func (p *Processor) offloadResult(ctx context.Context, id string, result *string) *string {
+	if p.blobs == nil || result == nil || len(*result) <= p.blobThreshold {
+		return result
+	}
+	key := "results/" + id
+	if err := p.blobs.PutBlob(ctx, key, []byte(*result)); err != nil {
+		return result
+	}
+	b, _ := json.Marshal(blobResult{Blob: key})
+	s := string(b)
+	return &s
+}
+
+// resolveResult returns the result a blobResult stands for, or result.
+func resolveResult(ctx context.Context, blobs BlobStore, result json.RawMessage) (json.RawMessage, error) {
+	if blobs == nil || !strings.HasPrefix(string(result), `{"$blob":`) {
+		return result, nil
+	}
+	var ref blobResult
+	if err := json.Unmarshal(result, &ref); err != nil || ref.Blob == "" {
+		return result, nil
+	}
+	return blobs.GetBlob(ctx, ref.Blob)
+}
@@ -0,0 +1,129 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestBlobStore_OffloadsPayloadsAndResults(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	blobs := DirBlobStore{Dir: t.TempDir()}
+	client := NewClient(redis, store, ClientOptions{Blobs: blobs, BlobThreshold: 64})
+	defer client.Close()
+
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, Blobs: blobs, BlobThreshold: 64})
+	defer processor.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error {
+		var rows []string
+		if err := Decode(ctx, tsk, &rows); err != nil {
+			return err
+		}
+		return SetResult(ctx, map[string][]string{"rows": rows})
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	rows := make([]string, 100)
+	for i := range rows {
+		rows[i] = strings.Repeat("r", 10)
+	}
+	info, err := client.Enqueue(ctx, "report:build", rows)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	raw, err := client.WaitForResult(ctx, info.ID, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForResult: %v", err)
+	}
+	var result map[string][]string
+	if err := json.Unmarshal(raw, &result); err != nil || len(result["rows"]) != 100 {
+		t.Fatalf("result %s: %v", raw, err)
+	}
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !strings.HasPrefix(rec.PayloadJSON, "asyncx:payload-ref:blob:payloads/") {
+		t.Fatalf("record should hold the blob key, got %.40q", rec.PayloadJSON)
+	}
+	if rec.ResultJSON == nil || !strings.Contains(*rec.ResultJSON, `"$blob":"results/`+info.ID) {
+		t.Fatalf("result_json should reference the blob, got %v", rec.ResultJSON)
+	}
+}
+
+func TestBlobStore_ReenqueuesAndDeletesBlobs(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	blobs := DirBlobStore{Dir: t.TempDir()}
+	client := NewClient(redis, store, ClientOptions{Blobs: blobs, BlobThreshold: 64})
+	defer client.Close()
+
+	info, err := client.Enqueue(ctx, "report:build", strings.Repeat("r", 100))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	key, ok := blobKey([]byte(rec.PayloadJSON))
+	if !ok {
+		t.Fatalf("record should hold the blob key, got %.40q", rec.PayloadJSON)
+	}
+
+	// Re-enqueued tasks keep the reference instead of wrapping it again.
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+	if err := inspector.DeleteTask(info.Queue, info.ID); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if err := client.reenqueue(ctx, rec); err != nil {
+		t.Fatalf("reenqueue: %v", err)
+	}
+	ti, err := inspector.GetTaskInfo(info.Queue, info.ID)
+	if err != nil || string(ti.Payload) != rec.PayloadJSON {
+		t.Fatalf("re-enqueued payload %.60q, want %.60q (%v)", ti.Payload, rec.PayloadJSON, err)
+	}
+
+	// Purging the payload deletes its blob.
+	if err := store.MarkCompleted(ctx, info.ID, nil, time.Now()); err != nil {
+		t.Fatalf("MarkCompleted: %v", err)
+	}
+	if err := store.MarkStatus(ctx, info.ID, StatusCompleted, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkStatus: %v", err)
+	}
+	j := NewJanitor(store, JanitorConfig{PayloadRetention: PayloadRetention{Completed: time.Minute}, Blobs: blobs})
+	if err := j.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if _, err := blobs.GetBlob(ctx, key); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("blob %s should be deleted, got %v", key, err)
+	}
+}
+
+func TestNewClient_BlobsRequirePositiveThreshold(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewClient should panic on Blobs without a BlobThreshold")
+		}
+	}()
+	NewClient(asynq.RedisClientOpt{Addr: "localhost:0"}, nil, ClientOptions{Blobs: DirBlobStore{Dir: t.TempDir()}})
+}
@@ -0,0 +1,341 @@
+package asyncx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltTasksBucket = []byte("asyncx_tasks")
+	boltIndexBucket = []byte("asyncx_index")
+)
+
+type BoltStoreOptions struct {
+	// Retention, if positive, is used by Purge to delete finished records
+	// older than this.
+	Retention time.Duration
+}
+
+// BoltStore implements Store on an embedded bbolt file for single-binary
+// deployments. Records are stored as JSON; listing uses prefix scans over
+// index keys of the form "<index>\x00<value>\x00<created_at>\x00<id>".
+type BoltStore struct {
+	db   *bolt.DB
+	opts BoltStoreOptions
+}
+
+// NewBoltStore opens (or creates) the bbolt file at path.
+func NewBoltStore(path string, opts BoltStoreOptions) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(boltTasksBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(boltIndexBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db, opts: opts}, nil
+}
+
+func (s *BoltStore) Close() error { return s.db.Close() }
+
+// indexKeys returns every index entry for rec. The created_at component is
+// zero-padded nanoseconds so that byte order equals time order.
+func indexKeys(rec *TaskRecord) [][]byte {
+	ts := fmt.Sprintf("%020d", rec.CreatedAt.UnixNano())
+	key := func(index, value string) []byte {
+		return []byte(index + "\x00" + value + "\x00" + ts + "\x00" + rec.ID)
+	}
+	return [][]byte{
+		key("all", ""),
+		key("status", string(rec.Status)),
+		key("type", rec.Type),
+		key("queue", rec.Queue),
+	}
+}
+
+func (s *BoltStore) put(tx *bolt.Tx, old, rec *TaskRecord) error {
+	idx := tx.Bucket(boltIndexBucket)
+	if old != nil {
+		for _, k := range indexKeys(old) {
+			if err := idx.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range indexKeys(rec) {
+		if err := idx.Put(k, nil); err != nil {
+			return err
+		}
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltTasksBucket).Put([]byte(rec.ID), b)
+}
+
+func getBolt(tx *bolt.Tx, id string) (*TaskRecord, error) {
+	b := tx.Bucket(boltTasksBucket).Get([]byte(id))
+	if b == nil {
+		return nil, nil
+	}
+	var rec TaskRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (s *BoltStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
+	rec.Status = insertStatus(&rec)
+	rec.CreatedAt = time.Now().UTC()
+	return s.db.Update(func(tx *bolt.Tx) error {
+		old, err := getBolt(tx, rec.ID)
+		if err != nil {
+			return err
+		}
+		return s.put(tx, old, &rec)
+	})
+}
+
+// update applies fn to an existing record. Unknown IDs are ignored, matching
+// SQL UPDATE semantics.
+func (s *BoltStore) update(taskID string, fn func(rec *TaskRecord)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		old, err := getBolt(tx, taskID)
+		if err != nil || old == nil {
+			return err
+		}
+		rec := *old
+		now := time.Now().UTC()
+		rec.UpdatedAt = &now
+		fn(&rec)
+		return s.put(tx, old, &rec)
+	})
+}
+
+func (s *BoltStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		if slices.Contains(unenqueuedStatuses, rec.Status) {
+			rec.Status = StatusCreated
+		}
+		rec.Queue = queue
+		rec.EnqueuedAt = enqueuedAt.UTC()
+	})
+}
+
+func (s *BoltStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := startedAt.UTC()
+		rec.Status = StatusInProgress
+		rec.StartedAt = &t
+		rec.NextRetryAt = nil
+		rec.FailureKind = ""
+		w := workerFromContext(ctx)
+		rec.WorkerID, rec.Hostname, rec.PID = w.ID, w.Host, w.PID
+	})
+}
+
+func (s *BoltStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := finishedAt.UTC()
+		rec.Status = StatusCompleted
+		rec.ResultJSON = resultJSON
+		rec.FinishedAt = &t
+	})
+}
+
+func (s *BoltStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.markFailed(taskID, errorMsg, FailureTransient, finishedAt)
+}
+
+func (s *BoltStore) MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.markFailed(taskID, errorMsg, FailurePermanent, finishedAt)
+}
+
+func (s *BoltStore) markFailed(taskID string, errorMsg string, kind FailureKind, finishedAt time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := finishedAt.UTC()
+		rec.Status = StatusFailed
+		rec.ErrorMsg = &errorMsg
+		rec.FailureKind = kind
+		rec.FinishedAt = &t
+	})
+}
+
+func (s *BoltStore) MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := nextRetryAt.UTC()
+		rec.Status = StatusFailed
+		rec.ErrorMsg = &errorMsg
+		rec.FailureKind = FailureTransient
+		rec.NextRetryAt = &t
+	})
+}
+
+func (s *BoltStore) MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := at.UTC()
+		msg := timeoutMsg(timeout)
+		rec.Status = StatusTimedOut
+		rec.ErrorMsg = &msg
+		rec.TimeoutMS = timeout.Milliseconds()
+		rec.FinishedAt = &t
+		rec.UpdatedAt = &t
+	})
+}
+
+func (s *BoltStore) AddRuntime(ctx context.Context, taskID string, d time.Duration) (time.Duration, error) {
+	var total int64
+	err := s.update(taskID, func(rec *TaskRecord) {
+		rec.RuntimeMS += d.Milliseconds()
+		total = rec.RuntimeMS
+	})
+	return time.Duration(total) * time.Millisecond, err
+}
+
+func (s *BoltStore) MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) error {
+	return s.markFailed(taskID, budgetMsg(budget), FailureBudgetExhausted, finishedAt)
+}
+
+func (s *BoltStore) SetErrorDetails(ctx context.Context, taskID string, details string) error {
+	return s.update(taskID, func(rec *TaskRecord) { rec.ErrorDetails = &details })
+}
+
+func (s *BoltStore) Heartbeat(ctx context.Context, taskID string, at time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := at.UTC()
+		rec.LastHeartbeatAt = &t
+	})
+}
+
+func (s *BoltStore) MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := at.UTC()
+		rec.Status = status
+		rec.UpdatedAt = &t
+	})
+}
+
+// Ping fails once the database has been closed.
+func (s *BoltStore) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+func (s *BoltStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	var rec *TaskRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rec, err = getBolt(tx, taskID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return nil, ErrNotFound
+	}
+	return rec, nil
+}
+
+// List scans the most selective index prefix for f in creation order.
+func (s *BoltStore) List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error) {
+	prefix := "all\x00\x00"
+	switch {
+	case f.Status != "":
+		prefix = "status\x00" + string(f.Status) + "\x00"
+	case f.Type != "":
+		prefix = "type\x00" + f.Type + "\x00"
+	case f.Queue != "":
+		prefix = "queue\x00" + f.Queue + "\x00"
+	}
+	var out []*TaskRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return s.scan(tx, []byte(prefix), f.CreatedAfter, f.CreatedBefore, func(rec *TaskRecord) bool {
+			if f.matches(rec) {
+				out = append(out, rec)
+			}
+			return f.Limit <= 0 || len(out) < f.Limit
+		})
+	})
+	return out, err
+}
+
+// scan calls fn for every record under prefix created in [after, before),
+// stopping when fn returns false.
+func (s *BoltStore) scan(tx *bolt.Tx, prefix []byte, after, before time.Time, fn func(rec *TaskRecord) bool) error {
+	c := tx.Bucket(boltIndexBucket).Cursor()
+	start := prefix
+	if !after.IsZero() {
+		start = append(append([]byte{}, prefix...), fmt.Sprintf("%020d", after.UnixNano())...)
+	}
+	var end []byte
+	if !before.IsZero() {
+		end = append(append([]byte{}, prefix...), fmt.Sprintf("%020d", before.UnixNano())...)
+	}
+	for k, _ := c.Seek(start); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		if end != nil && bytes.Compare(k, end) >= 0 {
+			break
+		}
+		id := k[bytes.LastIndexByte(k, 0)+1:]
+		rec, err := getBolt(tx, string(id))
+		if err != nil {
+			return err
+		}
+		if rec != nil && !fn(rec) {
+			break
+		}
+	}
+	return nil
+}
+
+// Purge deletes finished records created more than Retention ago and returns
+// how many were removed. It is a no-op when Retention is not set.
+func (s *BoltStore) Purge(ctx context.Context) (int, error) {
+	if s.opts.Retention <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-s.opts.Retention)
+	n := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var victims []*TaskRecord
+		err := s.scan(tx, []byte("all\x00\x00"), time.Time{}, cutoff, func(rec *TaskRecord) bool {
+			if rec.FinishedAt != nil {
+				victims = append(victims, rec)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		idx := tx.Bucket(boltIndexBucket)
+		tasks := tx.Bucket(boltTasksBucket)
+		for _, rec := range victims {
+			for _, k := range indexKeys(rec) {
+				if err := idx.Delete(k); err != nil {
+					return err
+				}
+			}
+			if err := tasks.Delete([]byte(rec.ID)); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
@@ -0,0 +1,73 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBoltStore_LifecycleListPurge(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "asyncx.db"), BoltStoreOptions{Retention: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"b-1", "b-2", "b-3", "b-4"} {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "email:deliver", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	if err := store.MarkStarted(ctx, "b-1", time.Now()); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+	result := `{"ok":true}`
+	if err := store.MarkCompleted(ctx, "b-1", &result, time.Now()); err != nil {
+		t.Fatalf("MarkCompleted: %v", err)
+	}
+	if err := store.MarkFailed(ctx, "b-2", "boom", time.Now()); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if err := store.MarkTimedOut(ctx, "b-4", time.Second, time.Now()); err != nil {
+		t.Fatalf("MarkTimedOut: %v", err)
+	}
+
+	got, err := store.GetByID(ctx, "b-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Status != StatusCompleted || got.ResultJSON == nil || *got.ResultJSON != result {
+		t.Fatalf("unexpected record: %#v", got)
+	}
+	if got, _ := store.GetByID(ctx, "b-4"); got.Status != StatusTimedOut || got.FinishedAt == nil {
+		t.Fatalf("timed-out record should be finished: %#v", got)
+	}
+	if _, err := store.GetByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+
+	created, err := store.List(ctx, TaskFilter{Status: StatusCreated})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(created) != 1 || created[0].ID != "b-3" {
+		t.Fatalf("unexpected created records: %#v", created)
+	}
+	if all, _ := store.List(ctx, TaskFilter{Type: "email:deliver", Limit: 2}); len(all) != 2 || all[0].ID != "b-1" {
+		t.Fatalf("want first two records in creation order, got %#v", all)
+	}
+
+	n, err := store.Purge(ctx)
+	if err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("want 3 finished records purged, got %d", n)
+	}
+	if all, _ := store.List(ctx, TaskFilter{}); len(all) != 1 || all[0].ID != "b-3" {
+		t.Fatalf("only the unfinished record should remain, got %#v", all)
+	}
+}
@@ -0,0 +1,258 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// BreakerState is the state of a CircuitBreaker for one task type.
+type BreakerState string
+
+const (
+	// BreakerClosed lets tasks run.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen defers every task until the cool-down ends.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets one trial task run; its outcome closes or
+	// re-opens the breaker.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// BreakerPolicy opens a breaker after Failures consecutive failures, for
+// CoolDown (default one minute). A zero Failures disables the breaker.
+type BreakerPolicy struct {
+	Failures int
+	CoolDown time.Duration
+}
+
+// BreakerEvent records a state change of a CircuitBreaker.
+type BreakerEvent struct {
+	Type  string
+	State BreakerState
+	// Failures is the count of consecutive failures at the change.
+	Failures int
+	At       time.Time
+}
+
+// BreakerEventStore is implemented by stores that keep the state changes of
+// circuit breakers.
+type BreakerEventStore interface {
+	RecordBreakerEvent(ctx context.Context, e BreakerEvent) error
+	// BreakerEvents returns the events for taskType, newest first. A
+	// limit <= 0 returns all of them.
+	BreakerEvents(ctx context.Context, taskType string, limit int) ([]BreakerEvent, error)
+}
+
+type CircuitBreakerOptions struct {
+	// Policies maps a task type to its policy.
+	Policies map[string]BreakerPolicy
+	// Default applies to types absent from Policies. The zero value
+	// disables breaking.
+	Default BreakerPolicy
+	// Events, if set, records every state change.
+	Events BreakerEventStore
+}
+
+// CircuitBreaker stops running a task type whose handlers keep failing, e.g.
+// because a downstream API is down: after a policy's consecutive failures,
+// tasks of the type are put back with a delay instead of running, until the
+// cool-down ends and a trial task succeeds. Deferred tasks do not use up
+// their retries. Failures marked NonRetryable do not count. State is kept
+// per process. It is safe for concurrent use.
+type CircuitBreaker struct {
+	opts   CircuitBreakerOptions
+	mu     sync.Mutex
+	states map[string]*breaker
+}
+
+type breaker struct {
+	state    BreakerState
+	failures int
+	until    time.Time // end of the cool-down while open
+	trial    time.Time // start of the running half-open trial, if any
+}
+
+func NewCircuitBreaker(opts CircuitBreakerOptions) *CircuitBreaker {
+	return &CircuitBreaker{opts: opts, states: make(map[string]*breaker)}
+}
+
+// CircuitOpenError is returned for a task whose type's breaker is open. The
+// Processor retries it after RetryAfter without counting it as a failure.
+type CircuitOpenError struct {
+	Type       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open: type=%s retry_after=%s", e.Type, e.RetryAfter)
+}
+
+func isCircuitOpen(err error) bool {
+	var ce *CircuitOpenError
+	return errors.As(err, &ce)
+}
+
+func (b *CircuitBreaker) policy(taskType string) BreakerPolicy {
+	p, ok := b.opts.Policies[taskType]
+	if !ok {
+		p = b.opts.Default
+	}
+	if p.CoolDown <= 0 {
+		p.CoolDown = time.Minute
+	}
+	return p
+}
+
+// State returns the state of the breaker for taskType.
+func (b *CircuitBreaker) State(taskType string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.states[taskType]; ok && s.state != "" {
+		return s.state
+	}
+	return BreakerClosed
+}
+
+// allow returns a *CircuitOpenError unless a task of taskType may run. A
+// task allowed as the half-open trial gets its start time as trial, to be
+// passed back to done.
+func (b *CircuitBreaker) allow(ctx context.Context, taskType string) (trial time.Time, err error) {
+	if b.policy(taskType).Failures <= 0 {
+		return time.Time{}, nil
+	}
+	b.mu.Lock()
+	s := b.get(taskType)
+	now := time.Now()
+	var changed *BreakerEvent
+	switch s.state {
+	case BreakerOpen:
+		if now.Before(s.until) {
+			b.mu.Unlock()
+			return time.Time{}, &CircuitOpenError{Type: taskType, RetryAfter: max(s.until.Sub(now), time.Second)}
+		}
+		s.state, s.trial = BreakerHalfOpen, now
+		changed = &BreakerEvent{Type: taskType, State: BreakerHalfOpen, Failures: s.failures, At: now.UTC()}
+	case BreakerHalfOpen:
+		// A trial whose outcome never arrived, e.g. because the task was
+		// suppressed, is given up after a cool-down.
+		if !s.trial.IsZero() && now.Sub(s.trial) < b.policy(taskType).CoolDown {
+			b.mu.Unlock()
+			return time.Time{}, &CircuitOpenError{Type: taskType, RetryAfter: time.Second}
+		}
+		s.trial = now
+	}
+	trial = s.trial
+	b.mu.Unlock()
+	b.record(ctx, changed)
+	return trial, nil
+}
+
+// done records the outcome of a task allowed to run, with the trial allow
+// returned for it. Only the current half-open trial closes or re-opens the
+// breaker; tasks that started before it opened do not count once it has.
+func (b *CircuitBreaker) done(ctx context.Context, taskType string, trial time.Time, failed bool) {
+	p := b.policy(taskType)
+	if p.Failures <= 0 {
+		return
+	}
+	b.mu.Lock()
+	s := b.get(taskType)
+	now := time.Now()
+	var changed *BreakerEvent
+	isTrial := s.state == BreakerHalfOpen && !trial.IsZero() && trial.Equal(s.trial)
+	switch {
+	case isTrial && !failed:
+		s.state, s.failures, s.trial = BreakerClosed, 0, time.Time{}
+		changed = &BreakerEvent{Type: taskType, State: BreakerClosed, At: now.UTC()}
+	case isTrial:
+		s.failures++
+		s.state, s.until, s.trial = BreakerOpen, now.Add(p.CoolDown), time.Time{}
+		changed = &BreakerEvent{Type: taskType, State: BreakerOpen, Failures: s.failures, At: now.UTC()}
+	case s.state != BreakerClosed:
+	case !failed:
+		s.failures = 0
+	default:
+		s.failures++
+		if s.failures >= p.Failures {
+			s.state, s.until = BreakerOpen, now.Add(p.CoolDown)
+			changed = &BreakerEvent{Type: taskType, State: BreakerOpen, Failures: s.failures, At: now.UTC()}
+		}
+	}
+	b.mu.Unlock()
+	b.record(ctx, changed)
+}
+
+func (b *CircuitBreaker) get(taskType string) *breaker {
+	s, ok := b.states[taskType]
+	if !ok {
+		s = &breaker{state: BreakerClosed}
+		b.states[taskType] = s
+	}
+	return s
+}
+
+func (b *CircuitBreaker) record(ctx context.Context, e *BreakerEvent) {
+	if e != nil && b.opts.Events != nil {
+		_ = b.opts.Events.RecordBreakerEvent(context.WithoutCancel(ctx), *e)
+	}
+}
+
+// breakerFailure reports whether err counts as a failure for the breaker.
+func breakerFailure(err error) bool {
+	return err != nil && !IsNonRetryable(err) && !isThrottled(err) && !isDeferred(err) && !isCircuitOpen(err) && !isDependencyDown(err)
+}
+
+// Middleware defers tasks whose breaker is open with a *CircuitOpenError and
+// feeds handler outcomes to the breaker. ProcessorConfig.CircuitBreaker
+// does the same and also records deferred tasks as StatusDeferred; use one
+// or the other.
+func (b *CircuitBreaker) Middleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		trial, err := b.allow(ctx, t.Type())
+		if err != nil {
+			return err
+		}
+		err = next.ProcessTask(ctx, t)
+		b.done(ctx, t.Type(), trial, breakerFailure(err))
+		return err
+	})
+}
+
+func (s *SQLStore) RecordBreakerEvent(ctx context.Context, e BreakerEvent) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `INSERT INTO asyncx_breaker_events (type, state, failures, created_at) VALUES (?, ?, ?, ?)`
+	qpg := `INSERT INTO asyncx_breaker_events (type, state, failures, created_at) VALUES ($1, $2, $3, $4)`
+	return s.exec(ctx, q, qpg, e.Type, string(e.State), e.Failures, e.At.UTC())
+}
+
+func (s *SQLStore) BreakerEvents(ctx context.Context, taskType string, limit int) ([]BreakerEvent, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	q := `SELECT type, state, failures, created_at FROM asyncx_breaker_events WHERE type = ? ORDER BY created_at DESC`
+	args := []any{taskType}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	var out []BreakerEvent
+	err := s.queryRows(ctx, q, args, func(rows *sql.Rows) error {
+		var e BreakerEvent
+		var state string
+		if err := rows.Scan(&e.Type, &state, &e.Failures, &e.At); err != nil {
+			return err
+		}
+		e.State = BreakerState(state)
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
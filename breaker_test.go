@@ -0,0 +1,110 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_breaker_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	b := NewCircuitBreaker(CircuitBreakerOptions{
+		Policies: map[string]BreakerPolicy{"email:send": {Failures: 2, CoolDown: 50 * time.Millisecond}},
+		Events:   store,
+	})
+
+	fail := true
+	calls := 0
+	h := b.Middleware(asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		calls++
+		time.Sleep(2 * time.Millisecond)
+		if fail {
+			return errors.New("smtp down")
+		}
+		return nil
+	}))
+	task := asynq.NewTask("email:send", nil)
+	for range 2 {
+		_ = h.ProcessTask(ctx, task)
+	}
+	if b.State("email:send") != BreakerOpen {
+		t.Fatalf("state %s after two failures", b.State("email:send"))
+	}
+	err = h.ProcessTask(ctx, task)
+	var ce *CircuitOpenError
+	if !errors.As(err, &ce) || ce.RetryAfter <= 0 || calls != 2 {
+		t.Fatalf("open breaker: err %v, %d calls", err, calls)
+	}
+	if got := retryDelay(1, err, task); got != ce.RetryAfter {
+		t.Fatalf("retry delay %v, want %v", got, ce.RetryAfter)
+	}
+	if err := h.ProcessTask(ctx, asynq.NewTask("report:build", nil)); err == nil || isCircuitOpen(err) {
+		t.Fatalf("other types are not affected, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	fail = false
+	if err := h.ProcessTask(ctx, task); err != nil || calls != 4 {
+		t.Fatalf("trial task: %v, %d calls", err, calls)
+	}
+	if b.State("email:send") != BreakerClosed {
+		t.Fatalf("state %s after a successful trial", b.State("email:send"))
+	}
+
+	events, err := store.BreakerEvents(ctx, "email:send", 0)
+	if err != nil {
+		t.Fatalf("BreakerEvents: %v", err)
+	}
+	var states []BreakerState
+	for _, e := range events {
+		states = append(states, e.State)
+	}
+	if len(states) != 3 || states[0] != BreakerClosed || states[1] != BreakerHalfOpen || states[2] != BreakerOpen || events[2].Failures != 2 {
+		t.Fatalf("events %+v", events)
+	}
+}
+
+func TestCircuitBreaker_OnlyTrialCloses(t *testing.T) {
+	ctx := context.Background()
+	b := NewCircuitBreaker(CircuitBreakerOptions{
+		Policies: map[string]BreakerPolicy{"email:send": {Failures: 1, CoolDown: 20 * time.Millisecond}},
+	})
+	straggler, err := b.allow(ctx, "email:send")
+	if err != nil || !straggler.IsZero() {
+		t.Fatalf("closed breaker: trial %v, err %v", straggler, err)
+	}
+	failing, _ := b.allow(ctx, "email:send")
+	b.done(ctx, "email:send", failing, true)
+	// A task that started before the breaker opened does not close it.
+	b.done(ctx, "email:send", straggler, false)
+	if b.State("email:send") != BreakerOpen {
+		t.Fatalf("state %s after a straggler succeeded", b.State("email:send"))
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	trial, err := b.allow(ctx, "email:send")
+	if err != nil || trial.IsZero() {
+		t.Fatalf("half-open breaker: trial %v, err %v", trial, err)
+	}
+	b.done(ctx, "email:send", straggler, false)
+	if b.State("email:send") != BreakerHalfOpen {
+		t.Fatalf("state %s before the trial finished", b.State("email:send"))
+	}
+	b.done(ctx, "email:send", trial, false)
+	if b.State("email:send") != BreakerClosed {
+		t.Fatalf("state %s after a successful trial", b.State("email:send"))
+	}
+}
@@ -0,0 +1,63 @@
+package asyncx
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+// Broker puts tasks on a message broker for a Client; *asynq.Client, the
+// default, satisfies it. See ClientOptions.Broker.
+type Broker interface {
+	EnqueueContext(ctx context.Context, t *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error)
+	Close() error
+}
+
+// brokerTask is what asynq's server puts in a handler's context, for tasks
+// delivered by another broker.
+type brokerTask struct {
+	id       string
+	queue    string
+	retried  int
+	maxRetry int
+}
+
+type brokerTaskKey struct{}
+
+func withBrokerTask(ctx context.Context, bt brokerTask) context.Context {
+	return context.WithValue(ctx, brokerTaskKey{}, bt)
+}
+
+// getTaskID is asynq.GetTaskID for tasks from any broker. So are the
+// other getters below.
+func getTaskID(ctx context.Context) (string, bool) {
+	if id, ok := asynq.GetTaskID(ctx); ok {
+		return id, true
+	}
+	bt, ok := ctx.Value(brokerTaskKey{}).(brokerTask)
+	return bt.id, ok
+}
+
+func getQueueName(ctx context.Context) (string, bool) {
+	if q, ok := asynq.GetQueueName(ctx); ok {
+		return q, true
+	}
+	bt, ok := ctx.Value(brokerTaskKey{}).(brokerTask)
+	return bt.queue, ok
+}
+
+func getRetryCount(ctx context.Context) (int, bool) {
+	if n, ok := asynq.GetRetryCount(ctx); ok {
+		return n, true
+	}
+	bt, ok := ctx.Value(brokerTaskKey{}).(brokerTask)
+	return bt.retried, ok
+}
+
+func getMaxRetry(ctx context.Context) (int, bool) {
+	if n, ok := asynq.GetMaxRetry(ctx); ok {
+		return n, true
+	}
+	bt, ok := ctx.Value(brokerTaskKey{}).(brokerTask)
+	return bt.maxRetry, ok
+}
@@ -0,0 +1,59 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_RuntimeBudget(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{
+		Concurrency:    1,
+		RuntimeBudgets: map[string]time.Duration{"budget:slow": 20 * time.Millisecond},
+	})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("budget:slow", func(ctx context.Context, tsk *asynq.Task) error {
+		time.Sleep(50 * time.Millisecond)
+		return errors.New("upstream unavailable")
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+	info, err := client.Enqueue(ctx, "budget:slow", nil, asynq.MaxRetry(5))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	var rec *TaskRecord
+	if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+		rec, err = store.GetByID(ctx, info.ID)
+		return err == nil && rec.FailureKind == FailureBudgetExhausted, nil
+	}); err != nil {
+		t.Fatalf("task not marked budget_exhausted: %+v", rec)
+	}
+	if rec.Status != StatusFailed || rec.RuntimeMS < 50 || rec.FinishedAt == nil {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if rec.ErrorMsg == nil || *rec.ErrorMsg != budgetMsg(20*time.Millisecond) {
+		t.Fatalf("unexpected error_msg: %v", rec.ErrorMsg)
+	}
+	ti, err := processor.inspector.GetTaskInfo(DefaultQueue, info.ID)
+	if err != nil {
+		t.Fatalf("GetTaskInfo: %v", err)
+	}
+	if ti.State != asynq.TaskStateArchived {
+		t.Fatalf("exhausted task must not be retried, state %v", ti.State)
+	}
+}
@@ -0,0 +1,364 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrStoreClosed is returned by BufferedStore writes after Close.
+var ErrStoreClosed = errors.New("asyncx: buffered store closed")
+
+type BufferedStoreOptions struct {
+	// QueueSize bounds the number of buffered writes. Writers block, up to
+	// their context's deadline, while the queue is full. Defaults to 10000.
+	QueueSize int
+	// BatchSize is the most writes applied per flush. Defaults to 100.
+	BatchSize int
+	// FlushInterval is the longest a write waits before being flushed.
+	// Defaults to 100ms.
+	FlushInterval time.Duration
+	// OnError, if set, is called with every write the wrapped store rejects.
+	// The errors are also returned by the next Flush.
+	OnError func(error)
+}
+
+// BufferedStore is a write-behind Store: lifecycle writes return as soon as
+// they are queued and a background goroutine applies them to the wrapped
+// store in order, in batches. Consecutive InsertCreated calls go through
+// BatchStore.InsertCreatedBatch and other writes share one transaction when
+// the wrapped store supports it (SQLStore does). It trades durability lag
+// for throughput: buffered writes are lost if the process dies before they
+// are flushed, and write errors surface through Flush and OnError rather
+// than to the caller.
+//
+// GetByID and AddRuntime flush the task's pending writes first, so a task
+// always sees its own updates. List reads the wrapped store directly and may
+// lag. Optional interfaces of the wrapped store, such as AggregateStore or
+// QueueEventStore, are reached through Unwrap and bypass the buffer. Call
+// Close on shutdown to flush everything.
+type BufferedStore struct {
+	Store
+	opts BufferedStoreOptions
+	ops  chan bufferedOp
+
+	mu     sync.RWMutex // held for reading while sending to ops
+	closed bool
+	done   chan struct{}
+
+	stateMu sync.Mutex
+	errs    []error
+	pending map[string]int // queued writes per task ID
+}
+
+// bufferedOp is one queued write, or a flush marker when fn is nil.
+type bufferedOp struct {
+	ctx     context.Context
+	taskID  string
+	fn      func(ctx context.Context, st Store) error
+	rec     *TaskRecord // set for InsertCreated, which batches separately
+	flushed chan struct{}
+}
+
+// txStore is implemented by stores that can apply several writes in one
+// transaction, committed only if fn succeeds.
+type txStore interface {
+	applyTx(ctx context.Context, fn func(st Store) error) error
+}
+
+// NewBufferedStore wraps store and starts the flushing goroutine.
+func NewBufferedStore(store Store, opts BufferedStoreOptions) *BufferedStore {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 10000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 100 * time.Millisecond
+	}
+	s := &BufferedStore{Store: store, opts: opts, ops: make(chan bufferedOp, opts.QueueSize), done: make(chan struct{}), pending: map[string]int{}}
+	go s.loop()
+	return s
+}
+
+func (s *BufferedStore) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+	var batch []bufferedOp
+	for {
+		select {
+		case op, ok := <-s.ops:
+			if !ok {
+				s.apply(batch)
+				return
+			}
+			if op.fn == nil {
+				s.apply(batch)
+				batch = batch[:0]
+				close(op.flushed)
+				continue
+			}
+			batch = append(batch, op)
+			if len(batch) >= s.opts.BatchSize {
+				s.apply(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.apply(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// apply writes batch to the wrapped store in runs of InsertCreated calls and
+// of other writes. A run the store cannot apply at once, or that fails as a
+// whole, is replayed one write at a time so that each error is attributed
+// to its own write.
+func (s *BufferedStore) apply(batch []bufferedOp) {
+	for len(batch) > 0 {
+		n := 1
+		for n < len(batch) && (batch[n].rec == nil) == (batch[0].rec == nil) {
+			n++
+		}
+		run := batch[:n]
+		batch = batch[n:]
+		if n > 1 && s.applyRun(run) == nil {
+			for _, op := range run {
+				s.finish(op, nil)
+			}
+			continue
+		}
+		for _, op := range run {
+			s.finish(op, op.fn(op.ctx, s.Store))
+		}
+	}
+}
+
+// applyRun applies run at once, or fails with errors.ErrUnsupported if the
+// wrapped store cannot.
+func (s *BufferedStore) applyRun(run []bufferedOp) error {
+	ctx := run[0].ctx
+	if run[0].rec != nil {
+		bs, ok := s.Store.(BatchStore)
+		if !ok {
+			return errors.ErrUnsupported
+		}
+		recs := make([]TaskRecord, len(run))
+		for i, op := range run {
+			recs[i] = *op.rec
+		}
+		return bs.InsertCreatedBatch(ctx, recs)
+	}
+	ts, ok := s.Store.(txStore)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return ts.applyTx(ctx, func(st Store) error {
+		for _, op := range run {
+			if err := op.fn(op.ctx, st); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// finish records the outcome of op.
+func (s *BufferedStore) finish(op bufferedOp, err error) {
+	s.stateMu.Lock()
+	if err != nil {
+		s.errs = append(s.errs, err)
+	}
+	s.track(op.taskID, -1)
+	s.stateMu.Unlock()
+	if err != nil && s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
+
+// track adjusts the pending count of taskID. stateMu must be held.
+func (s *BufferedStore) track(taskID string, d int) {
+	if n := s.pending[taskID] + d; n > 0 {
+		s.pending[taskID] = n
+	} else {
+		delete(s.pending, taskID)
+	}
+}
+
+// send queues op, blocking while the queue is full.
+func (s *BufferedStore) send(ctx context.Context, op bufferedOp) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrStoreClosed
+	}
+	select {
+	case s.ops <- op:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *BufferedStore) write(ctx context.Context, taskID string, fn func(ctx context.Context, st Store) error) error {
+	return s.queue(ctx, bufferedOp{taskID: taskID, fn: fn})
+}
+
+func (s *BufferedStore) queue(ctx context.Context, op bufferedOp) error {
+	s.stateMu.Lock()
+	s.track(op.taskID, 1)
+	s.stateMu.Unlock()
+	// The write outlives the caller's context, so cancellation must not abort it.
+	op.ctx = context.WithoutCancel(ctx)
+	err := s.send(ctx, op)
+	if err != nil {
+		s.stateMu.Lock()
+		s.track(op.taskID, -1)
+		s.stateMu.Unlock()
+	}
+	return err
+}
+
+// drain blocks until every write queued before the call has been applied.
+func (s *BufferedStore) drain(ctx context.Context) error {
+	flushed := make(chan struct{})
+	if err := s.send(ctx, bufferedOp{flushed: flushed}); err != nil {
+		if errors.Is(err, ErrStoreClosed) {
+			// Close has already applied everything.
+			return nil
+		}
+		return err
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush blocks until every write queued before the call has been applied
+// and returns the errors of the writes applied since the previous Flush.
+func (s *BufferedStore) Flush(ctx context.Context) error {
+	if err := s.drain(ctx); err != nil {
+		return err
+	}
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	err := errors.Join(s.errs...)
+	s.errs = nil
+	return err
+}
+
+// Close flushes the queue and stops the background goroutine. Writes after
+// Close fail with ErrStoreClosed. It does not close the wrapped store.
+func (s *BufferedStore) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.ops)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return s.Flush(context.Background())
+}
+
+// flushTask drains the queue if taskID has writes pending.
+func (s *BufferedStore) flushTask(ctx context.Context, taskID string) error {
+	s.stateMu.Lock()
+	n := s.pending[taskID]
+	s.stateMu.Unlock()
+	if n == 0 {
+		return nil
+	}
+	return s.drain(ctx)
+}
+
+// Unwrap returns the wrapped store.
+func (s *BufferedStore) Unwrap() Store {
+	return s.Store
+}
+
+func (s *BufferedStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
+	// InsertCreatedBatch marks records with EnqueuedAt enqueued; InsertCreated
+	// does not.
+	batched := rec
+	batched.EnqueuedAt = time.Time{}
+	return s.queue(ctx, bufferedOp{taskID: rec.ID, rec: &batched, fn: func(ctx context.Context, st Store) error {
+		return st.InsertCreated(ctx, rec)
+	}})
+}
+
+func (s *BufferedStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return st.MarkEnqueued(ctx, taskID, queue, enqueuedAt) })
+}
+
+func (s *BufferedStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return st.MarkStarted(ctx, taskID, startedAt) })
+}
+
+func (s *BufferedStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error {
+		return st.MarkCompleted(ctx, taskID, resultJSON, finishedAt)
+	})
+}
+
+func (s *BufferedStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return st.MarkFailed(ctx, taskID, errorMsg, finishedAt) })
+}
+
+func (s *BufferedStore) MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error {
+		return markPermanentFailure(ctx, st, taskID, errorMsg, finishedAt)
+	})
+}
+
+func (s *BufferedStore) MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return markRetry(ctx, st, taskID, errorMsg, nextRetryAt) })
+}
+
+func (s *BufferedStore) MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return markTimedOut(ctx, st, taskID, timeout, at) })
+}
+
+func (s *BufferedStore) MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error {
+		return markBudgetExhausted(ctx, st, taskID, budget, finishedAt)
+	})
+}
+
+func (s *BufferedStore) SetErrorDetails(ctx context.Context, taskID string, details string) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return setErrorDetails(ctx, st, taskID, details) })
+}
+
+func (s *BufferedStore) Heartbeat(ctx context.Context, taskID string, at time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return heartbeat(ctx, st, taskID, at) })
+}
+
+func (s *BufferedStore) MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) error {
+	return s.write(ctx, taskID, func(ctx context.Context, st Store) error { return markStatus(ctx, st, taskID, status, at) })
+}
+
+// AddRuntime flushes the task's pending writes and runs synchronously, since
+// the caller needs the new total.
+func (s *BufferedStore) AddRuntime(ctx context.Context, taskID string, d time.Duration) (time.Duration, error) {
+	if err := s.flushTask(ctx, taskID); err != nil {
+		return 0, err
+	}
+	rs, ok := storeAs[RuntimeStore](s.Store)
+	if !ok {
+		return 0, nil
+	}
+	return rs.AddRuntime(ctx, taskID, d)
+}
+
+// GetByID flushes the task's pending writes before reading.
+func (s *BufferedStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	if err := s.flushTask(ctx, taskID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetByID(ctx, taskID)
+}
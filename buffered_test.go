@@ -0,0 +1,102 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBufferedStore_WriteBehind(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	inner := NewSQLStore(db)
+	store := NewBufferedStore(inner, BufferedStoreOptions{BatchSize: 10, FlushInterval: time.Hour})
+	ctx := context.Background()
+
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "buf-0", Type: "buf:task", Queue: "default", PayloadJSON: "{}"}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if err := store.MarkStarted(ctx, "buf-0", time.Now()); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+	// GetByID sees the task's own buffered writes.
+	rec, err := store.GetByID(ctx, "buf-0")
+	if err != nil || rec.Status != StatusInProgress {
+		t.Fatalf("GetByID: %+v %v", rec, err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: fmt.Sprintf("buf-%d", i), Type: "buf:task", Queue: "default", PayloadJSON: "{}"}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	// Below BatchSize and before FlushInterval nothing has been written yet.
+	if recs, _ := inner.List(ctx, TaskFilter{Type: "buf:task"}); len(recs) != 1 {
+		t.Fatalf("want 1 flushed record before Flush, got %d", len(recs))
+	}
+	if err := store.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if recs, _ := inner.List(ctx, TaskFilter{Type: "buf:task"}); len(recs) != 4 {
+		t.Fatalf("want 4 records after Flush, got %d", len(recs))
+	}
+
+	if err := store.MarkCompleted(ctx, "buf-1", nil, time.Now()); err != nil {
+		t.Fatalf("MarkCompleted: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if rec, _ := inner.GetByID(ctx, "buf-1"); rec == nil || rec.Status != StatusCompleted {
+		t.Fatalf("Close should flush pending writes, got %+v", rec)
+	}
+	if err := store.MarkStarted(ctx, "buf-2", time.Now()); !errors.Is(err, ErrStoreClosed) {
+		t.Fatalf("want ErrStoreClosed after Close, got %v", err)
+	}
+}
+
+func TestBufferedStore_BatchesAndAttributesErrors(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	inner := NewSQLStore(db, SQLStoreOptions{StrictTransitions: true})
+	var reported []error
+	store := NewBufferedStore(inner, BufferedStoreOptions{BatchSize: 100, FlushInterval: time.Hour, OnError: func(err error) { reported = append(reported, err) }})
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"bat-a", "bat-b", "bat-a"} {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "bat:task", Queue: "default", PayloadJSON: "{}"}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	now := time.Now()
+	_ = store.MarkStarted(ctx, "bat-b", now)
+	_ = store.MarkCompleted(ctx, "bat-b", nil, now)
+	_ = store.MarkStarted(ctx, "bat-b", now) // completed records cannot restart
+	_ = store.MarkStarted(ctx, "bat-a", now)
+	err := store.Flush(ctx)
+	var te *TransitionError
+	if err == nil || !errors.As(err, &te) || te.TaskID != "bat-b" {
+		t.Fatalf("want the duplicate insert and bat-b's TransitionError, got %v", err)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("want 2 errors reported to OnError, got %v", reported)
+	}
+	// The failed writes do not roll back the rest of their batch.
+	if rec, _ := inner.GetByID(ctx, "bat-a"); rec == nil || rec.Status != StatusInProgress {
+		t.Fatalf("bat-a: %+v", rec)
+	}
+	if rec, _ := inner.GetByID(ctx, "bat-b"); rec == nil || rec.Status != StatusCompleted {
+		t.Fatalf("bat-b: %+v", rec)
+	}
+
+	// Optional interfaces of the wrapped store are reachable.
+	if _, ok := storeAs[StatsStore](store); !ok {
+		t.Fatal("StatsStore should be reachable through Unwrap")
+	}
+	if stats, err := CountByStatus(ctx, store, TaskFilter{Type: "bat:task"}); err != nil || stats[StatusCompleted] != 1 {
+		t.Fatalf("CountByStatus: %v %v", stats, err)
+	}
+}
@@ -0,0 +1,143 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// BatchStore is implemented by stores that can insert many new records at
+// once. Client.EnqueueBatch uses it when available. The records carry their
+// EnqueuedAt, so no MarkEnqueued follows.
+type BatchStore interface {
+	InsertCreatedBatch(ctx context.Context, recs []TaskRecord) error
+}
+
+// CopyFunc bulk-loads rows into table with the Postgres COPY protocol and
+// returns the number of rows copied. It keeps SQLStore free of a driver
+// dependency; with pgx it is
+//
+//	func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+//		return conn.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+//	}
+type CopyFunc func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error)
+
+// InsertCreatedBatch inserts recs with SQLStoreOptions.Copy when set, and
+// otherwise with one prepared INSERT per record in a single transaction,
+// together with their history.
+func (s *SQLStore) InsertCreatedBatch(ctx context.Context, recs []TaskRecord) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	if len(recs) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	rows := make([][]any, len(recs))
+	for i, rec := range recs {
+		var enqueuedAt *time.Time
+		// Fire-and-forget records are not marked enqueued; see Client.
+		if rec.Class != ClassFireAndForget && !rec.EnqueuedAt.IsZero() {
+			t := rec.EnqueuedAt.UTC()
+			enqueuedAt = &t
+		}
+		rows[i] = s.insertArgs(rec, now, enqueuedAt)
+	}
+	if s.copy != nil {
+		n, err := s.copy(ctx, "asyncx_tasks", insertColumns, rows)
+		if err == nil && n != int64(len(rows)) {
+			err = fmt.Errorf("asyncx: copied %d of %d records", n, len(rows))
+		}
+		if err != nil {
+			return err
+		}
+		// COPY runs on its own connection, so the history follows in a
+		// transaction of its own.
+		return s.inTx(ctx, func(s *SQLStore) error { return s.recordBatch(ctx, recs, now) })
+	}
+	return s.inTx(ctx, func(s *SQLStore) error {
+		for _, row := range rows {
+			if err := s.exec(ctx, insertSQL, dollarPlaceholders(insertSQL), row...); err != nil {
+				return err
+			}
+		}
+		return s.recordBatch(ctx, recs, now)
+	})
+}
+
+// recordBatch records the creation of recs in the task history.
+func (s *SQLStore) recordBatch(ctx context.Context, recs []TaskRecord, now time.Time) error {
+	for _, rec := range recs {
+		if err := s.recordTransition(ctx, rec.ID, insertStatus(&rec), "", now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchTask is one task of an EnqueueBatch call.
+type BatchTask struct {
+	Type    string
+	Payload any
+	Options []asynq.Option
+}
+
+// EnqueueBatch enqueues tasks like Enqueue, interceptors and content dedup
+// included, and persists their records together, through BatchStore when
+// the store implements it. Tasks that must be persisted before they are
+// enqueued, see ClientOptions.PersistBeforeEnqueue and WithDedupKey, are
+// written one by one. If a task fails to enqueue, the ones before it are
+// still persisted and their infos returned along with the error.
+func (c *Client) EnqueueBatch(ctx context.Context, tasks []BatchTask) ([]*asynq.TaskInfo, error) {
+	infos := make([]*asynq.TaskInfo, 0, len(tasks))
+	var recs []TaskRecord
+	var recInfos []*asynq.TaskInfo
+	enqueue := chainInterceptors(c.interceptors, func(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
+		// Records of this batch are not in the store yet, so sameContent
+		// cannot see them.
+		if c.dedupWindows[taskType] > 0 {
+			if b, err := encode(c.codec, payload); err == nil {
+				hash := contentHash(taskType, b)
+				for i := range recs {
+					if recs[i].ContentHash == hash {
+						return recInfos[i], nil
+					}
+				}
+			}
+		}
+		info, rec, err := c.submit(ctx, taskType, payload, options...)
+		if err != nil || rec == nil {
+			return info, err
+		}
+		recs, recInfos = append(recs, *rec), append(recInfos, info)
+		return info, nil
+	})
+	var enqueueErr error
+	for i, t := range tasks {
+		info, err := enqueue(ctx, t.Type, t.Payload, t.Options...)
+		if err != nil {
+			enqueueErr = fmt.Errorf("asyncx: batch task %d: %w", i, err)
+			break
+		}
+		infos = append(infos, info)
+	}
+	if c.store != nil {
+		if bs, ok := storeAs[BatchStore](c.store); ok {
+			if err := bs.InsertCreatedBatch(ctx, recs); err != nil {
+				return infos, errors.Join(enqueueErr, err)
+			}
+		} else {
+			for _, rec := range recs {
+				c.persist(ctx, rec)
+			}
+		}
+	}
+	for i, info := range recInfos {
+		b, _ := PayloadBytes(&recs[i])
+		c.notifyEnqueued(ctx, info, b)
+	}
+	return infos, enqueueErr
+}
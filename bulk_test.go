@@ -0,0 +1,124 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_EnqueueBatch(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	infos, err := client.EnqueueBatch(ctx, []BatchTask{
+		{Type: "fanout:item", Payload: map[string]int{"n": 1}},
+		{Type: "fanout:item", Payload: map[string]int{"n": 2}, Options: []asynq.Option{WithMetadata(map[string]string{"k": "v"})}},
+		{Type: "fanout:item", Payload: map[string]int{"n": 3}},
+	})
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("EnqueueBatch: %v %v", infos, err)
+	}
+	for _, info := range infos {
+		rec, err := store.GetByID(ctx, info.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if rec.Status != StatusCreated || rec.EnqueuedAt.IsZero() || rec.Queue != DefaultQueue {
+			t.Fatalf("unexpected record %+v", rec)
+		}
+	}
+	if rec, _ := store.GetByID(ctx, infos[1].ID); rec.Metadata["k"] != "v" {
+		t.Fatalf("options should apply per task, got %v", rec.Metadata)
+	}
+}
+
+func TestSQLStore_InsertCreatedBatch_Copy(t *testing.T) {
+	var gotTable string
+	var gotCols []string
+	var gotRows [][]any
+	copyFn := func(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+		gotTable, gotCols, gotRows = table, columns, rows
+		return int64(len(rows)), nil
+	}
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db, SQLStoreOptions{Copy: copyFn})
+	recs := []TaskRecord{{ID: "copy-1", Type: "t", Queue: "default", PayloadJSON: "{}"}, {ID: "copy-2", Type: "t", Queue: "default", PayloadJSON: "{}"}}
+	if err := store.InsertCreatedBatch(context.Background(), recs); err != nil {
+		t.Fatalf("InsertCreatedBatch: %v", err)
+	}
+	if gotTable != "asyncx_tasks" || len(gotRows) != 2 || len(gotRows[0]) != len(gotCols) || gotRows[1][0] != "copy-2" {
+		t.Fatalf("unexpected copy: %s %v %v", gotTable, gotCols, gotRows)
+	}
+}
+
+func TestClient_EnqueueBatchLikeEnqueue(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	var intercepted []string
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{
+		ContentDedupWindows: map[string]time.Duration{"fanout:item": time.Hour},
+		Interceptors: []ClientInterceptor{func(ctx context.Context, taskType string, payload any, options []asynq.Option, next EnqueueFunc) (*asynq.TaskInfo, error) {
+			intercepted = append(intercepted, taskType)
+			return next(ctx, taskType, payload, options...)
+		}},
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	first, err := client.Enqueue(ctx, "fanout:item", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	infos, err := client.EnqueueBatch(ctx, []BatchTask{
+		{Type: "fanout:item", Payload: map[string]int{"n": 1}},
+		{Type: "fanout:item", Payload: map[string]int{"n": 2}},
+		{Type: "fanout:item", Payload: map[string]int{"n": 2}},
+	})
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("EnqueueBatch: %v %v", infos, err)
+	}
+	if len(intercepted) != 4 {
+		t.Fatalf("interceptor should run per task, ran %d times", len(intercepted))
+	}
+	if infos[0].ID != first.ID || infos[2].ID != infos[1].ID || infos[1].ID == first.ID {
+		t.Fatalf("same content should return the existing task: %s %s %s %s", first.ID, infos[0].ID, infos[1].ID, infos[2].ID)
+	}
+	recs, err := store.List(ctx, TaskFilter{Type: "fanout:item"})
+	if err != nil || len(recs) != 2 {
+		t.Fatalf("want 2 records, got %d %v", len(recs), err)
+	}
+}
+
+func TestClient_EnqueueBatchPersistFirst(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{PersistBeforeEnqueue: true})
+	defer client.Close()
+	ctx := context.Background()
+
+	infos, err := client.EnqueueBatch(ctx, []BatchTask{{Type: "fanout:item", Payload: 1}, {Type: "fanout:item", Payload: 2}})
+	if err != nil || len(infos) != 2 {
+		t.Fatalf("EnqueueBatch: %v %v", infos, err)
+	}
+	for _, info := range infos {
+		rec, err := store.GetByID(ctx, info.ID)
+		if err != nil || rec.EnqueuedAt.IsZero() {
+			t.Fatalf("GetByID: %+v %v", rec, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package asyncx
+
+import (
+	"slices"
+	"time"
+)
+
+// Calendar decides which days are business days. Implementations see the
+// day in the location of the Schedule asking.
+type Calendar interface {
+	IsBusinessDay(day time.Time) bool
+}
+
+// HolidayCalendar is a Calendar of fixed weekend days plus a holiday list.
+// Define one per region, e.g. {Holidays: []string{"2025-12-25", "2025-12-26"}}.
+type HolidayCalendar struct {
+	// Weekend defaults to Saturday and Sunday.
+	Weekend []time.Weekday
+	// Holidays are dates formatted as "2006-01-02".
+	Holidays []string
+}
+
+func (c HolidayCalendar) IsBusinessDay(day time.Time) bool {
+	weekend := c.Weekend
+	if weekend == nil {
+		weekend = []time.Weekday{time.Saturday, time.Sunday}
+	}
+	if slices.Contains(weekend, day.Weekday()) {
+		return false
+	}
+	return !slices.Contains(c.Holidays, day.Format(time.DateOnly))
+}
+
+// BusinessDayOfMonth fires on the Nth business day of every month at
+// Hour:Minute in Location (UTC if nil). N = 1 is the first business day and
+// N = -1 the last. For example, the first business day at 09:00 in Berlin:
+//
+//	BusinessDayOfMonth{N: 1, Hour: 9, Location: berlin, Calendar: germany}
+type BusinessDayOfMonth struct {
+	N            int
+	Hour, Minute int
+	Location     *time.Location
+	Calendar     Calendar
+}
+
+func (s BusinessDayOfMonth) Next(after time.Time) time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	a := after.In(loc)
+	// Look a few years ahead so that sparse calendars still terminate.
+	for m := 0; m < 48; m++ {
+		first := time.Date(a.Year(), a.Month()+time.Month(m), 1, s.Hour, s.Minute, 0, 0, loc)
+		var days []time.Time
+		for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+			if s.Calendar == nil || s.Calendar.IsBusinessDay(d) {
+				days = append(days, d)
+			}
+		}
+		i := s.N - 1
+		if s.N < 0 {
+			i = len(days) + s.N
+		}
+		if s.N == 0 || i < 0 || i >= len(days) {
+			continue
+		}
+		if t := days[i]; t.After(after) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// OnBusinessDays restricts schedule to the business days of cal, skipping
+// occurrences that fall on other days.
+func OnBusinessDays(schedule Schedule, cal Calendar) Schedule {
+	return businessDays{schedule: schedule, cal: cal}
+}
+
+type businessDays struct {
+	schedule Schedule
+	cal      Calendar
+}
+
+func (b businessDays) Next(after time.Time) time.Time {
+	t := after
+	// Bounded so that a calendar without business days cannot loop forever.
+	for i := 0; i < 10000; i++ {
+		t = b.schedule.Next(t)
+		if t.IsZero() || b.cal.IsBusinessDay(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
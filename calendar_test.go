@@ -0,0 +1,46 @@
+package asyncx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessDayOfMonth_Next(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	germany := HolidayCalendar{Holidays: []string{"2025-01-01", "2025-12-25", "2025-12-26"}}
+	first := BusinessDayOfMonth{N: 1, Hour: 9, Location: berlin, Calendar: germany}
+
+	// Jan 1 2025 is a holiday, so the first business day is Thursday Jan 2.
+	got := first.Next(time.Date(2024, 12, 31, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2025, 1, 2, 9, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Fatalf("first business day: got %v, want %v", got, want)
+	}
+	// After that, February 2025 starts on a Saturday: Monday Feb 3.
+	got = first.Next(got)
+	if want := time.Date(2025, 2, 3, 9, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Fatalf("next month: got %v, want %v", got, want)
+	}
+
+	last := BusinessDayOfMonth{N: -1, Hour: 17, Location: berlin, Calendar: germany}
+	// Dec 31 2025 is a Wednesday and not a holiday in this calendar.
+	got = last.Next(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+	if want := time.Date(2025, 12, 31, 17, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Fatalf("last business day: got %v, want %v", got, want)
+	}
+}
+
+func TestOnBusinessDays_SkipsWeekendsAndHolidays(t *testing.T) {
+	daily, err := Cron("0 9 * * *")
+	if err != nil {
+		t.Fatalf("Cron: %v", err)
+	}
+	s := OnBusinessDays(daily, HolidayCalendar{Holidays: []string{"2025-01-06"}})
+	// Friday Jan 3 2025 after 09:00: skip the weekend and Monday's holiday.
+	got := s.Next(time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC))
+	if want := time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
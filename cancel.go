@@ -0,0 +1,74 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// CancelFilter selects the pending tasks Client.CancelWhere cancels. At
+// least one field must be set.
+type CancelFilter struct {
+	Type  string
+	Queue string
+	// Metadata selects tasks carrying all of these labels.
+	Metadata map[string]string
+}
+
+// pendingStatuses are the statuses of records whose task has not run to an
+// end yet. Failed records count too while asynq will retry them.
+var pendingStatuses = []Status{StatusCreated, StatusThrottled, StatusDeferred, StatusInterrupted, StatusHeld, StatusParked, StatusEnqueueFailed, StatusFailed}
+
+// CancelWhere deletes every pending task matching f from Redis and marks its
+// record StatusCanceled, e.g. to stop all emails to one tenant during an
+// incident. Tasks are found through their records, so a store is required.
+// Running tasks cannot be deleted and are reported in the error; the other
+// matches are canceled regardless. It returns how many tasks it canceled.
+func (c *Client) CancelWhere(ctx context.Context, f CancelFilter) (int, error) {
+	if c.store == nil {
+		return 0, errors.New("asyncx: CancelWhere requires a store")
+	}
+	if f.Type == "" && f.Queue == "" && len(f.Metadata) == 0 {
+		return 0, errors.New("asyncx: CancelWhere: empty filter")
+	}
+	canceled := 0
+	var errs []error
+	for _, status := range pendingStatuses {
+		recs, err := listRecords(ctx, c.store, TaskFilter{Status: status, Type: f.Type, Queue: f.Queue, Metadata: f.Metadata})
+		if err != nil {
+			return canceled, err
+		}
+		for _, rec := range recs {
+			if rec.Status == StatusFailed && rec.NextRetryAt == nil {
+				continue
+			}
+			ok, err := c.cancel(ctx, rec)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("asyncx: cancel %s: %w", rec.ID, err))
+			} else if ok {
+				canceled++
+			}
+		}
+	}
+	return canceled, errors.Join(errs...)
+}
+
+// cancel deletes the task of rec from Redis and marks rec canceled. It
+// reports false for a task that is gone from Redis, having finished in the
+// meantime, unless its record says it was never there.
+func (c *Client) cancel(ctx context.Context, rec *TaskRecord) (bool, error) {
+	err := c.inspector.DeleteTask(rec.Queue, rec.ID)
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		if rec.Status != StatusParked && rec.Status != StatusHeld && rec.Status != StatusEnqueueFailed {
+			return false, nil
+		}
+		err = nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, markStatus(ctx, c.store, rec.ID, StatusCanceled, time.Now().UTC())
+}
@@ -0,0 +1,81 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_CancelWhere(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	tenant := func(id string) asynq.Option { return WithMetadata(map[string]string{"tenant": id}) }
+	var doomed []string
+	for _, opts := range [][]asynq.Option{{tenant("x")}, {tenant("x"), asynq.ProcessIn(time.Hour)}} {
+		info, err := client.Enqueue(ctx, "email:send", nil, opts...)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		doomed = append(doomed, info.ID)
+	}
+	parked, err := client.EnqueueParked(ctx, "email:send", nil, tenant("x"))
+	if err != nil {
+		t.Fatalf("EnqueueParked: %v", err)
+	}
+	doomed = append(doomed, parked)
+	other, _ := client.Enqueue(ctx, "email:send", nil, tenant("y"))
+	report, _ := client.Enqueue(ctx, "report:build", nil, tenant("x"))
+	// Final records keep their status even while asynq still holds the task.
+	timedOut, _ := client.Enqueue(ctx, "email:send", nil, tenant("x"))
+	if err := store.MarkTimedOut(ctx, timedOut.ID, time.Second, time.Now()); err != nil {
+		t.Fatalf("MarkTimedOut: %v", err)
+	}
+	failed, _ := client.Enqueue(ctx, "email:send", nil, tenant("x"))
+	if err := store.MarkFailed(ctx, failed.ID, "boom", time.Now()); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+
+	if _, err := client.CancelWhere(ctx, CancelFilter{}); err == nil {
+		t.Fatal("an empty filter must be rejected")
+	}
+	n, err := client.CancelWhere(ctx, CancelFilter{Type: "email:send", Metadata: map[string]string{"tenant": "x"}})
+	if err != nil || n != 3 {
+		t.Fatalf("CancelWhere = %d, %v; want 3", n, err)
+	}
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+	for _, id := range doomed {
+		if rec, _ := store.GetByID(ctx, id); rec == nil || rec.Status != StatusCanceled {
+			t.Fatalf("record %s: %+v", id, rec)
+		}
+		if _, err := inspector.GetTaskInfo(DefaultQueue, id); err == nil {
+			t.Fatalf("task %s still on Redis", id)
+		}
+	}
+	for _, info := range []*asynq.TaskInfo{other, report} {
+		if rec, _ := store.GetByID(ctx, info.ID); rec == nil || rec.Status != StatusCreated {
+			t.Fatalf("unmatched record %s: %+v", info.ID, rec)
+		}
+		if _, err := inspector.GetTaskInfo(DefaultQueue, info.ID); err != nil {
+			t.Fatalf("unmatched task %s: %v", info.ID, err)
+		}
+	}
+	for id, status := range map[string]Status{timedOut.ID: StatusTimedOut, failed.ID: StatusFailed} {
+		if rec, _ := store.GetByID(ctx, id); rec == nil || rec.Status != status {
+			t.Fatalf("final record %s: %+v", id, rec)
+		}
+	}
+	if err := client.Release(ctx, parked); err == nil {
+		t.Fatal("a canceled parked task must not be released")
+	}
+}
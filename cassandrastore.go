@@ -0,0 +1,360 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CQLSession is the subset of a Cassandra/ScyllaDB driver used by
+// CassandraStore, so that asyncx does not depend on a specific driver.
+// A gocql session adapts in a few lines:
+//
+//	type gocqlSession struct{ s *gocql.Session }
+//
+//	func (g gocqlSession) Exec(ctx context.Context, stmt string, values ...any) error {
+//		return g.s.Query(stmt, values...).WithContext(ctx).Exec()
+//	}
+//
+//	func (g gocqlSession) Iter(ctx context.Context, stmt string, values ...any) asyncx.CQLIter {
+//		return g.s.Query(stmt, values...).WithContext(ctx).Iter()
+//	}
+type CQLSession interface {
+	Exec(ctx context.Context, stmt string, values ...any) error
+	Iter(ctx context.Context, stmt string, values ...any) CQLIter
+}
+
+// CQLIter iterates over query results; *gocql.Iter satisfies it.
+type CQLIter interface {
+	Scan(dest ...any) bool
+	Close() error
+}
+
+// CassandraSchema creates the tables used by CassandraStore:
+//   - asyncx_tasks holds the latest state of a task, keyed by ID.
+//   - asyncx_tasks_by_day is an append-only index partitioned by (day, type)
+//     and clustered by creation time for time-range scans.
+//   - asyncx_task_types_by_day lists the types seen each day so that listing
+//     without a type knows which partitions to read.
+var CassandraSchema = []string{
+	`CREATE TABLE IF NOT EXISTS asyncx_tasks (
+		id text PRIMARY KEY,
+		type text,
+		queue text,
+		payload_json text,
+		status text,
+		task_class text,
+		error_msg text,
+		result_json text,
+		created_at timestamp,
+		updated_at timestamp,
+		enqueued_at timestamp,
+		started_at timestamp,
+		finished_at timestamp,
+		last_heartbeat_at timestamp,
+		next_retry_at timestamp,
+		failure_kind text,
+		error_details text,
+		timeout_ms bigint,
+		request_json text,
+		priority text,
+		runtime_ms bigint,
+		metadata_json text,
+		created_by text,
+		source text,
+		dedup_key text,
+		workflow_traceparent text,
+		parent_task_id text,
+		worker_id text,
+		hostname text,
+		pid int,
+		content_type text,
+		payload_version int,
+		content_hash text,
+		shard text
+	)`,
+	`CREATE TABLE IF NOT EXISTS asyncx_tasks_by_day (
+		day text,
+		type text,
+		created_at timestamp,
+		id text,
+		PRIMARY KEY ((day, type), created_at, id)
+	) WITH CLUSTERING ORDER BY (created_at ASC, id ASC)`,
+	`CREATE TABLE IF NOT EXISTS asyncx_task_types_by_day (
+		day text,
+		type text,
+		PRIMARY KEY (day, type)
+	)`,
+}
+
+type CassandraStoreOptions struct {
+	// TTL, if positive, is applied to every write so old records expire
+	// without deletes (and tombstones) issued by a janitor.
+	TTL time.Duration
+	// ListWindow bounds List when TaskFilter.CreatedAfter is zero.
+	// Defaults to 7 days.
+	ListWindow time.Duration
+}
+
+// CassandraStore implements Store on Cassandra or ScyllaDB for very high write
+// volumes. Lifecycle updates are blind single-row upserts keyed by ID, with no
+// read-before-write or lightweight transactions; as a consequence, updates
+// to unknown IDs create partial rows instead of being ignored, and
+// MarkEnqueued always sets StatusCreated, so an enqueue recorded after a
+// worker started the task shows it as created until the worker's next mark.
+type CassandraStore struct {
+	session CQLSession
+	opts    CassandraStoreOptions
+}
+
+func NewCassandraStore(session CQLSession, opts CassandraStoreOptions) *CassandraStore {
+	if opts.ListWindow <= 0 {
+		opts.ListWindow = 7 * 24 * time.Hour
+	}
+	return &CassandraStore{session: session, opts: opts}
+}
+
+// CreateSchema applies CassandraSchema in the session's keyspace.
+func (s *CassandraStore) CreateSchema(ctx context.Context) error {
+	for _, stmt := range CassandraSchema {
+		if err := s.session.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cassandraDay(t time.Time) string { return t.UTC().Format("2006-01-02") }
+
+// using returns the USING TTL clause for writes, if configured.
+func (s *CassandraStore) using() string {
+	if s.opts.TTL <= 0 {
+		return ""
+	}
+	return " USING TTL " + strconv.Itoa(int(s.opts.TTL.Seconds()))
+}
+
+func (s *CassandraStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
+	now := time.Now().UTC()
+	day := cassandraDay(now)
+	err := s.session.Exec(ctx, `INSERT INTO asyncx_tasks (id, type, queue, payload_json, status, task_class, request_json, priority, metadata_json, created_by, source, dedup_key, workflow_traceparent, parent_task_id, content_type, payload_version, content_hash, shard, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`+s.using(),
+		rec.ID, rec.Type, rec.Queue, rec.PayloadJSON, string(insertStatus(&rec)), string(rec.Class), rec.RequestJSON, string(rec.Priority), encodeMetadata(rec.Metadata), rec.CreatedBy, rec.Source, rec.DedupKey, rec.WorkflowTraceparent, rec.ParentTaskID, rec.ContentType, rec.PayloadVersion, rec.ContentHash, rec.Shard, now)
+	if err != nil {
+		return err
+	}
+	if err := s.session.Exec(ctx, `INSERT INTO asyncx_tasks_by_day (day, type, created_at, id) VALUES (?, ?, ?, ?)`+s.using(),
+		day, rec.Type, now, rec.ID); err != nil {
+		return err
+	}
+	return s.session.Exec(ctx, `INSERT INTO asyncx_task_types_by_day (day, type) VALUES (?, ?)`+s.using(), day, rec.Type)
+}
+
+func (s *CassandraStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET status = ?, queue = ?, enqueued_at = ?, updated_at = ? WHERE id = ?`,
+		string(StatusCreated), queue, enqueuedAt.UTC(), time.Now().UTC(), taskID)
+}
+
+func (s *CassandraStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error {
+	w := workerFromContext(ctx)
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET status = ?, started_at = ?, next_retry_at = null, failure_kind = null, worker_id = ?, hostname = ?, pid = ?, updated_at = ? WHERE id = ?`,
+		string(StatusInProgress), startedAt.UTC(), w.ID, w.Host, w.PID, time.Now().UTC(), taskID)
+}
+
+func (s *CassandraStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET status = ?, result_json = ?, finished_at = ?, updated_at = ? WHERE id = ?`,
+		string(StatusCompleted), resultJSON, finishedAt.UTC(), time.Now().UTC(), taskID)
+}
+
+func (s *CassandraStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.markFailed(ctx, taskID, errorMsg, FailureTransient, finishedAt)
+}
+
+func (s *CassandraStore) MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.markFailed(ctx, taskID, errorMsg, FailurePermanent, finishedAt)
+}
+
+func (s *CassandraStore) markFailed(ctx context.Context, taskID string, errorMsg string, kind FailureKind, finishedAt time.Time) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET status = ?, error_msg = ?, failure_kind = ?, finished_at = ?, updated_at = ? WHERE id = ?`,
+		string(StatusFailed), errorMsg, string(kind), finishedAt.UTC(), time.Now().UTC(), taskID)
+}
+
+func (s *CassandraStore) MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET status = ?, error_msg = ?, failure_kind = ?, next_retry_at = ?, updated_at = ? WHERE id = ?`,
+		string(StatusFailed), errorMsg, string(FailureTransient), nextRetryAt.UTC(), time.Now().UTC(), taskID)
+}
+
+func (s *CassandraStore) MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET status = ?, error_msg = ?, timeout_ms = ?, finished_at = ?, updated_at = ? WHERE id = ?`,
+		string(StatusTimedOut), timeoutMsg(timeout), timeout.Milliseconds(), at.UTC(), at.UTC(), taskID)
+}
+
+// AddRuntime reads the current total before writing the new one. Unlike the
+// other updates it is not a blind write, and concurrent calls for one task
+// can lose an increment; the Processor makes at most one call per attempt.
+func (s *CassandraStore) AddRuntime(ctx context.Context, taskID string, d time.Duration) (time.Duration, error) {
+	iter := s.session.Iter(ctx, `SELECT runtime_ms FROM asyncx_tasks WHERE id = ?`, taskID)
+	var ms int64
+	found := iter.Scan(&ms)
+	if err := iter.Close(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, nil
+	}
+	ms += d.Milliseconds()
+	if err := s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET runtime_ms = ? WHERE id = ?`, ms, taskID); err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (s *CassandraStore) MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) error {
+	return s.markFailed(ctx, taskID, budgetMsg(budget), FailureBudgetExhausted, finishedAt)
+}
+
+func (s *CassandraStore) SetErrorDetails(ctx context.Context, taskID string, details string) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET error_details = ? WHERE id = ?`, details, taskID)
+}
+
+func (s *CassandraStore) Heartbeat(ctx context.Context, taskID string, at time.Time) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET last_heartbeat_at = ? WHERE id = ?`, at.UTC(), taskID)
+}
+
+func (s *CassandraStore) MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) error {
+	return s.session.Exec(ctx, `UPDATE asyncx_tasks`+s.using()+` SET status = ?, updated_at = ? WHERE id = ?`, string(status), at.UTC(), taskID)
+}
+
+const cassandraColumns = `id, type, queue, payload_json, status, task_class, error_msg, result_json, created_at, updated_at, enqueued_at, started_at, finished_at, last_heartbeat_at, next_retry_at, failure_kind, error_details, timeout_ms, request_json, priority, runtime_ms, metadata_json, created_by, source, dedup_key, workflow_traceparent, parent_task_id, worker_id, hostname, pid, content_type, payload_version, content_hash, shard`
+
+func (s *CassandraStore) Ping(ctx context.Context) error {
+	return s.session.Iter(ctx, `SELECT release_version FROM system.local`).Close()
+}
+
+func (s *CassandraStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	iter := s.session.Iter(ctx, `SELECT `+cassandraColumns+` FROM asyncx_tasks WHERE id = ?`, taskID)
+	rec, ok := scanCassandra(iter)
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return rec, nil
+}
+
+// scanCassandra reads one row. Nulls scan as zero values, which are mapped
+// back to nil pointers.
+func scanCassandra(iter CQLIter) (*TaskRecord, bool) {
+	var rec TaskRecord
+	var status, class, errorMsg, resultJSON, failureKind, errorDetails, requestJSON, priority, metadataJSON, workflowTP string
+	var updatedAt, startedAt, finishedAt, heartbeatAt, nextRetryAt time.Time
+	if !iter.Scan(&rec.ID, &rec.Type, &rec.Queue, &rec.PayloadJSON, &status, &class, &errorMsg, &resultJSON,
+		&rec.CreatedAt, &updatedAt, &rec.EnqueuedAt, &startedAt, &finishedAt, &heartbeatAt, &nextRetryAt, &failureKind, &errorDetails, &rec.TimeoutMS, &requestJSON, &priority, &rec.RuntimeMS, &metadataJSON, &rec.CreatedBy, &rec.Source, &rec.DedupKey, &workflowTP, &rec.ParentTaskID, &rec.WorkerID, &rec.Hostname, &rec.PID, &rec.ContentType, &rec.PayloadVersion, &rec.ContentHash, &rec.Shard) {
+		return nil, false
+	}
+	rec.Status = Status(status)
+	rec.Class = TaskClass(class)
+	rec.FailureKind = FailureKind(failureKind)
+	rec.Priority = Priority(priority)
+	rec.Metadata = decodeMetadata(metadataJSON)
+	str := func(v string) *string {
+		if v == "" {
+			return nil
+		}
+		return &v
+	}
+	ts := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+		return &t
+	}
+	rec.ErrorMsg = str(errorMsg)
+	rec.ErrorDetails = str(errorDetails)
+	rec.ResultJSON = str(resultJSON)
+	rec.RequestJSON = str(requestJSON)
+	rec.WorkflowTraceparent = str(workflowTP)
+	rec.UpdatedAt = ts(updatedAt)
+	rec.StartedAt = ts(startedAt)
+	rec.FinishedAt = ts(finishedAt)
+	rec.LastHeartbeatAt = ts(heartbeatAt)
+	rec.NextRetryAt = ts(nextRetryAt)
+	return &rec, true
+}
+
+// List reads the (day, type) partitions covering f's creation window, then
+// loads and filters each record. Without CreatedAfter the window is the last
+// ListWindow. Results are ordered by creation time.
+func (s *CassandraStore) List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error) {
+	end := f.CreatedBefore
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	start := f.CreatedAfter
+	if start.IsZero() {
+		start = end.Add(-s.opts.ListWindow)
+	}
+	var out []*TaskRecord
+	for _, day := range cassandraDays(start, end) {
+		types := []string{f.Type}
+		if f.Type == "" {
+			var err error
+			if types, err = s.typesOn(ctx, day); err != nil {
+				return nil, err
+			}
+		}
+		var dayRecs []*TaskRecord
+		for _, typ := range types {
+			iter := s.session.Iter(ctx, `SELECT id FROM asyncx_tasks_by_day WHERE day = ? AND type = ? AND created_at >= ? AND created_at < ?`,
+				day, typ, start.UTC(), end.UTC())
+			var ids []string
+			var id string
+			for iter.Scan(&id) {
+				ids = append(ids, id)
+			}
+			if err := iter.Close(); err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				rec, err := s.GetByID(ctx, id)
+				if errors.Is(err, ErrNotFound) {
+					continue // expired
+				}
+				if err != nil {
+					return nil, err
+				}
+				if f.matches(rec) {
+					dayRecs = append(dayRecs, rec)
+				}
+			}
+		}
+		sort.SliceStable(dayRecs, func(i, j int) bool { return dayRecs[i].CreatedAt.Before(dayRecs[j].CreatedAt) })
+		out = append(out, dayRecs...)
+		if f.Limit > 0 && len(out) >= f.Limit {
+			return out[:f.Limit], nil
+		}
+	}
+	return out, nil
+}
+
+func (s *CassandraStore) typesOn(ctx context.Context, day string) ([]string, error) {
+	iter := s.session.Iter(ctx, `SELECT type FROM asyncx_task_types_by_day WHERE day = ?`, day)
+	var types []string
+	var typ string
+	for iter.Scan(&typ) {
+		types = append(types, typ)
+	}
+	return types, iter.Close()
+}
+
+// cassandraDays returns the day partitions overlapping [start, end).
+func cassandraDays(start, end time.Time) []string {
+	var days []string
+	d := time.Date(start.UTC().Year(), start.UTC().Month(), start.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	for ; d.Before(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, cassandraDay(d))
+	}
+	return days
+}
@@ -0,0 +1,113 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCQL records statements and returns no rows.
+type fakeCQL struct {
+	stmts []string
+	args  [][]any
+}
+
+func (f *fakeCQL) Exec(ctx context.Context, stmt string, values ...any) error {
+	f.stmts = append(f.stmts, stmt)
+	f.args = append(f.args, values)
+	return nil
+}
+
+func (f *fakeCQL) Iter(ctx context.Context, stmt string, values ...any) CQLIter {
+	f.stmts = append(f.stmts, stmt)
+	f.args = append(f.args, values)
+	return emptyIter{}
+}
+
+type emptyIter struct{}
+
+func (emptyIter) Scan(dest ...any) bool { return false }
+func (emptyIter) Close() error          { return nil }
+
+func TestCassandraStore_InsertPartitionsByDayAndType(t *testing.T) {
+	sess := &fakeCQL{}
+	store := NewCassandraStore(sess, CassandraStoreOptions{TTL: 48 * time.Hour})
+	ctx := context.Background()
+
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "c-1", Type: "email:deliver", Queue: "default", PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if len(sess.stmts) != 3 {
+		t.Fatalf("want 3 writes, got %d", len(sess.stmts))
+	}
+	for _, stmt := range sess.stmts {
+		if !strings.Contains(stmt, "USING TTL 172800") {
+			t.Fatalf("write without TTL: %s", stmt)
+		}
+	}
+	byDay := sess.args[1]
+	if byDay[0] != cassandraDay(time.Now()) || byDay[1] != "email:deliver" {
+		t.Fatalf("unexpected partition key: %v", byDay[:2])
+	}
+
+	if _, err := store.GetByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+}
+
+func TestCassandraStore_MarkEnqueuedIsBlindWrite(t *testing.T) {
+	sess := &fakeCQL{}
+	store := NewCassandraStore(sess, CassandraStoreOptions{})
+
+	if err := store.MarkEnqueued(context.Background(), "c-1", "critical", time.Now()); err != nil {
+		t.Fatalf("MarkEnqueued: %v", err)
+	}
+	if len(sess.stmts) != 1 || strings.Contains(sess.stmts[0], " IF ") {
+		t.Fatalf("want one unconditional write, got %v", sess.stmts)
+	}
+	if sess.args[0][0] != string(StatusCreated) {
+		t.Fatalf("want status %s, got %v", StatusCreated, sess.args[0][0])
+	}
+}
+
+func TestCassandraStore_MarkTimedOutSetsFinishedAt(t *testing.T) {
+	sess := &fakeCQL{}
+	store := NewCassandraStore(sess, CassandraStoreOptions{})
+	at := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+
+	if err := store.MarkTimedOut(context.Background(), "c-1", time.Second, at); err != nil {
+		t.Fatalf("MarkTimedOut: %v", err)
+	}
+	if len(sess.stmts) != 1 || !strings.Contains(sess.stmts[0], "finished_at = ?") {
+		t.Fatalf("want finished_at written, got %v", sess.stmts)
+	}
+	if args := sess.args[0]; args[3] != at {
+		t.Fatalf("want finished_at %v, got %v", at, args[3])
+	}
+}
+
+func TestCassandraStore_ListReadsEachDayPartition(t *testing.T) {
+	sess := &fakeCQL{}
+	store := NewCassandraStore(sess, CassandraStoreOptions{})
+	start := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 3, 1, 0, 0, 0, time.UTC)
+
+	if _, err := store.List(context.Background(), TaskFilter{Type: "email:deliver", CreatedAfter: start, CreatedBefore: end}); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var days []any
+	for _, a := range sess.args {
+		days = append(days, a[0])
+	}
+	want := []any{"2026-03-01", "2026-03-02", "2026-03-03"}
+	if len(days) != len(want) {
+		t.Fatalf("want partitions %v, got %v", want, days)
+	}
+	for i := range want {
+		if days[i] != want[i] {
+			t.Fatalf("want partitions %v, got %v", want, days)
+		}
+	}
+}
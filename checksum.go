@@ -0,0 +1,47 @@
+package asyncx
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrChecksumMismatch is returned when a record read from an SQLStore with
+// a ChecksumKey no longer matches the checksum written at creation, i.e.
+// its id, type, payload or created_at were changed outside asyncx.
+var ErrChecksumMismatch = errors.New("asyncx: task record checksum mismatch")
+
+// recordChecksum is the hex HMAC-SHA256 of the immutable fields. created_at
+// is taken in whole seconds; insertArgs writes it truncated to the second,
+// since MySQL DATETIME rounds fractional seconds.
+func recordChecksum(key []byte, id, taskType, payload string, createdAt time.Time) string {
+	mac := hmac.New(sha256.New, key)
+	for _, f := range []string{id, taskType, payload, strconv.FormatInt(createdAt.Unix(), 10)} {
+		// Length-prefix each field so that boundaries cannot be shifted.
+		fmt.Fprintf(mac, "%d:%s", len(f), f)
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks rec against its checksum. Records created before
+// ChecksumSince may carry none and are then not verified.
+func (s *SQLStore) verify(rec *TaskRecord) error {
+	if len(s.checksumKey) == 0 {
+		return nil
+	}
+	if rec.Checksum == "" {
+		if rec.CreatedAt.Before(s.checkSince) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s has no checksum", ErrChecksumMismatch, rec.ID)
+	}
+	want := recordChecksum(s.checksumKey, rec.ID, rec.Type, rec.PayloadJSON, rec.CreatedAt)
+	if !hmac.Equal([]byte(want), []byte(rec.Checksum)) {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, rec.ID)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package asyncx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+type parentOption string
+
+func (o parentOption) String() string         { return fmt.Sprintf("Parent(%q)", string(o)) }
+func (o parentOption) Type() asynq.OptionType { return asyncxOpt }
+func (o parentOption) Value() interface{}     { return string(o) }
+
+// EnqueueChild enqueues a task spawned by the task parentID and records the
+// relationship in parent_task_id. In a handler, pass the running task's ID
+// from asynq.GetTaskID so that sub-tasks form a tree for ListChildren.
+func (c *Client) EnqueueChild(ctx context.Context, parentID, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
+	return c.Enqueue(ctx, taskType, payload, append(options, parentOption(parentID))...)
+}
+
+// ListChildren returns the records of the tasks enqueued with EnqueueChild
+// under parentID, in creation order.
+func ListChildren(ctx context.Context, store Store, parentID string) ([]*TaskRecord, error) {
+	return listRecords(ctx, store, TaskFilter{ParentID: parentID})
+}
@@ -0,0 +1,56 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestEnqueueChild_ListChildren(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	redisStore := NewRedisStore(redis, RedisStoreOptions{})
+	defer redisStore.Close()
+	ctx := context.Background()
+
+	for name, store := range map[string]Store{"sql": NewSQLStore(db), "redis": redisStore} {
+		client := NewClient(redis, store, ClientOptions{})
+		parent, err := client.Enqueue(ctx, "report:build", nil)
+		if err != nil {
+			t.Fatalf("%s: Enqueue: %v", name, err)
+		}
+		var childIDs []string
+		for _, part := range []string{"a", "b"} {
+			child, err := client.EnqueueChild(ctx, parent.ID, "report:part", map[string]string{"part": part})
+			if err != nil {
+				t.Fatalf("%s: EnqueueChild: %v", name, err)
+			}
+			childIDs = append(childIDs, child.ID)
+		}
+		if _, err := client.EnqueueChild(ctx, childIDs[0], "report:page", nil); err != nil {
+			t.Fatalf("%s: EnqueueChild: %v", name, err)
+		}
+		client.Close()
+
+		children, err := ListChildren(ctx, store, parent.ID)
+		if err != nil {
+			t.Fatalf("%s: ListChildren: %v", name, err)
+		}
+		if len(children) != 2 {
+			t.Fatalf("%s: want 2 children, got %d", name, len(children))
+		}
+		for _, c := range children {
+			if c.ParentTaskID != parent.ID || c.Type != "report:part" {
+				t.Errorf("%s: unexpected child %+v", name, c)
+			}
+		}
+		grandchildren, err := ListChildren(ctx, store, childIDs[0])
+		if err != nil || len(grandchildren) != 1 {
+			t.Fatalf("%s: want 1 grandchild, got %d, %v", name, len(grandchildren), err)
+		}
+	}
+}
@@ -1,72 +1,495 @@
 package asyncx
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 )
 
 // Client wraps asynq.Client and a Store to persist metadata.
 type Client struct {
-	client *asynq.Client
-	store  Store
-	queue  string
+	client        Broker
+	inspector     *asynq.Inspector
+	rdb           redis.UniversalClient // set with ResultNotifications
+	store         Store
+	queue         string
+	registry      *QueueRegistry
+	types         *TaskTypeRegistry
+	classes       map[string]TaskClass
+	hooks         Hooks
+	redaction     *RedactionPolicy
+	source        string
+	createdBy     string
+	renames       []QueueRename
+	codec         Codec
+	versions      map[string]int
+	maxPayload    int
+	offload       bool
+	blobs         BlobStore
+	blobThreshold int
+	ids           IDGenerator
+	persistFirst  bool
+	defaults      map[string]TaskDefaults
+	limiter       *RateLimiter
+	dedupWindows  map[string]time.Duration
+	limitWait     bool
+	router        *Router
+	shard         string
+	interceptors  []ClientInterceptor
+	enqueue       EnqueueFunc // doEnqueue wrapped by the interceptors
+
+	ping   PingPolicy
+	pinged atomic.Bool // the store answered checkStore
+	pingMu sync.Mutex  // serializes checkStore
 }
 
 type ClientOptions struct {
 	Queue string
+	// Registry, if set, restricts enqueues to registered queues.
+	// NewClient panics if Queue is not registered.
+	Registry *QueueRegistry
+	// TaskTypes, if set, restricts enqueues to registered, non-obsolete
+	// task types.
+	TaskTypes *TaskTypeRegistry
+	// Classes assigns a TaskClass per task type. Unlisted types are ClassStandard.
+	// WithClass overrides it for a single call.
+	Classes map[string]TaskClass
+	// Hooks, if set, is notified after every successful enqueue.
+	Hooks Hooks
+	// ResultNotifications makes WaitForResult listen for the Processor's
+	// pub/sub announcements (see ProcessorConfig.PublishResults) instead of
+	// relying on polling alone.
+	ResultNotifications bool
+	// Redaction, if set, scrubs payloads passed to Hooks.
+	Redaction *RedactionPolicy
+	// Interceptors wrap every Enqueue call, the first one outermost.
+	Interceptors []ClientInterceptor
+	// Source identifies the enqueueing service on every record. Defaults to
+	// "<program>@<hostname>".
+	Source string
+	// CreatedBy is recorded as the creator of tasks enqueued with no
+	// RequestInfo.Subject in the context, e.g. a service account name.
+	CreatedBy string
+	// StorePing configures the store check run before the first enqueue.
+	// While the store stays unreachable, enqueues fail with an error
+	// wrapping ErrStoreUnavailable and the next one checks again.
+	StorePing PingPolicy
+	// QueueRenames redirects enqueues for each rename's old queue, whether
+	// named by Queue or by an asynq.Queue option, to the new one.
+	QueueRenames []QueueRename
+	// Codec encodes payloads. Defaults to JSONCodec.
+	Codec Codec
+	// PayloadVersions records the payload schema version of each task type
+	// on its records, for ProcessorConfig.PayloadUpgrades. WithPayloadVersion
+	// overrides it per call.
+	PayloadVersions map[string]int
+	// MaxPayloadSize, if positive, is the largest encoded payload Enqueue
+	// puts on Redis. Larger ones fail with a *PayloadTooLargeError unless
+	// OffloadPayloads is set.
+	MaxPayloadSize int
+	// OffloadPayloads keeps payloads over MaxPayloadSize only in the task
+	// record and puts a reference on Redis; the Processor loads them back
+	// before the handler runs. Requires a store. Such tasks are enqueued as
+	// with PersistBeforeEnqueue, so an insert error fails the enqueue.
+	OffloadPayloads bool
+	// Blobs, if set, receives payloads over BlobThreshold bytes; Redis and
+	// the record only hold a reference, resolved by a Processor with the
+	// same ProcessorConfig.Blobs. It takes precedence over OffloadPayloads.
+	// BlobThreshold must be positive when Blobs is set. A blob is deleted
+	// if its task is not enqueued, and by a Janitor with JanitorConfig.Blobs
+	// once the record's payload is purged.
+	Blobs         BlobStore
+	BlobThreshold int
+	// IDGenerator, if set, chooses task IDs instead of asynq, e.g. ULID or
+	// UUIDv7 for time-sortable record keys. An asynq.TaskID option still
+	// wins.
+	IDGenerator IDGenerator
+	// PersistBeforeEnqueue makes Enqueue insert the record before putting
+	// the task on Redis, so that no task runs without a record. If Redis
+	// fails, the record is left in StatusEnqueueFailed. Task IDs come from
+	// IDGenerator, or are random UUIDs. Requires a store.
+	PersistBeforeEnqueue bool
+	// TaskDefaults supplies the queue, timeout, retries and uniqueness of
+	// each task type, e.g. from Processor.TaskDefaults, so that callers need
+	// not repeat them. Options passed to Enqueue win.
+	TaskDefaults map[string]TaskDefaults
+	// RateLimiter, if set, throttles enqueues per task type (or per its
+	// KeyFunc key), before anything reaches Redis or the store. Enqueue
+	// fails with a *ThrottledError when a bucket is empty, or waits for a
+	// token if RateLimitWait is set. Use a Prefix distinct from the
+	// Processor's limiter.
+	RateLimiter   *RateLimiter
+	RateLimitWait bool
+	// ContentDedupWindows enables content dedup per task type: Enqueue
+	// skips a task whose type and payload hash to the same value as a
+	// record created within the type's window, and returns that task's
+	// info instead. The check precedes the enqueue, so concurrent identical
+	// enqueues may both go through. EnqueueBatch records the hash but does
+	// not check it. Requires a store.
+	ContentDedupWindows map[string]time.Duration
+	// Router, if set, chooses the queue of tasks enqueued without an
+	// asynq.Queue option, ahead of TaskDefaults and Queue.
+	Router *Router
+	// Broker, if set, receives enqueued tasks instead of asynq on Redis,
+	// e.g. a JetStreamBroker. Features that inspect or edit queued tasks,
+	// such as CancelWhere, Freeze and PriorityAger, still go to Redis and
+	// do not work with it.
+	Broker Broker
 }
 
-func NewClient(redisOpt asynq.RedisClientOpt, store Store, opts ClientOptions) *Client {
+func NewClient(redisOpt asynq.RedisConnOpt, store Store, opts ClientOptions) *Client {
 	q := opts.Queue
 	if q == "" {
-		q = "default"
+		q = DefaultQueue
+	}
+	q = renamed(opts.QueueRenames, q)
+	if opts.Registry != nil {
+		if err := opts.Registry.Validate(q); err != nil {
+			panic(fmt.Sprintf("asyncx: NewClient: %v", err))
+		}
+	}
+	if opts.OffloadPayloads && store == nil {
+		panic("asyncx: NewClient: OffloadPayloads requires a store")
+	}
+	if len(opts.ContentDedupWindows) > 0 && store == nil {
+		panic("asyncx: NewClient: ContentDedupWindows requires a store")
+	}
+	if opts.PersistBeforeEnqueue && store == nil {
+		panic("asyncx: NewClient: PersistBeforeEnqueue requires a store")
+	}
+	if opts.Blobs != nil && opts.BlobThreshold <= 0 {
+		panic("asyncx: NewClient: Blobs requires a positive BlobThreshold")
+	}
+	broker := opts.Broker
+	if broker == nil {
+		broker = newAsynqClient(redisOpt)
+	}
+	c := &Client{
+		client:        broker,
+		inspector:     newInspector(redisOpt),
+		store:         store,
+		queue:         q,
+		registry:      opts.Registry,
+		types:         opts.TaskTypes,
+		classes:       opts.Classes,
+		hooks:         opts.Hooks,
+		redaction:     opts.Redaction,
+		source:        opts.Source,
+		createdBy:     opts.CreatedBy,
+		renames:       opts.QueueRenames,
+		codec:         opts.Codec,
+		versions:      opts.PayloadVersions,
+		maxPayload:    opts.MaxPayloadSize,
+		offload:       opts.OffloadPayloads && opts.MaxPayloadSize > 0,
+		blobs:         opts.Blobs,
+		blobThreshold: opts.BlobThreshold,
+		ids:           opts.IDGenerator,
+		persistFirst:  opts.PersistBeforeEnqueue,
+		defaults:      opts.TaskDefaults,
+		limiter:       opts.RateLimiter,
+		limitWait:     opts.RateLimitWait,
+		dedupWindows:  opts.ContentDedupWindows,
+		router:        opts.Router,
+		ping:          opts.StorePing,
+	}
+	if c.persistFirst && c.ids == nil {
+		c.ids = uuid.NewString
+	}
+	if c.codec == nil {
+		c.codec = JSONCodec{}
+	}
+	if c.source == "" {
+		c.source = defaultSource()
 	}
-	return &Client{
-		client: asynq.NewClient(redisOpt),
-		store:  store,
-		queue:  q,
+	if opts.ResultNotifications {
+		c.rdb = makeRedis(redisOpt)
 	}
+	c.interceptors = opts.Interceptors
+	c.enqueue = chainInterceptors(opts.Interceptors, c.doEnqueue)
+	return c
+}
+
+// checkStore pings the store with ClientOptions.StorePing until it has
+// answered once, so that an unreachable store fails enqueues up front
+// rather than on their first record write.
+func (c *Client) checkStore(ctx context.Context) error {
+	if c.pinged.Load() {
+		return nil
+	}
+	c.pingMu.Lock()
+	defer c.pingMu.Unlock()
+	if c.pinged.Load() {
+		return nil
+	}
+	if err := PingStore(ctx, c.store, c.ping); err != nil {
+		return err
+	}
+	c.pinged.Store(true)
+	return nil
 }
 
 // Enqueue enqueues a task with type and arbitrary payload (will be JSON encoded).
 // Returns asynq TaskInfo from enqueue and any error encountered.
+// The call passes through ClientOptions.Interceptors first.
 func (c *Client) Enqueue(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
-	if c.client == nil {
-		return nil, fmt.Errorf("nil asynq client")
+	if c.enqueue == nil {
+		return c.doEnqueue(ctx, taskType, payload, options...)
 	}
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
+	return c.enqueue(ctx, taskType, payload, options...)
+}
+
+// doEnqueue is the innermost EnqueueFunc, run after all interceptors.
+func (c *Client) doEnqueue(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
+	info, rec, err := c.submit(ctx, taskType, payload, options...)
+	if err != nil || rec == nil {
+		return info, err
+	}
+	if c.store != nil {
+		_ = c.store.InsertCreated(ctx, *rec)
+		c.markEnqueued(ctx, *rec)
+	}
+	b, _ := PayloadBytes(rec)
+	c.notifyEnqueued(ctx, info, b)
+	return info, nil
+}
+
+// enqueuePersisted inserts the record and then enqueues the task; see
+// ClientOptions.PersistBeforeEnqueue. Payloads offloaded to the record
+// always take this path.
+func (c *Client) enqueuePersisted(ctx context.Context, t *asynq.Task, options []asynq.Option, rec *TaskRecord) (*asynq.TaskInfo, error) {
+	if rec.ID == "" {
+		rec.ID = uuid.NewString()
+		options = append(options, asynq.TaskID(rec.ID))
+	}
+	if err := c.store.InsertCreated(ctx, *rec); err != nil {
+		c.discardBlob(ctx, t.Payload())
+		if rec.DedupKey != "" {
+			if dupErr := c.checkDedup(ctx, rec.DedupKey); dupErr != nil {
+				return nil, dupErr
+			}
+		}
 		return nil, err
 	}
-	t := asynq.NewTask(taskType, payloadBytes)
-	info, err := c.client.EnqueueContext(ctx, t, append(options, asynq.Queue(c.queue))...)
+	info, err := c.client.EnqueueContext(ctx, t, options...)
 	if err != nil {
+		_ = markStatus(ctx, c.store, rec.ID, StatusEnqueueFailed, time.Now().UTC())
 		return nil, err
 	}
-	// Persist created record
-	rec := TaskRecord{
-		ID:          info.ID,
-		Type:        taskType,
-		Queue:       info.Queue,
-		PayloadJSON: string(payloadBytes),
-		Status:      StatusCreated,
-		CreatedAt:   time.Now().UTC(),
-		EnqueuedAt:  time.Now().UTC(),
+	c.markEnqueued(ctx, *rec)
+	b, _ := PayloadBytes(rec)
+	c.notifyEnqueued(ctx, info, b)
+	return info, nil
+}
+
+// submit validates a task, enqueues it in Redis and returns the record to
+// persist for it. A task deduplicated by content, or persisted before
+// enqueueing, is returned without a record since nothing is left to write.
+// Tasks with a dedup key are persisted first, so that the store's unique
+// index rejects a concurrent duplicate before it reaches Redis.
+func (c *Client) submit(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, *TaskRecord, error) {
+	t, options, rec, err := c.prepare(ctx, taskType, payload, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info, err := c.sameContent(ctx, rec); info != nil || err != nil {
+		c.discardBlob(ctx, t.Payload())
+		return info, nil, err
 	}
-	if c.store != nil {
-		_ = c.store.InsertCreated(ctx, rec)
-		_ = c.store.MarkEnqueued(ctx, info.ID, info.Queue, time.Now().UTC())
+	if c.persistFirst || (rec.DedupKey != "" && c.store != nil) || bytes.Equal(t.Payload(), recordRef) {
+		// An offloaded payload must not reach Redis without its record.
+		info, err := c.enqueuePersisted(ctx, t, options, rec)
+		return info, nil, err
 	}
-	return info, nil
+	info, err := c.client.EnqueueContext(ctx, t, options...)
+	if err != nil {
+		c.discardBlob(ctx, t.Payload())
+		return nil, nil, err
+	}
+	rec.ID = info.ID
+	return info, rec, nil
+}
+
+// prepare validates a task and returns it with the options to enqueue it
+// with and its record. The record's ID is only set when an IDGenerator or an
+// asynq.TaskID option chose it; otherwise asynq does on enqueue.
+func (c *Client) prepare(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.Task, []asynq.Option, *TaskRecord, error) {
+	if c.client == nil {
+		return nil, nil, nil, fmt.Errorf("nil asynq client")
+	}
+	if err := c.checkStore(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+	if c.types != nil {
+		if err := c.types.Validate(taskType); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	payloadBytes, err := encode(c.codec, payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := c.throttle(ctx, asynq.NewTask(taskType, payloadBytes)); err != nil {
+		return nil, nil, nil, err
+	}
+	var hash string
+	if c.dedupWindows[taskType] > 0 {
+		hash = contentHash(taskType, payloadBytes)
+	}
+	options, eo := c.resolve(taskType, options)
+	class := eo.class
+	if class == "" {
+		class = classFor(c.classes, taskType)
+	}
+	if class == ClassFireAndForget {
+		options = append(options, asynq.MaxRetry(0))
+	}
+	if q := queueOf(options, c.queue); renamed(c.renames, q) != q {
+		options = append(options, asynq.Queue(renamed(c.renames, q)))
+	}
+	queue := queueOf(options, c.queue)
+	if c.registry != nil {
+		if err := c.registry.Validate(queue); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if eo.dedupKey != "" {
+		if err := c.checkDedup(ctx, eo.dedupKey); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	contentType := cmp.Or(eo.contentType, c.codec.ContentType())
+	onRedis, onRecord, err := c.placePayload(ctx, taskType, payloadBytes)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	t := asynq.NewTask(taskType, onRedis)
+	options = withTaskID(c.ids, options)
+	rec := &TaskRecord{
+		ID:                  taskIDOf(options),
+		Type:                taskType,
+		Queue:               queue,
+		PayloadJSON:         recordPayload(contentType, onRecord),
+		ContentType:         contentType,
+		PayloadVersion:      cmp.Or(eo.payloadVersion, c.versions[taskType]),
+		Status:              StatusCreated,
+		Class:               class,
+		Priority:            priorityOf(queue),
+		Metadata:            eo.metadata,
+		GuardToken:          eo.guard,
+		DedupKey:            eo.dedupKey,
+		ContentHash:         hash,
+		Shard:               c.shard,
+		ParentTaskID:        eo.parent,
+		WorkflowTraceparent: encodeWorkflow(ctx),
+		CreatedBy:           c.createdBy,
+		Source:              c.source,
+		CreatedAt:           time.Now().UTC(),
+		EnqueuedAt:          time.Now().UTC(),
+	}
+	if ri, ok := RequestInfoFromContext(ctx); ok {
+		if ri.Subject != "" {
+			rec.CreatedBy = ri.Subject
+		}
+		if b, err := json.Marshal(ri); err == nil {
+			s := string(b)
+			rec.RequestJSON = &s
+		}
+	}
+	// The client's queue goes first so that a per-call asynq.Queue option wins.
+	return t, append([]asynq.Option{asynq.Queue(c.queue)}, options...), rec, nil
+}
+
+// throttle applies ClientOptions.RateLimiter to t.
+func (c *Client) throttle(ctx context.Context, t *asynq.Task) error {
+	switch {
+	case c.limiter == nil:
+		return nil
+	case c.limitWait:
+		return c.limiter.wait(ctx, t)
+	}
+	return c.limiter.check(ctx, t)
+}
+
+// persist writes a new record one call at a time.
+func (c *Client) persist(ctx context.Context, rec TaskRecord) {
+	_ = c.store.InsertCreated(ctx, rec)
+	c.markEnqueued(ctx, rec)
+}
+
+func (c *Client) markEnqueued(ctx context.Context, rec TaskRecord) {
+	// Fire-and-forget tasks skip the enqueued write to keep persistence cheap.
+	if rec.Class != ClassFireAndForget {
+		_ = c.store.MarkEnqueued(ctx, rec.ID, rec.Queue, time.Now().UTC())
+	}
+}
+
+func (c *Client) notifyEnqueued(ctx context.Context, info *asynq.TaskInfo, payload []byte) {
+	if c.hooks != nil {
+		c.hooks.OnEnqueued(ctx, TaskEvent{TaskID: info.ID, Type: info.Type, Queue: info.Queue, Payload: c.redaction.RedactJSON(payload), MaxRetry: info.MaxRetry, At: time.Now().UTC()})
+	}
+}
+
+// resolve applies the task type's defaults and the Router to options and
+// splits off the asyncx options.
+func (c *Client) resolve(taskType string, options []asynq.Option) ([]asynq.Option, enqueueOptions) {
+	routed := c.router != nil && queueOf(options, "") == ""
+	if d, ok := c.defaults[taskType]; ok {
+		options = append(d.options(), options...)
+	}
+	options, eo := splitOptions(options)
+	if routed {
+		if q, ok := c.router.Route(taskType, eo.metadata); ok {
+			options = append(options, asynq.Queue(q))
+		}
+	}
+	return options, eo
+}
+
+// queueOf returns the queue selected by options, or def if none is given.
+func queueOf(options []asynq.Option, def string) string {
+	q := def
+	for _, o := range options {
+		if o != nil && o.Type() == asynq.QueueOpt {
+			q = o.Value().(string)
+		}
+	}
+	return q
+}
+
+// classFor returns the configured class for taskType, defaulting to ClassStandard.
+func classFor(classes map[string]TaskClass, taskType string) TaskClass {
+	if c, ok := classes[taskType]; ok && c != "" {
+		return c
+	}
+	return ClassStandard
 }
 
 func (c *Client) Close() error {
+	if c.rdb != nil {
+		_ = c.rdb.Close()
+	}
+	if c.inspector != nil {
+		_ = c.inspector.Close()
+	}
 	if c.client != nil {
 		return c.client.Close()
 	}
 	return nil
 }
+
+// defaultSource returns "<program>@<hostname>".
+func defaultSource() string {
+	host, _ := os.Hostname()
+	return filepath.Base(os.Args[0]) + "@" + host
+}
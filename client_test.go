@@ -0,0 +1,109 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_Enqueue_FireAndForget(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{
+		Classes: map[string]TaskClass{"analytics:track": ClassFireAndForget},
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	info, err := client.Enqueue(ctx, "analytics:track", map[string]any{"event": "click"}, asynq.MaxRetry(5))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if info.MaxRetry != 0 {
+		t.Fatalf("fire-and-forget task must not retry, got MaxRetry=%d", info.MaxRetry)
+	}
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if rec.Class != ClassFireAndForget {
+		t.Fatalf("want class=%s got=%s", ClassFireAndForget, rec.Class)
+	}
+
+	info, err = client.Enqueue(ctx, "analytics:track", nil, WithClass(ClassCritical))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if rec, _ := store.GetByID(ctx, info.ID); rec == nil || rec.Class != ClassCritical {
+		t.Fatalf("WithClass should override the per-type class, got %#v", rec)
+	}
+}
+
+func TestClient_Enqueue_RecordsCreatedByAndSource(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{Source: "billing-api", CreatedBy: "svc-billing"})
+	defer client.Close()
+	ctx := context.Background()
+
+	info, err := client.Enqueue(ctx, "invoice:send", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if rec, _ := store.GetByID(ctx, info.ID); rec == nil || rec.CreatedBy != "svc-billing" || rec.Source != "billing-api" {
+		t.Fatalf("want created_by=svc-billing source=billing-api, got %#v", rec)
+	}
+
+	info, err = client.Enqueue(WithRequestInfo(ctx, RequestInfo{Subject: "alice"}), "invoice:send", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if rec, _ := store.GetByID(ctx, info.ID); rec == nil || rec.CreatedBy != "alice" {
+		t.Fatalf("the context subject should win, got %#v", rec)
+	}
+}
+
+func TestClient_PersistBeforeEnqueue(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+	var last string
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{
+		PersistBeforeEnqueue: true,
+		IDGenerator: func() string {
+			last = ULID()
+			return last
+		},
+	})
+	defer client.Close()
+
+	info, err := client.Enqueue(ctx, "report:build", map[string]int{"id": 1})
+	if err != nil || info.ID != last {
+		t.Fatalf("Enqueue: %+v %v", info, err)
+	}
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil || rec.Status != StatusCreated || rec.EnqueuedAt.IsZero() {
+		t.Fatalf("record: %+v %v", rec, err)
+	}
+
+	s.Close()
+	if _, err := client.Enqueue(ctx, "report:build", map[string]int{"id": 2}); err == nil {
+		t.Fatal("Enqueue should fail with Redis down")
+	}
+	rec, err = store.GetByID(ctx, last)
+	if err != nil || rec.Status != StatusEnqueueFailed || rec.PayloadJSON != `{"id":2}` {
+		t.Fatalf("failed record: %+v %v", rec, err)
+	}
+}
@@ -0,0 +1,105 @@
+// Command asyncx runs maintenance tasks against an asyncx database.
+//
+//	asyncx export -dsn file:tasks.db -format csv -since 2026-09-01 -until 2026-10-01 > september.csv
+//
+// It bundles the pure-Go SQLite driver; build it with another
+// database/sql driver imported to use -driver postgres or mysql.
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mohans/asyncx"
+	_ "modernc.org/sqlite"
+)
+
+const usage = `usage: asyncx <command> [flags]
+
+commands:
+  export   write task records as JSONL or CSV
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = export(ctx, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "asyncx:", err)
+		os.Exit(1)
+	}
+}
+
+func export(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	driver := fs.String("driver", "sqlite", "database/sql driver name")
+	dsn := fs.String("dsn", "", "data source name (required)")
+	format := fs.String("format", "jsonl", "output format: jsonl or csv")
+	out := fs.String("o", "", "output file (default stdout)")
+	status := fs.String("status", "", "only records with this status")
+	taskType := fs.String("type", "", "only records of this task type")
+	queue := fs.String("queue", "", "only records of this queue")
+	since := fs.String("since", "", "only records created at or after this time (YYYY-MM-DD or RFC 3339)")
+	until := fs.String("until", "", "only records created before this time (YYYY-MM-DD or RFC 3339)")
+	_ = fs.Parse(args)
+	if *dsn == "" {
+		return fmt.Errorf("export: -dsn is required")
+	}
+	f := asyncx.TaskFilter{Status: asyncx.Status(*status), Type: *taskType, Queue: *queue}
+	var err error
+	if f.CreatedAfter, err = parseTime(*since); err != nil {
+		return err
+	}
+	if f.CreatedBefore, err = parseTime(*until); err != nil {
+		return err
+	}
+
+	db, err := sql.Open(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+	bw := bufio.NewWriter(w)
+	if err := asyncx.Export(ctx, asyncx.NewSQLStore(db), f, bw, asyncx.ExportFormat(*format)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q: want YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,195 @@
+// Command example is a small signup service showing asyncx end to end:
+// welcome emails and webhooks are written to the outbox in the same
+// transaction as the user, relayed to asynq, and handled exactly once with
+// idempotency keys, retries and recorded results. Task records can be
+// browsed under /tasks, and /healthz and /readyz serve the probes.
+//
+//	REDIS_ADDR=localhost:6379 go run ./cmd/example
+//	curl -X POST 'localhost:8080/signup?email=a@example.com&webhook=https://example.com/hook'
+//	curl 'localhost:8080/tasks?status=completed'
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/mohans/asyncx"
+	_ "modernc.org/sqlite"
+)
+
+type welcomeEmail struct {
+	Email string `json:"email"`
+}
+
+type webhook struct {
+	URL   string `json:"url"`
+	Event string `json:"event"`
+	Email string `json:"email"`
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	db, err := sql.Open("sqlite", env("DB_PATH", "asyncx-example.db"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := asyncx.Migrate(ctx, db, asyncx.DialectSQLite); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS users (email VARCHAR(255) PRIMARY KEY, created_at DATETIME NOT NULL)`); err != nil {
+		return err
+	}
+
+	redis := asynq.RedisClientOpt{Addr: env("REDIS_ADDR", "localhost:6379")}
+	store := asyncx.NewSQLStore(db)
+	client := asyncx.NewClient(redis, store, asyncx.ClientOptions{})
+	defer client.Close()
+	outbox := asyncx.NewOutbox(db, asyncx.DialectSQLite)
+	keys := asyncx.NewIdempotencyKeys(db, asyncx.DialectSQLite)
+
+	processor := asyncx.NewProcessor(redis, store, asyncx.ProcessorConfig{
+		RetryPolicies: map[string]asyncx.RetryPolicy{
+			"webhook:deliver": {MaxRetries: 8, BaseDelay: 5 * time.Second, MaxDelay: 10 * time.Minute, Jitter: 0.2},
+		},
+	})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("email:welcome", func(ctx context.Context, t *asynq.Task) error {
+		var p welcomeEmail
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return asyncx.NonRetryable(err)
+		}
+		id, _ := asynq.GetTaskID(ctx)
+		err := keys.Do(ctx, "welcome:"+p.Email, id, func(ctx context.Context) error {
+			log.Printf("sending welcome email to %s", p.Email)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return asyncx.SetResult(ctx, map[string]string{"sent_to": p.Email})
+	})
+	mux.HandleFunc("webhook:deliver", func(ctx context.Context, t *asynq.Task) error {
+		var p webhook
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return asyncx.NonRetryable(err)
+		}
+		id, _ := asynq.GetTaskID(ctx)
+		return keys.Do(ctx, "webhook:"+id, id, func(ctx context.Context) error {
+			body, _ := json.Marshal(p)
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+			if err != nil {
+				return asyncx.NonRetryable(err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			// Receivers dedupe on this header if a retry repeats a delivery.
+			req.Header.Set("Idempotency-Key", id)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				return fmt.Errorf("webhook %s: %s", p.URL, resp.Status)
+			}
+			return asyncx.SetResult(ctx, map[string]int{"status": resp.StatusCode})
+		})
+	})
+
+	http.HandleFunc("POST /signup", func(w http.ResponseWriter, r *http.Request) {
+		email := r.URL.Query().Get("email")
+		if err := signup(r.Context(), db, outbox, email, r.URL.Query().Get("webhook")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	http.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		rec, err := store.GetByID(r.Context(), r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(rec)
+	})
+	http.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		recs, err := store.List(r.Context(), asyncx.TaskFilter{
+			Status: asyncx.Status(r.URL.Query().Get("status")),
+			Type:   r.URL.Query().Get("type"),
+			Limit:  100,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(recs)
+	})
+	http.Handle("/healthz", processor.HealthHandler(0))
+	http.Handle("/readyz", processor.HealthHandler(0))
+
+	srv := &http.Server{Addr: env("HTTP_ADDR", ":8080")}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	go func() { _ = asyncx.NewOutboxRelay(outbox, client, asyncx.OutboxRelayConfig{}).Run(ctx) }()
+	go func() { _ = asyncx.NewJanitor(store, asyncx.JanitorConfig{}).Run(ctx) }()
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Print(err)
+			cancel()
+		}
+	}()
+	log.Printf("listening on %s", srv.Addr)
+	return processor.Run(ctx, mux)
+}
+
+// signup creates the user and its tasks in one transaction.
+func signup(ctx context.Context, db *sql.DB, outbox *asyncx.Outbox, email, hookURL string) error {
+	if email == "" {
+		return fmt.Errorf("email is required")
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO users (email, created_at) VALUES (?, ?)`, email, time.Now().UTC()); err != nil {
+		return err
+	}
+	if _, err := outbox.Add(ctx, tx, "email:welcome", welcomeEmail{Email: email}, ""); err != nil {
+		return err
+	}
+	if hookURL != "" {
+		if _, err := outbox.Add(ctx, tx, "webhook:deliver", webhook{URL: hookURL, Event: "user.created", Email: email}, ""); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
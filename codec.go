@@ -0,0 +1,96 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/hibiken/asynq"
+)
+
+// Codec encodes task payloads. The Client encodes with its
+// ClientOptions.Codec and handlers decode with Decode, which uses the
+// Processor's ProcessorConfig.Codec; both default to JSONCodec and must
+// agree.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	// ContentType is recorded on every TaskRecord, e.g.
+	// "application/msgpack".
+	ContentType() string
+}
+
+// JSONCodec encodes payloads with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (JSONCodec) ContentType() string                { return "application/json" }
+
+// EncodedPayload is a payload already encoded with the Client's codec.
+// Enqueue sends it unchanged.
+type EncodedPayload []byte
+
+type codecKey struct{}
+
+// Decode decodes the payload of t into v with the codec of the Processor
+// running the task, or with JSON outside a Processor.
+func Decode(ctx context.Context, t *asynq.Task, v any) error {
+	return codecFromContext(ctx).Unmarshal(t.Payload(), v)
+}
+
+func codecFromContext(ctx context.Context) Codec {
+	if c, ok := ctx.Value(codecKey{}).(Codec); ok {
+		return c
+	}
+	return JSONCodec{}
+}
+
+// encode encodes payload with codec, passing EncodedPayload through.
+func encode(codec Codec, payload any) ([]byte, error) {
+	if b, ok := payload.(EncodedPayload); ok {
+		return b, nil
+	}
+	return codec.Marshal(payload)
+}
+
+// jsonPayload returns JSON stored by asyncx, such as an outbox message or a
+// workflow node payload, as a payload for a Client with codec: unchanged
+// under a JSON codec, and otherwise re-encoded with codec, so that it is
+// not encoded a second time on top of the JSON.
+func jsonPayload(codec Codec, b []byte) (EncodedPayload, error) {
+	if isJSON(codec.ContentType()) {
+		return EncodedPayload(b), nil
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	b, err := codec.Marshal(v)
+	return EncodedPayload(b), err
+}
+
+// isJSON reports whether contentType is a JSON encoding. Records written
+// before content types were recorded have none and are JSON.
+func isJSON(contentType string) bool {
+	return contentType == "" || strings.HasSuffix(strings.SplitN(contentType, ";", 2)[0], "json")
+}
+
+// recordPayload renders an encoded payload for TaskRecord.PayloadJSON:
+// JSON as is, anything else as base64.
+func recordPayload(contentType string, b []byte) string {
+	if isJSON(contentType) {
+		return string(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// PayloadBytes returns the encoded payload of rec, reversing the base64 of
+// non-JSON codecs.
+func PayloadBytes(rec *TaskRecord) ([]byte, error) {
+	if isJSON(rec.ContentType) {
+		return []byte(rec.PayloadJSON), nil
+	}
+	return base64.StdEncoding.DecodeString(rec.PayloadJSON)
+}
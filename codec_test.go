@@ -0,0 +1,92 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v any) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v any) error { return xml.Unmarshal(data, v) }
+func (xmlCodec) ContentType() string                { return "application/xml" }
+
+type invoice struct {
+	Number string `xml:"number"`
+}
+
+func TestCodec_RoundTripsPayload(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	client := NewClient(redis, store, ClientOptions{Codec: xmlCodec{}})
+	defer client.Close()
+
+	got := make(chan invoice, 1)
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, Codec: xmlCodec{}})
+	defer processor.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("invoice:send", func(ctx context.Context, tsk *asynq.Task) error {
+		var inv invoice
+		if err := Decode(ctx, tsk, &inv); err != nil {
+			return err
+		}
+		got <- inv
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	info, err := client.Enqueue(ctx, "invoice:send", invoice{Number: "INV-7"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case inv := <-got:
+		if inv.Number != "INV-7" {
+			t.Fatalf("decoded %+v", inv)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not run")
+	}
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	b, err := PayloadBytes(rec)
+	if rec.ContentType != "application/xml" || err != nil || string(b) != "<invoice><number>INV-7</number></invoice>" {
+		t.Fatalf("record payload %q (%s): %q %v", rec.PayloadJSON, rec.ContentType, b, err)
+	}
+}
+
+// taggedCodec is JSON behind a marker byte, standing in for a binary codec.
+type taggedCodec struct{}
+
+func (taggedCodec) Marshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	return append([]byte{'#'}, b...), err
+}
+func (taggedCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data[1:], v) }
+func (taggedCodec) ContentType() string                { return "application/x-tagged" }
+
+func TestJSONPayload_EncodesOnce(t *testing.T) {
+	stored := []byte(`{"number":"INV-1"}`)
+	if got, err := jsonPayload(JSONCodec{}, stored); err != nil || string(got) != string(stored) {
+		t.Fatalf("JSON codec: %s %v", got, err)
+	}
+	got, err := jsonPayload(taggedCodec{}, stored)
+	if err != nil || string(got) != `#{"number":"INV-1"}` {
+		t.Fatalf("other codec: %s %v", got, err)
+	}
+	if b, _ := encode(taggedCodec{}, got); string(b) != string(got) {
+		t.Fatalf("encode re-encoded the payload: %s", b)
+	}
+}
@@ -0,0 +1,82 @@
+package asyncx
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/hibiken/asynq"
+)
+
+// Container holds handler dependencies (DB handles, HTTP clients, config)
+// keyed by type, so handlers receive them through their constructors
+// instead of globals. Register values with Provide and read them with
+// Resolve; tests provide fakes under the same types.
+type Container struct {
+	mu     sync.RWMutex
+	values map[reflect.Type]any
+}
+
+func NewContainer() *Container {
+	return &Container{values: make(map[reflect.Type]any)}
+}
+
+// Provide registers v as the dependency of type T, replacing any previous one.
+// Use an interface type for T to let tests substitute fakes.
+func Provide[T any](c *Container, v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[reflect.TypeFor[T]()] = v
+}
+
+// Resolve returns the dependency of type T.
+func Resolve[T any](c *Container) (T, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.values[reflect.TypeFor[T]()]
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("asyncx: no dependency of type %s provided", reflect.TypeFor[T]())
+	}
+	return v.(T), nil
+}
+
+// MustResolve is like Resolve but panics if T was not provided.
+func MustResolve[T any](c *Container) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// HandlerFactory builds a handler from the dependencies in c, typically by
+// resolving them and calling an explicit constructor such as
+// NewEmailHandler(mailer, cfg).
+type HandlerFactory func(c *Container) (asynq.Handler, error)
+
+// RegisterHandler registers h for taskType on mux. It exists so handler
+// wiring reads RegisterHandler(mux, "email:deliver", NewEmailHandler(mailer)).
+func RegisterHandler(mux *asynq.ServeMux, taskType string, h asynq.Handler) {
+	if h == nil {
+		panic(fmt.Sprintf("asyncx: RegisterHandler: nil handler for %q", taskType))
+	}
+	mux.Handle(taskType, h)
+}
+
+// RegisterHandlers builds every factory with c and registers the results on
+// mux. It fails before registering anything if a dependency is missing.
+func RegisterHandlers(mux *asynq.ServeMux, c *Container, factories map[string]HandlerFactory) error {
+	handlers := make(map[string]asynq.Handler, len(factories))
+	for taskType, f := range factories {
+		h, err := f(c)
+		if err != nil {
+			return fmt.Errorf("asyncx: building handler for %q: %w", taskType, err)
+		}
+		handlers[taskType] = h
+	}
+	for taskType, h := range handlers {
+		RegisterHandler(mux, taskType, h)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+type mailer interface {
+	Send(to, body string) error
+}
+
+type fakeMailer struct{ sent []string }
+
+func (m *fakeMailer) Send(to, body string) error {
+	m.sent = append(m.sent, to)
+	return nil
+}
+
+func newEmailHandler(m mailer) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var p struct {
+			To string `json:"to"`
+		}
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return err
+		}
+		if err := m.Send(p.To, "hello"); err != nil {
+			return err
+		}
+		return SetResult(ctx, map[string]string{"sent_to": p.To})
+	})
+}
+
+var emailFactory HandlerFactory = func(c *Container) (asynq.Handler, error) {
+	m, err := Resolve[mailer](c)
+	if err != nil {
+		return nil, err
+	}
+	return newEmailHandler(m), nil
+}
+
+func TestHarness_BuildsHandlersWithFakes(t *testing.T) {
+	h := NewHarness()
+	fake := &fakeMailer{}
+	Provide[mailer](h.Container, fake)
+
+	handler, err := h.Build(emailFactory)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	res, err := h.Run(context.Background(), handler, "email:deliver", map[string]string{"to": "ann@example.com"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if string(res) != `{"sent_to":"ann@example.com"}` || len(fake.sent) != 1 {
+		t.Fatalf("unexpected result %s, sent %v", res, fake.sent)
+	}
+
+	crash := asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error { panic("boom") })
+	var pe *PanicError
+	if _, err := h.Run(context.Background(), crash, "x", nil); !errors.As(err, &pe) {
+		t.Fatalf("panics should surface as *PanicError, got %v", err)
+	}
+}
+
+func TestRegisterHandlers_MissingDependency(t *testing.T) {
+	mux := asynq.NewServeMux()
+	err := RegisterHandlers(mux, NewContainer(), map[string]HandlerFactory{"email:deliver": emailFactory})
+	if err == nil || !strings.Contains(err.Error(), "mailer") {
+		t.Fatalf("want missing dependency error naming the type, got %v", err)
+	}
+
+	c := NewContainer()
+	Provide[mailer](c, &fakeMailer{})
+	if err := RegisterHandlers(mux, c, map[string]HandlerFactory{"email:deliver": emailFactory}); err != nil {
+		t.Fatalf("RegisterHandlers: %v", err)
+	}
+	if h, pattern := mux.Handler(asynq.NewTask("email:deliver", nil)); pattern != "email:deliver" || h == nil {
+		t.Fatalf("handler not registered")
+	}
+}
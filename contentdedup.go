@@ -0,0 +1,39 @@
+package asyncx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// contentHash returns the hex SHA-256 of taskType and payload.
+func contentHash(taskType string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(taskType))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sameContent returns the info of a task with rec's content hash created
+// within the type's ClientOptions.ContentDedupWindows, or nil if there is
+// none.
+func (c *Client) sameContent(ctx context.Context, rec *TaskRecord) (*asynq.TaskInfo, error) {
+	if rec.ContentHash == "" {
+		return nil, nil
+	}
+	since := time.Now().Add(-c.dedupWindows[rec.Type])
+	recs, err := listRecords(ctx, c.store, TaskFilter{ContentHash: rec.ContentHash, CreatedAfter: since, Limit: 1})
+	if err != nil || len(recs) == 0 {
+		return nil, err
+	}
+	prev := recs[0]
+	if info, err := c.inspector.GetTaskInfo(prev.Queue, prev.ID); err == nil {
+		return info, nil
+	}
+	// The task already finished and left Redis.
+	return &asynq.TaskInfo{ID: prev.ID, Queue: prev.Queue, Type: prev.Type}, nil
+}
@@ -0,0 +1,41 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrDuplicateTask is returned by Enqueue when a record with the same
+// dedup key already exists.
+var ErrDuplicateTask = errors.New("asyncx: duplicate task")
+
+type dedupOption string
+
+// WithDedupKey makes Enqueue idempotent per key: a second Enqueue with the
+// same key fails with ErrDuplicateTask and nothing is enqueued. The record
+// is inserted before the task is enqueued, as with PersistBeforeEnqueue.
+// SQLStore enforces the key with a unique index (migration 018), so
+// concurrent enqueues race safely; other stores only check before inserting.
+func WithDedupKey(key string) asynq.Option { return dedupOption(key) }
+
+func (o dedupOption) String() string         { return fmt.Sprintf("DedupKey(%q)", string(o)) }
+func (o dedupOption) Type() asynq.OptionType { return asyncxOpt }
+func (o dedupOption) Value() interface{}     { return string(o) }
+
+// checkDedup returns ErrDuplicateTask if a record already holds key.
+func (c *Client) checkDedup(ctx context.Context, key string) error {
+	if c.store == nil {
+		return nil
+	}
+	recs, err := listRecords(ctx, c.store, TaskFilter{DedupKey: key, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(recs) > 0 {
+		return fmt.Errorf("%w: key %q is held by task %s", ErrDuplicateTask, key, recs[0].ID)
+	}
+	return nil
+}
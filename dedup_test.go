@@ -0,0 +1,148 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_Enqueue_DedupKey(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_dedup_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	first, err := client.Enqueue(ctx, "charge:card", map[string]int{"cents": 500}, WithDedupKey("order-7"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := client.Enqueue(ctx, "charge:card", map[string]int{"cents": 500}, WithDedupKey("order-7")); !errors.Is(err, ErrDuplicateTask) {
+		t.Fatalf("want ErrDuplicateTask, got %v", err)
+	}
+	if _, err := client.Enqueue(ctx, "charge:card", nil, WithDedupKey("order-8")); err != nil {
+		t.Fatalf("Enqueue with another key: %v", err)
+	}
+	info, err := client.inspector.GetQueueInfo(DefaultQueue)
+	if err != nil || info.Pending != 2 {
+		t.Fatalf("want 2 pending tasks, got %+v %v", info, err)
+	}
+	recs, err := store.List(ctx, TaskFilter{DedupKey: "order-7"})
+	if err != nil || len(recs) != 1 || recs[0].ID != first.ID {
+		t.Fatalf("List by dedup key: %v %v", recs, err)
+	}
+
+	// The unique index is the arbiter when two enqueues race past the check.
+	err = store.InsertCreated(ctx, TaskRecord{ID: "dedup-race", Type: "charge:card", Queue: DefaultQueue, PayloadJSON: "{}", DedupKey: "order-7"})
+	if err == nil {
+		t.Fatal("unique index should reject a second record with the same dedup key")
+	}
+}
+
+func TestClient_Enqueue_DedupKeyConcurrent(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_dedup_race_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+
+	// Both pass checkDedup before either inserts; the unique index decides.
+	errs := make(chan error, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Enqueue(ctx, "charge:card", nil, WithDedupKey("order-9"))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	var ok, dup int
+	for err := range errs {
+		switch {
+		case err == nil:
+			ok++
+		case errors.Is(err, ErrDuplicateTask):
+			dup++
+		default:
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	if ok != 1 || dup != 1 {
+		t.Fatalf("want one enqueue and one ErrDuplicateTask, got %d and %d", ok, dup)
+	}
+	info, err := client.inspector.GetQueueInfo(DefaultQueue)
+	if err != nil || info.Pending != 1 {
+		t.Fatalf("want 1 pending task, got %+v %v", info, err)
+	}
+}
+
+func TestClient_Enqueue_ContentDedupWindow(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{ContentDedupWindows: map[string]time.Duration{"email:send": 100 * time.Millisecond}})
+	defer client.Close()
+
+	first, err := client.Enqueue(ctx, "email:send", map[string]string{"to": "a@example.com"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	again, err := client.Enqueue(ctx, "email:send", map[string]string{"to": "a@example.com"})
+	if err != nil || again.ID != first.ID {
+		t.Fatalf("identical task: got %+v %v, want the existing task %s", again, err, first.ID)
+	}
+	other, err := client.Enqueue(ctx, "email:send", map[string]string{"to": "b@example.com"})
+	if err != nil || other.ID == first.ID {
+		t.Fatalf("different payload: %+v %v", other, err)
+	}
+	r1, _ := client.Enqueue(ctx, "report:build", nil)
+	r2, _ := client.Enqueue(ctx, "report:build", nil)
+	if r1 == nil || r2 == nil || r1.ID == r2.ID {
+		t.Fatal("types without a window must not be deduplicated")
+	}
+	rec, err := store.GetByID(ctx, first.ID)
+	if err != nil || len(rec.ContentHash) != 64 {
+		t.Fatalf("content hash not recorded: %+v %v", rec, err)
+	}
+	recs, _ := store.List(ctx, TaskFilter{ContentHash: rec.ContentHash})
+	if len(recs) != 1 {
+		t.Fatalf("want one record with the hash, got %d", len(recs))
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	later, err := client.Enqueue(ctx, "email:send", map[string]string{"to": "a@example.com"})
+	if err != nil || later.ID == first.ID {
+		t.Fatalf("after the window: %+v %v", later, err)
+	}
+}
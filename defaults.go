@@ -0,0 +1,66 @@
+package asyncx
+
+import (
+	"maps"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// TaskDefaults are the options of a task type, declared once where its
+// handler is registered instead of on every Enqueue. Zero fields are left
+// to the Client and asynq; options passed to Enqueue still win.
+type TaskDefaults struct {
+	// Queue the type is enqueued on.
+	Queue string
+	// Timeout bounds the handler's run time, as ProcessorConfig.Timeouts.
+	Timeout time.Duration
+	// MaxRetry, if positive, replaces asynq's default of 25 retries. Use
+	// ClassFireAndForget for none.
+	MaxRetry int
+	// Unique, if positive, rejects duplicates of a task for this long, as
+	// asynq.Unique.
+	Unique time.Duration
+}
+
+// options returns d as asynq options.
+func (d TaskDefaults) options() []asynq.Option {
+	var out []asynq.Option
+	if d.Queue != "" {
+		out = append(out, asynq.Queue(d.Queue))
+	}
+	if d.Timeout > 0 {
+		out = append(out, asynq.Timeout(d.Timeout))
+	}
+	if d.MaxRetry > 0 {
+		out = append(out, asynq.MaxRetry(d.MaxRetry))
+	}
+	if d.Unique > 0 {
+		out = append(out, asynq.Unique(d.Unique))
+	}
+	return out
+}
+
+// TaskDefaults returns the defaults declared with Handle, to pass as
+// ClientOptions.TaskDefaults to Clients in the same program.
+func (p *Processor) TaskDefaults() map[string]TaskDefaults {
+	return maps.Clone(p.defaults)
+}
+
+// declare records the defaults of taskType. A Timeout applies unless
+// ProcessorConfig.Timeouts sets one.
+func (p *Processor) declare(taskType string, d TaskDefaults) {
+	if p.defaults == nil {
+		p.defaults = make(map[string]TaskDefaults)
+	}
+	p.defaults[taskType] = d
+	if _, ok := p.timeouts[taskType]; !ok && d.Timeout > 0 {
+		// Copied so that the caller's ProcessorConfig.Timeouts is left alone.
+		timeouts := maps.Clone(p.timeouts)
+		if timeouts == nil {
+			timeouts = make(map[string]time.Duration)
+		}
+		timeouts[taskType] = d.Timeout
+		p.timeouts = timeouts
+	}
+}
@@ -0,0 +1,46 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestTaskDefaults(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	processor.HandleFunc("report:build", func(ctx context.Context, t *asynq.Task) error { return nil },
+		TaskDefaults{Queue: "reports", Timeout: time.Minute, MaxRetry: 3, Unique: time.Hour})
+	if processor.timeouts["report:build"] != time.Minute {
+		t.Fatalf("timeout not applied: %v", processor.timeouts)
+	}
+
+	client := NewClient(redis, store, ClientOptions{TaskDefaults: processor.TaskDefaults()})
+	defer client.Close()
+	info, err := client.Enqueue(ctx, "report:build", map[string]int{"id": 1})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if info.Queue != "reports" || info.MaxRetry != 3 || info.Timeout != time.Minute {
+		t.Fatalf("defaults not applied: %+v", info)
+	}
+	if rec, err := store.GetByID(ctx, info.ID); err != nil || rec.Queue != "reports" {
+		t.Fatalf("record: %+v %v", rec, err)
+	}
+	if _, err := client.Enqueue(ctx, "report:build", map[string]int{"id": 1}); err == nil {
+		t.Fatal("Unique should reject the duplicate")
+	}
+	info, err = client.Enqueue(ctx, "report:build", map[string]int{"id": 2}, asynq.Queue("urgent"), asynq.MaxRetry(1))
+	if err != nil || info.Queue != "urgent" || info.MaxRetry != 1 {
+		t.Fatalf("explicit options: %+v %v", info, err)
+	}
+}
@@ -0,0 +1,119 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Dependency is an external system tasks need, such as "smtp" or
+// "billing-api", with a check of its health.
+type Dependency struct {
+	Name  string
+	Check func(ctx context.Context) error
+	// Interval is how long a check result is reused. Defaults to ten
+	// seconds; it is also the delay before deferred tasks are tried again.
+	Interval time.Duration
+	// Timeout bounds each check. Defaults to five seconds.
+	Timeout time.Duration
+}
+
+// DependencyDownError is returned for a task whose type depends on an
+// unhealthy Dependency. The Processor retries it after RetryAfter without
+// counting it as a failure.
+type DependencyDownError struct {
+	Type       string
+	Dependency string
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *DependencyDownError) Error() string {
+	return fmt.Sprintf("dependency down: type=%s dependency=%s: %v", e.Type, e.Dependency, e.Err)
+}
+
+func (e *DependencyDownError) Unwrap() error { return e.Err }
+
+func isDependencyDown(err error) bool {
+	var de *DependencyDownError
+	return errors.As(err, &de)
+}
+
+// dependencyGate caches the health of the Processor's dependencies.
+type dependencyGate struct {
+	deps  map[string]*dependencyState
+	types map[string][]string
+}
+
+type dependencyState struct {
+	Dependency
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// newDependencyGate returns nil if no task type depends on anything.
+func newDependencyGate(deps []Dependency, types map[string][]string) (*dependencyGate, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+	g := &dependencyGate{deps: make(map[string]*dependencyState, len(deps)), types: types}
+	for _, d := range deps {
+		if d.Interval <= 0 {
+			d.Interval = 10 * time.Second
+		}
+		if d.Timeout <= 0 {
+			d.Timeout = 5 * time.Second
+		}
+		g.deps[d.Name] = &dependencyState{Dependency: d}
+	}
+	for typ, names := range types {
+		for _, name := range names {
+			if _, ok := g.deps[name]; !ok {
+				return nil, fmt.Errorf("task type %q depends on unknown dependency %q", typ, name)
+			}
+		}
+	}
+	return g, nil
+}
+
+// check returns a *DependencyDownError if a dependency of taskType is
+// unhealthy.
+func (g *dependencyGate) check(ctx context.Context, taskType string) error {
+	for _, name := range g.types[taskType] {
+		d := g.deps[name]
+		if err := d.health(ctx); err != nil {
+			return &DependencyDownError{Type: taskType, Dependency: name, Err: err, RetryAfter: d.Interval}
+		}
+	}
+	return nil
+}
+
+// health returns the result of the last check, checking again once it is
+// older than Interval.
+func (d *dependencyState) health(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if time.Since(d.checked) < d.Interval {
+		return d.err
+	}
+	cctx, cancel := context.WithTimeout(ctx, d.Timeout)
+	defer cancel()
+	d.err = d.Check(cctx)
+	d.checked = time.Now()
+	return d.err
+}
+
+// report returns "ok" or the error of every dependency.
+func (g *dependencyGate) report(ctx context.Context) map[string]string {
+	out := make(map[string]string, len(g.deps))
+	for name, d := range g.deps {
+		out[name] = "ok"
+		if err := d.health(ctx); err != nil {
+			out[name] = err.Error()
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_DefersTasksWhileDependencyIsDown(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	var smtpDown atomic.Bool
+	smtpDown.Store(true)
+	var checks atomic.Int32
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{
+		Concurrency: 1,
+		Dependencies: []Dependency{{Name: "smtp", Interval: time.Hour, Check: func(ctx context.Context) error {
+			checks.Add(1)
+			if smtpDown.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		}}},
+		DependsOn: map[string][]string{"email:send": {"smtp"}},
+	})
+	var ran atomic.Int32
+	mux := asynq.NewServeMux()
+	handler := func(ctx context.Context, tsk *asynq.Task) error {
+		ran.Add(1)
+		return nil
+	}
+	mux.HandleFunc("email:send", handler)
+	mux.HandleFunc("report:build", handler)
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+	email, err := client.Enqueue(ctx, "email:send", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	report, err := client.Enqueue(ctx, "report:build", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 5*time.Second, func() (bool, error) {
+		e, err1 := store.GetByID(ctx, email.ID)
+		r, err2 := store.GetByID(ctx, report.ID)
+		return err1 == nil && err2 == nil && e.Status == StatusDeferred && r.Status == StatusCompleted, nil
+	}); err != nil {
+		e, _ := store.GetByID(ctx, email.ID)
+		r, _ := store.GetByID(ctx, report.ID)
+		t.Fatalf("email not deferred or report not run: %v %+v %+v", err, e, r)
+	}
+	if ran.Load() != 1 {
+		t.Fatalf("only the report handler may run, ran %d", ran.Load())
+	}
+	ti, err := processor.inspector.GetTaskInfo(DefaultQueue, email.ID)
+	if err != nil || ti.State != asynq.TaskStateRetry || ti.Retried != 0 {
+		t.Fatalf("deferred task must wait without using a retry: %+v %v", ti, err)
+	}
+	if d := retryDelay(0, &DependencyDownError{RetryAfter: time.Hour}, nil); d != time.Hour {
+		t.Fatalf("retry delay %s", d)
+	}
+
+	report2 := processor.Healthz(ctx)
+	if report2.Dependencies["smtp"] != "connection refused" || !report2.Ready() {
+		t.Fatalf("health report %+v", report2)
+	}
+	if checks.Load() != 1 {
+		t.Fatalf("checks within Interval must be cached, ran %d", checks.Load())
+	}
+}
+
+func TestNewProcessor_UnknownDependencyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("want a panic for an unknown dependency")
+		}
+	}()
+	NewProcessor(asynq.RedisClientOpt{Addr: "localhost:0"}, nil, ProcessorConfig{DependsOn: map[string][]string{"email:send": {"smtp"}}})
+}
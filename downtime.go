@@ -0,0 +1,66 @@
+package asyncx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DowntimeWindow is a daily maintenance window during which tasks of a type
+// must not run, e.g. {Start: 0, End: time.Hour} for 00:00–01:00. Start and
+// End are offsets from midnight in Location (UTC if nil); a window with End
+// before Start spans midnight.
+type DowntimeWindow struct {
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+}
+
+// until reports whether now falls inside w and, if so, when w ends.
+func (w DowntimeWindow) until(now time.Time) (time.Time, bool) {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	n := now.In(loc)
+	midnight := time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, loc)
+	off := n.Sub(midnight)
+	switch {
+	case w.Start <= w.End:
+		if off >= w.Start && off < w.End {
+			return midnight.Add(w.End), true
+		}
+	case off >= w.Start:
+		return midnight.AddDate(0, 0, 1).Add(w.End), true
+	case off < w.End:
+		return midnight.Add(w.End), true
+	}
+	return time.Time{}, false
+}
+
+// DeferredError is returned for a task that arrived during one of its type's
+// downtime windows. The Processor retries it when the window ends without
+// counting it as a failure.
+type DeferredError struct {
+	Type  string
+	Until time.Time
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("deferred by downtime window: type=%s until=%s", e.Type, e.Until.UTC().Format(time.RFC3339))
+}
+
+func isDeferred(err error) bool {
+	var de *DeferredError
+	return errors.As(err, &de)
+}
+
+// downtimeCheck returns a *DeferredError if taskType is inside a downtime window.
+func downtimeCheck(windows map[string][]DowntimeWindow, taskType string, now time.Time) error {
+	for _, w := range windows[taskType] {
+		if until, ok := w.until(now); ok {
+			return &DeferredError{Type: taskType, Until: until}
+		}
+	}
+	return nil
+}
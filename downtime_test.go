@@ -0,0 +1,108 @@
+package asyncx
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestDowntimeWindow_Until(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	night := DowntimeWindow{Start: 23 * time.Hour, End: time.Hour}
+	cases := []struct {
+		name   string
+		w      DowntimeWindow
+		now    time.Time
+		in     bool
+		resume time.Time
+	}{
+		{"inside", DowntimeWindow{End: time.Hour}, day.Add(30 * time.Minute), true, day.Add(time.Hour)},
+		{"at end", DowntimeWindow{End: time.Hour}, day.Add(time.Hour), false, time.Time{}},
+		{"wraps before midnight", night, day.Add(23*time.Hour + 30*time.Minute), true, day.Add(25 * time.Hour)},
+		{"wraps after midnight", night, day.Add(30 * time.Minute), true, day.Add(time.Hour)},
+		{"outside wrapped", night, day.Add(12 * time.Hour), false, time.Time{}},
+	}
+	for _, c := range cases {
+		resume, in := c.w.until(c.now)
+		if in != c.in || !resume.Equal(c.resume) {
+			t.Errorf("%s: got (%v, %v), want (%v, %v)", c.name, resume, in, c.resume, c.in)
+		}
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	w := DowntimeWindow{Start: 0, End: time.Hour, Location: berlin}
+	// 23:30 UTC is 00:30 in Berlin (UTC+1 in March before DST).
+	if _, in := w.until(day.Add(-30 * time.Minute)); !in {
+		t.Errorf("window should honour its location")
+	}
+}
+
+func TestProcessor_DefersTasksInDowntime(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{
+		Concurrency: 1,
+		Downtime:    map[string][]DowntimeWindow{"bank:settlement": {{Start: 0, End: 24 * time.Hour}}},
+	})
+	var ran atomic.Bool
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("bank:settlement", func(ctx context.Context, tsk *asynq.Task) error {
+		ran.Store(true)
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+	info, err := client.Enqueue(ctx, "bank:settlement", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(ctx, info.ID)
+		return err == nil && rec.Status == StatusDeferred, nil
+	}); err != nil {
+		t.Fatalf("task not deferred: %v", err)
+	}
+	if ran.Load() {
+		t.Fatal("handler must not run during downtime")
+	}
+	// The deferral is not a failure: the task waits to be retried, not archived.
+	ti, err := processor.inspector.GetTaskInfo(DefaultQueue, info.ID)
+	if err != nil {
+		t.Fatalf("GetTaskInfo: %v", err)
+	}
+	if ti.State != asynq.TaskStateRetry {
+		t.Fatalf("want retry state, got %v", ti.State)
+	}
+	// Without retries left the task is rescheduled under its ID instead.
+	last, err := client.Enqueue(ctx, "bank:settlement", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 5*time.Second, func() (bool, error) {
+		ti, err := processor.inspector.GetTaskInfo(DefaultQueue, last.ID)
+		return err == nil && ti.State == asynq.TaskStateScheduled, nil
+	}); err != nil {
+		t.Fatalf("task without retries was not rescheduled: %v", err)
+	}
+	if d := retryDelay(0, &DeferredError{Until: time.Now().Add(time.Hour)}, nil); d < 59*time.Minute {
+		t.Fatalf("retry should wait for the window to end, got %s", d)
+	}
+	if downtimeCheck(nil, "x", time.Now()) != nil {
+		t.Fatal("no windows must not defer")
+	}
+}
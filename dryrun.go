@@ -0,0 +1,41 @@
+package asyncx
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+// DryRunConfig configures dry-run mode; see ProcessorConfig.DryRun.
+//
+// In dry-run mode the Processor consumes tasks and runs the rate limiter and
+// all Use/UseFor middleware, but handlers are replaced by a no-op. Tasks that
+// get through are recorded as StatusDryRun; errors from middleware are
+// recorded as usual, showing what would have failed.
+type DryRunConfig struct {
+	// CallThrough lists task types whose handlers are still invoked. They
+	// must check IsDryRun and skip side effects, e.g. to validate payloads.
+	CallThrough []string
+}
+
+type dryRunKey struct{}
+
+// IsDryRun reports whether ctx belongs to a task processed in dry-run mode.
+func IsDryRun(ctx context.Context) bool {
+	v, _ := ctx.Value(dryRunKey{}).(bool)
+	return v
+}
+
+// wrap replaces h with a no-op except for CallThrough types.
+func (c *DryRunConfig) wrap(h asynq.Handler) asynq.Handler {
+	through := make(map[string]bool, len(c.CallThrough))
+	for _, taskType := range c.CallThrough {
+		through[taskType] = true
+	}
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if !through[t.Type()] {
+			return nil
+		}
+		return h.ProcessTask(ctx, t)
+	})
+}
@@ -0,0 +1,70 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_DryRun(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{
+		Concurrency: 1,
+		DryRun:      &DryRunConfig{CallThrough: []string{"dr:validate"}},
+	})
+	processor.UseFor("dr:reject", func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			return NonRetryable(errors.New("invalid payload"))
+		})
+	})
+	var charged, validated atomic.Bool
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("dr:charge", func(ctx context.Context, tsk *asynq.Task) error {
+		charged.Store(true)
+		return nil
+	})
+	mux.HandleFunc("dr:validate", func(ctx context.Context, tsk *asynq.Task) error {
+		validated.Store(IsDryRun(ctx))
+		return nil
+	})
+	mux.HandleFunc("dr:reject", func(ctx context.Context, tsk *asynq.Task) error { return nil })
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	want := map[string]Status{}
+	for taskType, status := range map[string]Status{"dr:charge": StatusDryRun, "dr:validate": StatusDryRun, "dr:reject": StatusFailed} {
+		info, err := client.Enqueue(ctx, taskType, nil)
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		want[info.ID] = status
+	}
+	for id, status := range want {
+		if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+			rec, err := store.GetByID(ctx, id)
+			return err == nil && rec.Status == status, nil
+		}); err != nil {
+			t.Fatalf("task %s: want status %s: %v", id, status, err)
+		}
+	}
+	if charged.Load() {
+		t.Fatalf("handlers must not run in dry-run mode")
+	}
+	if !validated.Load() {
+		t.Fatalf("call-through handler should run and see IsDryRun")
+	}
+}
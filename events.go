@@ -0,0 +1,172 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// EventKind names a lifecycle change in a LifecycleEvent.
+type EventKind string
+
+const (
+	EventCreated   EventKind = "created"
+	EventStarted   EventKind = "started"
+	EventCompleted EventKind = "completed"
+	EventRetry     EventKind = "retry"
+	EventFailed    EventKind = "failed"
+)
+
+// LifecycleEvent is a task lifecycle change published to an EventSink.
+type LifecycleEvent struct {
+	Kind       EventKind `json:"kind"`
+	TaskID     string    `json:"task_id"`
+	Type       string    `json:"type"`
+	Queue      string    `json:"queue"`
+	Retried    int       `json:"retried,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// EventSink receives lifecycle events, e.g. by producing them to a Kafka
+// topic, a NATS subject or a Redis stream (see NewRedisStreamSink).
+type EventSink interface {
+	Publish(ctx context.Context, e LifecycleEvent) error
+}
+
+type EventPublisherConfig struct {
+	// Buffer is how many events may wait for the sink. Events beyond it
+	// are dropped rather than slowing down enqueues and workers. Defaults
+	// to 1024.
+	Buffer int
+	// Timeout bounds each Publish call. Defaults to five seconds.
+	Timeout time.Duration
+	// OnError, if set, is told about events the sink failed to publish.
+	OnError func(e LifecycleEvent, err error)
+}
+
+// EventPublisher is a Hooks implementation that streams lifecycle events
+// to an EventSink in the background, in order, in addition to the store.
+// Pass it as ClientOptions.Hooks for created events and as
+// ProcessorConfig.Hooks for the others, combined with other hooks via
+// MultiHooks if needed. Events are best effort: failed publishes are not
+// retried, and Close flushes the buffer.
+type EventPublisher struct {
+	sink    EventSink
+	cfg     EventPublisherConfig
+	events  chan LifecycleEvent
+	dropped atomic.Uint64
+	mu      sync.RWMutex // guards closed and sends on events
+	closed  bool
+	done    chan struct{}
+}
+
+func NewEventPublisher(sink EventSink, cfg EventPublisherConfig) *EventPublisher {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 1024
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	p := &EventPublisher{sink: sink, cfg: cfg, events: make(chan LifecycleEvent, cfg.Buffer), done: make(chan struct{})}
+	go p.run()
+	return p
+}
+
+func (p *EventPublisher) run() {
+	defer close(p.done)
+	for e := range p.events {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.Timeout)
+		if err := p.sink.Publish(ctx, e); err != nil && p.cfg.OnError != nil {
+			p.cfg.OnError(e, err)
+		}
+		cancel()
+	}
+}
+
+// Dropped returns how many events were dropped because the buffer was full
+// or p was closed.
+func (p *EventPublisher) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Close publishes the buffered events and stops p. Events of hooks called
+// afterwards, e.g. by tasks a Processor is still draining, are dropped.
+func (p *EventPublisher) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.events)
+	}
+	p.mu.Unlock()
+	<-p.done
+}
+
+func (p *EventPublisher) publish(kind EventKind, e TaskEvent) {
+	le := LifecycleEvent{Kind: kind, TaskID: e.TaskID, Type: e.Type, Queue: e.Queue, Retried: e.Retried, DurationMS: e.Duration.Milliseconds(), At: e.At}
+	if e.Err != nil {
+		le.Error = e.Err.Error()
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		p.dropped.Add(1)
+		return
+	}
+	select {
+	case p.events <- le:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+func (p *EventPublisher) OnEnqueued(ctx context.Context, e TaskEvent)  { p.publish(EventCreated, e) }
+func (p *EventPublisher) OnStarted(ctx context.Context, e TaskEvent)   { p.publish(EventStarted, e) }
+func (p *EventPublisher) OnCompleted(ctx context.Context, e TaskEvent) { p.publish(EventCompleted, e) }
+func (p *EventPublisher) OnFailed(ctx context.Context, e TaskEvent)    { p.publish(EventFailed, e) }
+func (p *EventPublisher) OnRetry(ctx context.Context, e TaskEvent)     { p.publish(EventRetry, e) }
+
+type RedisStreamSinkOptions struct {
+	// Stream is the stream key. Defaults to "asyncx:events".
+	Stream string
+	// MaxLen, if positive, approximately caps the stream length.
+	MaxLen int64
+}
+
+// RedisStreamSink appends events to a Redis stream, as JSON in the
+// "event" field with the task ID and kind as separate fields for
+// filtering consumers.
+type RedisStreamSink struct {
+	rdb  redis.UniversalClient
+	opts RedisStreamSinkOptions
+}
+
+func NewRedisStreamSink(redisOpt asynq.RedisConnOpt, opts RedisStreamSinkOptions) *RedisStreamSink {
+	if opts.Stream == "" {
+		opts.Stream = "asyncx:events"
+	}
+	return &RedisStreamSink{rdb: makeRedis(redisOpt), opts: opts}
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, e LifecycleEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.opts.Stream,
+		MaxLen: s.opts.MaxLen,
+		Approx: s.opts.MaxLen > 0,
+		Values: []any{"task_id", e.TaskID, "kind", string(e.Kind), "event", string(b)},
+	}).Err()
+}
+
+func (s *RedisStreamSink) Close() error {
+	return s.rdb.Close()
+}
@@ -0,0 +1,105 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestEventPublisher_RedisStream(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	redisOpt := asynq.RedisClientOpt{Addr: s.Addr()}
+	sink := NewRedisStreamSink(redisOpt, RedisStreamSinkOptions{MaxLen: 100})
+	defer sink.Close()
+	events := NewEventPublisher(sink, EventPublisherConfig{})
+
+	processor := NewProcessor(redisOpt, store, ProcessorConfig{Concurrency: 1, Hooks: events})
+	processor.HandleFunc("email:send", func(ctx context.Context, t *asynq.Task) error { return nil })
+	processor.HandleFunc("email:bounce", func(ctx context.Context, t *asynq.Task) error { return errors.New("boom") })
+	go func() { _ = processor.Start(nil) }()
+	client := NewClient(redisOpt, store, ClientOptions{Hooks: events})
+	defer client.Close()
+
+	ok, err := client.Enqueue(ctx, "email:send", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	bad, err := client.Enqueue(ctx, "email:bounce", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 5*time.Second, func() (bool, error) {
+		a, err := store.GetByID(ctx, ok.ID)
+		if err != nil {
+			return false, err
+		}
+		b, err := store.GetByID(ctx, bad.ID)
+		if err != nil {
+			return false, err
+		}
+		return a.Status == StatusCompleted && b.Status == StatusFailed, nil
+	}); err != nil {
+		t.Fatalf("tasks did not finish: %v", err)
+	}
+	processor.Shutdown()
+	events.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+	msgs, err := rdb.XRange(ctx, "asyncx:events", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRange: %v", err)
+	}
+	kinds := map[string][]EventKind{}
+	for _, m := range msgs {
+		var e LifecycleEvent
+		if err := json.Unmarshal([]byte(m.Values["event"].(string)), &e); err != nil {
+			t.Fatalf("event %v: %v", m.Values, err)
+		}
+		if m.Values["task_id"] != e.TaskID || m.Values["kind"] != string(e.Kind) {
+			t.Fatalf("fields %v do not match event %+v", m.Values, e)
+		}
+		if e.Kind == EventFailed && e.Error != "boom" {
+			t.Fatalf("failed event %+v", e)
+		}
+		kinds[e.TaskID] = append(kinds[e.TaskID], e.Kind)
+	}
+	want := map[string][]EventKind{
+		ok.ID:  {EventCreated, EventStarted, EventCompleted},
+		bad.ID: {EventCreated, EventStarted, EventFailed},
+	}
+	for id, w := range want {
+		if !slices.Equal(kinds[id], w) {
+			t.Fatalf("%s: events %v, want %v", id, kinds[id], w)
+		}
+	}
+	if events.Dropped() != 0 {
+		t.Fatalf("dropped %d events", events.Dropped())
+	}
+}
+
+type discardSink struct{}
+
+func (discardSink) Publish(ctx context.Context, e LifecycleEvent) error { return nil }
+
+func TestEventPublisher_DropsAfterClose(t *testing.T) {
+	events := NewEventPublisher(discardSink{}, EventPublisherConfig{})
+	events.Close()
+	events.Close()
+	events.OnCompleted(context.Background(), TaskEvent{TaskID: "late"})
+	if n := events.Dropped(); n != 1 {
+		t.Fatalf("Dropped = %d, want 1", n)
+	}
+}
@@ -0,0 +1,96 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ExportFormat selects how Export writes records.
+type ExportFormat string
+
+const (
+	// ExportJSONL writes one TaskRecord JSON object per line.
+	ExportJSONL ExportFormat = "jsonl"
+	// ExportCSV writes a header row and one row per record with the
+	// columns in ExportColumns.
+	ExportCSV ExportFormat = "csv"
+)
+
+// RecordStreamer is implemented by stores that can visit the records
+// matching a filter without loading them all, as SQLStore does.
+type RecordStreamer interface {
+	Each(ctx context.Context, f TaskFilter, fn func(*TaskRecord) error) error
+}
+
+// ExportColumns are the CSV columns written by Export.
+var ExportColumns = []string{"id", "type", "queue", "status", "task_class", "priority", "created_at", "enqueued_at", "started_at", "finished_at",
+	"error_msg", "failure_kind", "created_by", "source", "dedup_key", "parent_task_id", "worker_id", "hostname", "metadata_json", "payload_json", "result_json"}
+
+// Export writes the records matching f to w, oldest first, for audit
+// extracts. It streams from a RecordStreamer and falls back to List.
+// Times are RFC 3339 in UTC.
+func Export(ctx context.Context, store Store, f TaskFilter, w io.Writer, format ExportFormat) error {
+	var write func(*TaskRecord) error
+	var flush func() error
+	switch format {
+	case ExportJSONL:
+		enc := json.NewEncoder(w)
+		write = func(rec *TaskRecord) error { return enc.Encode(rec) }
+		flush = func() error { return nil }
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(ExportColumns); err != nil {
+			return err
+		}
+		write = func(rec *TaskRecord) error { return cw.Write(csvRow(rec)) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return fmt.Errorf("asyncx: unknown export format %q", format)
+	}
+	var err error
+	if rs, ok := storeAs[RecordStreamer](store); ok {
+		err = rs.Each(ctx, f, write)
+	} else {
+		var recs []*TaskRecord
+		recs, err = listRecords(ctx, store, f)
+		for _, rec := range recs {
+			if err = write(rec); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// csvRow renders rec in ExportColumns order.
+func csvRow(rec *TaskRecord) []string {
+	ts := func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	var metadata string
+	if md := encodeMetadata(rec.Metadata); md != nil {
+		metadata = *md
+	}
+	return []string{rec.ID, rec.Type, rec.Queue, string(rec.Status), string(rec.Class), string(rec.Priority), ts(&rec.CreatedAt), ts(&rec.EnqueuedAt),
+		ts(rec.StartedAt), ts(rec.FinishedAt), str(rec.ErrorMsg), string(rec.FailureKind), rec.CreatedBy, rec.Source, rec.DedupKey,
+		rec.ParentTaskID, rec.WorkerID, rec.Hostname, metadata, rec.PayloadJSON, str(rec.ResultJSON)}
+}
@@ -0,0 +1,53 @@
+package asyncx
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+	for _, rec := range []TaskRecord{
+		{ID: "a", Type: "invoice:send", Queue: "default", PayloadJSON: `{"n":1}`, Metadata: map[string]string{"tenant": "acme"}},
+		{ID: "b", Type: "invoice:send", Queue: "default", PayloadJSON: `{"text":"a,\"quoted\"\nline"}`},
+		{ID: "c", Type: "report:build", Queue: "low", PayloadJSON: `{}`},
+	} {
+		if err := store.InsertCreated(ctx, rec); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	f := TaskFilter{Type: "invoice:send"}
+
+	var buf bytes.Buffer
+	if err := Export(ctx, store, f, &buf, ExportJSONL); err != nil {
+		t.Fatalf("Export jsonl: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var first TaskRecord
+	if len(lines) != 2 || json.Unmarshal([]byte(lines[0]), &first) != nil || first.ID != "a" || first.Metadata["tenant"] != "acme" {
+		t.Fatalf("jsonl export:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Export(ctx, store, f, &buf, ExportCSV); err != nil {
+		t.Fatalf("Export csv: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 3 || rows[0][0] != "id" || rows[2][0] != "b" {
+		t.Fatalf("csv export: %v %v", rows, err)
+	}
+	if got := rows[2][len(ExportColumns)-2]; got != `{"text":"a,\"quoted\"\nline"}` {
+		t.Fatalf("payload column %q", got)
+	}
+
+	if err := Export(ctx, store, f, &buf, "xml"); err == nil {
+		t.Fatal("Export should reject unknown formats")
+	}
+}
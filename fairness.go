@@ -0,0 +1,102 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// TenantFairness caps the share of ProcessorConfig.Concurrency the tasks of
+// one tenant may take, so that a tenant with a large backlog cannot starve
+// the others. Tasks over their tenant's share are recorded as
+// StatusThrottled and put back after RetryAfter without counting as a
+// failure. Shares are enforced per Processor.
+type TenantFairness struct {
+	// MaxShare is the largest fraction of Concurrency a tenant may use,
+	// e.g. 0.25. Every tenant gets at least one worker.
+	MaxShare float64
+	// Shares overrides MaxShare per tenant.
+	Shares map[string]float64
+	// Tenant returns the tenant of a task; rec is nil for tasks without a
+	// record. Defaults to the record's "tenant" metadata label, then the
+	// tenant of its RequestInfo. Tasks with no tenant are not limited.
+	Tenant func(ctx context.Context, t *asynq.Task, rec *TaskRecord) string
+	// RetryAfter is when a throttled task is tried again. Defaults to one
+	// second.
+	RetryAfter time.Duration
+}
+
+// tenantGate counts the running tasks of each tenant.
+type tenantGate struct {
+	cfg         TenantFairness
+	concurrency int
+	mu          sync.Mutex
+	running     map[string]int
+}
+
+func newTenantGate(cfg *TenantFairness, concurrency int) *tenantGate {
+	if cfg == nil {
+		return nil
+	}
+	g := &tenantGate{cfg: *cfg, concurrency: concurrency, running: make(map[string]int)}
+	if g.cfg.Tenant == nil {
+		g.cfg.Tenant = recordTenant
+	}
+	if g.cfg.RetryAfter <= 0 {
+		g.cfg.RetryAfter = time.Second
+	}
+	return g
+}
+
+// recordTenant is the default TenantFairness.Tenant.
+func recordTenant(_ context.Context, _ *asynq.Task, rec *TaskRecord) string {
+	if rec == nil {
+		return ""
+	}
+	if tenant := rec.Metadata["tenant"]; tenant != "" {
+		return tenant
+	}
+	var ri RequestInfo
+	if rec.RequestJSON != nil {
+		_ = json.Unmarshal([]byte(*rec.RequestJSON), &ri)
+	}
+	return ri.Tenant
+}
+
+// acquire takes a worker for the tenant of t, or returns a *ThrottledError
+// if the tenant uses up its share. release must be called once the task
+// is done.
+func (g *tenantGate) acquire(ctx context.Context, t *asynq.Task, rec *TaskRecord) (release func(), err error) {
+	tenant := g.cfg.Tenant(ctx, t, rec)
+	if tenant == "" {
+		return func() {}, nil
+	}
+	share, ok := g.cfg.Shares[tenant]
+	if !ok {
+		share = g.cfg.MaxShare
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	limit := max(int(share*float64(g.concurrency)), 1)
+	if g.running[tenant] >= limit {
+		return nil, &ThrottledError{Key: "tenant:" + tenant, RetryAfter: g.cfg.RetryAfter}
+	}
+	g.running[tenant]++
+	return func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.running[tenant]--; g.running[tenant] == 0 {
+			delete(g.running, tenant)
+		}
+	}, nil
+}
+
+// resize applies a new Concurrency to the shares.
+func (g *tenantGate) resize(concurrency int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.concurrency = concurrency
+}
@@ -0,0 +1,78 @@
+package asyncx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_TenantFairness(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{
+		Concurrency:    4,
+		TenantFairness: &TenantFairness{MaxShare: 0.25, RetryAfter: time.Hour},
+	})
+	var mu sync.Mutex
+	running := map[string]int{}
+	block := make(chan struct{})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error {
+		tenant := MetadataFromContext(ctx)["tenant"]
+		mu.Lock()
+		running[tenant]++
+		mu.Unlock()
+		<-block
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	// The record, and so the tenant, must exist before the task is picked up.
+	client := NewClient(redis, store, ClientOptions{PersistBeforeEnqueue: true})
+	defer client.Close()
+	ctx := context.Background()
+	var ids []string
+	for _, tenant := range []string{"a", "a", "a", "b"} {
+		info, err := client.Enqueue(ctx, "report:build", nil, WithMetadata(map[string]string{"tenant": tenant}))
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		ids = append(ids, info.ID)
+	}
+	if err := pollUntil(t, 5*time.Second, func() (bool, error) {
+		recs, err := store.List(ctx, TaskFilter{Status: StatusThrottled})
+		mu.Lock()
+		defer mu.Unlock()
+		return err == nil && len(recs) == 2 && running["b"] == 1, nil
+	}); err != nil {
+		mu.Lock()
+		t.Fatalf("want two of tenant a's tasks throttled while b runs: %v %v", err, running)
+	}
+	mu.Lock()
+	if running["a"] != 1 {
+		t.Fatalf("tenant a ran %d tasks at once, want 1", running["a"])
+	}
+	mu.Unlock()
+	close(block)
+	if err := pollUntil(t, 5*time.Second, func() (bool, error) {
+		recs, err := store.List(ctx, TaskFilter{Status: StatusCompleted})
+		return err == nil && len(recs) == 2, nil
+	}); err != nil {
+		t.Fatalf("admitted tasks did not complete: %v", err)
+	}
+	// Completed tasks give their worker back.
+	release, err := processor.tenants.acquire(ctx, nil, &TaskRecord{Metadata: map[string]string{"tenant": "a"}})
+	if err != nil {
+		t.Fatalf("acquire after completion: %v", err)
+	}
+	release()
+}
@@ -0,0 +1,76 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrNotHeld is returned by Client.Unfreeze for a task that was not frozen.
+var ErrNotHeld = errors.New("asyncx: task is not held")
+
+// Freeze keeps a pending, scheduled or retrying task from running, without
+// losing it, until Unfreeze is called, e.g. while a job waits for a human
+// decision. Like a parked task, it is taken off Redis and kept in its
+// record, set to StatusHeld, so that no asynq archive trimming can lose it.
+// Only its type, payload and queue are kept: the type's TaskDefaults apply
+// once it is unfrozen, and its retry count starts over. Running tasks
+// cannot be frozen. Requires a store.
+func (c *Client) Freeze(ctx context.Context, queue, taskID string) error {
+	if c.store == nil {
+		return errors.New("asyncx: Freeze requires a store")
+	}
+	info, err := c.inspector.GetTaskInfo(queue, taskID)
+	if err != nil {
+		return fmt.Errorf("asyncx: freeze %s: %w", taskID, err)
+	}
+	switch info.State {
+	case asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry:
+	default:
+		return fmt.Errorf("asyncx: freeze %s: task is %s", taskID, info.State)
+	}
+	rec, err := c.store.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	// Marked first, so that a crash leaves the task on Redis rather than
+	// lost; a worker that starts it moves the record on.
+	if err := markStatus(ctx, c.store, taskID, StatusHeld, time.Now().UTC()); err != nil {
+		return err
+	}
+	if err := c.inspector.DeleteTask(queue, taskID); err != nil {
+		// A worker took the task in the meantime; MarkEnqueued leaves
+		// its record alone once it has started.
+		enqueuedAt := rec.EnqueuedAt
+		if enqueuedAt.IsZero() {
+			enqueuedAt = time.Now().UTC()
+		}
+		_ = c.store.MarkEnqueued(ctx, taskID, rec.Queue, enqueuedAt)
+		return fmt.Errorf("asyncx: freeze %s: %w", taskID, err)
+	}
+	return nil
+}
+
+// Unfreeze puts a task frozen with Freeze back on Redis, pending; it runs
+// as soon as a worker is free, even if it was scheduled for later. It
+// returns ErrNotHeld for any other task, so that archived failures are not
+// revived by mistake. If Redis fails, the record is left in
+// StatusEnqueueFailed for a Reenqueuer.
+func (c *Client) Unfreeze(ctx context.Context, taskID string) error {
+	if c.store == nil {
+		return errors.New("asyncx: Unfreeze requires a store")
+	}
+	rec, err := c.store.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if rec.Status != StatusHeld {
+		return fmt.Errorf("%w: %s is %s", ErrNotHeld, taskID, rec.Status)
+	}
+	// Tasks frozen by earlier versions are still archived in asynq.
+	_ = c.inspector.DeleteTask(rec.Queue, taskID)
+	return c.reenqueue(ctx, rec)
+}
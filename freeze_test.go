@@ -0,0 +1,50 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_FreezeUnfreeze(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	info, err := client.Enqueue(ctx, "freeze:task", map[string]int{"n": 1}, asynq.ProcessIn(time.Hour))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := client.Unfreeze(ctx, info.ID); !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("Unfreeze of a scheduled task: got %v, want ErrNotHeld", err)
+	}
+	if err := client.Freeze(ctx, info.Queue, info.ID); err != nil {
+		t.Fatalf("Freeze: %v", err)
+	}
+	if rec, _ := store.GetByID(ctx, info.ID); rec.Status != StatusHeld {
+		t.Fatalf("want %s, got %s", StatusHeld, rec.Status)
+	}
+	if ti, err := client.inspector.GetTaskInfo(info.Queue, info.ID); !errors.Is(err, asynq.ErrTaskNotFound) {
+		t.Fatalf("frozen task still on Redis: %v, %v", ti, err)
+	}
+
+	if err := client.Unfreeze(ctx, info.ID); err != nil {
+		t.Fatalf("Unfreeze: %v", err)
+	}
+	if rec, _ := store.GetByID(ctx, info.ID); rec.Status != StatusCreated {
+		t.Fatalf("want %s, got %s", StatusCreated, rec.Status)
+	}
+	ti, err := client.inspector.GetTaskInfo(info.Queue, info.ID)
+	if err != nil || ti.State != asynq.TaskStatePending || string(ti.Payload) != `{"n":1}` {
+		t.Fatalf("want pending task, got %v, %v", ti, err)
+	}
+}
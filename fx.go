@@ -0,0 +1,38 @@
+package asyncx
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/fx"
+)
+
+// FxModule provides Store, *Client, *Processor and *asynq.ServeMux from a
+// supplied Config, and runs the Processor for the lifetime of the fx app.
+// Register handlers on the mux from an fx.Invoke:
+//
+//	fx.New(
+//		fx.Supply(cfg),
+//		asyncx.FxModule,
+//		fx.Invoke(func(mux *asynq.ServeMux, m Mailer) {
+//			asyncx.RegisterHandler(mux, "email:deliver", NewEmailHandler(m))
+//		}),
+//	)
+var FxModule = fx.Module("asyncx",
+	fx.Provide(ProvideStore, ProvideClient, ProvideProcessor, ProvideServeMux),
+	fx.Invoke(runWithLifecycle),
+)
+
+// runWithLifecycle starts the Processor on app start and shuts it and the
+// Client down on app stop.
+func runWithLifecycle(lc fx.Lifecycle, c *Client, p *Processor, mux *asynq.ServeMux) {
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			return p.start(ctx, mux)
+		},
+		OnStop: func(context.Context) error {
+			p.Shutdown()
+			return c.Close()
+		},
+	})
+}
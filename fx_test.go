@@ -0,0 +1,65 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/fx"
+)
+
+func TestFxModule_RunsProcessor(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_fx_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	cfg := Config{
+		Redis:     asynq.RedisClientOpt{Addr: s.Addr()},
+		DB:        db,
+		Dialect:   DialectSQLite,
+		Processor: ProcessorConfig{Concurrency: 1},
+	}
+	var client *Client
+	var store Store
+	var processor *Processor
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(cfg),
+		FxModule,
+		fx.Invoke(func(mux *asynq.ServeMux) {
+			mux.HandleFunc("fx:task", func(ctx context.Context, t *asynq.Task) error { return nil })
+		}),
+		fx.Populate(&client, &store, &processor),
+	)
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer app.Stop(ctx)
+	if !processor.Healthz(ctx).Running {
+		t.Fatal("processor not reported running")
+	}
+
+	info, err := client.Enqueue(ctx, "fx:task", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(ctx, info.ID)
+		return err == nil && rec.Status == StatusCompleted, nil
+	}); err != nil {
+		t.Fatalf("task not completed: %v", err)
+	}
+}
+
+func TestProvideStore_RequiresDB(t *testing.T) {
+	if _, err := ProvideStore(Config{}); err == nil {
+		t.Fatal("expected error without DB")
+	}
+}
@@ -0,0 +1,151 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ExecutionGuardStore is implemented by stores that can arbitrate between a
+// re-driven task and the original it replaces. Records sharing a guard
+// token run at most once between them: the first to claim the token wins
+// and the Processor marks the others StatusSuppressed without running them.
+type ExecutionGuardStore interface {
+	// SetGuardToken attaches token to an existing record.
+	SetGuardToken(ctx context.Context, taskID, token string) error
+	// ClaimGuard claims token for taskID unless another task holds it, and
+	// returns the ID of the task that holds it.
+	ClaimGuard(ctx context.Context, token, taskID string) (string, error)
+}
+
+type guardOption string
+
+// WithExecutionGuard stores token with the new record so that it runs only
+// if no other record carrying token ran first. Client.Redrive sets it.
+func WithExecutionGuard(token string) asynq.Option { return guardOption(token) }
+
+func (o guardOption) String() string         { return fmt.Sprintf("ExecutionGuard(%q)", string(o)) }
+func (o guardOption) Type() asynq.OptionType { return asyncxOpt }
+func (o guardOption) Value() interface{}     { return string(o) }
+
+// Redrive enqueues a fresh copy of rec with the same type, queue and
+// payload. When the store implements ExecutionGuardStore, the original and
+// the copy share a guard token, so if the original's Redis copy reappears
+// only one of them executes.
+func (c *Client) Redrive(ctx context.Context, rec *TaskRecord, options ...asynq.Option) (*asynq.TaskInfo, error) {
+	if rec.PayloadPurgedAt != nil {
+		return nil, ErrPayloadPurged
+	}
+	opts := append([]asynq.Option{asynq.Queue(rec.Queue)}, options...)
+	if gs, ok := storeAs[ExecutionGuardStore](c.store); ok {
+		// The original's ID is the token. A record that was itself a re-drive
+		// already holds its old token, so it is replaced.
+		if err := gs.SetGuardToken(ctx, rec.ID, rec.ID); err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithExecutionGuard(rec.ID))
+	}
+	if !isJSON(rec.ContentType) || isPayloadRef([]byte(rec.PayloadJSON)) {
+		b, err := PayloadBytes(rec)
+		if err != nil {
+			return nil, err
+		}
+		return c.Enqueue(ctx, rec.Type, EncodedPayload(b), opts...)
+	}
+	return c.Enqueue(ctx, rec.Type, json.RawMessage(rec.PayloadJSON), opts...)
+}
+
+// guardCheck reports whether the task with record rec may run.
+func guardCheck(ctx context.Context, store Store, rec *TaskRecord) (bool, error) {
+	gs, ok := storeAs[ExecutionGuardStore](store)
+	if !ok || rec.GuardToken == "" {
+		return true, nil
+	}
+	holder, err := gs.ClaimGuard(ctx, rec.GuardToken, rec.ID)
+	if err != nil {
+		return false, err
+	}
+	return holder == rec.ID, nil
+}
+
+func (s *SQLStore) SetGuardToken(ctx context.Context, taskID, token string) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET guard_token = ? WHERE id = ?`
+	_, err := s.db.ExecContext(ctx, q, token, taskID)
+	if err != nil {
+		qpg := `UPDATE asyncx_tasks SET guard_token = $1 WHERE id = $2`
+		_, err2 := s.db.ExecContext(ctx, qpg, token, taskID)
+		return err2
+	}
+	return nil
+}
+
+func (s *SQLStore) ClaimGuard(ctx context.Context, token, taskID string) (string, error) {
+	if s.db == nil {
+		return "", errors.New("nil db")
+	}
+	// The insert fails when the token is already claimed; the select below
+	// reports the holder either way.
+	q := `INSERT INTO asyncx_execution_guards (token, task_id, claimed_at) VALUES (?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, q, token, taskID, time.Now().UTC()); err != nil {
+		qpg := `INSERT INTO asyncx_execution_guards (token, task_id, claimed_at) VALUES ($1, $2, $3)`
+		_, _ = s.db.ExecContext(ctx, qpg, token, taskID, time.Now().UTC())
+	}
+	var holder string
+	err := s.db.QueryRowContext(ctx, `SELECT task_id FROM asyncx_execution_guards WHERE token = ?`, token).Scan(&holder)
+	if err != nil {
+		if err2 := s.db.QueryRowContext(ctx, `SELECT task_id FROM asyncx_execution_guards WHERE token = $1`, token).Scan(&holder); err2 != nil {
+			return "", err2
+		}
+	}
+	return holder, nil
+}
+
+func (s *RedisStore) guardKey(token string) string { return s.opts.Prefix + "guard:" + token }
+
+func (s *RedisStore) SetGuardToken(ctx context.Context, taskID, token string) error {
+	key := s.taskKey(taskID)
+	n, err := s.rdb.Exists(ctx, key).Result()
+	if err != nil || n == 0 {
+		return err
+	}
+	return s.rdb.HSet(ctx, key, "guard_token", token).Err()
+}
+
+func (s *RedisStore) ClaimGuard(ctx context.Context, token, taskID string) (string, error) {
+	key := s.guardKey(token)
+	if err := s.rdb.SetNX(ctx, key, taskID, s.opts.TTL).Err(); err != nil {
+		return "", err
+	}
+	return s.rdb.Get(ctx, key).Result()
+}
+
+var boltGuardsBucket = []byte("asyncx_guards")
+
+func (s *BoltStore) SetGuardToken(ctx context.Context, taskID, token string) error {
+	return s.update(taskID, func(rec *TaskRecord) { rec.GuardToken = token })
+}
+
+func (s *BoltStore) ClaimGuard(ctx context.Context, token, taskID string) (string, error) {
+	var holder string
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(boltGuardsBucket)
+		if err != nil {
+			return err
+		}
+		if v := b.Get([]byte(token)); v != nil {
+			holder = string(v)
+			return nil
+		}
+		holder = taskID
+		return b.Put([]byte(token), []byte(taskID))
+	})
+	return holder, err
+}
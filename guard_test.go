@@ -0,0 +1,74 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRedrive_OnlyOneCopyRuns(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_guard_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	// The original stays in Redis, as if it reappeared after being re-driven.
+	orig, err := client.Enqueue(ctx, "guard:task", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	rec, err := store.GetByID(ctx, orig.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	copyInfo, err := client.Redrive(ctx, rec)
+	if err != nil {
+		t.Fatalf("Redrive: %v", err)
+	}
+
+	var runs atomic.Int32
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("guard:task", func(ctx context.Context, tsk *asynq.Task) error {
+		runs.Add(1)
+		return nil
+	})
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	deadline := time.Now().Add(5 * time.Second)
+	statuses := map[Status]int{}
+	for time.Now().Before(deadline) {
+		statuses = map[Status]int{}
+		for _, id := range []string{orig.ID, copyInfo.ID} {
+			if got, err := store.GetByID(ctx, id); err == nil {
+				statuses[got.Status]++
+			}
+		}
+		if statuses[StatusCompleted]+statuses[StatusSuppressed] == 2 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if statuses[StatusCompleted] != 1 || statuses[StatusSuppressed] != 1 {
+		t.Fatalf("want one completed and one suppressed record, got %v", statuses)
+	}
+	if n := runs.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+}
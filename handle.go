@@ -0,0 +1,59 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hibiken/asynq"
+)
+
+// Handle registers h for taskType on the Processor's own mux, used by Start
+// and Run when they are given a nil mux. Registering a type twice panics,
+// as with asynq.ServeMux. At most one TaskDefaults may be given; see
+// Processor.TaskDefaults. Call Handle before Start or Run.
+func (p *Processor) Handle(taskType string, h asynq.Handler, defaults ...TaskDefaults) {
+	if p.mux == nil {
+		p.mux = asynq.NewServeMux()
+	}
+	RegisterHandler(p.mux, taskType, h)
+	if len(defaults) > 0 {
+		p.declare(taskType, defaults[0])
+	}
+}
+
+// HandleFunc registers fn for taskType; see Handle.
+func (p *Processor) HandleFunc(taskType string, fn func(context.Context, *asynq.Task) error, defaults ...TaskDefaults) {
+	p.Handle(taskType, asynq.HandlerFunc(fn), defaults...)
+}
+
+// HandleTyped registers fn for taskType on p, decoding each payload into a
+// T with the Processor's Codec. A payload that does not decode fails
+// without retries.
+func HandleTyped[T any](p *Processor, taskType string, fn func(ctx context.Context, payload T) error, defaults ...TaskDefaults) {
+	p.HandleFunc(taskType, func(ctx context.Context, t *asynq.Task) error {
+		var payload T
+		if err := Decode(ctx, t, &payload); err != nil {
+			return NonRetryable(err)
+		}
+		return fn(ctx, payload)
+	}, defaults...)
+}
+
+// serveMux returns the mux Start and Run serve: mux if given, otherwise
+// the one built with Handle. It marks the handled types in
+// ProcessorConfig.TaskTypes.
+func (p *Processor) serveMux(mux *asynq.ServeMux) (*asynq.ServeMux, error) {
+	switch {
+	case mux != nil && p.mux != nil:
+		return nil, errors.New("asyncx: handlers were registered with Handle; start the Processor with a nil mux")
+	case mux == nil && p.mux != nil:
+		mux = p.mux
+	case mux == nil:
+		mux = asynq.NewServeMux()
+	}
+	if p.types != nil {
+		p.types.markHandled(mux)
+	}
+	p.routes = mux
+	return mux, nil
+}
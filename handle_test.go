@@ -0,0 +1,111 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_Handle(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+
+	type invoice struct {
+		Customer string `json:"customer"`
+		Cents    int    `json:"cents"`
+	}
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	defer processor.Shutdown()
+	var order []string
+	processor.Use(func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			order = append(order, "mw")
+			return next.ProcessTask(ctx, t)
+		})
+	})
+	invoices := make(chan invoice, 1)
+	HandleTyped(processor, "billing:invoice", func(ctx context.Context, inv invoice) error {
+		order = append(order, "handler")
+		invoices <- inv
+		return nil
+	})
+	processor.HandleFunc("billing:ping", func(ctx context.Context, t *asynq.Task) error { return nil })
+	if err := processor.Start(asynq.NewServeMux()); err == nil {
+		t.Fatal("Start with a mux after Handle should fail")
+	}
+	go func() { _ = processor.Start(nil) }()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	if _, err := client.Enqueue(ctx, "billing:invoice", invoice{Customer: "acme", Cents: 1200}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case inv := <-invoices:
+		if inv.Customer != "acme" || inv.Cents != 1200 {
+			t.Fatalf("handler got %+v", inv)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler did not run")
+	}
+	if len(order) != 2 || order[0] != "mw" {
+		t.Fatalf("order = %v", order)
+	}
+
+	bad, err := client.Enqueue(ctx, "billing:invoice", EncodedPayload("not json"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(ctx, bad.ID)
+		return err == nil && rec.Status == StatusFailed && rec.FailureKind == FailurePermanent, nil
+	}); err != nil {
+		t.Fatalf("undecodable payload: %v", err)
+	}
+}
+
+func TestProcessor_NotFoundHandler(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+
+	forwarded := make(chan string, 1)
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1,
+		NotFoundHandler: asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			forwarded <- t.Type()
+			return nil
+		}),
+	})
+	defer processor.Shutdown()
+	processor.HandleFunc("report:build", func(ctx context.Context, t *asynq.Task) error { return nil })
+	go func() { _ = processor.Start(nil) }()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	info, err := client.Enqueue(ctx, "report:retired", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case typ := <-forwarded:
+		if typ != "report:retired" {
+			t.Fatalf("forwarded %s", typ)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("NotFoundHandler did not run")
+	}
+	if rec, err := store.GetByID(ctx, info.ID); err != nil || rec.Status != StatusUnroutable {
+		t.Fatalf("record: %+v %v", rec, err)
+	}
+}
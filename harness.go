@@ -0,0 +1,47 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hibiken/asynq"
+)
+
+// Harness runs handlers in unit tests without Redis or a Processor. Provide
+// fakes in Container, build handlers from it, then call Run.
+type Harness struct {
+	Container *Container
+	// DryRun makes IsDryRun report true inside handlers.
+	DryRun bool
+}
+
+func NewHarness() *Harness {
+	return &Harness{Container: NewContainer()}
+}
+
+// Build runs factory against the harness container.
+func (h *Harness) Build(factory HandlerFactory) (asynq.Handler, error) {
+	return factory(h.Container)
+}
+
+// Run executes handler on a task of taskType with payload JSON encoded, as
+// the Processor would: panics become *PanicError and SetResult works. It
+// returns the result recorded with SetResult, if any.
+func (h *Harness) Run(ctx context.Context, handler asynq.Handler, taskType string, payload any) (json.RawMessage, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	result := &resultHolder{}
+	ctx = context.WithValue(ctx, resultKey{}, result)
+	if h.DryRun {
+		ctx = context.WithValue(ctx, dryRunKey{}, true)
+	}
+	if err := invoke(ctx, handler, asynq.NewTask(taskType, b)); err != nil {
+		return nil, err
+	}
+	if result.json == nil {
+		return nil, nil
+	}
+	return json.RawMessage(*result.json), nil
+}
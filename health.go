@@ -0,0 +1,74 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// HealthReport is the state reported by Processor.Healthz. Redis and Store
+// are "ok" or the error of the last check; Store is empty without a store.
+// Dependencies holds the same for ProcessorConfig.Dependencies; they do not
+// affect readiness.
+type HealthReport struct {
+	Running      bool              `json:"running"`
+	Redis        string            `json:"redis"`
+	Store        string            `json:"store,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// Live reports whether the processor's workers are running.
+func (r HealthReport) Live() bool { return r.Running }
+
+// Ready reports whether the processor is running and can reach Redis and
+// its store.
+func (r HealthReport) Ready() bool {
+	return r.Running && r.Redis == "ok" && (r.Store == "" || r.Store == "ok")
+}
+
+// Healthz checks Redis and store connectivity and whether the workers are
+// running, i.e. started and not shut down.
+func (p *Processor) Healthz(ctx context.Context) HealthReport {
+	status := func(err error) string {
+		if err != nil {
+			return err.Error()
+		}
+		return "ok"
+	}
+	r := HealthReport{Running: p.running.Load()}
+	r.Redis = status(p.redis().Ping(ctx).Err())
+	if pinger, ok := storeAs[Pinger](p.store); ok {
+		r.Store = status(pinger.Ping(ctx))
+	}
+	if p.deps != nil {
+		r.Dependencies = p.deps.report(ctx)
+	}
+	return r
+}
+
+// HealthHandler serves Kubernetes probes: /healthz answers 200 while the
+// workers run and /readyz while Healthz reports ready, 503 otherwise. Both
+// write the HealthReport as JSON. Each check is bounded by timeout, five
+// seconds if zero.
+func (p *Processor) HealthHandler(timeout time.Duration) http.Handler {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	probe := func(ok func(HealthReport) bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			report := p.Healthz(ctx)
+			w.Header().Set("Content-Type", "application/json")
+			if !ok(report) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			_ = json.NewEncoder(w).Encode(report)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", probe(HealthReport.Live))
+	mux.Handle("/readyz", probe(HealthReport.Ready))
+	return mux
+}
@@ -0,0 +1,56 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_HealthHandler(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	processor := NewProcessor(asynq.RedisClientOpt{Addr: s.Addr()}, NewSQLStore(db), ProcessorConfig{Concurrency: 1})
+	defer processor.Shutdown()
+	h := processor.HealthHandler(time.Second)
+
+	probe := func(path string) (int, HealthReport) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var report HealthReport
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("%s: decode: %v", path, err)
+		}
+		return rec.Code, report
+	}
+
+	if code, _ := probe("/healthz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("healthz before start: got %d", code)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = processor.Run(ctx, asynq.NewServeMux()) }()
+	err := pollUntil(t, 5*time.Second, func() (bool, error) {
+		return processor.Healthz(ctx).Running, nil
+	})
+	if err != nil {
+		t.Fatalf("processor did not start")
+	}
+	if code, report := probe("/readyz"); code != http.StatusOK || report.Redis != "ok" || report.Store != "ok" {
+		t.Fatalf("readyz while running: got %d %+v", code, report)
+	}
+
+	s.Close()
+	if code, report := probe("/readyz"); code != http.StatusServiceUnavailable || report.Redis == "ok" {
+		t.Fatalf("readyz without redis: got %d %+v", code, report)
+	}
+	if code, _ := probe("/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz without redis: got %d", code)
+	}
+}
@@ -0,0 +1,100 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"time"
+)
+
+type workerKey struct{}
+
+// workerIdentity identifies a Processor. It is fixed at NewProcessor.
+type workerIdentity struct {
+	ID   string
+	Host string
+	PID  int
+}
+
+func newWorkerIdentity() workerIdentity {
+	host, _ := os.Hostname()
+	return workerIdentity{ID: newWorkerID(), Host: host, PID: os.Getpid()}
+}
+
+// workerFromContext returns the identity of the Processor running the task,
+// or the zero identity outside a handler.
+func workerFromContext(ctx context.Context) workerIdentity {
+	w, _ := ctx.Value(workerKey{}).(workerIdentity)
+	return w
+}
+
+// WorkerIDFromContext returns the ID of the Processor running the task, as
+// listed by Processor.Workers, or "" outside a handler.
+func WorkerIDFromContext(ctx context.Context) string {
+	return workerFromContext(ctx).ID
+}
+
+// TaskTransition is one row of a task's history in asyncx_task_events.
+type TaskTransition struct {
+	TaskID string
+	Status Status
+	// ErrorMsg is set for failures, retries and timeouts. For
+	// StatusPromoted it names the old and the new queue.
+	ErrorMsg string
+	// WorkerID is set for transitions made by a Processor.
+	WorkerID string
+	At       time.Time
+}
+
+// HistoryStore is implemented by stores that keep every transition of a
+// task, not only its latest state.
+type HistoryStore interface {
+	// History returns the transitions of taskID, oldest first.
+	History(ctx context.Context, taskID string) ([]TaskTransition, error)
+}
+
+// recordTransition appends a transition of taskID to asyncx_task_events
+// when SQLStoreOptions.History is set.
+func (s *SQLStore) recordTransition(ctx context.Context, taskID string, status Status, errorMsg string, at time.Time) error {
+	if !s.history {
+		return nil
+	}
+	q := `INSERT INTO asyncx_task_events (task_id, status, error_msg, worker_id, created_at) VALUES (?, ?, ?, ?, ?)`
+	qpg := `INSERT INTO asyncx_task_events (task_id, status, error_msg, worker_id, created_at) VALUES ($1, $2, $3, $4, $5)`
+	return s.exec(ctx, q, qpg, taskID, string(status), optional(errorMsg), optional(WorkerIDFromContext(ctx)), at.UTC())
+}
+
+// withHistory runs update and, with SQLStoreOptions.History, records the
+// transition in the same transaction.
+func (s *SQLStore) withHistory(ctx context.Context, taskID string, status Status, errorMsg string, at time.Time, update func(s *SQLStore) error) error {
+	if !s.history {
+		return update(s)
+	}
+	return s.inTx(ctx, func(s *SQLStore) error {
+		if err := update(s); err != nil {
+			return err
+		}
+		return s.recordTransition(ctx, taskID, status, errorMsg, at)
+	})
+}
+
+func (s *SQLStore) History(ctx context.Context, taskID string) ([]TaskTransition, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	q := `SELECT task_id, status, error_msg, worker_id, created_at FROM asyncx_task_events WHERE task_id = ? ORDER BY id`
+	var out []TaskTransition
+	err := s.queryRows(ctx, q, []any{taskID}, func(rows *sql.Rows) error {
+		var e TaskTransition
+		var status string
+		var errorMsg, worker sql.NullString
+		if err := rows.Scan(&e.TaskID, &status, &errorMsg, &worker, &e.At); err != nil {
+			return err
+		}
+		e.Status, e.ErrorMsg, e.WorkerID = Status(status), errorMsg.String, worker.String
+		out = append(out, e)
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,126 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestSQLStore_HistoryRecordsEveryTransition(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_history_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db, SQLStoreOptions{History: true})
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	defer processor.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error { return nil })
+	mux.HandleFunc("report:broken", func(ctx context.Context, tsk *asynq.Task) error {
+		return NonRetryable(errors.New("bad input"))
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	ok, err := client.Enqueue(ctx, "report:build", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	broken, err := client.Enqueue(ctx, "report:broken", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	history := func(id string, n int) []TaskTransition {
+		t.Helper()
+		var events []TaskTransition
+		err := pollUntil(t, 10*time.Second, func() (bool, error) {
+			var err error
+			events, err = store.History(ctx, id)
+			return len(events) >= n, err
+		})
+		if err != nil {
+			t.Fatalf("history of %s: %v %+v", id, err, events)
+		}
+		return events
+	}
+	statuses := func(events []TaskTransition) []Status {
+		var out []Status
+		for _, e := range events {
+			out = append(out, e.Status)
+		}
+		return out
+	}
+
+	events := history(ok.ID, 4)
+	if want := []Status{StatusCreated, StatusCreated, StatusInProgress, StatusCompleted}; !slices.Equal(statuses(events), want) {
+		t.Fatalf("history = %v, want %v", statuses(events), want)
+	}
+	if events[0].WorkerID != "" || events[2].WorkerID != processor.worker.ID || events[3].WorkerID != processor.worker.ID {
+		t.Fatalf("worker IDs not recorded: %+v", events)
+	}
+
+	events = history(broken.ID, 4)
+	if last := events[len(events)-1]; last.Status != StatusFailed || last.ErrorMsg == "" {
+		t.Fatalf("failure not recorded: %+v", last)
+	}
+
+	if err := store.MarkStatus(ctx, ok.ID, StatusNeedsReview, time.Now()); err != nil {
+		t.Fatalf("MarkStatus: %v", err)
+	}
+	if events := history(ok.ID, 5); events[4].Status != StatusNeedsReview {
+		t.Fatalf("MarkStatus not recorded: %+v", events[4])
+	}
+}
+
+func TestSQLStore_HistoryWrittenWithTheTransition(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_history_tx_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db, SQLStoreOptions{History: true})
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "hist-tx", Type: "report:build", Queue: "default", PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	// Transitions sharing a timestamp keep their order.
+	at := time.Now()
+	for _, status := range []Status{StatusThrottled, StatusDeferred, StatusThrottled} {
+		if err := store.MarkStatus(ctx, "hist-tx", status, at); err != nil {
+			t.Fatalf("MarkStatus: %v", err)
+		}
+	}
+	events, err := store.History(ctx, "hist-tx")
+	if err != nil || len(events) != 4 || events[1].Status != StatusThrottled || events[2].Status != StatusDeferred || events[3].Status != StatusThrottled {
+		t.Fatalf("History: %+v %v", events, err)
+	}
+
+	// A transition whose history cannot be written is rolled back.
+	if _, err := db.Exec(`DROP TABLE asyncx_task_events`); err != nil {
+		t.Fatalf("drop: %v", err)
+	}
+	if err := store.MarkCompleted(ctx, "hist-tx", nil, time.Now()); err == nil {
+		t.Fatal("MarkCompleted should fail without the history table")
+	}
+	if rec, err := store.GetByID(ctx, "hist-tx"); err != nil || rec.Status != StatusThrottled {
+		t.Fatalf("status should be unchanged: %+v %v", rec, err)
+	}
+}
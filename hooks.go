@@ -0,0 +1,98 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// TaskEvent describes a lifecycle transition passed to Hooks.
+type TaskEvent struct {
+	TaskID   string
+	Type     string
+	Queue    string
+	Payload  []byte
+	Retried  int           // retries so far; processor events only
+	MaxRetry int           // processor events only
+	Err      error         // set for OnFailed and OnRetry
+	Duration time.Duration // handler run time; set for OnCompleted, OnFailed and OnRetry
+	StolenBy string        // queue whose idle workers stole the task (see QueueLimit.StealFrom)
+	At       time.Time
+}
+
+// Hooks lets applications attach side effects (metrics, notifications, cache
+// invalidation) to lifecycle transitions. Hooks run synchronously on the
+// enqueueing or worker goroutine and should return quickly.
+// Embed NopHooks to implement only the methods you need.
+type Hooks interface {
+	OnEnqueued(ctx context.Context, e TaskEvent)
+	OnStarted(ctx context.Context, e TaskEvent)
+	OnCompleted(ctx context.Context, e TaskEvent)
+	// OnFailed is called when a handler error is final: retries are exhausted
+	// or the error wraps asynq.SkipRetry.
+	OnFailed(ctx context.Context, e TaskEvent)
+	// OnRetry is called when a handler error will be retried.
+	OnRetry(ctx context.Context, e TaskEvent)
+}
+
+// NopHooks implements Hooks with no-ops.
+type NopHooks struct{}
+
+func (NopHooks) OnEnqueued(ctx context.Context, e TaskEvent)  {}
+func (NopHooks) OnStarted(ctx context.Context, e TaskEvent)   {}
+func (NopHooks) OnCompleted(ctx context.Context, e TaskEvent) {}
+func (NopHooks) OnFailed(ctx context.Context, e TaskEvent)    {}
+func (NopHooks) OnRetry(ctx context.Context, e TaskEvent)     {}
+
+type multiHooks []Hooks
+
+// MultiHooks fans every event out to hs in order.
+func MultiHooks(hs ...Hooks) Hooks { return multiHooks(hs) }
+
+func (m multiHooks) OnEnqueued(ctx context.Context, e TaskEvent) {
+	for _, h := range m {
+		h.OnEnqueued(ctx, e)
+	}
+}
+
+func (m multiHooks) OnStarted(ctx context.Context, e TaskEvent) {
+	for _, h := range m {
+		h.OnStarted(ctx, e)
+	}
+}
+
+func (m multiHooks) OnCompleted(ctx context.Context, e TaskEvent) {
+	for _, h := range m {
+		h.OnCompleted(ctx, e)
+	}
+}
+
+func (m multiHooks) OnFailed(ctx context.Context, e TaskEvent) {
+	for _, h := range m {
+		h.OnFailed(ctx, e)
+	}
+}
+
+func (m multiHooks) OnRetry(ctx context.Context, e TaskEvent) {
+	for _, h := range m {
+		h.OnRetry(ctx, e)
+	}
+}
+
+// taskEvent builds a TaskEvent from the asynq handler context.
+func taskEvent(ctx context.Context, t *asynq.Task) TaskEvent {
+	e := TaskEvent{Type: t.Type(), Payload: t.Payload(), At: time.Now().UTC()}
+	e.TaskID, _ = getTaskID(ctx)
+	e.Queue, _ = getQueueName(ctx)
+	e.Retried, _ = getRetryCount(ctx)
+	e.MaxRetry, _ = getMaxRetry(ctx)
+	e.StolenBy = stolenBy(ctx, e.Queue)
+	return e
+}
+
+// willRetry reports whether asynq will retry a task that returned err.
+func willRetry(e TaskEvent, err error) bool {
+	return e.Retried < e.MaxRetry && !errors.Is(err, asynq.SkipRetry) && !errors.Is(err, asynq.RevokeTask)
+}
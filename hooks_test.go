@@ -0,0 +1,74 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+type recordingHooks struct {
+	NopHooks
+	mu     sync.Mutex
+	events map[string][]string
+}
+
+func (h *recordingHooks) add(kind string, e TaskEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events[e.Type] = append(h.events[e.Type], kind)
+}
+
+func (h *recordingHooks) get(taskType string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.events[taskType]...)
+}
+
+func (h *recordingHooks) OnEnqueued(ctx context.Context, e TaskEvent)  { h.add("enqueued", e) }
+func (h *recordingHooks) OnStarted(ctx context.Context, e TaskEvent)   { h.add("started", e) }
+func (h *recordingHooks) OnCompleted(ctx context.Context, e TaskEvent) { h.add("completed", e) }
+func (h *recordingHooks) OnFailed(ctx context.Context, e TaskEvent)    { h.add("failed", e) }
+
+func TestHooks_ClientAndProcessor(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	hooks := &recordingHooks{events: map[string][]string{}}
+
+	processor := NewProcessor(redis, nil, ProcessorConfig{Hooks: hooks})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("hook:ok", func(ctx context.Context, t *asynq.Task) error { return nil })
+	mux.HandleFunc("hook:fail", func(ctx context.Context, t *asynq.Task) error { return errors.New("boom") })
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, nil, ClientOptions{Hooks: hooks})
+	defer client.Close()
+	if _, err := client.Enqueue(context.Background(), "hook:ok", nil); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := client.Enqueue(context.Background(), "hook:fail", nil, asynq.MaxRetry(0)); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	want := map[string][]string{
+		"hook:ok":   {"enqueued", "started", "completed"},
+		"hook:fail": {"enqueued", "started", "failed"},
+	}
+	for typ, w := range want {
+		err := pollUntil(t, 3*time.Second, func() (bool, error) { return len(hooks.get(typ)) == len(w), nil })
+		got := hooks.get(typ)
+		if err != nil {
+			t.Fatalf("%s: want events %v, got %v", typ, w, got)
+		}
+		for i := range w {
+			if got[i] != w[i] {
+				t.Fatalf("%s: want events %v, got %v", typ, w, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrKeyInProgress is returned by IdempotencyKeys.Do for a key another
+// task holds while it runs, so that asynq retries the duplicate later.
+var ErrKeyInProgress = errors.New("asyncx: idempotency key in progress")
+
+// IdempotencyKeys records processed keys in asyncx_idempotency_keys so that
+// handlers, which asynq runs at least once, apply their effects once.
+type IdempotencyKeys struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+func NewIdempotencyKeys(db *sql.DB, dialect Dialect) *IdempotencyKeys {
+	return &IdempotencyKeys{db: db, dialect: dialect}
+}
+
+// Seen reports whether key has been recorded as processed.
+func (k *IdempotencyKeys) Seen(ctx context.Context, key string) (bool, error) {
+	_, done, _, err := k.lookup(ctx, key)
+	return done, err
+}
+
+// lookup returns the task holding key and whether it finished, with found
+// false if key is not recorded.
+func (k *IdempotencyKeys) lookup(ctx context.Context, key string) (taskID string, done, found bool, err error) {
+	var id sql.NullString
+	var completedAt sql.NullTime
+	q := `SELECT task_id, completed_at FROM asyncx_idempotency_keys WHERE idem_key = ?`
+	err = k.db.QueryRowContext(ctx, bind(k.dialect, q), key).Scan(&id, &completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, false, nil
+	}
+	return id.String, completedAt.Valid, err == nil, err
+}
+
+// Record marks key processed by taskID, using tx so that the key commits
+// with the handler's own writes. It fails if key is already recorded, which
+// makes a concurrent duplicate roll its transaction back.
+func (k *IdempotencyKeys) Record(ctx context.Context, tx Execer, key, taskID string) error {
+	now := time.Now().UTC()
+	q := `INSERT INTO asyncx_idempotency_keys (idem_key, task_id, created_at, completed_at) VALUES (?, ?, ?, ?)`
+	_, err := tx.ExecContext(ctx, bind(k.dialect, q), key, optional(taskID), now, now)
+	return err
+}
+
+// Do runs fn unless key has been processed. It first reserves key for
+// taskID, so that a concurrent duplicate fails with ErrKeyInProgress
+// instead of running fn too, then marks it processed once fn succeeds, or
+// releases it if fn fails. A reservation left by a crash is taken over by
+// a retry of the same task. For effects outside the database, such as
+// sending an email, a crash between fn and the record can still repeat fn;
+// pass key on to services that accept idempotency keys to close that gap.
+func (k *IdempotencyKeys) Do(ctx context.Context, key, taskID string, fn func(ctx context.Context) error) error {
+	q := `INSERT INTO asyncx_idempotency_keys (idem_key, task_id, created_at) VALUES (?, ?, ?)`
+	if _, err := k.db.ExecContext(ctx, bind(k.dialect, q), key, optional(taskID), time.Now().UTC()); err != nil {
+		// Most likely the key is taken; otherwise report the insert error.
+		owner, done, found, lerr := k.lookup(ctx, key)
+		switch {
+		case lerr != nil:
+			return lerr
+		case !found:
+			return err
+		case done:
+			return nil
+		case taskID == "" || owner != taskID:
+			return ErrKeyInProgress
+		}
+	}
+	if err := fn(ctx); err != nil {
+		qd := `DELETE FROM asyncx_idempotency_keys WHERE idem_key = ? AND completed_at IS NULL`
+		_, _ = k.db.ExecContext(context.WithoutCancel(ctx), bind(k.dialect, qd), key)
+		return err
+	}
+	qc := `UPDATE asyncx_idempotency_keys SET completed_at = ? WHERE idem_key = ?`
+	_, err := k.db.ExecContext(ctx, bind(k.dialect, qc), time.Now().UTC(), key)
+	return err
+}
+
+// KeyFunc derives the idempotency key of a task, e.g. the order ID in its
+// payload for a charge.
+type KeyFunc func(ctx context.Context, t *asynq.Task) (string, error)
+
+// Idempotent wraps handler so that it succeeds at most once per key, with
+// IdempotencyKeys.Do: a task whose key keys has recorded completes without
+// running handler, and one whose key another task holds fails with
+// ErrKeyInProgress and is retried. Tasks with an empty key always run; a
+// keyFn error fails the task without retries.
+func Idempotent(keys *IdempotencyKeys, handler asynq.Handler, keyFn KeyFunc) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		key, err := keyFn(ctx, t)
+		if err != nil {
+			return NonRetryable(err)
+		}
+		if key == "" {
+			return handler.ProcessTask(ctx, t)
+		}
+		taskID, _ := getTaskID(ctx)
+		return keys.Do(ctx, key, taskID, func(ctx context.Context) error {
+			return handler.ProcessTask(ctx, t)
+		})
+	})
+}
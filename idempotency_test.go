@@ -0,0 +1,60 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestIdempotent(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_idempotent_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	charges := map[string]int{}
+	fail := true
+	h := Idempotent(NewIdempotencyKeys(db, DialectSQLite), asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if fail {
+			fail = false
+			return errors.New("card declined")
+		}
+		var p struct{ Order string }
+		_ = json.Unmarshal(t.Payload(), &p)
+		charges[p.Order]++
+		return nil
+	}), func(ctx context.Context, t *asynq.Task) (string, error) {
+		var p struct{ Order string }
+		if err := json.Unmarshal(t.Payload(), &p); err != nil {
+			return "", err
+		}
+		return "charge:" + p.Order, nil
+	})
+
+	task := asynq.NewTask("billing:charge", []byte(`{"Order":"o-1"}`))
+	if err := h.ProcessTask(ctx, task); err == nil {
+		t.Fatal("first attempt should fail")
+	}
+	for range 3 {
+		if err := h.ProcessTask(ctx, task); err != nil {
+			t.Fatalf("ProcessTask: %v", err)
+		}
+	}
+	if err := h.ProcessTask(ctx, asynq.NewTask("billing:charge", []byte(`{"Order":"o-2"}`))); err != nil {
+		t.Fatalf("other order: %v", err)
+	}
+	if charges["o-1"] != 1 || charges["o-2"] != 1 {
+		t.Fatalf("charges = %v", charges)
+	}
+	if err := h.ProcessTask(ctx, asynq.NewTask("billing:charge", []byte(`nope`))); !IsNonRetryable(err) {
+		t.Fatalf("bad payload: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package asyncx
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// IDGenerator returns a new task ID for ClientOptions.IDGenerator. IDs must
+// be unique; ULID and UUIDv7 are time-sortable, so records keyed by them
+// sort by creation in the database.
+type IDGenerator func() string
+
+// crockford is the ULID alphabet.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns a 26 character ULID: a millisecond timestamp followed by 80
+// random bits. IDs from the same millisecond are not ordered among
+// themselves.
+func ULID() string {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixMilli())<<16)
+	_, _ = rand.Read(b[6:])
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// UUIDv7 returns a version 7 UUID in its canonical 36 character form.
+func UUIDv7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// withTaskID adds an asynq.TaskID option from gen unless options set one.
+func withTaskID(gen IDGenerator, options []asynq.Option) []asynq.Option {
+	if gen == nil || taskIDOf(options) != "" {
+		return options
+	}
+	return append(options, asynq.TaskID(gen()))
+}
+
+// taskIDOf returns the ID set by an asynq.TaskID option, or "".
+func taskIDOf(options []asynq.Option) string {
+	var id string
+	for _, o := range options {
+		if o != nil && o.Type() == asynq.TaskIDOpt {
+			id = o.Value().(string)
+		}
+	}
+	return id
+}
+
+// maxTaskIDLen is the size of the id column of asyncx_tasks.
+const maxTaskIDLen = 64
+
+// boundedTaskID returns id if it fits the id column, and otherwise its
+// start followed by a hash of the whole, so that derived IDs stay
+// deterministic and unique.
+func boundedTaskID(id string) string {
+	if len(id) <= maxTaskIDLen {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	hash := hex.EncodeToString(sum[:12])
+	prefix := id[:maxTaskIDLen-len(hash)-1]
+	for !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix + "~" + hash
+}
@@ -0,0 +1,66 @@
+package asyncx
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestIDGenerators(t *testing.T) {
+	first := ULID()
+	time.Sleep(2 * time.Millisecond)
+	second := ULID()
+	if !regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).MatchString(first) || first == second || first > second {
+		t.Fatalf("ULIDs %q, %q", first, second)
+	}
+	u := UUIDv7()
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(u) {
+		t.Fatalf("UUIDv7 %q", u)
+	}
+}
+
+func TestClient_IDGenerator(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+	n := 0
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{IDGenerator: func() string {
+		n++
+		return "job-" + string(rune('0'+n))
+	}})
+	defer client.Close()
+
+	info, err := client.Enqueue(ctx, "report:build", nil)
+	if err != nil || info.ID != "job-1" {
+		t.Fatalf("Enqueue: %+v %v", info, err)
+	}
+	if _, err := store.GetByID(ctx, "job-1"); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	info, err = client.Enqueue(ctx, "report:build", nil, asynq.TaskID("mine"))
+	if err != nil || info.ID != "mine" || n != 1 {
+		t.Fatalf("explicit TaskID: %+v %v (generated %d)", info, err, n)
+	}
+}
+
+func TestBoundedTaskID(t *testing.T) {
+	if got := boundedTaskID("wf:short"); got != "wf:short" {
+		t.Fatalf("short ID changed to %q", got)
+	}
+	long := strings.Repeat("a", 40) + ":" + strings.Repeat("é", 30)
+	got := boundedTaskID(long)
+	if len(got) > maxTaskIDLen || !utf8.ValidString(got) || !strings.HasPrefix(got, strings.Repeat("a", 39)) {
+		t.Fatalf("bounded ID %q (%d bytes)", got, len(got))
+	}
+	if boundedTaskID(long) != got || boundedTaskID(long+"x") == got {
+		t.Fatal("bounded IDs must be deterministic and distinct")
+	}
+}
@@ -0,0 +1,29 @@
+package asyncx
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+// EnqueueFunc has the signature of Client.Enqueue.
+type EnqueueFunc func(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error)
+
+// ClientInterceptor wraps Client.Enqueue, like a gRPC unary client
+// interceptor. It may inspect or replace the context, payload and options,
+// reject the call by returning an error, or observe the result. It must call
+// next to continue the enqueue.
+type ClientInterceptor func(ctx context.Context, taskType string, payload any, options []asynq.Option, next EnqueueFunc) (*asynq.TaskInfo, error)
+
+// chainInterceptors builds an EnqueueFunc running interceptors in order
+// around final.
+func chainInterceptors(interceptors []ClientInterceptor, final EnqueueFunc) EnqueueFunc {
+	next := final
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, inner := interceptors[i], next
+		next = func(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
+			return ic(ctx, taskType, payload, options, inner)
+		}
+	}
+	return next
+}
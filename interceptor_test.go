@@ -0,0 +1,63 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_Interceptors(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	var order []string
+	trace := func(name string) ClientInterceptor {
+		return func(ctx context.Context, taskType string, payload any, options []asynq.Option, next EnqueueFunc) (*asynq.TaskInfo, error) {
+			order = append(order, name)
+			return next(ctx, taskType, payload, options...)
+		}
+	}
+	errRejected := errors.New("rejected")
+	validate := func(ctx context.Context, taskType string, payload any, options []asynq.Option, next EnqueueFunc) (*asynq.TaskInfo, error) {
+		if taskType == "ic:forbidden" {
+			return nil, errRejected
+		}
+		return next(ctx, taskType, payload, options...)
+	}
+	scrub := func(ctx context.Context, taskType string, payload any, options []asynq.Option, next EnqueueFunc) (*asynq.TaskInfo, error) {
+		if m, ok := payload.(map[string]string); ok {
+			delete(m, "password")
+		}
+		return next(ctx, taskType, payload, append(options, asynq.Queue("scrubbed"))...)
+	}
+
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{
+		Interceptors: []ClientInterceptor{trace("outer"), validate, trace("inner"), scrub},
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	info, err := client.Enqueue(ctx, "ic:login", map[string]string{"user": "ann", "password": "hunter2"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Fatalf("interceptors ran out of order: %v", order)
+	}
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if rec.PayloadJSON != `{"user":"ann"}` || rec.Queue != "scrubbed" {
+		t.Fatalf("interceptor changes not applied: %#v", rec)
+	}
+
+	if _, err := client.Enqueue(ctx, "ic:forbidden", nil); !errors.Is(err, errRejected) {
+		t.Fatalf("want rejection, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type JanitorConfig struct {
+	// Interval between sweeps. Defaults to one minute.
+	Interval time.Duration
+	// AckTimeout is how long a task may stay in StatusAwaitingAck before it is
+	// flagged StatusNeedsReview. Defaults to ten minutes.
+	AckTimeout time.Duration
+	// PayloadRetention, if set, purges the payloads of finished records once
+	// they are older than its retention for their status, e.g. quickly for
+	// completed tasks and later for failed ones. The store must implement
+	// PayloadPurgeStore; purged records keep everything but the payload and
+	// carry payload_purged_at.
+	PayloadRetention PayloadRetention
+	// PayloadRetentionByType overrides PayloadRetention per task type.
+	PayloadRetentionByType map[string]PayloadRetention
+	// Blobs, if set, is the ClientOptions.Blobs store; the blob of a
+	// payload is deleted when the payload is purged.
+	Blobs BlobStore
+	// DeleteExportedAfter, if set, deletes records a WarehouseSink exported
+	// longer ago than this. The store must implement WarehouseStore.
+	DeleteExportedAfter time.Duration
+	// Leader, if set, limits sweeps to the elected replica.
+	Leader Leader
+}
+
+// Janitor periodically sweeps the store for records that need attention.
+type Janitor struct {
+	store Store
+	cfg   JanitorConfig
+}
+
+func NewJanitor(store Store, cfg JanitorConfig) *Janitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 10 * time.Minute
+	}
+	return &Janitor{store: store, cfg: cfg}
+}
+
+// Run sweeps every Interval until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = j.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs a single sweep, or nothing unless j leads.
+func (j *Janitor) RunOnce(ctx context.Context) error {
+	if !leads(j.cfg.Leader) {
+		return nil
+	}
+	now := time.Now().UTC()
+	recs, err := listRecords(ctx, j.store, TaskFilter{Status: StatusAwaitingAck, UpdatedBefore: now.Add(-j.cfg.AckTimeout)})
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		if err := markStatus(ctx, j.store, rec.ID, StatusNeedsReview, now); err != nil {
+			return err
+		}
+	}
+	return errors.Join(j.purgePayloads(ctx, now), j.deleteExported(ctx, now))
+}
@@ -0,0 +1,124 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestJanitor_FlagsUnconfirmedTasks(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	for _, id := range []string{"ack-old", "ack-new"} {
+		rec := TaskRecord{ID: id, Type: "payment:capture", Queue: "default", PayloadJSON: `{}`, CreatedAt: time.Now().UTC()}
+		if err := store.InsertCreated(ctx, rec); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	if err := store.MarkStatus(ctx, "ack-old", StatusAwaitingAck, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkStatus: %v", err)
+	}
+	if err := store.MarkStatus(ctx, "ack-new", StatusAwaitingAck, time.Now()); err != nil {
+		t.Fatalf("MarkStatus: %v", err)
+	}
+
+	j := NewJanitor(store, JanitorConfig{AckTimeout: 10 * time.Minute})
+	if err := j.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if got, _ := store.GetByID(ctx, "ack-old"); got.Status != StatusNeedsReview {
+		t.Fatalf("want ack-old=%s got=%s", StatusNeedsReview, got.Status)
+	}
+	if got, _ := store.GetByID(ctx, "ack-new"); got.Status != StatusAwaitingAck {
+		t.Fatalf("want ack-new=%s got=%s", StatusAwaitingAck, got.Status)
+	}
+
+	if err := Ack(ctx, store, "ack-old"); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	if got, _ := store.GetByID(ctx, "ack-old"); got.Status != StatusCompleted {
+		t.Fatalf("want ack-old=%s after Ack, got=%s", StatusCompleted, got.Status)
+	}
+	if err := Ack(ctx, store, "ack-old"); err == nil {
+		t.Fatalf("Ack of a completed task should fail")
+	}
+}
+
+func TestJanitor_KeepsPayloadsOfRetryingTasks(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	for _, id := range []string{"ret-final", "ret-retrying"} {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "email:send", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	if err := store.MarkFailed(ctx, "ret-final", "boom", time.Now()); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if err := store.MarkRetry(ctx, "ret-retrying", "boom", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("MarkRetry: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE asyncx_tasks SET updated_at = ?`, time.Now().Add(-time.Hour).UTC()); err != nil {
+		t.Fatalf("backdate: %v", err)
+	}
+
+	j := NewJanitor(store, JanitorConfig{PayloadRetention: PayloadRetention{Failed: time.Minute}})
+	if err := j.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	for id, purged := range map[string]bool{"ret-final": true, "ret-retrying": false} {
+		got, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID %s: %v", id, err)
+		}
+		if (got.PayloadPurgedAt != nil) != purged {
+			t.Errorf("%s: purged=%v, want %v", id, got.PayloadPurgedAt != nil, purged)
+		}
+	}
+}
+
+func TestJanitor_PurgesPayloadsByRetention(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db, SQLStoreOptions{ChecksumKey: []byte("k")})
+	ctx := context.Background()
+
+	// Finished an hour ago.
+	finish := map[string]Status{"ret-ok": StatusCompleted, "ret-failed": StatusFailed, "ret-audit": StatusCompleted, "ret-running": StatusInProgress}
+	for id, status := range finish {
+		typ := "email:send"
+		if id == "ret-audit" {
+			typ = "audit:log"
+		}
+		rec := TaskRecord{ID: id, Type: typ, Queue: "default", PayloadJSON: `{"email":"a@example.com"}`, CreatedAt: time.Now().UTC()}
+		if err := store.InsertCreated(ctx, rec); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		if err := store.MarkStatus(ctx, id, status, time.Now().Add(-time.Hour)); err != nil {
+			t.Fatalf("MarkStatus: %v", err)
+		}
+	}
+
+	j := NewJanitor(store, JanitorConfig{
+		PayloadRetention:       PayloadRetention{Completed: time.Minute, Failed: 24 * time.Hour},
+		PayloadRetentionByType: map[string]PayloadRetention{"audit:log": {}},
+	})
+	if err := j.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	for id, purged := range map[string]bool{"ret-ok": true, "ret-failed": false, "ret-audit": false, "ret-running": false} {
+		got, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID %s: %v", id, err)
+		}
+		if (got.PayloadPurgedAt != nil) != purged || (got.PayloadJSON == "null") != purged {
+			t.Errorf("%s: purged=%v, want %v (payload %s)", id, got.PayloadPurgedAt != nil, purged, got.PayloadJSON)
+		}
+	}
+}
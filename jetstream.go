@@ -0,0 +1,239 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// Headers of the messages JetStreamBroker publishes. JetStream drops
+// messages repeating a Nats-Msg-Id within the stream's duplicate window, so
+// the copy RunJetStream publishes for a retry gets its own.
+const (
+	jsMsgID     = "Nats-Msg-Id"
+	jsTaskID    = "Asyncx-Task-Id"
+	jsType      = "Asyncx-Type"
+	jsQueue     = "Asyncx-Queue"
+	jsMaxRetry  = "Asyncx-Max-Retry"
+	jsTimeout   = "Asyncx-Timeout-Ms"
+	jsRetried   = "Asyncx-Retried"
+	jsNotBefore = "Asyncx-Not-Before-Ms"
+)
+
+// defaultMaxRetry is asynq's default retry limit.
+const defaultMaxRetry = 25
+
+// JetStreamPublisher publishes to a NATS JetStream stream, so that asyncx
+// does not depend on the NATS client. With nats.go's jetstream package:
+//
+//	type jsPublisher struct{ js jetstream.JetStream }
+//
+//	func (p jsPublisher) Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error {
+//		msg := nats.NewMsg(subject)
+//		msg.Data = data
+//		for k, v := range headers {
+//			msg.Header.Set(k, v)
+//		}
+//		_, err := p.js.PublishMsg(ctx, msg)
+//		return err
+//	}
+type JetStreamPublisher interface {
+	Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error
+}
+
+// JetStreamMessage is a message delivered by a JetStreamConsumer;
+// jetstream.Msg adapts to it directly, apart from Headers, which come from
+// Header.
+type JetStreamMessage interface {
+	Subject() string
+	Data() []byte
+	Headers() map[string]string
+	Ack() error
+	NakWithDelay(delay time.Duration) error
+	Term() error
+}
+
+// JetStreamConsumer fetches messages from a pull consumer with explicit
+// acks, e.g. over jetstream.Consumer.Fetch. Its AckWait must exceed the
+// longest handler run.
+type JetStreamConsumer interface {
+	Fetch(ctx context.Context, max int) ([]JetStreamMessage, error)
+}
+
+type JetStreamBrokerOptions struct {
+	// SubjectPrefix is prepended to the queue name to form the subject.
+	// Defaults to "asyncx.".
+	SubjectPrefix string
+}
+
+// JetStreamBroker is an experimental Broker publishing tasks to NATS
+// JetStream, one subject per queue. Tasks keep their ID, type, queue,
+// retry limit and timeout; other asynq options, such as ProcessIn or
+// Unique, are rejected. Run its tasks with Processor.RunJetStream.
+type JetStreamBroker struct {
+	pub  JetStreamPublisher
+	opts JetStreamBrokerOptions
+}
+
+func NewJetStreamBroker(pub JetStreamPublisher, opts JetStreamBrokerOptions) *JetStreamBroker {
+	if opts.SubjectPrefix == "" {
+		opts.SubjectPrefix = "asyncx."
+	}
+	return &JetStreamBroker{pub: pub, opts: opts}
+}
+
+func (b *JetStreamBroker) EnqueueContext(ctx context.Context, t *asynq.Task, opts ...asynq.Option) (*asynq.TaskInfo, error) {
+	info := &asynq.TaskInfo{Queue: DefaultQueue, Type: t.Type(), Payload: t.Payload(), MaxRetry: defaultMaxRetry, State: asynq.TaskStatePending}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		switch o.Type() {
+		case asynq.TaskIDOpt:
+			info.ID = o.Value().(string)
+		case asynq.QueueOpt:
+			info.Queue = o.Value().(string)
+		case asynq.MaxRetryOpt:
+			info.MaxRetry = o.Value().(int)
+		case asynq.TimeoutOpt:
+			info.Timeout = o.Value().(time.Duration)
+		default:
+			return nil, fmt.Errorf("asyncx: JetStreamBroker does not support option %s", o)
+		}
+	}
+	if info.ID == "" {
+		info.ID = uuid.NewString()
+	}
+	headers := map[string]string{
+		jsMsgID:    info.ID,
+		jsTaskID:   info.ID,
+		jsType:     info.Type,
+		jsQueue:    info.Queue,
+		jsMaxRetry: strconv.Itoa(info.MaxRetry),
+	}
+	if info.Timeout > 0 {
+		headers[jsTimeout] = strconv.FormatInt(info.Timeout.Milliseconds(), 10)
+	}
+	if err := b.pub.Publish(ctx, b.opts.SubjectPrefix+info.Queue, t.Payload(), headers); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Close does nothing; the caller owns the NATS connection.
+func (b *JetStreamBroker) Close() error { return nil }
+
+// errShutdown cancels the handlers RunJetStream is still running once
+// GracePeriod is over.
+var errShutdown = errors.New("asyncx: processor shut down")
+
+// RunJetStream is Run for tasks published by a JetStreamBroker: it fetches
+// from consumer and runs up to Concurrency handlers at a time, with the
+// same middleware and store tracking, until ctx is cancelled. It then
+// waits up to GracePeriod for running handlers, cancels their contexts and
+// redelivers their tasks. A failed task is acked and published again to
+// its subject through pub, with its retry count in a header, to run after
+// the retry delay asynq would use; at its retry limit it is terminated.
+// Tasks the Processor puts back (throttled, deferred) are redelivered
+// without counting towards the limit, and so are tasks whose handler did
+// not return, e.g. on a crash; bound those with the consumer's MaxDeliver.
+// The Processor still needs Redis for its worker registry and health
+// checks. Experimental.
+func (p *Processor) RunJetStream(ctx context.Context, consumer JetStreamConsumer, pub JetStreamPublisher, mux *asynq.ServeMux) error {
+	h, err := p.prepare(ctx, mux)
+	if err != nil {
+		return err
+	}
+	p.running.Store(true)
+	defer p.running.Store(false)
+	hctx, abort := context.WithCancelCause(context.WithoutCancel(ctx))
+	defer abort(errShutdown)
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(p.grace, func() { abort(errShutdown) })
+	})
+	defer stop()
+	workers := make(chan struct{}, p.shared.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		msgs, err := consumer.Fetch(ctx, cap(workers))
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, m := range msgs {
+			workers <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() { <-workers; wg.Done() }()
+				p.deliver(hctx, pub, h, m)
+			}()
+		}
+	}
+}
+
+// deliver runs h for m and acknowledges it as asynq's server would.
+func (p *Processor) deliver(ctx context.Context, pub JetStreamPublisher, h asynq.Handler, m JetStreamMessage) {
+	headers := m.Headers()
+	if ms, err := strconv.ParseInt(headers[jsNotBefore], 10, 64); err == nil {
+		if wait := time.Until(time.UnixMilli(ms)); wait > 0 {
+			_ = m.NakWithDelay(wait)
+			return
+		}
+	}
+	maxRetry, err := strconv.Atoi(headers[jsMaxRetry])
+	if err != nil {
+		maxRetry = defaultMaxRetry
+	}
+	retried, _ := strconv.Atoi(headers[jsRetried])
+	id := headers[jsTaskID]
+	if id == "" {
+		// Published before retries were counted in a header.
+		id = headers[jsMsgID]
+	}
+	ctx = withBrokerTask(ctx, brokerTask{id: id, queue: headers[jsQueue], retried: retried, maxRetry: maxRetry})
+	if ms, err := strconv.ParseInt(headers[jsTimeout], 10, 64); err == nil && ms > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+		defer cancel()
+	}
+	t := asynq.NewTask(headers[jsType], m.Data())
+	err = h.ProcessTask(ctx, t)
+	switch {
+	case err == nil, errors.Is(err, asynq.RevokeTask):
+		_ = m.Ack()
+	case errors.Is(context.Cause(ctx), errShutdown):
+		_ = m.NakWithDelay(0)
+	case !isFailure(err):
+		_ = m.NakWithDelay(retryDelay(retried, err, t))
+	case retried < maxRetry && !errors.Is(err, asynq.SkipRetry):
+		delay := retryDelay(retried, err, t)
+		retry := maps.Clone(headers)
+		retry[jsTaskID] = id
+		retry[jsMsgID] = id + ":" + strconv.Itoa(retried+1)
+		retry[jsRetried] = strconv.Itoa(retried + 1)
+		retry[jsNotBefore] = strconv.FormatInt(time.Now().Add(delay).UnixMilli(), 10)
+		if err := pub.Publish(context.WithoutCancel(ctx), m.Subject(), m.Data(), retry); err != nil {
+			// Redelivered without counting, rather than lost.
+			_ = m.NakWithDelay(delay)
+			return
+		}
+		_ = m.Ack()
+	default:
+		_ = m.Term()
+	}
+}
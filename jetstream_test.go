@@ -0,0 +1,202 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// fakeJetStream is an in-memory stream; naked messages are redelivered at
+// once.
+type fakeJetStream struct {
+	msgs     chan *fakeJSMsg
+	mu       sync.Mutex
+	subjects []string
+	outcomes map[string][]string // by task ID
+}
+
+type fakeJSMsg struct {
+	js      *fakeJetStream
+	subject string
+	data    []byte
+	headers map[string]string
+}
+
+func (f *fakeJetStream) Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error {
+	m := &fakeJSMsg{js: f, subject: subject, data: data, headers: headers}
+	f.mu.Lock()
+	f.subjects = append(f.subjects, subject)
+	f.mu.Unlock()
+	if headers[jsRetried] != "" {
+		f.outcome(m, "retry")
+	}
+	f.msgs <- m
+	return nil
+}
+
+func (f *fakeJetStream) Fetch(ctx context.Context, max int) ([]JetStreamMessage, error) {
+	select {
+	case m := <-f.msgs:
+		return []JetStreamMessage{m}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeJetStream) outcome(m *fakeJSMsg, o string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := m.headers[jsTaskID]
+	f.outcomes[id] = append(f.outcomes[id], o)
+}
+
+func (m *fakeJSMsg) Subject() string            { return m.subject }
+func (m *fakeJSMsg) Data() []byte               { return m.data }
+func (m *fakeJSMsg) Headers() map[string]string { return m.headers }
+func (m *fakeJSMsg) Ack() error                 { m.js.outcome(m, "ack"); return nil }
+func (m *fakeJSMsg) Term() error                { m.js.outcome(m, "term"); return nil }
+func (m *fakeJSMsg) NakWithDelay(time.Duration) error {
+	m.js.outcome(m, "nak")
+	m.js.msgs <- m
+	return nil
+}
+
+func TestJetStreamBroker(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	js := &fakeJetStream{msgs: make(chan *fakeJSMsg, 16), outcomes: map[string][]string{}}
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{Broker: NewJetStreamBroker(js, JetStreamBrokerOptions{})})
+	defer client.Close()
+
+	processor := NewProcessor(redis, store, ProcessorConfig{
+		Concurrency:   2,
+		RetryPolicies: map[string]RetryPolicy{"report:build": {BaseDelay: time.Nanosecond}},
+	})
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	processor.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error {
+		task, _ := TaskFromContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[string(tsk.Payload())]++
+		switch {
+		case string(tsk.Payload()) == `"flaky"` && task.RetryCount == 0:
+			return errors.New("try again")
+		case string(tsk.Payload()) == `"bad"`:
+			return errors.New("boom")
+		case string(tsk.Payload()) == `"throttled"` && attempts[`"throttled"`] <= 3:
+			return &ThrottledError{Key: "reports", RetryAfter: time.Millisecond}
+		case string(tsk.Payload()) == `"throttled"` && attempts[`"throttled"`] == 4:
+			return errors.New("try again")
+		}
+		return nil
+	})
+	done := make(chan error, 1)
+	go func() { done <- processor.RunJetStream(ctx, js, js, nil) }()
+
+	ok, err := client.Enqueue(ctx, "report:build", "ok", asynq.Queue("reports"))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	flaky, err := client.Enqueue(ctx, "report:build", "flaky")
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	bad, err := client.Enqueue(ctx, "report:build", "bad", asynq.MaxRetry(1))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// Throttled deliveries do not use up its one retry.
+	throttled, err := client.Enqueue(ctx, "report:build", "throttled", asynq.MaxRetry(1))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := client.Enqueue(ctx, "report:build", "later", asynq.ProcessIn(time.Hour)); err == nil {
+		t.Fatal("ProcessIn should be rejected")
+	}
+	want := map[string]Status{ok.ID: StatusCompleted, flaky.ID: StatusCompleted, bad.ID: StatusFailed, throttled.ID: StatusCompleted}
+	if err := pollUntil(t, 5*time.Second, func() (bool, error) {
+		for id, status := range want {
+			rec, err := store.GetByID(ctx, id)
+			if err != nil || rec.Status != status {
+				return false, err
+			}
+		}
+		return true, nil
+	}); err != nil {
+		t.Fatalf("tasks did not finish: %v", err)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("RunJetStream: %v", err)
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.subjects[0] != "asyncx.reports" || js.subjects[1] != "asyncx.default" {
+		t.Fatalf("subjects %v", js.subjects)
+	}
+	outcomes := map[string]string{ok.ID: "[ack]", flaky.ID: "[retry ack ack]", bad.ID: "[retry ack term]", throttled.ID: "[nak nak nak retry ack ack]"}
+	for id, w := range outcomes {
+		if got := fmt.Sprint(js.outcomes[id]); got != w {
+			t.Errorf("%s: outcomes %s, want %s", id, got, w)
+		}
+	}
+}
+
+func TestJetStreamBroker_ShutdownRedelivers(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	js := &fakeJetStream{msgs: make(chan *fakeJSMsg, 16), outcomes: map[string][]string{}}
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{Broker: NewJetStreamBroker(js, JetStreamBrokerOptions{})})
+	defer client.Close()
+
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, GracePeriod: 50 * time.Millisecond})
+	started := make(chan struct{})
+	processor.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	done := make(chan error, 1)
+	go func() { done <- processor.RunJetStream(ctx, js, js, nil) }()
+
+	info, err := client.Enqueue(ctx, "report:build", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunJetStream: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunJetStream did not abort the handler after GracePeriod")
+	}
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if got := fmt.Sprint(js.outcomes[info.ID]); got != "[nak]" {
+		t.Fatalf("outcomes %s, want [nak]", got)
+	}
+}
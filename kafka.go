@@ -0,0 +1,204 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// KafkaMessage is a message consumed from Kafka.
+type KafkaMessage struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+}
+
+// KafkaReader is the subset of a Kafka consumer used by KafkaBridge, so
+// that asyncx does not depend on a specific client. Messages must be
+// committed explicitly. A kafka-go reader in a consumer group adapts in a
+// few lines:
+//
+//	type kafkaGoReader struct{ r *kafka.Reader }
+//
+//	func (k kafkaGoReader) FetchMessage(ctx context.Context) (asyncx.KafkaMessage, error) {
+//		m, err := k.r.FetchMessage(ctx)
+//		headers := make(map[string]string, len(m.Headers))
+//		for _, h := range m.Headers {
+//			headers[h.Key] = string(h.Value)
+//		}
+//		return asyncx.KafkaMessage{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Key: m.Key, Value: m.Value, Headers: headers}, err
+//	}
+//
+//	func (k kafkaGoReader) CommitMessage(ctx context.Context, m asyncx.KafkaMessage) error {
+//		return k.r.CommitMessages(ctx, kafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset})
+//	}
+type KafkaReader interface {
+	FetchMessage(ctx context.Context) (KafkaMessage, error)
+	CommitMessage(ctx context.Context, m KafkaMessage) error
+}
+
+type KafkaBridgeConfig struct {
+	// Type is the task type of every message, unless TypeHeader names a
+	// header that is set.
+	Type       string
+	TypeHeader string
+	// Map, if set, replaces Type and TypeHeader: it returns the task type,
+	// payload and options of a message. A message it returns an error for
+	// is skipped.
+	Map func(m KafkaMessage) (taskType string, payload any, options []asynq.Option, err error)
+	// RetryInterval is how long to wait after a failed fetch, enqueue or
+	// commit before trying again. Defaults to one second.
+	RetryInterval time.Duration
+	// OnSkip, if set, is told about every skipped message.
+	OnSkip func(m KafkaMessage, err error)
+}
+
+// KafkaBridge enqueues the messages of a Kafka topic as tasks, so that
+// event producers need no Redis access. By default the message value is
+// the JSON payload, re-encoded if the Client's codec is not JSON. Each task
+// carries kafka_topic, kafka_partition, kafka_offset and kafka_key metadata,
+// and "kafka:<topic>:<partition>:<offset>" as its task ID and dedup key, so
+// a message redelivered after a crash is enqueued once. IDs longer than
+// the store's 64 bytes end in a hash instead. A message is committed only once enqueued; an enqueue
+// that fails is retried, holding back the messages after it. Messages that
+// cannot become tasks (no type, invalid JSON, rejected by the Client's
+// registries) are skipped and committed.
+type KafkaBridge struct {
+	reader KafkaReader
+	client *Client
+	cfg    KafkaBridgeConfig
+}
+
+// NewKafkaBridge panics if cfg has neither Type, TypeHeader nor Map.
+func NewKafkaBridge(reader KafkaReader, client *Client, cfg KafkaBridgeConfig) *KafkaBridge {
+	if cfg.Type == "" && cfg.TypeHeader == "" && cfg.Map == nil {
+		panic("asyncx: NewKafkaBridge: no Type, TypeHeader or Map")
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = time.Second
+	}
+	return &KafkaBridge{reader: reader, client: client, cfg: cfg}
+}
+
+// Run consumes messages until ctx is cancelled.
+func (b *KafkaBridge) Run(ctx context.Context) error {
+	for {
+		m, err := b.reader.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			err = b.retry(ctx, func() error { return b.relay(ctx, m) })
+		}
+		if err == nil {
+			err = b.retry(ctx, func() error { return b.reader.CommitMessage(ctx, m) })
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			b.sleep(ctx)
+		}
+	}
+}
+
+// retry calls fn until it succeeds or ctx is cancelled.
+func (b *KafkaBridge) retry(ctx context.Context, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		b.sleep(ctx)
+	}
+}
+
+func (b *KafkaBridge) sleep(ctx context.Context) {
+	timer := time.NewTimer(b.cfg.RetryInterval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// errSkip marks messages that cannot become tasks.
+type errSkip struct{ err error }
+
+func (e errSkip) Error() string { return e.err.Error() }
+
+// relay enqueues m, or skips it. It fails only if m should be retried.
+func (b *KafkaBridge) relay(ctx context.Context, m KafkaMessage) error {
+	taskType, payload, options, err := b.task(m)
+	if err == nil {
+		_, err = b.client.Enqueue(ctx, taskType, payload, options...)
+	}
+	switch {
+	case err == nil, errors.Is(err, asynq.ErrTaskIDConflict), errors.Is(err, ErrDuplicateTask):
+		return nil
+	case skippable(err):
+		if b.cfg.OnSkip != nil {
+			b.cfg.OnSkip(m, err)
+		}
+		return nil
+	}
+	return err
+}
+
+// task returns the task type, payload and options of m.
+func (b *KafkaBridge) task(m KafkaMessage) (string, any, []asynq.Option, error) {
+	var taskType string
+	var payload any
+	var options []asynq.Option
+	if b.cfg.Map != nil {
+		var err error
+		if taskType, payload, options, err = b.cfg.Map(m); err != nil {
+			return "", nil, nil, errSkip{err}
+		}
+	} else {
+		taskType = b.cfg.Type
+		if t := m.Headers[b.cfg.TypeHeader]; b.cfg.TypeHeader != "" && t != "" {
+			taskType = t
+		}
+		if !json.Valid(m.Value) {
+			return "", nil, nil, errSkip{fmt.Errorf("asyncx: kafka message %s:%d:%d is not JSON", m.Topic, m.Partition, m.Offset)}
+		}
+		var err error
+		if payload, err = jsonPayload(b.client.codec, m.Value); err != nil {
+			return "", nil, nil, errSkip{err}
+		}
+	}
+	if taskType == "" {
+		return "", nil, nil, errSkip{fmt.Errorf("asyncx: kafka message %s:%d:%d has no task type", m.Topic, m.Partition, m.Offset)}
+	}
+	id := boundedTaskID(fmt.Sprintf("kafka:%s:%d:%d", m.Topic, m.Partition, m.Offset))
+	md := map[string]string{
+		"kafka_topic":     m.Topic,
+		"kafka_partition": strconv.Itoa(m.Partition),
+		"kafka_offset":    strconv.FormatInt(m.Offset, 10),
+	}
+	if len(m.Key) > 0 {
+		md["kafka_key"] = string(m.Key)
+	}
+	options = append([]asynq.Option{asynq.TaskID(id), WithDedupKey(id), WithMetadata(md)}, options...)
+	return taskType, payload, options, nil
+}
+
+// skippable reports whether an enqueue error will not go away on retry.
+func skippable(err error) bool {
+	var skip errSkip
+	var unknown *UnknownTaskTypeError
+	var obsolete *ObsoleteTaskTypeError
+	var queue *UnknownQueueError
+	var tooLarge *PayloadTooLargeError
+	return errors.As(err, &skip) || errors.As(err, &unknown) || errors.As(err, &obsolete) ||
+		errors.As(err, &queue) || errors.As(err, &tooLarge)
+}
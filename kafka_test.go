@@ -0,0 +1,101 @@
+package asyncx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// fakeKafka serves msgs once, then blocks until ctx is cancelled.
+type fakeKafka struct {
+	mu        sync.Mutex
+	msgs      []KafkaMessage
+	committed []int64
+	done      chan struct{}
+}
+
+func (f *fakeKafka) FetchMessage(ctx context.Context) (KafkaMessage, error) {
+	f.mu.Lock()
+	if len(f.msgs) > 0 {
+		m := f.msgs[0]
+		f.msgs = f.msgs[1:]
+		f.mu.Unlock()
+		return m, nil
+	}
+	f.mu.Unlock()
+	close(f.done)
+	<-ctx.Done()
+	return KafkaMessage{}, ctx.Err()
+}
+
+func (f *fakeKafka) CommitMessage(ctx context.Context, m KafkaMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.committed = append(f.committed, m.Offset)
+	return nil
+}
+
+func TestKafkaBridge(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	longTopic := "com.example.fulfilment.warehouse-events.orders-v2.partitioned"
+	reader := &fakeKafka{done: make(chan struct{}), msgs: []KafkaMessage{
+		{Topic: "orders", Partition: 2, Offset: 7, Key: []byte("order-1"), Value: []byte(`{"id":1}`)},
+		{Topic: "orders", Partition: 2, Offset: 8, Value: []byte(`not json`)},
+		{Topic: "orders", Partition: 2, Offset: 9, Value: []byte(`{"id":2}`), Headers: map[string]string{"type": "order:refund"}},
+		{Topic: "orders", Partition: 2, Offset: 7, Key: []byte("order-1"), Value: []byte(`{"id":1}`)},
+		{Topic: longTopic, Partition: 0, Offset: 1, Value: []byte(`{"id":3}`)},
+	}}
+	var skipped []int64
+	bridge := NewKafkaBridge(reader, client, KafkaBridgeConfig{
+		Type:          "order:created",
+		TypeHeader:    "type",
+		RetryInterval: 10 * time.Millisecond,
+		OnSkip:        func(m KafkaMessage, err error) { skipped = append(skipped, m.Offset) },
+	})
+	done := make(chan error, 1)
+	go func() { done <- bridge.Run(ctx) }()
+	select {
+	case <-reader.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("bridge did not consume the messages")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if len(reader.committed) != 5 || len(skipped) != 1 || skipped[0] != 8 {
+		t.Fatalf("committed %v, skipped %v", reader.committed, skipped)
+	}
+	recs, err := store.List(context.Background(), TaskFilter{})
+	if err != nil || len(recs) != 3 {
+		t.Fatalf("records: %d %v", len(recs), err)
+	}
+	rec, err := store.GetByID(context.Background(), "kafka:orders:2:7")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	md := rec.Metadata
+	if rec.Type != "order:created" || rec.PayloadJSON != `{"id":1}` || md["kafka_topic"] != "orders" || md["kafka_partition"] != "2" || md["kafka_offset"] != "7" || md["kafka_key"] != "order-1" {
+		t.Fatalf("record %+v", rec)
+	}
+	if rec, err := store.GetByID(context.Background(), "kafka:orders:2:9"); err != nil || rec.Type != "order:refund" {
+		t.Fatalf("header type: %+v %v", rec, err)
+	}
+	id := boundedTaskID("kafka:" + longTopic + ":0:1")
+	if rec, err := store.GetByID(context.Background(), id); err != nil || len(id) > maxTaskIDLen || rec.DedupKey != id || rec.Metadata["kafka_topic"] != longTopic {
+		t.Fatalf("long topic: %q %+v %v", id, rec, err)
+	}
+}
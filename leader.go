@@ -0,0 +1,144 @@
+package asyncx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// Leader reports whether this replica should act. The Scheduler, Reaper and
+// Janitor skip their work while their configured Leader is not leading, so
+// they can run on every replica.
+type Leader interface {
+	IsLeader() bool
+}
+
+type LeaderElectorOptions struct {
+	// Name identifies the election; replicas using the same name compete for
+	// one lease. Defaults to "default".
+	Name string
+	// TTL is how long a lease lasts without renewal, and so how long a
+	// crashed leader blocks a takeover. Defaults to 15 seconds.
+	TTL time.Duration
+	// Prefix namespaces lease keys in Redis. Defaults to "asyncx:leader:".
+	Prefix string
+}
+
+// LeaderElector elects one leader among replicas with a lease in Redis. Run
+// campaigns for the lease and renews it every TTL/3; a leader that cannot
+// renew stops leading once its lease would have expired.
+type LeaderElector struct {
+	rdb  redis.UniversalClient
+	id   string
+	key  string
+	opts LeaderElectorOptions
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+func NewLeaderElector(redisOpt asynq.RedisConnOpt, opts LeaderElectorOptions) *LeaderElector {
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 15 * time.Second
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "asyncx:leader:"
+	}
+	return &LeaderElector{
+		rdb:  makeRedis(redisOpt),
+		id:   newWorkerID(),
+		key:  opts.Prefix + opts.Name,
+		opts: opts,
+	}
+}
+
+// ID is the identity this elector holds the lease under.
+func (l *LeaderElector) ID() string { return l.id }
+
+// IsLeader reports whether l holds an unexpired lease.
+func (l *LeaderElector) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return time.Now().Before(l.until)
+}
+
+// acquireScript takes the lease if it is free and renews it if held by
+// ARGV[1]. It returns 1 when the caller holds the lease.
+var acquireScript = redis.NewScript(`
+local holder = redis.call("GET", KEYS[1])
+if holder == false then
+  redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+  return 1
+end
+if holder == ARGV[1] then
+  redis.call("PEXPIRE", KEYS[1], ARGV[2])
+  return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lease if held by ARGV[1].
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+  return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// TryAcquire takes or renews the lease once and reports whether l leads.
+func (l *LeaderElector) TryAcquire(ctx context.Context) (bool, error) {
+	start := time.Now()
+	ok, err := acquireScript.Run(ctx, l.rdb, []string{l.key}, l.id, l.opts.TTL.Milliseconds()).Int()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil {
+		// Keep leading until the lease may have expired.
+		return time.Now().Before(l.until), err
+	}
+	if ok == 1 {
+		// Measured from before the call, so l never outlives the Redis key.
+		l.until = start.Add(l.opts.TTL)
+	} else {
+		l.until = time.Time{}
+	}
+	return ok == 1, nil
+}
+
+// Resign gives up the lease so another replica can take over at once.
+func (l *LeaderElector) Resign(ctx context.Context) error {
+	l.mu.Lock()
+	l.until = time.Time{}
+	l.mu.Unlock()
+	return releaseScript.Run(ctx, l.rdb, []string{l.key}, l.id).Err()
+}
+
+// Run campaigns for the lease every TTL/3 until ctx is cancelled, then
+// resigns.
+func (l *LeaderElector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(l.opts.TTL / 3)
+	defer ticker.Stop()
+	for {
+		_, _ = l.TryAcquire(ctx)
+		select {
+		case <-ctx.Done():
+			return l.Resign(context.Background())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the Redis client. It does not resign.
+func (l *LeaderElector) Close() error {
+	return l.rdb.Close()
+}
+
+// leads reports whether a component configured with leader should act.
+func leads(leader Leader) bool {
+	return leader == nil || leader.IsLeader()
+}
@@ -0,0 +1,88 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestLeaderElector_SingleLeaderAndTakeover(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	a := NewLeaderElector(redis, LeaderElectorOptions{Name: "cron", TTL: time.Minute})
+	defer a.Close()
+	b := NewLeaderElector(redis, LeaderElectorOptions{Name: "cron", TTL: time.Minute})
+	defer b.Close()
+
+	if ok, err := a.TryAcquire(ctx); !ok || err != nil {
+		t.Fatalf("a should lead: %v %v", ok, err)
+	}
+	if ok, err := b.TryAcquire(ctx); ok || err != nil {
+		t.Fatalf("b must not lead while a does: %v %v", ok, err)
+	}
+	if ok, _ := a.TryAcquire(ctx); !ok || !a.IsLeader() || b.IsLeader() {
+		t.Fatal("a should renew its lease")
+	}
+
+	if err := a.Resign(ctx); err != nil {
+		t.Fatalf("Resign: %v", err)
+	}
+	if ok, _ := b.TryAcquire(ctx); !ok || a.IsLeader() {
+		t.Fatal("b should take over after a resigns")
+	}
+
+	// b stops renewing; its lease expires in Redis.
+	s.FastForward(time.Minute + time.Second)
+	if ok, _ := a.TryAcquire(ctx); !ok {
+		t.Fatal("a should take over an expired lease")
+	}
+	if ok, _ := b.TryAcquire(ctx); ok || b.IsLeader() {
+		t.Fatal("b lost its lease")
+	}
+}
+
+func TestScheduler_FollowerDoesNotEnqueue(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewRedisStore(redis, RedisStoreOptions{})
+	defer store.Close()
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	leader := NewLeaderElector(redis, LeaderElectorOptions{})
+	defer leader.Close()
+	follower := NewLeaderElector(redis, LeaderElectorOptions{})
+	defer follower.Close()
+	if ok, _ := leader.TryAcquire(ctx); !ok {
+		t.Fatal("leader should lead")
+	}
+	_, _ = follower.TryAcquire(ctx)
+
+	hourly, _ := Cron("0 * * * *")
+	var scheds []*Scheduler
+	for _, l := range []Leader{leader, follower} {
+		sched := NewScheduler(client, SchedulerConfig{Leader: l})
+		if err := sched.Register(SchedulerEntry{Name: "report", Schedule: hourly, Type: "report:build"}); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		scheds = append(scheds, sched)
+	}
+	due := scheds[0].entries[0].next
+	for _, sched := range scheds {
+		if err := sched.runDue(ctx, due.Add(time.Second)); err != nil {
+			t.Fatalf("runDue: %v", err)
+		}
+	}
+	recs, err := store.List(ctx, TaskFilter{Type: "report:build"})
+	if err != nil || len(recs) != 1 {
+		t.Fatalf("want one enqueue from the leader, got %d, %v", len(recs), err)
+	}
+	if !scheds[1].entries[0].next.After(due) {
+		t.Fatal("follower should advance past the occurrence it skipped")
+	}
+}
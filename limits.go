@@ -0,0 +1,59 @@
+package asyncx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONLimits bounds JSON documents written to the store so that one producer
+// cannot fill a column with megabyte blobs. Zero fields are unlimited.
+type JSONLimits struct {
+	// MaxBytes caps the encoded size of the whole document.
+	MaxBytes int
+	// MaxKeys caps the number of top-level keys of an object.
+	MaxKeys int
+	// MaxValueLen caps the encoded size of each top-level value of an object.
+	MaxValueLen int
+}
+
+// JSONLimitError reports which limit a document exceeded.
+type JSONLimitError struct {
+	Field string // column being written, e.g. "result_json"
+	Key   string // offending top-level key, if any
+	Limit string // "MaxBytes", "MaxKeys" or "MaxValueLen"
+	Max   int
+	Got   int
+}
+
+func (e *JSONLimitError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("asyncx: %s key %q is %d bytes, exceeds %s=%d", e.Field, e.Key, e.Got, e.Limit, e.Max)
+	}
+	return fmt.Sprintf("asyncx: %s exceeds %s=%d (got %d)", e.Field, e.Limit, e.Max, e.Got)
+}
+
+// Check validates the encoded document doc destined for field.
+// Non-object documents are only subject to MaxBytes.
+func (l JSONLimits) Check(field string, doc []byte) error {
+	if l.MaxBytes > 0 && len(doc) > l.MaxBytes {
+		return &JSONLimitError{Field: field, Limit: "MaxBytes", Max: l.MaxBytes, Got: len(doc)}
+	}
+	if l.MaxKeys <= 0 && l.MaxValueLen <= 0 {
+		return nil
+	}
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(doc, &obj) != nil {
+		return nil
+	}
+	if l.MaxKeys > 0 && len(obj) > l.MaxKeys {
+		return &JSONLimitError{Field: field, Limit: "MaxKeys", Max: l.MaxKeys, Got: len(obj)}
+	}
+	if l.MaxValueLen > 0 {
+		for k, v := range obj {
+			if len(v) > l.MaxValueLen {
+				return &JSONLimitError{Field: field, Key: k, Limit: "MaxValueLen", Max: l.MaxValueLen, Got: len(v)}
+			}
+		}
+	}
+	return nil
+}
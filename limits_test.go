@@ -0,0 +1,46 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONLimits_Check(t *testing.T) {
+	l := JSONLimits{MaxBytes: 64, MaxKeys: 2, MaxValueLen: 8}
+	cases := []struct {
+		doc   string
+		limit string
+	}{
+		{`{"a":1,"b":2}`, ""},
+		{`[1,2,3,4,5,6,7,8,9,10]`, ""},
+		{`{"a":"` + strings.Repeat("x", 70) + `"}`, "MaxBytes"},
+		{`{"a":1,"b":2,"c":3}`, "MaxKeys"},
+		{`{"a":"0123456789"}`, "MaxValueLen"},
+	}
+	for _, c := range cases {
+		err := l.Check("result_json", []byte(c.doc))
+		var le *JSONLimitError
+		switch {
+		case c.limit == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", c.doc, err)
+		case c.limit != "" && (!errors.As(err, &le) || le.Limit != c.limit):
+			t.Errorf("%s: want %s violation, got %v", c.doc, c.limit, err)
+		}
+	}
+	if err := (JSONLimits{}).Check("result_json", []byte(strings.Repeat("x", 1<<20))); err != nil {
+		t.Fatalf("zero limits must not reject: %v", err)
+	}
+}
+
+func TestSetResult_EnforcesLimits(t *testing.T) {
+	h := &resultHolder{limits: JSONLimits{MaxBytes: 10}}
+	ctx := context.WithValue(context.Background(), resultKey{}, h)
+	if err := SetResult(ctx, strings.Repeat("x", 20)); err == nil || h.json != nil {
+		t.Fatalf("oversized result should be rejected, err=%v", err)
+	}
+	if err := SetResult(ctx, 42); err != nil || h.json == nil || *h.json != "42" {
+		t.Fatalf("SetResult: %v", err)
+	}
+}
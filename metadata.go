@@ -0,0 +1,50 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type metadataKey struct{}
+
+// MetadataFromContext returns the metadata the running task was enqueued
+// with via WithMetadata, or nil.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return md
+}
+
+// matchesMetadata reports whether md contains every pair in want.
+func matchesMetadata(md, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := md[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeMetadata returns md as JSON for the metadata_json column, or nil if empty.
+func encodeMetadata(md map[string]string) *string {
+	if len(md) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil
+	}
+	s := string(b)
+	return &s
+}
+
+// decodeMetadata parses the metadata_json column; malformed values decode as nil.
+func decodeMetadata(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	var md map[string]string
+	if err := json.Unmarshal([]byte(s), &md); err != nil {
+		return nil
+	}
+	return md
+}
@@ -0,0 +1,77 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestMetadata_StoredFilteredAndPropagated(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	seen := make(chan map[string]string, 1)
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("md:task", func(ctx context.Context, tsk *asynq.Task) error {
+		seen <- MetadataFromContext(ctx)
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+	info, err := client.Enqueue(ctx, "md:task", nil,
+		WithMetadata(map[string]string{"user_id": "42"}),
+		WithMetadata(map[string]string{"flag": "beta"}))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	select {
+	case md := <-seen:
+		if md["user_id"] != "42" || md["flag"] != "beta" {
+			t.Fatalf("handler saw metadata %v", md)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler did not run")
+	}
+
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil || rec.Metadata["user_id"] != "42" {
+		t.Fatalf("metadata not stored: %+v %v", rec, err)
+	}
+	got, err := store.List(ctx, TaskFilter{Type: "md:task", Metadata: map[string]string{"flag": "beta"}})
+	if err != nil || len(got) != 1 || got[0].ID != info.ID {
+		t.Fatalf("filter by metadata: %v %v", got, err)
+	}
+	got, err = store.List(ctx, TaskFilter{Type: "md:task", Metadata: map[string]string{"flag": "ga"}})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("non-matching metadata filter returned %v %v", got, err)
+	}
+}
+
+func TestRedisStore_MetadataFilter(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	store := NewRedisStore(asynq.RedisClientOpt{Addr: s.Addr()}, RedisStoreOptions{})
+	ctx := context.Background()
+	for id, tenant := range map[string]string{"a": "acme", "b": "globex"} {
+		rec := TaskRecord{ID: id, Type: "t", Queue: "default", PayloadJSON: "{}", Metadata: map[string]string{"tenant": tenant}}
+		if err := store.InsertCreated(ctx, rec); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	got, err := store.List(ctx, TaskFilter{Metadata: map[string]string{"tenant": "acme"}})
+	if err != nil || len(got) != 1 || got[0].ID != "a" || got[0].Metadata["tenant"] != "acme" {
+		t.Fatalf("got %v %v", got, err)
+	}
+}
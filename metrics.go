@@ -0,0 +1,198 @@
+package asyncx
+
+import (
+	"context"
+	"time"
+)
+
+// Instrument names reported to a MetricsSink. Counters end in _total and
+// histograms in _seconds.
+const (
+	MetricEnqueued      = "asyncx_tasks_enqueued_total"             // labels: type, queue
+	MetricStarted       = "asyncx_tasks_started_total"              // labels: type, queue
+	MetricCompleted     = "asyncx_tasks_completed_total"            // labels: type, queue
+	MetricFailed        = "asyncx_tasks_failed_total"               // labels: type, queue
+	MetricRetried       = "asyncx_tasks_retried_total"              // labels: type, queue
+	MetricTaskDuration  = "asyncx_task_duration_seconds"            // labels: type, queue, outcome
+	MetricStolen        = "asyncx_tasks_stolen_total"               // labels: type, queue, stolen_by
+	MetricStoreErrors   = "asyncx_store_errors_total"               // labels: op
+	MetricStoreDuration = "asyncx_store_operation_duration_seconds" // labels: op
+)
+
+// Gauges set by a StatsExporter.
+const (
+	MetricRecords           = "asyncx_tasks"                     // labels: type, queue, status
+	MetricRecordDurationP50 = "asyncx_task_duration_p50_seconds" // labels: type, queue, status
+	MetricRecordDurationP95 = "asyncx_task_duration_p95_seconds" // labels: type, queue, status
+)
+
+// MetricsSink receives asyncx metrics, decoupling instrumentation from a
+// specific metrics library. See NewOTelMetrics for an OpenTelemetry sink.
+// Implementations must be safe for concurrent use.
+type MetricsSink interface {
+	AddCounter(ctx context.Context, name string, delta float64, labels map[string]string)
+	RecordHistogram(ctx context.Context, name string, value float64, labels map[string]string)
+}
+
+// GaugeSink is a MetricsSink that can also set gauges, as StatsExporter
+// needs. OTelMetrics implements it.
+type GaugeSink interface {
+	MetricsSink
+	SetGauge(ctx context.Context, name string, value float64, labels map[string]string)
+}
+
+// MetricsHooks reports client and processor lifecycle metrics to sink.
+// Pass it as ClientOptions.Hooks and ProcessorConfig.Hooks, combined with
+// other hooks via MultiHooks if needed.
+func MetricsHooks(sink MetricsSink) Hooks { return metricsHooks{sink} }
+
+type metricsHooks struct{ sink MetricsSink }
+
+func taskLabels(e TaskEvent) map[string]string {
+	return map[string]string{"type": e.Type, "queue": e.Queue}
+}
+
+func (h metricsHooks) OnEnqueued(ctx context.Context, e TaskEvent) {
+	h.sink.AddCounter(ctx, MetricEnqueued, 1, taskLabels(e))
+}
+
+func (h metricsHooks) OnStarted(ctx context.Context, e TaskEvent) {
+	h.sink.AddCounter(ctx, MetricStarted, 1, taskLabels(e))
+	if e.StolenBy != "" {
+		labels := taskLabels(e)
+		labels["stolen_by"] = e.StolenBy
+		h.sink.AddCounter(ctx, MetricStolen, 1, labels)
+	}
+}
+
+func (h metricsHooks) OnCompleted(ctx context.Context, e TaskEvent) {
+	h.finished(ctx, e, MetricCompleted, "completed")
+}
+
+func (h metricsHooks) OnFailed(ctx context.Context, e TaskEvent) {
+	h.finished(ctx, e, MetricFailed, "failed")
+}
+
+func (h metricsHooks) OnRetry(ctx context.Context, e TaskEvent) {
+	h.finished(ctx, e, MetricRetried, "retry")
+}
+
+func (h metricsHooks) finished(ctx context.Context, e TaskEvent, counter, outcome string) {
+	h.sink.AddCounter(ctx, counter, 1, taskLabels(e))
+	labels := taskLabels(e)
+	labels["outcome"] = outcome
+	h.sink.RecordHistogram(ctx, MetricTaskDuration, e.Duration.Seconds(), labels)
+}
+
+// InstrumentStore wraps store so that every call reports its latency and
+// errors to sink, labelled by operation name (e.g. "MarkStarted").
+func InstrumentStore(store Store, sink MetricsSink) Store {
+	return &instrumentedStore{store: store, sink: sink}
+}
+
+type instrumentedStore struct {
+	store Store
+	sink  MetricsSink
+}
+
+// observe is deferred with a pointer to the method's named error result.
+func (s *instrumentedStore) observe(ctx context.Context, op string, begin time.Time, err *error) {
+	labels := map[string]string{"op": op}
+	s.sink.RecordHistogram(ctx, MetricStoreDuration, time.Since(begin).Seconds(), labels)
+	if *err != nil {
+		s.sink.AddCounter(ctx, MetricStoreErrors, 1, labels)
+	}
+}
+
+func (s *instrumentedStore) InsertCreated(ctx context.Context, rec TaskRecord) (err error) {
+	defer s.observe(ctx, "InsertCreated", time.Now(), &err)
+	return s.store.InsertCreated(ctx, rec)
+}
+
+func (s *instrumentedStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) (err error) {
+	defer s.observe(ctx, "MarkEnqueued", time.Now(), &err)
+	return s.store.MarkEnqueued(ctx, taskID, queue, enqueuedAt)
+}
+
+func (s *instrumentedStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) (err error) {
+	defer s.observe(ctx, "MarkStarted", time.Now(), &err)
+	return s.store.MarkStarted(ctx, taskID, startedAt)
+}
+
+func (s *instrumentedStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) (err error) {
+	defer s.observe(ctx, "MarkCompleted", time.Now(), &err)
+	return s.store.MarkCompleted(ctx, taskID, resultJSON, finishedAt)
+}
+
+func (s *instrumentedStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) (err error) {
+	defer s.observe(ctx, "MarkFailed", time.Now(), &err)
+	return s.store.MarkFailed(ctx, taskID, errorMsg, finishedAt)
+}
+
+func (s *instrumentedStore) MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) (err error) {
+	defer s.observe(ctx, "MarkPermanentFailure", time.Now(), &err)
+	return markPermanentFailure(ctx, s.store, taskID, errorMsg, finishedAt)
+}
+
+func (s *instrumentedStore) MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) (err error) {
+	defer s.observe(ctx, "MarkRetry", time.Now(), &err)
+	return markRetry(ctx, s.store, taskID, errorMsg, nextRetryAt)
+}
+
+func (s *instrumentedStore) MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) (err error) {
+	defer s.observe(ctx, "MarkTimedOut", time.Now(), &err)
+	return markTimedOut(ctx, s.store, taskID, timeout, at)
+}
+
+func (s *instrumentedStore) AddRuntime(ctx context.Context, taskID string, d time.Duration) (total time.Duration, err error) {
+	rs, ok := storeAs[RuntimeStore](s.store)
+	if !ok {
+		return 0, nil
+	}
+	defer s.observe(ctx, "AddRuntime", time.Now(), &err)
+	return rs.AddRuntime(ctx, taskID, d)
+}
+
+func (s *instrumentedStore) MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) (err error) {
+	defer s.observe(ctx, "MarkBudgetExhausted", time.Now(), &err)
+	return markBudgetExhausted(ctx, s.store, taskID, budget, finishedAt)
+}
+
+func (s *instrumentedStore) SetErrorDetails(ctx context.Context, taskID string, details string) (err error) {
+	defer s.observe(ctx, "SetErrorDetails", time.Now(), &err)
+	return setErrorDetails(ctx, s.store, taskID, details)
+}
+
+func (s *instrumentedStore) Heartbeat(ctx context.Context, taskID string, at time.Time) (err error) {
+	defer s.observe(ctx, "Heartbeat", time.Now(), &err)
+	return heartbeat(ctx, s.store, taskID, at)
+}
+
+func (s *instrumentedStore) MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) (err error) {
+	defer s.observe(ctx, "MarkStatus", time.Now(), &err)
+	return markStatus(ctx, s.store, taskID, status, at)
+}
+
+func (s *instrumentedStore) GetByID(ctx context.Context, taskID string) (_ *TaskRecord, err error) {
+	defer s.observe(ctx, "GetByID", time.Now(), &err)
+	return s.store.GetByID(ctx, taskID)
+}
+
+func (s *instrumentedStore) Ping(ctx context.Context) (err error) {
+	pinger, ok := storeAs[Pinger](s.store)
+	if !ok {
+		return nil
+	}
+	defer s.observe(ctx, "Ping", time.Now(), &err)
+	return pinger.Ping(ctx)
+}
+
+func (s *instrumentedStore) List(ctx context.Context, f TaskFilter) (_ []*TaskRecord, err error) {
+	defer s.observe(ctx, "List", time.Now(), &err)
+	return listRecords(ctx, s.store, f)
+}
+
+func (s *instrumentedStore) Stats(ctx context.Context, f TaskFilter) (_ TaskStats, err error) {
+	defer s.observe(ctx, "Stats", time.Now(), &err)
+	return CountByStatus(ctx, s.store, f)
+}
@@ -0,0 +1,65 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestOTelMetrics_HooksAndStore(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	sink := NewOTelMetrics(provider.Meter("asyncx-test"))
+	ctx := context.Background()
+
+	hooks := MetricsHooks(sink)
+	ev := TaskEvent{Type: "email:deliver", Queue: "default", Duration: 250 * time.Millisecond}
+	hooks.OnEnqueued(ctx, ev)
+	hooks.OnStarted(ctx, ev)
+	hooks.OnCompleted(ctx, ev)
+
+	db := openTestDB(t)
+	defer db.Close()
+	store := InstrumentStore(NewSQLStore(db), sink)
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "m-1", Type: "email:deliver", Queue: "default", PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if _, err := store.GetByID(ctx, "m-missing"); err == nil {
+		t.Fatalf("want error for unknown ID")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	got := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+	sum, ok := got[MetricEnqueued].(metricdata.Sum[float64])
+	if !ok || len(sum.DataPoints) != 1 || sum.DataPoints[0].Value != 1 {
+		t.Fatalf("unexpected %s: %#v", MetricEnqueued, got[MetricEnqueued])
+	}
+	if v, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("type")); v.AsString() != "email:deliver" {
+		t.Fatalf("missing type attribute: %v", sum.DataPoints[0].Attributes)
+	}
+	if _, ok := got[MetricTaskDuration].(metricdata.Histogram[float64]); !ok {
+		t.Fatalf("missing %s", MetricTaskDuration)
+	}
+	if hist, ok := got[MetricStoreDuration].(metricdata.Histogram[float64]); !ok || len(hist.DataPoints) != 2 {
+		t.Fatalf("want store latency for two ops, got %#v", got[MetricStoreDuration])
+	}
+	errs, ok := got[MetricStoreErrors].(metricdata.Sum[float64])
+	if !ok || len(errs.DataPoints) != 1 {
+		t.Fatalf("want one store error series, got %#v", got[MetricStoreErrors])
+	}
+	if v, _ := errs.DataPoints[0].Attributes.Value(attribute.Key("op")); v.AsString() != "GetByID" {
+		t.Fatalf("store error should be labelled with op, got %v", errs.DataPoints[0].Attributes)
+	}
+}
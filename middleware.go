@@ -0,0 +1,57 @@
+package asyncx
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+// MiddlewareFunc wraps a task handler; it is asynq.MiddlewareFunc.
+type MiddlewareFunc = asynq.MiddlewareFunc
+
+// Use adds middleware that runs around every handler, in the order given.
+// It runs inside the lifecycle bookkeeping, so errors it returns (e.g. from
+// recovered panics or timeouts) are recorded like handler errors.
+// Call Use before Start or Run.
+func (p *Processor) Use(mw ...MiddlewareFunc) {
+	p.mw = append(p.mw, mw...)
+}
+
+// UseFor adds middleware that runs only for tasks of taskType, inside the
+// middleware added with Use. Call UseFor before Start or Run.
+func (p *Processor) UseFor(taskType string, mw ...MiddlewareFunc) {
+	if p.typeMW == nil {
+		p.typeMW = make(map[string][]MiddlewareFunc)
+	}
+	p.typeMW[taskType] = append(p.typeMW[taskType], mw...)
+}
+
+// handler assembles lifecycle bookkeeping, Use and UseFor middleware
+// around h, outermost first.
+func (p *Processor) handler(h asynq.Handler) asynq.Handler {
+	if p.dryRun != nil {
+		h = p.dryRun.wrap(h)
+	}
+	if len(p.typeMW) > 0 {
+		chains := make(map[string]asynq.Handler, len(p.typeMW))
+		for taskType, mw := range p.typeMW {
+			chains[taskType] = chain(h, mw)
+		}
+		base := h
+		h = asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			if c, ok := chains[t.Type()]; ok {
+				return c.ProcessTask(ctx, t)
+			}
+			return base.ProcessTask(ctx, t)
+		})
+	}
+	return p.lifecycleMiddleware(chain(h, p.mw))
+}
+
+// chain wraps h so that mw[0] runs first.
+func chain(h asynq.Handler, mw []MiddlewareFunc) asynq.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
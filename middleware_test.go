@@ -0,0 +1,65 @@
+package asyncx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_UseAndUseFor(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	p := NewProcessor(asynq.RedisClientOpt{Addr: s.Addr()}, nil, ProcessorConfig{})
+
+	var calls []string
+	record := func(name string) MiddlewareFunc {
+		return func(next asynq.Handler) asynq.Handler {
+			return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+				calls = append(calls, name)
+				return next.ProcessTask(ctx, t)
+			})
+		}
+	}
+	recoverer := func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			return next.ProcessTask(ctx, t)
+		})
+	}
+	p.Use(recoverer, record("global"))
+	p.UseFor("mw:special", record("special"))
+
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("mw:plain", func(ctx context.Context, t *asynq.Task) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+	mux.HandleFunc("mw:special", func(ctx context.Context, t *asynq.Task) error {
+		calls = append(calls, "handler")
+		panic("kaboom")
+	})
+	h := p.handler(mux)
+	ctx := context.Background()
+
+	if err := h.ProcessTask(ctx, asynq.NewTask("mw:plain", nil)); err != nil {
+		t.Fatalf("plain: %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "global,handler" {
+		t.Fatalf("plain task calls = %s", got)
+	}
+	calls = nil
+	err := h.ProcessTask(ctx, asynq.NewTask("mw:special", nil))
+	if err == nil || !strings.Contains(err.Error(), "kaboom") {
+		t.Fatalf("recovery middleware should turn the panic into an error, got %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "global,special,handler" {
+		t.Fatalf("special task calls = %s", got)
+	}
+}
@@ -0,0 +1,169 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// Migrations exposes the embedded schema files, for applications that run
+// them with their own migration tool. Most files are MySQL-flavoured and
+// apply to every database; a migration that differs per database ships as
+// one file per dialect instead, e.g. 018_add_dedup_key.postgres.sql.
+var Migrations fs.FS = migrationFS
+
+// Dialect selects the SQL flavour Migrate writes.
+type Dialect string
+
+const (
+	DialectMySQL    Dialect = "mysql"
+	DialectPostgres Dialect = "postgres"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// migrationsTable records which embedded migrations have been applied.
+const migrationsTable = "asyncx_schema_migrations"
+
+// Migrate creates or upgrades the asyncx_tasks schema by applying every
+// embedded migration not yet recorded in asyncx_schema_migrations, in
+// file name order. It is safe to call on every startup.
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	switch dialect {
+	case DialectMySQL, DialectPostgres, DialectSQLite:
+	default:
+		return fmt.Errorf("asyncx: unknown dialect %q", dialect)
+	}
+	create := `CREATE TABLE IF NOT EXISTS ` + migrationsTable + ` (version VARCHAR(255) PRIMARY KEY, applied_at DATETIME NOT NULL)`
+	if _, err := db.ExecContext(ctx, translateSQL(create, dialect)); err != nil {
+		return fmt.Errorf("asyncx: create %s: %w", migrationsTable, err)
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+	files, err := migrationFiles(dialect)
+	if err != nil {
+		return err
+	}
+	insert := `INSERT INTO ` + migrationsTable + ` (version, applied_at) VALUES (?, ?)`
+	if dialect == DialectPostgres {
+		insert = `INSERT INTO ` + migrationsTable + ` (version, applied_at) VALUES ($1, $2)`
+	}
+	for _, f := range files {
+		version, name := f[0], f[1]
+		if applied[version] {
+			continue
+		}
+		body, err := migrationFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range splitStatements(string(body)) {
+			if _, err := tx.ExecContext(ctx, translateSQL(stmt, dialect)); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("asyncx: migration %s: %w", version, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, insert, version, time.Now().UTC()); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("asyncx: record migration %s: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrationFiles returns (version, file) pairs in version order, picking the
+// file for dialect where a migration has per-dialect variants.
+func migrationFiles(dialect Dialect) ([][2]string, error) {
+	names, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	generic := map[string]string{}
+	specific := map[string]string{}
+	variants := map[string]bool{}
+	for _, name := range names {
+		version := strings.TrimSuffix(strings.TrimPrefix(name, "migrations/"), ".sql")
+		if i := strings.LastIndexByte(version, '.'); i >= 0 {
+			base, d := version[:i], Dialect(version[i+1:])
+			variants[base] = true
+			if d == dialect {
+				specific[base] = name
+			}
+			continue
+		}
+		generic[version] = name
+	}
+	var out [][2]string
+	for version, name := range generic {
+		out = append(out, [2]string{version, name})
+	}
+	for version := range variants {
+		name, ok := specific[version]
+		if !ok {
+			return nil, fmt.Errorf("asyncx: migration %s has no variant for %s", version, dialect)
+		}
+		out = append(out, [2]string{version, name})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out, nil
+}
+
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version FROM `+migrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[string]bool{}
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// splitStatements drops "--" comment lines and splits on ";".
+func splitStatements(body string) []string {
+	var b strings.Builder
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	var out []string
+	for _, stmt := range strings.Split(b.String(), ";") {
+		if s := strings.TrimSpace(stmt); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// translateSQL adapts the MySQL-flavoured migration files to dialect.
+func translateSQL(stmt string, dialect Dialect) string {
+	if dialect == DialectPostgres {
+		return strings.ReplaceAll(stmt, "DATETIME", "TIMESTAMP")
+	}
+	return stmt
+}
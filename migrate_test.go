@@ -0,0 +1,86 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestMigrate_FreshDatabase(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_migrate_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	// A second run must be a no-op.
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM asyncx_schema_migrations`).Scan(&n); err != nil {
+		t.Fatalf("count migrations: %v", err)
+	}
+	files, _ := migrationFiles(DialectSQLite)
+	if n != len(files) {
+		t.Fatalf("expected %d applied migrations, got %d", len(files), n)
+	}
+
+	store := NewSQLStore(db)
+	now := time.Now().UTC()
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "m-1", Type: "t", Queue: "default", PayloadJSON: "{}", Status: StatusCreated, CreatedAt: now}); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := store.MarkTimedOut(ctx, "m-1", time.Second, now); err != nil {
+		t.Fatalf("mark timed out: %v", err)
+	}
+	rec, err := store.GetByID(ctx, "m-1")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if rec.Status != StatusTimedOut || rec.TimeoutMS != 1000 || rec.FinishedAt == nil {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+}
+
+func TestMigrationFiles_PicksDialectVariant(t *testing.T) {
+	for _, d := range []Dialect{DialectMySQL, DialectPostgres, DialectSQLite} {
+		files, err := migrationFiles(d)
+		if err != nil {
+			t.Fatalf("%s: %v", d, err)
+		}
+		versions, _ := fs.Glob(Migrations, "migrations/0*.sql")
+		seen := map[string]bool{}
+		for _, f := range files {
+			if seen[f[0]] {
+				t.Fatalf("%s: version %s listed twice", d, f[0])
+			}
+			seen[f[0]] = true
+			if f[0] == "018_add_dedup_key" && f[1] != "migrations/018_add_dedup_key."+string(d)+".sql" {
+				t.Fatalf("%s: got %s for 018", d, f[1])
+			}
+		}
+		if len(files) >= len(versions) {
+			t.Fatalf("%s: %d files for %d embedded files; variants should collapse", d, len(files), len(versions))
+		}
+	}
+}
+
+func TestMigrate_UnknownDialect(t *testing.T) {
+	if err := Migrate(context.Background(), nil, "oracle"); err == nil {
+		t.Fatal("expected error for unknown dialect")
+	}
+}
+
+func TestTranslateSQL_Postgres(t *testing.T) {
+	got := translateSQL("ALTER TABLE asyncx_tasks ADD COLUMN x DATETIME NULL", DialectPostgres)
+	if got != "ALTER TABLE asyncx_tasks ADD COLUMN x TIMESTAMP NULL" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,4 @@
+-- asyncx: task classification (standard, critical, fire_and_forget)
+-- Same statement works for MySQL and Postgres.
+
+ALTER TABLE asyncx_tasks ADD COLUMN task_class VARCHAR(32) NULL;
@@ -0,0 +1,4 @@
+-- asyncx: heartbeat timestamp for long-running tasks
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_tasks ADD COLUMN last_heartbeat_at DATETIME NULL;
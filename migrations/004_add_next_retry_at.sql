@@ -0,0 +1,4 @@
+-- asyncx: when a failed task will next be retried
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_tasks ADD COLUMN next_retry_at DATETIME NULL;
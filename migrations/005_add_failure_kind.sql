@@ -0,0 +1,3 @@
+-- asyncx: distinguishes permanent failures (not retried) from transient ones
+
+ALTER TABLE asyncx_tasks ADD COLUMN failure_kind VARCHAR(16) NULL;
@@ -0,0 +1,3 @@
+-- asyncx: diagnostic details for the last error, e.g. a recovered panic's stack trace
+
+ALTER TABLE asyncx_tasks ADD COLUMN error_details TEXT NULL;
@@ -0,0 +1,3 @@
+-- asyncx: the per-type timeout in force when a task timed out
+
+ALTER TABLE asyncx_tasks ADD COLUMN timeout_ms BIGINT NULL;
@@ -0,0 +1,3 @@
+-- asyncx: request attribution (correlation ID, tenant, subject, trace) captured at enqueue
+
+ALTER TABLE asyncx_tasks ADD COLUMN request_json TEXT NULL;
@@ -0,0 +1,5 @@
+-- asyncx: schema version checked by SQLStore.CheckSchema
+-- Every later migration ends with UPDATE asyncx_schema_version SET version = <its number>.
+
+CREATE TABLE IF NOT EXISTS asyncx_schema_version (version INT NOT NULL);
+INSERT INTO asyncx_schema_version (version) VALUES (9);
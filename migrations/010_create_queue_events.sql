@@ -0,0 +1,10 @@
+-- asyncx: audit trail of queue pause/resume operations
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+CREATE TABLE IF NOT EXISTS asyncx_queue_events (
+    queue      VARCHAR(64)  NOT NULL,
+    action     VARCHAR(32)  NOT NULL,
+    actor      VARCHAR(255) NULL,
+    created_at DATETIME     NOT NULL
+);
+UPDATE asyncx_schema_version SET version = 10;
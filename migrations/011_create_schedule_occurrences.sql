@@ -0,0 +1,10 @@
+-- asyncx: occurrences enqueued by the Scheduler, for audit
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+CREATE TABLE IF NOT EXISTS asyncx_schedule_occurrences (
+    entry         VARCHAR(255) NOT NULL,
+    scheduled_for DATETIME     NOT NULL,
+    task_id       VARCHAR(64)  NOT NULL,
+    enqueued_at   DATETIME     NOT NULL
+);
+UPDATE asyncx_schema_version SET version = 11;
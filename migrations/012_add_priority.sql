@@ -0,0 +1,4 @@
+-- asyncx: effective priority tier (critical, default, low) at enqueue
+
+ALTER TABLE asyncx_tasks ADD COLUMN priority VARCHAR(16) NULL;
+UPDATE asyncx_schema_version SET version = 12;
@@ -0,0 +1,4 @@
+-- asyncx: cumulative handler run time across attempts, for runtime budgets
+
+ALTER TABLE asyncx_tasks ADD COLUMN runtime_ms BIGINT NULL;
+UPDATE asyncx_schema_version SET version = 13;
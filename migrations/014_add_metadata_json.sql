@@ -0,0 +1,4 @@
+-- asyncx: free-form string labels attached at enqueue
+
+ALTER TABLE asyncx_tasks ADD COLUMN metadata_json TEXT NULL;
+UPDATE asyncx_schema_version SET version = 14;
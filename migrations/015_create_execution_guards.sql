@@ -0,0 +1,10 @@
+-- asyncx: execution guards that keep a re-driven task and its original from both running
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_tasks ADD COLUMN guard_token VARCHAR(64) NULL;
+CREATE TABLE IF NOT EXISTS asyncx_execution_guards (
+    token      VARCHAR(64) PRIMARY KEY,
+    task_id    VARCHAR(64) NOT NULL,
+    claimed_at DATETIME    NOT NULL
+);
+UPDATE asyncx_schema_version SET version = 15;
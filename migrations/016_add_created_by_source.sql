@@ -0,0 +1,5 @@
+-- asyncx: who enqueued each task, for audits
+
+ALTER TABLE asyncx_tasks ADD COLUMN created_by VARCHAR(255) NULL;
+ALTER TABLE asyncx_tasks ADD COLUMN source VARCHAR(255) NULL;
+UPDATE asyncx_schema_version SET version = 16;
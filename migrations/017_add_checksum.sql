@@ -0,0 +1,4 @@
+-- asyncx: HMAC over the immutable columns, for tamper detection
+
+ALTER TABLE asyncx_tasks ADD COLUMN checksum VARCHAR(64) NULL;
+UPDATE asyncx_schema_version SET version = 17;
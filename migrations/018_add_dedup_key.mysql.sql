@@ -0,0 +1,8 @@
+-- asyncx: dedup keys for idempotent enqueues (MySQL)
+-- MySQL has no partial indexes, so a generated column maps empty keys to
+-- NULL and carries the unique index.
+
+ALTER TABLE asyncx_tasks ADD COLUMN dedup_key VARCHAR(255) NULL;
+ALTER TABLE asyncx_tasks ADD COLUMN dedup_key_unique VARCHAR(255) GENERATED ALWAYS AS (NULLIF(dedup_key, '')) STORED;
+CREATE UNIQUE INDEX asyncx_tasks_dedup_key ON asyncx_tasks (dedup_key_unique);
+UPDATE asyncx_schema_version SET version = 18;
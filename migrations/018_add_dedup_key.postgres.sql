@@ -0,0 +1,5 @@
+-- asyncx: dedup keys for idempotent enqueues (Postgres)
+
+ALTER TABLE asyncx_tasks ADD COLUMN dedup_key VARCHAR(255) NULL;
+CREATE UNIQUE INDEX asyncx_tasks_dedup_key ON asyncx_tasks (dedup_key) WHERE dedup_key IS NOT NULL AND dedup_key <> '';
+UPDATE asyncx_schema_version SET version = 18;
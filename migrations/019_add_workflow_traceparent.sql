@@ -0,0 +1,4 @@
+-- asyncx: root span of the workflow a task belongs to, as a W3C traceparent
+
+ALTER TABLE asyncx_tasks ADD COLUMN workflow_traceparent VARCHAR(64) NULL;
+UPDATE asyncx_schema_version SET version = 19;
@@ -0,0 +1,5 @@
+-- asyncx: when the janitor purged the payload under a retention policy
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_tasks ADD COLUMN payload_purged_at DATETIME NULL;
+UPDATE asyncx_schema_version SET version = 20;
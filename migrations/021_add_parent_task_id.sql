@@ -0,0 +1,5 @@
+-- asyncx: parent of tasks enqueued with EnqueueChild
+
+ALTER TABLE asyncx_tasks ADD COLUMN parent_task_id VARCHAR(64) NULL;
+CREATE INDEX asyncx_tasks_parent_task_id ON asyncx_tasks (parent_task_id);
+UPDATE asyncx_schema_version SET version = 21;
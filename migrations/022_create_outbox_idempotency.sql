@@ -0,0 +1,18 @@
+-- asyncx: transactional outbox relayed by OutboxRelay, and processed idempotency keys
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+CREATE TABLE IF NOT EXISTS asyncx_outbox (
+    id           VARCHAR(64)  PRIMARY KEY,
+    type         VARCHAR(255) NOT NULL,
+    queue        VARCHAR(64)  NULL,
+    payload_json TEXT         NOT NULL,
+    created_at   DATETIME     NOT NULL,
+    relayed_at   DATETIME     NULL
+);
+CREATE INDEX asyncx_outbox_pending ON asyncx_outbox (relayed_at, created_at);
+CREATE TABLE IF NOT EXISTS asyncx_idempotency_keys (
+    idem_key   VARCHAR(255) PRIMARY KEY,
+    task_id    VARCHAR(64)  NULL,
+    created_at DATETIME     NOT NULL
+);
+UPDATE asyncx_schema_version SET version = 22;
@@ -0,0 +1,25 @@
+-- asyncx: DAG workflows run by the Orchestrator and the state of their nodes
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+CREATE TABLE IF NOT EXISTS asyncx_workflows (
+    id         VARCHAR(64)  PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    status     VARCHAR(32)  NOT NULL,
+    created_at DATETIME     NOT NULL,
+    updated_at DATETIME     NULL
+);
+CREATE INDEX asyncx_workflows_status ON asyncx_workflows (status);
+CREATE TABLE IF NOT EXISTS asyncx_workflow_nodes (
+    workflow_id  VARCHAR(64)  NOT NULL,
+    node_id      VARCHAR(255) NOT NULL,
+    position     INTEGER      NOT NULL,
+    type         VARCHAR(255) NOT NULL,
+    queue        VARCHAR(64)  NULL,
+    payload_json TEXT         NOT NULL,
+    depends_on   TEXT         NULL,
+    status       VARCHAR(32)  NOT NULL,
+    task_id      VARCHAR(64)  NULL,
+    updated_at   DATETIME     NULL,
+    PRIMARY KEY (workflow_id, node_id)
+);
+UPDATE asyncx_schema_version SET version = 23;
@@ -0,0 +1,10 @@
+-- asyncx: workflows started by the Scheduler record their entry, occurrence
+-- and run number. The unique index keeps two scheduler replicas from
+-- starting the same occurrence twice.
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_workflows ADD COLUMN schedule_entry VARCHAR(255) NULL;
+ALTER TABLE asyncx_workflows ADD COLUMN scheduled_for DATETIME NULL;
+ALTER TABLE asyncx_workflows ADD COLUMN run_number INTEGER NULL;
+CREATE UNIQUE INDEX asyncx_workflows_schedule ON asyncx_workflows (schedule_entry, scheduled_for);
+UPDATE asyncx_schema_version SET version = 24;
@@ -0,0 +1,14 @@
+-- asyncx: append-only history of task transitions, written by SQLStore when
+-- SQLStoreOptions.History is set. seq orders events of one task.
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+CREATE TABLE IF NOT EXISTS asyncx_task_events (
+    task_id    VARCHAR(64)  NOT NULL,
+    seq        BIGINT       NOT NULL,
+    status     VARCHAR(32)  NOT NULL,
+    error_msg  TEXT         NULL,
+    worker_id  VARCHAR(255) NULL,
+    created_at DATETIME     NOT NULL
+);
+CREATE INDEX asyncx_task_events_task ON asyncx_task_events (task_id, seq);
+UPDATE asyncx_schema_version SET version = 25;
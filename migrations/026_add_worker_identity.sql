@@ -0,0 +1,7 @@
+-- asyncx: identity of the Processor that last started the task
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_tasks ADD COLUMN worker_id VARCHAR(255) NULL;
+ALTER TABLE asyncx_tasks ADD COLUMN hostname VARCHAR(255) NULL;
+ALTER TABLE asyncx_tasks ADD COLUMN pid INTEGER NULL;
+UPDATE asyncx_schema_version SET version = 26;
@@ -0,0 +1,4 @@
+-- asyncx: encoding of the task payload, see Codec
+
+ALTER TABLE asyncx_tasks ADD COLUMN content_type VARCHAR(255) NULL;
+UPDATE asyncx_schema_version SET version = 27;
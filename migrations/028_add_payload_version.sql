@@ -0,0 +1,4 @@
+-- asyncx: payload schema version, see PayloadUpgrades
+
+ALTER TABLE asyncx_tasks ADD COLUMN payload_version INTEGER NULL;
+UPDATE asyncx_schema_version SET version = 28;
@@ -0,0 +1,6 @@
+-- asyncx: when a WarehouseSink exported the record
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_tasks ADD COLUMN exported_at DATETIME NULL;
+CREATE INDEX asyncx_tasks_exported_at ON asyncx_tasks (exported_at);
+UPDATE asyncx_schema_version SET version = 29;
@@ -0,0 +1,18 @@
+-- asyncx: summary views for dashboards; replaced by the tables of 037
+-- MySQL has no percentile function: p50_seconds and p95_seconds are NULL.
+
+CREATE VIEW asyncx_failures_per_hour AS
+SELECT DATE_FORMAT(COALESCE(finished_at, updated_at), '%Y-%m-%d %H:00:00') AS hour, type, queue, COUNT(*) AS failures
+FROM asyncx_tasks
+WHERE status IN ('failed', 'timed_out')
+GROUP BY 1, 2, 3;
+CREATE VIEW asyncx_durations_by_type AS
+SELECT type, COUNT(*) AS finished,
+    AVG(TIMESTAMPDIFF(MICROSECOND, started_at, finished_at)) / 1000000 AS avg_seconds,
+    MAX(TIMESTAMPDIFF(MICROSECOND, started_at, finished_at)) / 1000000 AS max_seconds,
+    NULL AS p50_seconds,
+    NULL AS p95_seconds
+FROM asyncx_tasks
+WHERE started_at IS NOT NULL AND finished_at IS NOT NULL
+GROUP BY type;
+UPDATE asyncx_schema_version SET version = 30;
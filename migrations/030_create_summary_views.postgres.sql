@@ -0,0 +1,17 @@
+-- asyncx: summary views for dashboards; replaced by the tables of 037
+
+CREATE VIEW asyncx_failures_per_hour AS
+SELECT date_trunc('hour', COALESCE(finished_at, updated_at)) AS hour, type, queue, COUNT(*) AS failures
+FROM asyncx_tasks
+WHERE status IN ('failed', 'timed_out')
+GROUP BY 1, 2, 3;
+CREATE VIEW asyncx_durations_by_type AS
+SELECT type, COUNT(*) AS finished,
+    AVG(EXTRACT(EPOCH FROM finished_at - started_at)) AS avg_seconds,
+    MAX(EXTRACT(EPOCH FROM finished_at - started_at)) AS max_seconds,
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM finished_at - started_at)) AS p50_seconds,
+    percentile_cont(0.95) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM finished_at - started_at)) AS p95_seconds
+FROM asyncx_tasks
+WHERE started_at IS NOT NULL AND finished_at IS NOT NULL
+GROUP BY type;
+UPDATE asyncx_schema_version SET version = 30;
@@ -0,0 +1,19 @@
+-- asyncx: summary views for dashboards; replaced by the tables of 037
+-- SQLite has no percentile function: p50_seconds and p95_seconds are NULL.
+-- Durations have one second resolution.
+
+CREATE VIEW asyncx_failures_per_hour AS
+SELECT substr(COALESCE(finished_at, updated_at), 1, 13) || ':00:00' AS hour, type, queue, COUNT(*) AS failures
+FROM asyncx_tasks
+WHERE status IN ('failed', 'timed_out')
+GROUP BY 1, 2, 3;
+CREATE VIEW asyncx_durations_by_type AS
+SELECT type, COUNT(*) AS finished,
+    AVG(ROUND((julianday(substr(finished_at, 1, 19)) - julianday(substr(started_at, 1, 19))) * 86400)) AS avg_seconds,
+    MAX(ROUND((julianday(substr(finished_at, 1, 19)) - julianday(substr(started_at, 1, 19))) * 86400)) AS max_seconds,
+    NULL AS p50_seconds,
+    NULL AS p95_seconds
+FROM asyncx_tasks
+WHERE started_at IS NOT NULL AND finished_at IS NOT NULL
+GROUP BY type;
+UPDATE asyncx_schema_version SET version = 30;
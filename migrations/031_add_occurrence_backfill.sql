@@ -0,0 +1,4 @@
+-- asyncx: flags occurrences the Scheduler enqueued late, for missed runs
+
+ALTER TABLE asyncx_schedule_occurrences ADD COLUMN backfill INTEGER NOT NULL DEFAULT 0;
+UPDATE asyncx_schema_version SET version = 31;
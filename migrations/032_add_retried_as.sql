@@ -0,0 +1,4 @@
+-- asyncx: the task that Client.RetryFailed enqueued in place of a failed one
+
+ALTER TABLE asyncx_tasks ADD COLUMN retried_as VARCHAR(64) NULL;
+UPDATE asyncx_schema_version SET version = 32;
@@ -0,0 +1,5 @@
+-- asyncx: hash of type and payload for ClientOptions.ContentDedupWindows
+
+ALTER TABLE asyncx_tasks ADD COLUMN content_hash VARCHAR(64) NULL;
+CREATE INDEX asyncx_tasks_content_hash ON asyncx_tasks (content_hash, created_at);
+UPDATE asyncx_schema_version SET version = 33;
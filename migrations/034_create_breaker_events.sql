@@ -0,0 +1,11 @@
+-- asyncx: state changes of CircuitBreaker per task type
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+CREATE TABLE IF NOT EXISTS asyncx_breaker_events (
+    type       VARCHAR(255) NOT NULL,
+    state      VARCHAR(16)  NOT NULL,
+    failures   INTEGER      NOT NULL,
+    created_at DATETIME     NOT NULL
+);
+CREATE INDEX asyncx_breaker_events_type ON asyncx_breaker_events (type, created_at);
+UPDATE asyncx_schema_version SET version = 34;
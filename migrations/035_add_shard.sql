@@ -0,0 +1,4 @@
+-- asyncx: Redis shard a ShardedClient enqueued the task on
+
+ALTER TABLE asyncx_tasks ADD COLUMN shard VARCHAR(64) NULL;
+UPDATE asyncx_schema_version SET version = 35;
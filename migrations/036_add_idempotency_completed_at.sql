@@ -0,0 +1,6 @@
+-- asyncx: completion time of idempotency keys, NULL while a task holds the key
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+ALTER TABLE asyncx_idempotency_keys ADD COLUMN completed_at DATETIME NULL;
+UPDATE asyncx_idempotency_keys SET completed_at = created_at;
+UPDATE asyncx_schema_version SET version = 36;
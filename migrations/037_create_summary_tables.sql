@@ -0,0 +1,22 @@
+-- asyncx: summary tables for SQL dashboards, replacing the views of 030,
+-- which read asyncx_tasks on every query. StatsExporter refreshes them.
+-- For Postgres, replace DATETIME with TIMESTAMP.
+
+DROP VIEW asyncx_failures_per_hour;
+DROP VIEW asyncx_durations_by_type;
+CREATE TABLE asyncx_failures_per_hour (
+    hour     DATETIME     NOT NULL,
+    type     VARCHAR(255) NOT NULL,
+    queue    VARCHAR(255) NOT NULL,
+    failures INTEGER      NOT NULL,
+    PRIMARY KEY (hour, type, queue)
+);
+CREATE TABLE asyncx_durations_by_type (
+    type        VARCHAR(255) PRIMARY KEY,
+    finished    INTEGER      NOT NULL,
+    avg_seconds DOUBLE PRECISION,
+    max_seconds DOUBLE PRECISION,
+    p50_seconds DOUBLE PRECISION,
+    p95_seconds DOUBLE PRECISION
+);
+UPDATE asyncx_schema_version SET version = 37;
@@ -0,0 +1,17 @@
+-- asyncx: order task history by an autoincrement id rather than a
+-- timestamp seq, which is not monotonic across hosts (Postgres).
+
+CREATE TABLE asyncx_task_events_new (
+    id         BIGSERIAL    PRIMARY KEY,
+    task_id    VARCHAR(64)  NOT NULL,
+    status     VARCHAR(32)  NOT NULL,
+    error_msg  TEXT         NULL,
+    worker_id  VARCHAR(255) NULL,
+    created_at DATETIME     NOT NULL
+);
+INSERT INTO asyncx_task_events_new (task_id, status, error_msg, worker_id, created_at)
+    SELECT task_id, status, error_msg, worker_id, created_at FROM asyncx_task_events ORDER BY seq;
+DROP TABLE asyncx_task_events;
+ALTER TABLE asyncx_task_events_new RENAME TO asyncx_task_events;
+CREATE INDEX asyncx_task_events_task ON asyncx_task_events (task_id, id);
+UPDATE asyncx_schema_version SET version = 38;
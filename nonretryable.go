@@ -0,0 +1,30 @@
+package asyncx
+
+import (
+	"errors"
+
+	"github.com/hibiken/asynq"
+)
+
+// nonRetryableError marks a permanent failure. It matches both the wrapped
+// error and asynq.SkipRetry, so asynq archives the task without retrying.
+type nonRetryableError struct{ err error }
+
+// NonRetryable marks err as a permanent failure, e.g. invalid input that no
+// retry can fix. The Processor skips the remaining retries and records the
+// task as failed with FailurePermanent.
+func NonRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &nonRetryableError{err: err}
+}
+
+// IsNonRetryable reports whether err was marked with NonRetryable.
+func IsNonRetryable(err error) bool {
+	var nr *nonRetryableError
+	return errors.As(err, &nr)
+}
+
+func (e *nonRetryableError) Error() string   { return e.err.Error() }
+func (e *nonRetryableError) Unwrap() []error { return []error{e.err, asynq.SkipRetry} }
@@ -0,0 +1,67 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestNonRetryable_WrapsAndSkipsRetry(t *testing.T) {
+	base := errors.New("bad input")
+	err := NonRetryable(base)
+	if !errors.Is(err, base) || !errors.Is(err, asynq.SkipRetry) || !IsNonRetryable(err) || err.Error() != "bad input" {
+		t.Fatalf("unexpected wrapper behaviour: %v", err)
+	}
+	if NonRetryable(nil) != nil || IsNonRetryable(base) {
+		t.Fatalf("NonRetryable(nil) must be nil and plain errors are retryable")
+	}
+}
+
+func TestProcessor_RecordsFailureKind(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	// One worker: concurrent writes to shared-cache sqlite can fail with SQLITE_LOCKED.
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("fk:permanent", func(ctx context.Context, tsk *asynq.Task) error {
+		return NonRetryable(errors.New("bad input"))
+	})
+	mux.HandleFunc("fk:transient", func(ctx context.Context, tsk *asynq.Task) error {
+		return errors.New("timeout")
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	permanent, err := client.Enqueue(ctx, "fk:permanent", nil, asynq.MaxRetry(5))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	transient, err := client.Enqueue(ctx, "fk:transient", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	for id, want := range map[string]FailureKind{permanent.ID: FailurePermanent, transient.ID: FailureTransient} {
+		if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+			rec, err := store.GetByID(ctx, id)
+			return err == nil && rec.FailureKind == want, nil
+		}); err != nil {
+			t.Fatalf("task %s: want failure_kind %s: %v", id, want, err)
+		}
+	}
+	rec, _ := store.GetByID(ctx, permanent.ID)
+	if rec.Status != StatusFailed || rec.ErrorMsg == nil || *rec.ErrorMsg != "bad input" || rec.NextRetryAt != nil {
+		t.Fatalf("unexpected permanent failure record: %#v", rec)
+	}
+}
@@ -0,0 +1,76 @@
+package asyncx
+
+import (
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+// asyncxOpt is the asynq.OptionType of options consumed by Client.Enqueue.
+// asynq ignores option types it does not know, but Enqueue strips them anyway.
+const asyncxOpt asynq.OptionType = -1
+
+type classOption TaskClass
+
+// WithClass overrides the task class for a single Enqueue call.
+func WithClass(c TaskClass) asynq.Option { return classOption(c) }
+
+func (o classOption) String() string         { return fmt.Sprintf("Class(%q)", string(o)) }
+func (o classOption) Type() asynq.OptionType { return asyncxOpt }
+func (o classOption) Value() interface{}     { return TaskClass(o) }
+
+type metadataOption map[string]string
+
+// WithMetadata attaches labels such as request or user IDs to a task. They
+// are stored with the record, filterable with TaskFilter.Metadata and
+// available to the handler through MetadataFromContext. Repeated options
+// are merged.
+func WithMetadata(md map[string]string) asynq.Option { return metadataOption(md) }
+
+func (o metadataOption) String() string         { return fmt.Sprintf("Metadata(%v)", map[string]string(o)) }
+func (o metadataOption) Type() asynq.OptionType { return asyncxOpt }
+func (o metadataOption) Value() interface{}     { return map[string]string(o) }
+
+// enqueueOptions collects the asyncx-specific options passed to Enqueue.
+type enqueueOptions struct {
+	class    TaskClass
+	metadata map[string]string
+	guard    string
+	dedupKey string
+	parent   string
+	// contentType overrides the codec's, for payloads encoded elsewhere.
+	contentType    string
+	payloadVersion int
+}
+
+// splitOptions separates asyncx options from the ones forwarded to asynq.
+func splitOptions(options []asynq.Option) ([]asynq.Option, enqueueOptions) {
+	var eo enqueueOptions
+	out := make([]asynq.Option, 0, len(options))
+	for _, o := range options {
+		switch o := o.(type) {
+		case classOption:
+			eo.class = TaskClass(o)
+		case metadataOption:
+			if eo.metadata == nil {
+				eo.metadata = make(map[string]string, len(o))
+			}
+			for k, v := range o {
+				eo.metadata[k] = v
+			}
+		case guardOption:
+			eo.guard = string(o)
+		case dedupOption:
+			eo.dedupKey = string(o)
+		case parentOption:
+			eo.parent = string(o)
+		case contentTypeOption:
+			eo.contentType = string(o)
+		case payloadVersionOption:
+			eo.payloadVersion = int(o)
+		default:
+			out = append(out, o)
+		}
+	}
+	return out, eo
+}
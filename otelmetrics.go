@@ -0,0 +1,94 @@
+package asyncx
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OTelMetrics is a MetricsSink that records through an OpenTelemetry Meter,
+// for teams exporting via a MeterProvider instead of a Prometheus client.
+// Instruments are created on first use; labels become attributes.
+type OTelMetrics struct {
+	meter metric.Meter
+
+	mu         sync.Mutex
+	counters   map[string]metric.Float64Counter
+	histograms map[string]metric.Float64Histogram
+	gauges     map[string]metric.Float64Gauge
+}
+
+// NewOTelMetrics returns a sink recording on meter, typically
+// provider.Meter("github.com/mohans/asyncx").
+func NewOTelMetrics(meter metric.Meter) *OTelMetrics {
+	return &OTelMetrics{
+		meter:      meter,
+		counters:   make(map[string]metric.Float64Counter),
+		histograms: make(map[string]metric.Float64Histogram),
+		gauges:     make(map[string]metric.Float64Gauge),
+	}
+}
+
+func (m *OTelMetrics) AddCounter(ctx context.Context, name string, delta float64, labels map[string]string) {
+	m.mu.Lock()
+	c, ok := m.counters[name]
+	if !ok {
+		var err error
+		if c, err = m.meter.Float64Counter(name); err != nil {
+			m.mu.Unlock()
+			return
+		}
+		m.counters[name] = c
+	}
+	m.mu.Unlock()
+	c.Add(ctx, delta, metric.WithAttributes(otelAttrs(labels)...))
+}
+
+func (m *OTelMetrics) RecordHistogram(ctx context.Context, name string, value float64, labels map[string]string) {
+	m.mu.Lock()
+	h, ok := m.histograms[name]
+	if !ok {
+		var opts []metric.Float64HistogramOption
+		if strings.HasSuffix(name, "_seconds") {
+			opts = append(opts, metric.WithUnit("s"))
+		}
+		var err error
+		if h, err = m.meter.Float64Histogram(name, opts...); err != nil {
+			m.mu.Unlock()
+			return
+		}
+		m.histograms[name] = h
+	}
+	m.mu.Unlock()
+	h.Record(ctx, value, metric.WithAttributes(otelAttrs(labels)...))
+}
+
+func (m *OTelMetrics) SetGauge(ctx context.Context, name string, value float64, labels map[string]string) {
+	m.mu.Lock()
+	g, ok := m.gauges[name]
+	if !ok {
+		var opts []metric.Float64GaugeOption
+		if strings.HasSuffix(name, "_seconds") {
+			opts = append(opts, metric.WithUnit("s"))
+		}
+		var err error
+		if g, err = m.meter.Float64Gauge(name, opts...); err != nil {
+			m.mu.Unlock()
+			return
+		}
+		m.gauges[name] = g
+	}
+	m.mu.Unlock()
+	g.Record(ctx, value, metric.WithAttributes(otelAttrs(labels)...))
+}
+
+func otelAttrs(labels map[string]string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for k, v := range labels {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return attrs
+}
@@ -0,0 +1,154 @@
+package asyncx
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// Execer is satisfied by *sql.DB, *sql.Tx and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Outbox is a transactional outbox in asyncx_outbox. Tasks are added in the
+// application's own transaction and enqueued by an OutboxRelay after it
+// commits, so a task exists exactly when the change it belongs to does.
+type Outbox struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewOutbox returns the outbox in db. The dialect picks the placeholder
+// style, since a failed statement cannot be retried inside a Postgres
+// transaction.
+func NewOutbox(db *sql.DB, dialect Dialect) *Outbox {
+	return &Outbox{db: db, dialect: dialect}
+}
+
+// bind rewrites q's placeholders for o's dialect.
+func bind(dialect Dialect, q string) string {
+	if dialect == DialectPostgres {
+		return dollarPlaceholders(q)
+	}
+	return q
+}
+
+// Add writes a task to the outbox with tx and returns its ID, which the
+// enqueued task will carry. An empty queue uses the relaying Client's. The
+// payload is stored as JSON and re-encoded by a relaying Client whose codec
+// is not JSON.
+func (o *Outbox) Add(ctx context.Context, tx Execer, taskType string, payload any, queue string) (string, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	id := randomID()
+	q := `INSERT INTO asyncx_outbox (id, type, queue, payload_json, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, bind(o.dialect, q), id, taskType, optional(queue), string(b), time.Now().UTC())
+	return id, err
+}
+
+// randomID returns 32 random hex digits.
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type OutboxRelayConfig struct {
+	// Interval between relay passes. Defaults to one second.
+	Interval time.Duration
+	// BatchSize is the most messages relayed per pass. Defaults to 100.
+	BatchSize int
+	// OnError, if set, is called with the error of every failed pass of Run.
+	OnError func(error)
+}
+
+// OutboxRelay enqueues committed outbox messages with a Client. Each task
+// gets the message ID as its asynq task ID and "outbox:<id>" as its dedup
+// key, so a message relayed twice, after a crash or by two relays, is
+// enqueued once.
+type OutboxRelay struct {
+	outbox *Outbox
+	client *Client
+	cfg    OutboxRelayConfig
+}
+
+func NewOutboxRelay(outbox *Outbox, client *Client, cfg OutboxRelayConfig) *OutboxRelay {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &OutboxRelay{outbox: outbox, client: client, cfg: cfg}
+}
+
+// Run relays every Interval until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RunOnce(ctx); err != nil && ctx.Err() == nil && r.cfg.OnError != nil {
+			r.cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce relays up to BatchSize pending messages, oldest first, and
+// returns how many were relayed.
+func (r *OutboxRelay) RunOnce(ctx context.Context) (int, error) {
+	o := r.outbox
+	q := `SELECT id, type, queue, payload_json FROM asyncx_outbox WHERE relayed_at IS NULL ORDER BY created_at LIMIT ?`
+	rows, err := o.db.QueryContext(ctx, bind(o.dialect, q), r.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	type message struct {
+		id, taskType, payload string
+		queue                 sql.NullString
+	}
+	var msgs []message
+	for rows.Next() {
+		var m message
+		if err := rows.Scan(&m.id, &m.taskType, &m.queue, &m.payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		msgs = append(msgs, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	done := `UPDATE asyncx_outbox SET relayed_at = ? WHERE id = ?`
+	for i, m := range msgs {
+		opts := []asynq.Option{asynq.TaskID(m.id), WithDedupKey("outbox:" + m.id)}
+		if m.queue.Valid {
+			opts = append(opts, asynq.Queue(m.queue.String))
+		}
+		payload, err := jsonPayload(r.client.codec, []byte(m.payload))
+		if err == nil {
+			_, err = r.client.Enqueue(ctx, m.taskType, payload, opts...)
+		}
+		if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) && !errors.Is(err, ErrDuplicateTask) {
+			return i, err
+		}
+		if _, err := o.db.ExecContext(ctx, bind(o.dialect, done), time.Now().UTC(), m.id); err != nil {
+			return i, err
+		}
+	}
+	return len(msgs), nil
+}
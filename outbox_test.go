@@ -0,0 +1,162 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestOutboxRelay_EnqueuesCommittedMessagesOnce(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_outbox_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+	outbox := NewOutbox(db, DialectSQLite)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	id, err := outbox.Add(ctx, tx, "email:welcome", map[string]string{"email": "a@example.com"}, "")
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := outbox.Add(ctx, tx, "email:welcome", map[string]string{"email": "b@example.com"}, ""); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if _, err := outbox.Add(ctx, db, "email:welcome", map[string]string{"email": "a@example.com"}, "mail"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	relay := NewOutboxRelay(outbox, client, OutboxRelayConfig{})
+	if n, err := relay.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RunOnce: relayed %d, %v; want 1 (rolled-back messages must not relay)", n, err)
+	}
+	if _, err := store.GetByID(ctx, id); err == nil {
+		t.Fatalf("rolled-back message %s was enqueued", id)
+	}
+
+	// As if the relay crashed before marking the message relayed.
+	if _, err := db.ExecContext(ctx, `UPDATE asyncx_outbox SET relayed_at = NULL`); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if n, err := relay.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("second RunOnce: relayed %d, %v", n, err)
+	}
+	recs, err := store.List(ctx, TaskFilter{Type: "email:welcome"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(recs) != 1 || recs[0].Queue != "mail" {
+		t.Fatalf("want one task on queue mail, got %d", len(recs))
+	}
+	if n, _ := relay.RunOnce(ctx); n != 0 {
+		t.Fatalf("relayed %d already relayed messages", n)
+	}
+}
+
+func TestOutboxRelay_RunReportsErrors(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, NewSQLStore(db), ClientOptions{})
+	defer client.Close()
+
+	// openTestDB has no asyncx_outbox table, so every pass fails.
+	errs := make(chan error, 1)
+	relay := NewOutboxRelay(NewOutbox(db, DialectSQLite), client, OutboxRelayConfig{
+		Interval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = relay.Run(ctx) }()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("OnError called with nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not report the failed pass")
+	}
+}
+
+func TestIdempotencyKeys_Do(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_idempotency_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	keys := NewIdempotencyKeys(db, DialectSQLite)
+
+	runs := 0
+	for i := 0; i < 3; i++ {
+		if err := keys.Do(ctx, "charge:42", "task-1", func(ctx context.Context) error { runs++; return nil }); err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+	}
+	if runs != 1 {
+		t.Fatalf("fn ran %d times, want 1", runs)
+	}
+	if err := keys.Record(ctx, db, "charge:42", "task-2"); err == nil {
+		t.Fatalf("recording a processed key again should fail")
+	}
+
+	// A duplicate arriving while the first task runs does not run fn.
+	started, finish := make(chan struct{}), make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- keys.Do(ctx, "charge:43", "task-3", func(ctx context.Context) error {
+			close(started)
+			<-finish
+			return nil
+		})
+	}()
+	<-started
+	if err := keys.Do(ctx, "charge:43", "task-4", func(ctx context.Context) error { runs++; return nil }); !errors.Is(err, ErrKeyInProgress) {
+		t.Fatalf("duplicate while in progress: %v", err)
+	}
+	close(finish)
+	if err := <-done; err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if seen, _ := keys.Seen(ctx, "charge:43"); !seen {
+		t.Fatal("key not marked processed")
+	}
+
+	// A failed fn releases the key for a retry.
+	fail := errors.New("boom")
+	if err := keys.Do(ctx, "charge:44", "task-5", func(ctx context.Context) error { return fail }); !errors.Is(err, fail) {
+		t.Fatalf("Do: %v", err)
+	}
+	if err := keys.Do(ctx, "charge:44", "task-6", func(ctx context.Context) error { runs++; return nil }); err != nil || runs != 2 {
+		t.Fatalf("retry after failure: %v (runs %d)", err, runs)
+	}
+}
@@ -0,0 +1,28 @@
+package asyncx
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/hibiken/asynq"
+)
+
+// PanicError is the error recorded when a handler panics. The Processor
+// stores Stack in error_details; the task is then retried like any failure.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string { return fmt.Sprintf("panic: %v", e.Value) }
+
+// invoke runs h, converting a panic into a *PanicError.
+func invoke(ctx context.Context, h asynq.Handler, t *asynq.Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return h.ProcessTask(ctx, t)
+}
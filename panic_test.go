@@ -0,0 +1,51 @@
+package asyncx
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_RecoversPanics(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("pn:crash", func(ctx context.Context, tsk *asynq.Task) error {
+		var m map[string]int
+		m["boom"]++ // nil map write
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	info, err := client.Enqueue(ctx, "pn:crash", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(ctx, info.ID)
+		return err == nil && rec.ErrorDetails != nil, nil
+	}); err != nil {
+		t.Fatalf("panic was not recorded: %v", err)
+	}
+	rec, _ := store.GetByID(ctx, info.ID)
+	if rec.Status != StatusFailed || rec.ErrorMsg == nil || !strings.HasPrefix(*rec.ErrorMsg, "panic: ") {
+		t.Fatalf("unexpected record: %#v", rec)
+	}
+	if !strings.Contains(*rec.ErrorDetails, "panic_test.go") {
+		t.Fatalf("error_details should hold the stack trace, got %q", *rec.ErrorDetails)
+	}
+}
@@ -0,0 +1,55 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// ErrNotParked is returned by Client.Release for a task that is not parked.
+var ErrNotParked = errors.New("asyncx: task is not parked")
+
+// EnqueueParked records a task in StatusParked without putting it on Redis;
+// Release pushes it later, e.g. once an approval or webhook arrives. The
+// task keeps its type, payload and queue; other asynq options are not
+// recorded, so the type's TaskDefaults apply on release. It returns the task
+// ID, from ClientOptions.IDGenerator or a random UUID. Requires a store.
+func (c *Client) EnqueueParked(ctx context.Context, taskType string, payload any, options ...asynq.Option) (string, error) {
+	if c.store == nil {
+		return "", errors.New("asyncx: EnqueueParked requires a store")
+	}
+	gen := c.ids
+	if gen == nil {
+		gen = uuid.NewString
+	}
+	_, _, rec, err := c.prepare(ctx, taskType, payload, withTaskID(gen, options)...)
+	if err != nil {
+		return "", err
+	}
+	rec.Status, rec.EnqueuedAt = StatusParked, time.Time{}
+	if err := c.store.InsertCreated(ctx, *rec); err != nil {
+		return "", err
+	}
+	return rec.ID, nil
+}
+
+// Release puts a task parked with EnqueueParked on Redis. It returns
+// ErrNotParked for any other task. If Redis fails, the record is left in
+// StatusEnqueueFailed for a Reenqueuer.
+func (c *Client) Release(ctx context.Context, taskID string) error {
+	if c.store == nil {
+		return errors.New("asyncx: Release requires a store")
+	}
+	rec, err := c.store.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if rec.Status != StatusParked {
+		return fmt.Errorf("%w: %s is %s", ErrNotParked, taskID, rec.Status)
+	}
+	return c.reenqueue(ctx, rec)
+}
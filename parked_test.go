@@ -0,0 +1,49 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_EnqueueParkedAndRelease(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	id, err := client.EnqueueParked(ctx, "payout:send", map[string]int{"cents": 500}, asynq.Queue("payouts"))
+	if err != nil {
+		t.Fatalf("EnqueueParked: %v", err)
+	}
+	rec, err := store.GetByID(ctx, id)
+	if err != nil || rec.Status != StatusParked || rec.Queue != "payouts" {
+		t.Fatalf("record: %+v %v", rec, err)
+	}
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+	if _, err := inspector.GetTaskInfo("payouts", id); err == nil {
+		t.Fatal("parked task is on Redis")
+	}
+
+	if err := client.Release(ctx, id); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	info, err := inspector.GetTaskInfo("payouts", id)
+	if err != nil || string(info.Payload) != `{"cents":500}` {
+		t.Fatalf("released task: %+v %v", info, err)
+	}
+	if rec, _ := store.GetByID(ctx, id); rec.Status != StatusCreated || rec.EnqueuedAt.IsZero() {
+		t.Fatalf("released record: %+v", rec)
+	}
+	if err := client.Release(ctx, id); !errors.Is(err, ErrNotParked) {
+		t.Fatalf("second Release: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package asyncx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+// PayloadTooLargeError is returned by Enqueue for a payload over
+// ClientOptions.MaxPayloadSize.
+type PayloadTooLargeError struct {
+	Type  string
+	Size  int
+	Limit int
+}
+
+func (e *PayloadTooLargeError) Error() string {
+	return fmt.Sprintf("asyncx: %s payload is %d bytes, exceeds MaxPayloadSize=%d", e.Type, e.Size, e.Limit)
+}
+
+// payloadRef prefixes the stand-in put on Redis for an offloaded payload.
+// What follows names where the payload is kept.
+var payloadRef = []byte("asyncx:payload-ref:")
+
+// recordRef is the stand-in for a payload kept only in the task record.
+var recordRef = append(bytes.Clone(payloadRef), "record"...)
+
+func isPayloadRef(payload []byte) bool {
+	return bytes.HasPrefix(payload, payloadRef)
+}
+
+// placePayload returns what to put on Redis and in the record for payload
+// b: b itself, a reference to the record when it exceeds MaxPayloadSize and
+// offloading is on, or a reference to both after moving it to the
+// BlobStore. A reference, as read back from a record, is returned as is.
+func (c *Client) placePayload(ctx context.Context, taskType string, b []byte) (onRedis, onRecord []byte, err error) {
+	if isPayloadRef(b) {
+		return b, b, nil
+	}
+	if c.blobs != nil && len(b) > c.blobThreshold {
+		key := "payloads/" + randomID()
+		if err := c.blobs.PutBlob(ctx, key, b); err != nil {
+			return nil, nil, err
+		}
+		b = blobRef(key)
+		return b, b, nil
+	}
+	if c.maxPayload <= 0 || len(b) <= c.maxPayload {
+		return b, b, nil
+	}
+	if !c.offload {
+		return nil, nil, &PayloadTooLargeError{Type: taskType, Size: len(b), Limit: c.maxPayload}
+	}
+	return recordRef, b, nil
+}
+
+// resolvePayload returns t with an offloaded payload loaded from rec or
+// blobs, or t unchanged. rec is nil when the record could not be read.
+func resolvePayload(ctx context.Context, t *asynq.Task, rec *TaskRecord, blobs BlobStore) (*asynq.Task, error) {
+	if !isPayloadRef(t.Payload()) {
+		return t, nil
+	}
+	if key, ok := blobKey(t.Payload()); ok {
+		if blobs == nil {
+			return nil, NonRetryable(errors.New("asyncx: payload is in a BlobStore but ProcessorConfig.Blobs is not set"))
+		}
+		b, err := blobs.GetBlob(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		return asynq.NewTask(t.Type(), b), nil
+	}
+	if !bytes.Equal(t.Payload(), recordRef) {
+		return nil, NonRetryable(fmt.Errorf("asyncx: unknown payload reference %q", t.Payload()))
+	}
+	if rec == nil {
+		// The Client inserts the record right after enqueueing; retry.
+		return nil, errors.New("asyncx: record of offloaded payload not found")
+	}
+	if rec.PayloadPurgedAt != nil {
+		return nil, NonRetryable(ErrPayloadPurged)
+	}
+	b, err := PayloadBytes(rec)
+	if err != nil {
+		return nil, NonRetryable(err)
+	}
+	return asynq.NewTask(t.Type(), b), nil
+}
+
+// loadPayload resolves an offloaded payload for the Processor, reading the
+// record itself when the lifecycle did not.
+func (p *Processor) loadPayload(ctx context.Context, t *asynq.Task, rec *TaskRecord) (*asynq.Task, error) {
+	if rec == nil && p.store != nil && bytes.Equal(t.Payload(), recordRef) {
+		if id, ok := getTaskID(ctx); ok {
+			rec, _ = p.store.GetByID(ctx, id)
+		}
+	}
+	return resolvePayload(ctx, t, rec, p.blobs)
+}
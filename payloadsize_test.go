@@ -0,0 +1,97 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_MaxPayloadSize(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, nil, ClientOptions{MaxPayloadSize: 16})
+	defer client.Close()
+
+	_, err := client.Enqueue(context.Background(), "report:build", strings.Repeat("x", 32))
+	var tooLarge *PayloadTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Size != 34 || tooLarge.Limit != 16 {
+		t.Fatalf("want PayloadTooLargeError, got %v", err)
+	}
+	if _, err := client.Enqueue(context.Background(), "report:build", "small"); err != nil {
+		t.Fatalf("Enqueue under the limit: %v", err)
+	}
+}
+
+func TestClient_OffloadPayloads(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	client := NewClient(redis, store, ClientOptions{MaxPayloadSize: 16, OffloadPayloads: true})
+	defer client.Close()
+
+	big := strings.Repeat("x", 1024)
+	info, err := client.Enqueue(ctx, "report:build", big)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+	queued, err := inspector.GetTaskInfo(info.Queue, info.ID)
+	if err != nil || !isPayloadRef(queued.Payload) {
+		t.Fatalf("Redis should hold a reference: %q %v", queued.Payload, err)
+	}
+
+	got := make(chan string, 1)
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	defer processor.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error {
+		var s string
+		if err := Decode(ctx, tsk, &s); err != nil {
+			return err
+		}
+		got <- s
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	select {
+	case s := <-got:
+		if s != big {
+			t.Fatalf("handler got %d bytes", len(s))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not run")
+	}
+}
+
+func TestClient_OffloadPayloadsFailsWithoutRecord(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	// No schema, so every insert fails.
+	db, err := sql.Open("sqlite", "file:asyncx_offload_test?mode=memory")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, NewSQLStore(db), ClientOptions{MaxPayloadSize: 16, OffloadPayloads: true})
+	defer client.Close()
+
+	if _, err := client.Enqueue(context.Background(), "report:build", strings.Repeat("x", 1024)); err == nil {
+		t.Fatal("Enqueue should fail when the record holding the payload cannot be inserted")
+	}
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+	if pending, _ := inspector.ListPendingTasks(DefaultQueue); len(pending) != 0 {
+		t.Fatalf("task without its payload left on Redis: %+v", pending)
+	}
+}
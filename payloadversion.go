@@ -0,0 +1,86 @@
+package asyncx
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+)
+
+type payloadVersionOption int
+
+// WithPayloadVersion records the payload schema version of a single
+// Enqueue call, overriding ClientOptions.PayloadVersions.
+func WithPayloadVersion(v int) asynq.Option { return payloadVersionOption(v) }
+
+func (o payloadVersionOption) String() string         { return fmt.Sprintf("PayloadVersion(%d)", int(o)) }
+func (o payloadVersionOption) Type() asynq.OptionType { return asyncxOpt }
+func (o payloadVersionOption) Value() interface{}     { return int(o) }
+
+// UpgradeFunc rewrites a payload of one version into the next.
+type UpgradeFunc func(payload []byte) ([]byte, error)
+
+// PayloadUpgrades holds, per task type, functions that each upgrade a
+// payload by one version. A Processor configured with them upgrades every
+// task to the latest version before its handler runs, so handlers only
+// deal with the current shape while older payloads are still queued.
+type PayloadUpgrades struct {
+	steps map[string]map[int]UpgradeFunc
+}
+
+func NewPayloadUpgrades() *PayloadUpgrades {
+	return &PayloadUpgrades{steps: map[string]map[int]UpgradeFunc{}}
+}
+
+// Register adds fn, which upgrades taskType payloads from version from to
+// from+1. Register every step before the Processor starts.
+func (u *PayloadUpgrades) Register(taskType string, from int, fn UpgradeFunc) *PayloadUpgrades {
+	if u.steps[taskType] == nil {
+		u.steps[taskType] = map[int]UpgradeFunc{}
+	}
+	u.steps[taskType][from] = fn
+	return u
+}
+
+// Latest returns the version taskType payloads are upgraded to, one past
+// its highest registered step, or 0 if it has none.
+func (u *PayloadUpgrades) Latest(taskType string) int {
+	latest := 0
+	for from := range u.steps[taskType] {
+		latest = max(latest, from+1)
+	}
+	return latest
+}
+
+// Upgrade runs the steps from version up to Latest(taskType). Version 0,
+// an unversioned payload, counts as version 1; payloads at or past the
+// latest version are returned unchanged.
+func (u *PayloadUpgrades) Upgrade(taskType string, version int, payload []byte) ([]byte, error) {
+	latest := u.Latest(taskType)
+	for v := max(version, 1); v < latest; v++ {
+		fn, ok := u.steps[taskType][v]
+		if !ok {
+			return nil, fmt.Errorf("asyncx: no upgrade registered for %s payload version %d", taskType, v)
+		}
+		var err error
+		if payload, err = fn(payload); err != nil {
+			return nil, fmt.Errorf("asyncx: upgrading %s payload from version %d: %w", taskType, v, err)
+		}
+	}
+	return payload, nil
+}
+
+// handler wraps next so that it receives t upgraded from version. An
+// upgraded task is a copy without t's ResultWriter; use SetResult instead.
+func (u *PayloadUpgrades) handler(version int, next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if max(version, 1) >= u.Latest(t.Type()) {
+			return next.ProcessTask(ctx, t)
+		}
+		b, err := u.Upgrade(t.Type(), version, t.Payload())
+		if err != nil {
+			return err
+		}
+		return next.ProcessTask(ctx, asynq.NewTask(t.Type(), b))
+	})
+}
@@ -0,0 +1,88 @@
+package asyncx
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// emailUpgrades renames "to" to "recipient" in v2 and adds "locale" in v3.
+func emailUpgrades() *PayloadUpgrades {
+	return NewPayloadUpgrades().
+		Register("email:deliver", 1, func(b []byte) ([]byte, error) {
+			return bytes.Replace(b, []byte(`"to"`), []byte(`"recipient"`), 1), nil
+		}).
+		Register("email:deliver", 2, func(b []byte) ([]byte, error) {
+			return bytes.Replace(b, []byte(`}`), []byte(`,"locale":"en"}`), 1), nil
+		})
+}
+
+func TestPayloadUpgrades_Upgrade(t *testing.T) {
+	u := emailUpgrades()
+	if u.Latest("email:deliver") != 3 || u.Latest("other") != 0 {
+		t.Fatalf("Latest = %d, %d", u.Latest("email:deliver"), u.Latest("other"))
+	}
+	for version, want := range map[int]string{
+		0: `{"recipient":"a@b.c","locale":"en"}`,
+		2: `{"to":"a@b.c","locale":"en"}`,
+		3: `{"to":"a@b.c"}`,
+	} {
+		b, err := u.Upgrade("email:deliver", version, []byte(`{"to":"a@b.c"}`))
+		if err != nil || string(b) != want {
+			t.Errorf("from v%d: %s %v, want %s", version, b, err, want)
+		}
+	}
+	gap := NewPayloadUpgrades().Register("report", 2, func(b []byte) ([]byte, error) { return b, nil })
+	if _, err := gap.Upgrade("report", 1, nil); err == nil {
+		t.Fatal("Upgrade should fail on a missing step")
+	}
+}
+
+func TestProcessor_UpgradesQueuedPayloads(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	// An old deploy enqueues v1 payloads.
+	client := NewClient(redis, store, ClientOptions{PayloadVersions: map[string]int{"email:deliver": 1}})
+	defer client.Close()
+	if _, err := client.Enqueue(ctx, "email:deliver", map[string]string{"to": "a@b.c"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	info, err := client.Enqueue(ctx, "email:deliver", map[string]string{"recipient": "d@e.f", "locale": "fr"}, WithPayloadVersion(3))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if rec, _ := store.GetByID(ctx, info.ID); rec == nil || rec.PayloadVersion != 3 {
+		t.Fatalf("record: %+v", rec)
+	}
+
+	got := make(chan string, 2)
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, PayloadUpgrades: emailUpgrades()})
+	defer processor.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("email:deliver", func(ctx context.Context, tsk *asynq.Task) error {
+		got <- string(tsk.Payload())
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	seen := map[string]bool{}
+	for range 2 {
+		select {
+		case p := <-got:
+			seen[p] = true
+		case <-time.After(10 * time.Second):
+			t.Fatal("tasks did not run")
+		}
+	}
+	if !seen[`{"recipient":"a@b.c","locale":"en"}`] || !seen[`{"locale":"fr","recipient":"d@e.f"}`] {
+		t.Fatalf("handler saw %v", seen)
+	}
+}
@@ -0,0 +1,70 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStoreUnavailable is returned, wrapping the last ping error, when the
+// store did not answer the startup check.
+var ErrStoreUnavailable = errors.New("asyncx: store unavailable")
+
+// Pinger is implemented by stores that can check that their backend is
+// reachable, as SQLStore does. A Client pings it before its first enqueue
+// and a Processor when it starts; see PingPolicy.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// PingPolicy controls how a Client, before its first enqueue, and
+// Processor.Start and Run check the store.
+type PingPolicy struct {
+	// Disabled skips the check.
+	Disabled bool
+	// Attempts is how many pings are tried. Defaults to 5.
+	Attempts int
+	// Backoff is the wait after the first failed ping, doubled after each
+	// further one up to MaxBackoff. Defaults to 200ms and 5s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// PingStore pings store until it answers or policy's attempts run out, and
+// then returns an error wrapping ErrStoreUnavailable. A nil store, or one
+// that is not a Pinger, passes.
+func PingStore(ctx context.Context, store Store, policy PingPolicy) error {
+	if store == nil || policy.Disabled {
+		return nil
+	}
+	pinger, ok := storeAs[Pinger](store)
+	if !ok {
+		return nil
+	}
+	if policy.Attempts <= 0 {
+		policy.Attempts = 5
+	}
+	if policy.Backoff <= 0 {
+		policy.Backoff = 200 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 5 * time.Second
+	}
+	wait := policy.Backoff
+	var err error
+	for i := 0; i < policy.Attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w: %v", ErrStoreUnavailable, ctx.Err())
+			case <-time.After(wait):
+			}
+			wait = min(2*wait, policy.MaxBackoff)
+		}
+		if err = pinger.Ping(ctx); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w after %d attempts: %v", ErrStoreUnavailable, policy.Attempts, err)
+}
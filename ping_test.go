@@ -0,0 +1,44 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestPingStore_SurfacesUnreachableStore(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_ping_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	db.Close()
+	store := NewSQLStore(db)
+	policy := PingPolicy{Attempts: 3, Backoff: time.Millisecond}
+	ctx := context.Background()
+
+	if err := PingStore(ctx, store, policy); !errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("PingStore: got %v, want ErrStoreUnavailable", err)
+	}
+	if err := PingStore(ctx, store, PingPolicy{Disabled: true}); err != nil {
+		t.Fatalf("disabled PingStore: %v", err)
+	}
+
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{StorePing: policy})
+	defer processor.Shutdown()
+	if err := processor.Run(ctx, nil); !errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("Run: got %v, want ErrStoreUnavailable", err)
+	}
+
+	client := NewClient(redis, store, ClientOptions{StorePing: policy})
+	defer client.Close()
+	if _, err := client.Enqueue(ctx, "email:send", nil); !errors.Is(err, ErrStoreUnavailable) {
+		t.Fatalf("Enqueue: got %v, want ErrStoreUnavailable", err)
+	}
+}
@@ -0,0 +1,39 @@
+package asyncx
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+// Priority is a named priority tier. Each tier is served from the queue of
+// the same name.
+type Priority string
+
+const (
+	PriorityCritical Priority = "critical"
+	PriorityDefault  Priority = "default"
+	PriorityLow      Priority = "low"
+)
+
+// DefaultPriorities weighs the tiers 6:3:1, for ProcessorConfig.Priorities.
+var DefaultPriorities = map[Priority]int{PriorityCritical: 6, PriorityDefault: 3, PriorityLow: 1}
+
+// priorityOf returns the tier served by queue, or "" if queue is not a tier.
+func priorityOf(queue string) Priority {
+	switch p := Priority(queue); p {
+	case PriorityCritical, PriorityDefault, PriorityLow:
+		return p
+	}
+	return ""
+}
+
+// EnqueueCritical enqueues on the critical tier, overriding any queue option.
+func (c *Client) EnqueueCritical(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
+	return c.Enqueue(ctx, taskType, payload, append(options, asynq.Queue(string(PriorityCritical)))...)
+}
+
+// EnqueueLow enqueues on the low tier, overriding any queue option.
+func (c *Client) EnqueueLow(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
+	return c.Enqueue(ctx, taskType, payload, append(options, asynq.Queue(string(PriorityLow)))...)
+}
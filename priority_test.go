@@ -0,0 +1,47 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_PriorityTiers(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	cases := []struct {
+		enqueue func() (*asynq.TaskInfo, error)
+		want    Priority
+	}{
+		{func() (*asynq.TaskInfo, error) {
+			return client.EnqueueCritical(ctx, "p:task", nil, asynq.Queue("reports"))
+		}, PriorityCritical},
+		{func() (*asynq.TaskInfo, error) { return client.EnqueueLow(ctx, "p:task", nil) }, PriorityLow},
+		{func() (*asynq.TaskInfo, error) { return client.Enqueue(ctx, "p:task", nil) }, PriorityDefault},
+		{func() (*asynq.TaskInfo, error) { return client.Enqueue(ctx, "p:task", nil, asynq.Queue("reports")) }, ""},
+	}
+	for _, c := range cases {
+		info, err := c.enqueue()
+		if err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		if c.want != "" && info.Queue != string(c.want) {
+			t.Fatalf("want queue %s, got %s", c.want, info.Queue)
+		}
+		rec, err := store.GetByID(ctx, info.ID)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if rec.Priority != c.want {
+			t.Fatalf("queue %s: want priority %q, got %q", info.Queue, c.want, rec.Priority)
+		}
+	}
+}
@@ -2,50 +2,551 @@ package asyncx
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
 )
 
 // Processor manages background workers and updates Store on lifecycle events.
 type Processor struct {
-	server *asynq.Server
-	store  Store
+	server    *asynq.Server
+	isolated  []isolatedServer // one per queue with QueueLimit.MaxConcurrency
+	inspector *asynq.Inspector
+	store     Store
+	limiter   *RateLimiter
+	breaker   *CircuitBreaker
+	deps      *dependencyGate
+	tenants   *tenantGate
+	shared    sharedServer // guarded by reload
+	reload    sync.Mutex   // serializes Reload and Shutdown
+	classes   map[string]TaskClass
+	acks      map[string]ConfirmFunc
+	beat      time.Duration
+	hooks     Hooks
+	limits    JSONLimits
+	retries   map[string]RetryPolicy
+	tracing   *TracingConfig
+	redact    *RedactionPolicy
+	dryRun    *DryRunConfig
+	timeouts  map[string]time.Duration
+	downtime  map[string][]DowntimeWindow
+	budgets   map[string]time.Duration
+	grace     time.Duration
+	ping      PingPolicy
+	mw        []MiddlewareFunc
+	typeMW    map[string][]MiddlewareFunc
+	mux       *asynq.ServeMux // built by Handle
+	defaults  map[string]TaskDefaults
+	types     *TaskTypeRegistry
+	notFound  asynq.Handler
+	routes    *asynq.ServeMux       // the mux being served
+	rdb       redis.UniversalClient // set with PublishResults
+
+	handleOnly    []string
+	exclude       []string
+	queues        []string
+	worker        workerIdentity
+	codec         Codec
+	upgrades      *PayloadUpgrades
+	blobs         BlobStore
+	blobThreshold int
+	redisOpt      asynq.RedisConnOpt
+	conn          redis.UniversalClient // see redis
+	connOnce      sync.Once
+	listed        bool   // recorded in the worker registry
+	unregister    func() // guarded by mu; set while registered
+	stopped       bool   // guarded by mu
+	rescheduling  sync.WaitGroup
+
+	mu       sync.Mutex
+	serving  asynq.Handler       // set by start, for Reload
+	inflight map[string]struct{} // IDs of tasks currently running
+	draining atomic.Bool
+	running  atomic.Bool // workers started and not shut down
 }
 
 type ProcessorConfig struct {
 	Concurrency int
 	Queues      map[string]int
+	// Priorities, used when Queues is nil, serves the named priority tiers
+	// with the given weights, e.g. DefaultPriorities.
+	Priorities map[Priority]int
+	// StrictPriority drains higher-weighted queues completely before serving
+	// lower ones, instead of sampling them by weight.
+	StrictPriority bool
+	// QueueLimits adds queues with a weight and an optional concurrency cap.
+	// A queue with MaxConcurrency set runs on its own asynq server with that
+	// many workers, so it can neither exceed the cap nor starve the other
+	// queues, which share Concurrency workers.
+	QueueLimits map[string]QueueLimit
+	// Registry, if set, supplies default weights when Queues is nil and
+	// NewProcessor panics if Queues names an unregistered queue.
+	Registry *QueueRegistry
+	// TaskTypes, if set, is told which registered task types the Processor
+	// has handlers for when it starts.
+	TaskTypes *TaskTypeRegistry
+	// RateLimiter, if set, is consulted before a task starts. Throttled tasks
+	// are recorded as StatusThrottled and retried once a token is available.
+	RateLimiter *RateLimiter
+	// CircuitBreaker, if set, is consulted before a task starts and told
+	// how its handler did. Tasks of a type whose breaker is open are
+	// recorded as StatusDeferred and retried after the cool-down.
+	CircuitBreaker *CircuitBreaker
+	// Dependencies are external systems with health checks, named by
+	// DependsOn. Tasks of a type whose dependency is unhealthy are
+	// recorded as StatusDeferred and retried after the dependency's
+	// Interval, without using up their retries. NewProcessor panics if
+	// DependsOn names an unknown dependency.
+	Dependencies []Dependency
+	DependsOn    map[string][]string
+	// TenantFairness, if set, caps the share of Concurrency each tenant's
+	// tasks may take.
+	TenantFairness *TenantFairness
+	// Classes mirrors ClientOptions.Classes. Fire-and-forget tasks skip the
+	// in_progress write and only record their terminal state.
+	Classes map[string]TaskClass
+	// RequireAck lists task types whose completion must be confirmed. After the
+	// handler returns nil the record moves to StatusAwaitingAck; a non-nil
+	// ConfirmFunc is then run and marks the task completed on success. With a
+	// nil ConfirmFunc, or when confirmation fails, the record waits for Ack and
+	// is flagged StatusNeedsReview by the Janitor after JanitorConfig.AckTimeout.
+	RequireAck map[string]ConfirmFunc
+	// HeartbeatInterval, if positive, makes the processor call Store.Heartbeat
+	// at this interval while a handler runs.
+	HeartbeatInterval time.Duration
+	// Hooks, if set, is notified on start, completion, retry and final failure.
+	Hooks Hooks
+	// GracePeriod is how long shutdown waits for in-flight handlers before
+	// aborting them. Defaults to asynq's 8 seconds.
+	GracePeriod time.Duration
+	// PublishResults announces each task that completes or fails for good on
+	// Redis pub/sub, waking Client.WaitForResult without waiting for its poll.
+	PublishResults bool
+	// ResultLimits bounds results recorded with SetResult.
+	ResultLimits JSONLimits
+	// RetryPolicies overrides retry limits, backoff and error classification
+	// per task type. The time of the next retry is recorded in next_retry_at
+	// for every type.
+	RetryPolicies map[string]RetryPolicy
+	// Tracing, if set, records a span per task with sampling that always keeps
+	// failures.
+	Tracing *TracingConfig
+	// Redaction, if set, scrubs payloads passed to Hooks and span tags.
+	Redaction *RedactionPolicy
+	// DryRun, if set, consumes tasks and runs all middleware but skips the
+	// handlers, for rehearsals against production-shaped queues.
+	DryRun *DryRunConfig
+	// Timeouts bounds handler run time per task type. A handler that overruns
+	// sees its context cancelled. The overrun is recorded as a retry, or, on
+	// the last attempt, as StatusTimedOut with the timeout saved in
+	// timeout_ms.
+	Timeouts map[string]time.Duration
+	// Downtime lists maintenance windows per task type. Tasks arriving in a
+	// window are recorded as StatusDeferred and retried when it ends, without
+	// using up their retries.
+	Downtime map[string][]DowntimeWindow
+	// RuntimeBudgets caps the handler run time of a task type summed over all
+	// attempts (tracked in runtime_ms). A failed attempt that reaches the
+	// budget is not retried and is recorded with failure_kind budget_exhausted.
+	RuntimeBudgets map[string]time.Duration
+	// HandleOnly, if set, limits this processor to the listed task types, so
+	// that replicas of one binary can be dedicated to heavy types. An entry
+	// ending in "*" matches by prefix. Start and Run fail unless every other
+	// entry has a handler registered.
+	//
+	// asynq hands out tasks by queue, not type, so route the dedicated types
+	// to queues of their own (with a Router or TaskDefaults.Queue) and serve
+	// those queues only from the dedicated replicas. HandleOnly and Exclude
+	// then guard against misrouted tasks: one this processor does not handle
+	// is put back after a second without counting as a failure, and is
+	// rescheduled rather than archived if it has no retries left.
+	HandleOnly []string
+	// Exclude lists task types, or "*"-suffixed prefixes, this processor does
+	// not run; see HandleOnly.
+	Exclude []string
+	// WorkerRegistry records this processor in the worker registry listed
+	// by Workers while it runs. Processors with HandleOnly or Exclude are
+	// always recorded, with their routing.
+	WorkerRegistry bool
+	// NotFoundHandler, if set, receives tasks whose type has no handler on
+	// the mux, after their record is marked StatusUnroutable, instead of
+	// asynq failing and retrying them until they are archived. Its error
+	// decides as usual whether asynq retries the task; nil drops it. Use it
+	// to forward such tasks to another queue or a dead-letter store.
+	NotFoundHandler asynq.Handler
+	// StorePing configures the store check Start and Run perform before
+	// starting workers; they fail with ErrStoreUnavailable if it does not pass.
+	StorePing PingPolicy
+	// QueueRenames makes the processor also consume each rename's old queue,
+	// with the weight of the new one, until the old queue is drained; see
+	// QueueRename.
+	QueueRenames []QueueRename
+	// Codec decodes payloads for Decode. It must match the Client's.
+	// Defaults to JSONCodec.
+	Codec Codec
+	// PayloadUpgrades, if set, upgrades payloads enqueued with an older
+	// TaskRecord.PayloadVersion before the handler runs. Tasks without a
+	// record are passed on unchanged.
+	PayloadUpgrades *PayloadUpgrades
+	// Blobs resolves payloads a Client put in a BlobStore, and receives
+	// results over BlobThreshold bytes; result_json then holds
+	// {"$blob": key}, which Client.WaitForResult resolves. BlobThreshold
+	// must be positive when Blobs is set.
+	Blobs         BlobStore
+	BlobThreshold int
 }
 
-func NewProcessor(redisOpt asynq.RedisClientOpt, store Store, cfg ProcessorConfig) *Processor {
+func NewProcessor(redisOpt asynq.RedisConnOpt, store Store, cfg ProcessorConfig) *Processor {
 	con := cfg.Concurrency
 	if con <= 0 {
 		con = 10
 	}
 	qs := cfg.Queues
-	if qs == nil {
-		qs = map[string]int{"default": 1}
+	if qs == nil && cfg.Priorities != nil {
+		qs = make(map[string]int, len(cfg.Priorities))
+		for p, w := range cfg.Priorities {
+			qs[string(p)] = w
+		}
+	}
+	if qs == nil && cfg.Registry != nil {
+		qs = cfg.Registry.Weights()
+	}
+	shared := make(map[string]int, len(qs)+len(cfg.QueueLimits))
+	for name, w := range qs {
+		shared[name] = w
+	}
+	for name, l := range cfg.QueueLimits {
+		if l.MaxConcurrency > 0 {
+			delete(shared, name)
+		} else {
+			shared[name] = max(l.Weight, 1)
+		}
+	}
+	if len(shared) == 0 {
+		shared = map[string]int{DefaultQueue: 1}
+	}
+	if cfg.Registry != nil {
+		names := []map[string]int{shared}
+		for _, qs := range isolatedQueues(cfg.QueueLimits) {
+			names = append(names, qs)
+		}
+		for _, qs := range names {
+			for name := range qs {
+				if err := cfg.Registry.Validate(name); err != nil {
+					panic(fmt.Sprintf("asyncx: NewProcessor: %v", err))
+				}
+			}
+		}
+	}
+	// Applied after validation, so old names need not stay registered.
+	queueLimits := applyRenames(cfg.QueueRenames, shared, cfg.QueueLimits)
+	newServer := func(concurrency int, queues map[string]int, strict bool) *asynq.Server {
+		return newAsynqServer(redisOpt, asynq.Config{
+			Concurrency:     concurrency,
+			Queues:          queues,
+			StrictPriority:  strict,
+			IsFailure:       isFailure,
+			RetryDelayFunc:  retryDelay,
+			ShutdownTimeout: cfg.GracePeriod,
+		})
 	}
-	server := asynq.NewServer(redisOpt, asynq.Config{Concurrency: con, Queues: qs})
-	return &Processor{server: server, store: store}
+	server := newServer(con, shared, cfg.StrictPriority)
+	var isolated []isolatedServer
+	for name, qs := range isolatedQueues(queueLimits) {
+		// Strict priority makes stealing happen only while the own queue is empty.
+		srv := newServer(queueLimits[name].MaxConcurrency, qs, cfg.StrictPriority || len(qs) > 1)
+		isolated = append(isolated, isolatedServer{queue: name, server: srv})
+	}
+	if cfg.Blobs != nil && cfg.BlobThreshold <= 0 {
+		panic("asyncx: NewProcessor: Blobs requires a positive BlobThreshold")
+	}
+	deps, err := newDependencyGate(cfg.Dependencies, cfg.DependsOn)
+	if err != nil {
+		panic(fmt.Sprintf("asyncx: NewProcessor: %v", err))
+	}
+	p := &Processor{
+		server:    server,
+		isolated:  isolated,
+		inspector: newInspector(redisOpt),
+		store:     store,
+		limiter:   cfg.RateLimiter,
+		breaker:   cfg.CircuitBreaker,
+		deps:      deps,
+		tenants:   newTenantGate(cfg.TenantFairness, con),
+		classes:   cfg.Classes,
+		acks:      cfg.RequireAck,
+		beat:      cfg.HeartbeatInterval,
+		hooks:     cfg.Hooks,
+		limits:    cfg.ResultLimits,
+		retries:   cfg.RetryPolicies,
+		tracing:   cfg.Tracing,
+		redact:    cfg.Redaction,
+		dryRun:    cfg.DryRun,
+		timeouts:  cfg.Timeouts,
+		downtime:  cfg.Downtime,
+		budgets:   cfg.RuntimeBudgets,
+		grace:     cfg.GracePeriod,
+		ping:      cfg.StorePing,
+		inflight:  make(map[string]struct{}),
+
+		handleOnly:    cfg.HandleOnly,
+		exclude:       cfg.Exclude,
+		queues:        queueNames(shared, queueLimits),
+		worker:        newWorkerIdentity(),
+		codec:         cfg.Codec,
+		upgrades:      cfg.PayloadUpgrades,
+		blobs:         cfg.Blobs,
+		blobThreshold: cfg.BlobThreshold,
+		redisOpt:      redisOpt,
+		listed:        cfg.WorkerRegistry || len(cfg.HandleOnly) > 0 || len(cfg.Exclude) > 0,
+		types:         cfg.TaskTypes,
+		notFound:      cfg.NotFoundHandler,
+	}
+	p.shared = sharedServer{
+		build:       func(con int, qs map[string]int) *asynq.Server { return newServer(con, qs, cfg.StrictPriority) },
+		concurrency: con,
+		queues:      shared,
+		limits:      queueLimits,
+		renames:     cfg.QueueRenames,
+		registry:    cfg.Registry,
+	}
+	if p.codec == nil {
+		p.codec = JSONCodec{}
+	}
+	if p.grace <= 0 {
+		p.grace = 8 * time.Second
+	}
+	if cfg.PublishResults {
+		p.rdb = makeRedis(redisOpt)
+	}
+	return p
 }
 
 // Middleware to mark started/completed/failed
 func (p *Processor) lifecycleMiddleware(next asynq.Handler) asynq.Handler {
 	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
-		if p.store != nil {
-			if id, ok := asynq.GetTaskID(ctx); ok {
-				_ = p.store.MarkStarted(ctx, id, time.Now().UTC())
+		if !p.handles(t.Type()) {
+			return p.putBack(ctx, t, &NotHandledError{Type: t.Type()})
+		}
+		if p.unroutable(t) {
+			if p.store != nil {
+				if id, ok := getTaskID(ctx); ok {
+					_ = markStatus(ctx, p.store, id, StatusUnroutable, time.Now().UTC())
+				}
+			}
+			return p.notFound.ProcessTask(ctx, t)
+		}
+		ctx = context.WithValue(ctx, workerKey{}, p.worker)
+		ctx = context.WithValue(ctx, codecKey{}, p.codec)
+		if err := downtimeCheck(p.downtime, t.Type(), time.Now()); err != nil {
+			if p.store != nil {
+				if id, ok := getTaskID(ctx); ok {
+					_ = markStatus(ctx, p.store, id, StatusDeferred, time.Now().UTC())
+				}
+			}
+			return p.putBack(ctx, t, err)
+		}
+		if p.limiter != nil {
+			if err := p.limiter.check(ctx, t); err != nil {
+				if p.store != nil {
+					if id, ok := getTaskID(ctx); ok {
+						_ = markStatus(ctx, p.store, id, StatusThrottled, time.Now().UTC())
+					}
+				}
+				return p.putBack(ctx, t, err)
+			}
+		}
+		if p.deps != nil {
+			if err := p.deps.check(ctx, t.Type()); err != nil {
+				if p.store != nil {
+					if id, ok := getTaskID(ctx); ok {
+						_ = markStatus(ctx, p.store, id, StatusDeferred, time.Now().UTC())
+					}
+				}
+				return p.putBack(ctx, t, err)
+			}
+		}
+		var trial time.Time
+		if p.breaker != nil {
+			var err error
+			if trial, err = p.breaker.allow(ctx, t.Type()); err != nil {
+				if p.store != nil {
+					if id, ok := getTaskID(ctx); ok {
+						_ = markStatus(ctx, p.store, id, StatusDeferred, time.Now().UTC())
+					}
+				}
+				return p.putBack(ctx, t, err)
+			}
+		}
+		if id, ok := getTaskID(ctx); ok {
+			p.track(id)
+			defer p.untrack(id)
+		}
+		handler := next
+		var record *TaskRecord
+		admitted := p.tenants == nil
+		if p.store != nil && classFor(p.classes, t.Type()) != ClassFireAndForget {
+			if id, ok := getTaskID(ctx); ok {
+				if rec, err := p.store.GetByID(ctx, id); err == nil {
+					record = rec
+					ok, err := guardCheck(ctx, p.store, rec)
+					if err != nil {
+						return err
+					}
+					if !ok {
+						_ = markStatus(ctx, p.store, id, StatusSuppressed, time.Now().UTC())
+						return nil
+					}
+					if len(rec.Metadata) > 0 {
+						ctx = context.WithValue(ctx, metadataKey{}, rec.Metadata)
+					}
+					if rec.WorkflowTraceparent != nil {
+						ctx = withWorkflow(ctx, *rec.WorkflowTraceparent)
+					}
+					if p.upgrades != nil {
+						handler = p.upgrades.handler(rec.PayloadVersion, next)
+					}
+				}
+				if !admitted {
+					release, err := p.tenants.acquire(ctx, t, record)
+					if err != nil {
+						_ = markStatus(ctx, p.store, id, StatusThrottled, time.Now().UTC())
+						return p.putBack(ctx, t, err)
+					}
+					defer release()
+					admitted = true
+				}
+				if err := p.store.MarkStarted(ctx, id, time.Now().UTC()); err != nil {
+					var te *TransitionError
+					if errors.As(err, &te) {
+						// A duplicate delivery of a task already finished.
+						return nil
+					}
+				}
+				if p.beat > 0 {
+					stop := p.heartbeat(ctx, id)
+					defer stop()
+				}
+			}
+		}
+		if !admitted {
+			release, err := p.tenants.acquire(ctx, t, nil)
+			if err != nil {
+				return p.putBack(ctx, t, err)
+			}
+			defer release()
+		}
+		if resolved, err := p.loadPayload(ctx, t, record); err != nil {
+			// Failed like a handler error, so that it is recorded and retried.
+			handler = asynq.HandlerFunc(func(context.Context, *asynq.Task) error { return err })
+		} else {
+			t = resolved
+		}
+		ctx = withRunningTask(ctx, t, record)
+		ev := taskEvent(ctx, t)
+		ev.Payload = p.redact.RedactJSON(ev.Payload)
+		if p.hooks != nil {
+			p.hooks.OnStarted(ctx, ev)
+		}
+		var span *taskSpan
+		if p.tracing != nil && p.tracing.Tracer != nil {
+			ctx, span = startSpan(ctx, p.tracing, p.redact, t, ev)
+		}
+		result := &resultHolder{limits: p.limits}
+		ctx = context.WithValue(ctx, resultKey{}, result)
+		if p.dryRun != nil {
+			ctx = context.WithValue(ctx, dryRunKey{}, true)
+		}
+		begin := time.Now()
+		hctx := ctx
+		timeout := p.timeouts[t.Type()]
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			hctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		handlerErr := invoke(hctx, handler, t)
+		timedOut := handlerErr != nil && timeout > 0 && errors.Is(hctx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+		err := handlerErr
+		interrupted := err != nil && ctx.Err() != nil && p.draining.Load()
+		if p.breaker != nil && !interrupted {
+			p.breaker.done(ctx, t.Type(), trial, breakerFailure(handlerErr))
+		}
+		var retrying bool
+		var after time.Duration
+		if err != nil && !interrupted && !isThrottled(err) {
+			var policy *RetryPolicy
+			if rp, ok := p.retries[t.Type()]; ok {
+				policy = &rp
+			}
+			err, after, retrying = applyRetryPolicy(policy, ev, t, err)
+		}
+		var exhausted bool
+		budget := p.budgets[t.Type()]
+		if rs, ok := storeAs[RuntimeStore](p.store); ok && budget > 0 && !interrupted {
+			if id, ok := getTaskID(ctx); ok {
+				total, aerr := rs.AddRuntime(ctx, id, time.Since(begin))
+				if aerr == nil && retrying && total >= budget {
+					exhausted, retrying = true, false
+					err = fmt.Errorf("%s: %v: %w", budgetMsg(budget), handlerErr, asynq.SkipRetry)
+				}
+			}
+		}
+		if span != nil {
+			span.end(handlerErr, retrying)
+		}
+		if p.hooks != nil && !isThrottled(err) {
+			ev.Duration, ev.At, ev.Err = time.Since(begin), time.Now().UTC(), handlerErr
+			switch {
+			case err == nil:
+				p.hooks.OnCompleted(ctx, ev)
+			case retrying:
+				p.hooks.OnRetry(ctx, ev)
+			default:
+				p.hooks.OnFailed(ctx, ev)
 			}
 		}
-		err := next.ProcessTask(ctx, t)
 		if p.store != nil {
-			if id, ok := asynq.GetTaskID(ctx); ok {
-				if err != nil {
-					_ = p.store.MarkFailed(ctx, id, err.Error(), time.Now().UTC())
-				} else {
-					_ = p.store.MarkCompleted(ctx, id, nil, time.Now().UTC())
+			if id, ok := getTaskID(ctx); ok {
+				switch {
+				case interrupted:
+					// Aborted by shutdown; asynq re-queues the task rather than failing it.
+					_ = markStatus(context.Background(), p.store, id, StatusInterrupted, time.Now().UTC())
+				case isThrottled(err):
+					_ = markStatus(ctx, p.store, id, StatusThrottled, time.Now().UTC())
+				case exhausted:
+					_ = markBudgetExhausted(ctx, p.store, id, budget, time.Now().UTC())
+				case timedOut && !retrying:
+					_ = markTimedOut(ctx, p.store, id, timeout, time.Now().UTC())
+				case retrying:
+					msg := handlerErr.Error()
+					if timedOut {
+						msg = timeoutMsg(timeout)
+					}
+					_ = markRetry(ctx, p.store, id, msg, time.Now().Add(after).UTC())
+				case IsNonRetryable(err) || errors.Is(handlerErr, asynq.SkipRetry):
+					_ = markPermanentFailure(ctx, p.store, id, handlerErr.Error(), time.Now().UTC())
+				case err != nil:
+					_ = p.store.MarkFailed(ctx, id, handlerErr.Error(), time.Now().UTC())
+				case p.dryRun != nil:
+					_ = markStatus(ctx, p.store, id, StatusDryRun, time.Now().UTC())
+				default:
+					p.complete(ctx, id, t, p.offloadResult(ctx, id, result.json))
+				}
+				var pe *PanicError
+				if errors.As(handlerErr, &pe) {
+					_ = setErrorDetails(ctx, p.store, id, string(pe.Stack))
+				}
+				if !interrupted && !isThrottled(err) && !retrying {
+					p.publishResult(id)
 				}
 			}
 		}
@@ -53,14 +554,171 @@ func (p *Processor) lifecycleMiddleware(next asynq.Handler) asynq.Handler {
 	})
 }
 
+// heartbeat calls Store.Heartbeat every p.beat until the returned stop func is called.
+func (p *Processor) heartbeat(ctx context.Context, id string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(p.beat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				_ = heartbeat(ctx, p.store, id, now.UTC())
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// complete marks a successful task completed, running the two-phase
+// confirmation first when the task type requires one.
+func (p *Processor) complete(ctx context.Context, id string, t *asynq.Task, resultJSON *string) {
+	confirm, ok := p.acks[t.Type()]
+	if !ok {
+		_ = p.store.MarkCompleted(ctx, id, resultJSON, time.Now().UTC())
+		return
+	}
+	_ = markStatus(ctx, p.store, id, StatusAwaitingAck, time.Now().UTC())
+	if confirm != nil && confirm(ctx, t) == nil {
+		_ = p.store.MarkCompleted(ctx, id, resultJSON, time.Now().UTC())
+	}
+}
+
+// publishResult announces a task that completed or will not be retried.
+func (p *Processor) publishResult(id string) {
+	if p.rdb == nil {
+		return
+	}
+	_ = p.rdb.Publish(context.Background(), resultChannelPrefix+id, "").Err()
+}
+
 // Start runs the server with provided mux/handler registrations.
-// The caller should build a mux and pass it in; we wrap with middleware.
+// The caller should build a mux and pass it in, or pass nil after
+// registering handlers with Handle; we wrap with middleware. It blocks until
+// the process receives SIGTERM or SIGINT and then shuts down as Run does.
 func (p *Processor) Start(mux *asynq.ServeMux) error {
-	if mux == nil {
-		mux = asynq.NewServeMux()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	return p.Run(ctx, mux)
+}
+
+// Run starts the server and blocks until ctx is cancelled. It then stops
+// fetching new tasks, waits up to GracePeriod for in-flight handlers, and
+// marks any task still running as StatusInterrupted.
+func (p *Processor) Run(ctx context.Context, mux *asynq.ServeMux) error {
+	if err := p.start(ctx, mux); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	p.Shutdown()
+	return nil
+}
+
+// prepare checks mux and the store and returns the handler to serve.
+func (p *Processor) prepare(ctx context.Context, mux *asynq.ServeMux) (asynq.Handler, error) {
+	mux, err := p.serveMux(mux)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.validateRouting(mux); err != nil {
+		return nil, err
 	}
-	h := p.lifecycleMiddleware(mux)
-	return p.server.Run(h)
+	if err := PingStore(ctx, p.store, p.ping); err != nil {
+		return nil, err
+	}
+	return p.handler(mux), nil
+}
+
+// start prepares mux, registers p and starts every server without
+// blocking. Servers already started are shut down if one fails to start.
+func (p *Processor) start(ctx context.Context, mux *asynq.ServeMux) error {
+	h, err := p.prepare(ctx, mux)
+	if err != nil {
+		return err
+	}
+	p.startRegistry()
+	if err := p.startServers(h); err != nil {
+		p.Shutdown()
+		return err
+	}
+	p.mu.Lock()
+	p.serving = h
+	p.mu.Unlock()
+	p.running.Store(true)
+	return nil
 }
 
-func (p *Processor) Shutdown() { p.server.Shutdown() }
+// startServers starts every server without blocking.
+func (p *Processor) startServers(h asynq.Handler) error {
+	if err := p.server.Start(h); err != nil {
+		return err
+	}
+	for _, iso := range p.isolated {
+		if err := iso.server.Start(withWorkerQueue(h, iso.queue)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the server; see Run.
+func (p *Processor) Shutdown() {
+	p.reload.Lock()
+	defer p.reload.Unlock()
+	p.draining.Store(true)
+	p.running.Store(false)
+	p.mu.Lock()
+	p.serving = nil
+	p.mu.Unlock()
+	p.server.Shutdown()
+	for _, iso := range p.isolated {
+		iso.server.Shutdown()
+	}
+	p.markInterrupted()
+	p.stopRegistry()
+	p.rescheduling.Wait()
+	_ = p.redis().Close()
+	if p.rdb != nil {
+		_ = p.rdb.Close()
+	}
+	_ = p.inspector.Close()
+}
+
+// redis returns the client of the worker registry, health checks and
+// put-back tasks, created on first use.
+func (p *Processor) redis() redis.UniversalClient {
+	p.connOnce.Do(func() { p.conn = makeRedis(p.redisOpt) })
+	return p.conn
+}
+
+func (p *Processor) track(id string) {
+	p.mu.Lock()
+	p.inflight[id] = struct{}{}
+	p.mu.Unlock()
+}
+
+func (p *Processor) untrack(id string) {
+	p.mu.Lock()
+	delete(p.inflight, id)
+	p.mu.Unlock()
+}
+
+// markInterrupted records every task whose handler outlived the grace period.
+func (p *Processor) markInterrupted() {
+	p.mu.Lock()
+	ids := make([]string, 0, len(p.inflight))
+	for id := range p.inflight {
+		ids = append(ids, id)
+	}
+	p.mu.Unlock()
+	if p.store == nil {
+		return
+	}
+	for _, id := range ids {
+		_ = markStatus(context.Background(), p.store, id, StatusInterrupted, time.Now().UTC())
+	}
+}
@@ -115,3 +115,58 @@ func TestProcessor_Integration_SuccessAndFailure(t *testing.T) {
 		t.Fatalf("fail task did not fail: %v", err)
 	}
 }
+
+func TestProcessor_Run_MarksInterruptedOnShutdown(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, GracePeriod: 200 * time.Millisecond})
+	mux := asynq.NewServeMux()
+	started := make(chan struct{})
+	mux.HandleFunc("it:slow", func(ctx context.Context, tsk *asynq.Task) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- processor.Run(ctx, mux) }()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	info, err := client.Enqueue(context.Background(), "it:slow", nil)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("handler did not start")
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Run did not return after cancellation")
+	}
+
+	if err := pollUntil(t, 2*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(context.Background(), info.ID)
+		if err != nil {
+			return false, nil
+		}
+		return rec.Status == StatusInterrupted, nil
+	}); err != nil {
+		rec, _ := store.GetByID(context.Background(), info.ID)
+		t.Fatalf("task not marked interrupted: %v (record=%#v)", err, rec)
+	}
+}
@@ -0,0 +1,73 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEnqueueProto(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	got := make(chan string, 1)
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1})
+	defer processor.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.Handle("greet", ProtoHandler(func(ctx context.Context, m *wrapperspb.StringValue) error {
+		got <- m.GetValue()
+		return nil
+	}))
+	go func() { _ = processor.Start(mux) }()
+
+	info, err := client.EnqueueProto(ctx, "greet", wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("EnqueueProto: %v", err)
+	}
+	select {
+	case v := <-got:
+		if v != "hello" {
+			t.Fatalf("decoded %q", v)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not run")
+	}
+	rec, err := store.GetByID(ctx, info.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if name := ProtoMessageType(rec); name != "google.protobuf.StringValue" {
+		t.Fatalf("message type %q (content type %q)", name, rec.ContentType)
+	}
+
+}
+
+func TestProtoHandler_RejectsOtherTypes(t *testing.T) {
+	h := ProtoHandler(func(ctx context.Context, m *wrapperspb.StringValue) error { return nil })
+	a, err := anypb.New(durationpb.New(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := proto.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range [][]byte{b, []byte("not protobuf")} {
+		if err := h.ProcessTask(context.Background(), asynq.NewTask("greet", payload)); !IsNonRetryable(err) {
+			t.Fatalf("want a non-retryable error, got %v", err)
+		}
+	}
+}
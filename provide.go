@@ -0,0 +1,55 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/hibiken/asynq"
+)
+
+// Config gathers everything needed to construct the asyncx components. It
+// is the single input of FxModule and WireSet.
+type Config struct {
+	Redis asynq.RedisConnOpt
+	// DB backs the SQLStore built by ProvideStore. Applications using another
+	// backend provide their own Store instead of ProvideStore.
+	DB *sql.DB
+	// Dialect, if set, makes ProvideStore run Migrate before returning.
+	Dialect   Dialect
+	Client    ClientOptions
+	Processor ProcessorConfig
+}
+
+// ProvideStore returns a SQLStore over cfg.DB, migrating it first if
+// cfg.Dialect is set, and fails if its schema is outdated.
+func ProvideStore(cfg Config) (Store, error) {
+	if cfg.DB == nil {
+		return nil, errors.New("asyncx: Config.DB is nil")
+	}
+	if cfg.Dialect != "" {
+		if err := Migrate(context.Background(), cfg.DB, cfg.Dialect); err != nil {
+			return nil, err
+		}
+	}
+	store, err := OpenSQLStore(context.Background(), cfg.DB, SQLStoreOptions{Dialect: cfg.Dialect, CheckSchema: true})
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// ProvideClient returns a Client built from cfg.Client.
+func ProvideClient(cfg Config, store Store) *Client {
+	return NewClient(cfg.Redis, store, cfg.Client)
+}
+
+// ProvideProcessor returns a Processor built from cfg.Processor.
+func ProvideProcessor(cfg Config, store Store) *Processor {
+	return NewProcessor(cfg.Redis, store, cfg.Processor)
+}
+
+// ProvideServeMux returns an empty mux for handlers to register on.
+func ProvideServeMux() *asynq.ServeMux {
+	return asynq.NewServeMux()
+}
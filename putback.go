@@ -0,0 +1,62 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// rescheduleTimeout bounds how long a put-back copy waits for asynq to
+// remove the task it replaces.
+const rescheduleTimeout = 5 * time.Second
+
+// putBack returns err, which must not count as a failure, for a task the
+// Processor declines to run now, so that asynq offers it again after
+// retryDelay without using up a retry. asynq archives a task with no
+// retries left whatever the error, so such a task is revoked instead and a
+// copy with the same ID, queue and TaskDefaults is scheduled after the delay,
+// once asynq has removed the original. A copy that cannot be scheduled
+// leaves its record StatusEnqueueFailed for a Reenqueuer.
+func (p *Processor) putBack(ctx context.Context, t *asynq.Task, err error) error {
+	if _, ok := ctx.Value(brokerTaskKey{}).(brokerTask); ok {
+		// Other brokers redeliver without a retry limit; see RunJetStream.
+		return err
+	}
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	id, ok := asynq.GetTaskID(ctx)
+	if retried < maxRetry || !ok {
+		return err
+	}
+	queue, _ := asynq.GetQueueName(ctx)
+	opts := append(p.defaults[t.Type()].options(),
+		asynq.TaskID(id), asynq.Queue(queue), asynq.MaxRetry(0), asynq.ProcessIn(retryDelay(retried, err, t)))
+	p.rescheduling.Add(1)
+	go func() {
+		defer p.rescheduling.Done()
+		p.reschedule(id, asynq.NewTask(t.Type(), t.Payload()), opts)
+	}()
+	return asynq.RevokeTask
+}
+
+// reschedule enqueues copy, retrying while the task it replaces is still
+// on Redis.
+func (p *Processor) reschedule(id string, copy *asynq.Task, opts []asynq.Option) {
+	client := asynq.NewClientFromRedisClient(p.redis())
+	deadline := time.Now().Add(rescheduleTimeout)
+	for {
+		_, err := client.Enqueue(copy, opts...)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, asynq.ErrTaskIDConflict) || time.Now().After(deadline) {
+			if p.store != nil {
+				_ = markStatus(context.Background(), p.store, id, StatusEnqueueFailed, time.Now().UTC())
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,114 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// QueueAction is an operator action on a queue.
+type QueueAction string
+
+const (
+	QueuePaused  QueueAction = "paused"
+	QueueResumed QueueAction = "resumed"
+)
+
+// QueueEvent records a QueueAction for audit.
+type QueueEvent struct {
+	Queue  string
+	Action QueueAction
+	// Actor is the RequestInfo.Subject of the caller's context, if any.
+	Actor string
+	At    time.Time
+}
+
+// QueueEventStore is implemented by stores that keep an audit trail of queue
+// operations. Processor.PauseQueue and ResumeQueue record to it when the
+// Processor's store implements it.
+type QueueEventStore interface {
+	RecordQueueEvent(ctx context.Context, e QueueEvent) error
+	// QueueEvents returns the events for queue, newest first. A limit <= 0
+	// returns all of them.
+	QueueEvents(ctx context.Context, queue string, limit int) ([]QueueEvent, error)
+}
+
+// PauseQueue stops all Processors from fetching tasks from queue until
+// ResumeQueue is called. Tasks can still be enqueued while it is paused.
+func (p *Processor) PauseQueue(ctx context.Context, queue string) error {
+	if err := p.inspector.PauseQueue(queue); err != nil {
+		return err
+	}
+	return p.recordQueueEvent(ctx, queue, QueuePaused)
+}
+
+// ResumeQueue undoes PauseQueue.
+func (p *Processor) ResumeQueue(ctx context.Context, queue string) error {
+	if err := p.inspector.UnpauseQueue(queue); err != nil {
+		return err
+	}
+	return p.recordQueueEvent(ctx, queue, QueueResumed)
+}
+
+func (p *Processor) recordQueueEvent(ctx context.Context, queue string, action QueueAction) error {
+	qs, ok := storeAs[QueueEventStore](p.store)
+	if !ok {
+		return nil
+	}
+	ri, _ := RequestInfoFromContext(ctx)
+	return qs.RecordQueueEvent(ctx, QueueEvent{Queue: queue, Action: action, Actor: ri.Subject, At: time.Now().UTC()})
+}
+
+func (s *SQLStore) RecordQueueEvent(ctx context.Context, e QueueEvent) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	var actor *string
+	if e.Actor != "" {
+		actor = &e.Actor
+	}
+	q := `INSERT INTO asyncx_queue_events (queue, action, actor, created_at) VALUES (?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, q, e.Queue, string(e.Action), actor, e.At.UTC())
+	if err != nil {
+		qpg := `INSERT INTO asyncx_queue_events (queue, action, actor, created_at) VALUES ($1, $2, $3, $4)`
+		_, err2 := s.db.ExecContext(ctx, qpg, e.Queue, string(e.Action), actor, e.At.UTC())
+		return err2
+	}
+	return nil
+}
+
+func (s *SQLStore) QueueEvents(ctx context.Context, queue string, limit int) ([]QueueEvent, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	q := `SELECT queue, action, actor, created_at FROM asyncx_queue_events WHERE queue = ? ORDER BY created_at DESC`
+	qpg := `SELECT queue, action, actor, created_at FROM asyncx_queue_events WHERE queue = $1 ORDER BY created_at DESC`
+	args := []any{queue}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		qpg += ` LIMIT $2`
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		rows, err = s.db.QueryContext(ctx, qpg, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer rows.Close()
+	var out []QueueEvent
+	for rows.Next() {
+		var e QueueEvent
+		var action string
+		var actor sql.NullString
+		if err := rows.Scan(&e.Queue, &action, &actor, &e.At); err != nil {
+			return nil, err
+		}
+		e.Action = QueueAction(action)
+		e.Actor = actor.String
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
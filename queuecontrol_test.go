@@ -0,0 +1,66 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_PauseResumeQueue(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_queuecontrol_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	p := NewProcessor(redis, store, ProcessorConfig{})
+	defer p.inspector.Close()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	if _, err := client.Enqueue(ctx, "q:task", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	opCtx := WithRequestInfo(ctx, RequestInfo{Subject: "oncall"})
+	if err := p.PauseQueue(opCtx, DefaultQueue); err != nil {
+		t.Fatalf("PauseQueue: %v", err)
+	}
+	info, err := p.inspector.GetQueueInfo(DefaultQueue)
+	if err != nil || !info.Paused {
+		t.Fatalf("queue should be paused: %+v %v", info, err)
+	}
+	if err := p.ResumeQueue(ctx, DefaultQueue); err != nil {
+		t.Fatalf("ResumeQueue: %v", err)
+	}
+	if info, _ := p.inspector.GetQueueInfo(DefaultQueue); info == nil || info.Paused {
+		t.Fatalf("queue should be resumed: %+v", info)
+	}
+
+	events, err := store.QueueEvents(ctx, DefaultQueue, 0)
+	if err != nil {
+		t.Fatalf("QueueEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("want 2 events, got %+v", events)
+	}
+	actions := map[QueueAction]string{}
+	for _, e := range events {
+		actions[e.Action] = e.Actor
+	}
+	if actor, ok := actions[QueuePaused]; !ok || actor != "oncall" {
+		t.Fatalf("pause event missing or without actor: %+v", events)
+	}
+	if _, ok := actions[QueueResumed]; !ok {
+		t.Fatalf("resume event missing: %+v", events)
+	}
+}
@@ -0,0 +1,67 @@
+package asyncx
+
+import (
+	"context"
+	"slices"
+)
+
+// QueueRename moves a queue from the name From to To without losing tasks.
+// During the transition, pass it in ClientOptions.QueueRenames so that new
+// enqueues go to To, and in ProcessorConfig.QueueRenames so that processors
+// consume both names. Once Processor.QueueDrained reports From empty,
+// the rename can be dropped from the configuration.
+type QueueRename struct {
+	From, To string
+}
+
+// renamed returns the queue enqueues to queue go to.
+func renamed(renames []QueueRename, queue string) string {
+	for _, r := range renames {
+		if r.From == queue {
+			return r.To
+		}
+	}
+	return queue
+}
+
+// applyRenames makes the processor consume every renamed queue's old name
+// alongside its new one: with the same weight when To is shared, and as the
+// first StealFrom queue when To is isolated.
+func applyRenames(renames []QueueRename, shared map[string]int, limits map[string]QueueLimit) map[string]QueueLimit {
+	for _, r := range renames {
+		if w, ok := shared[r.To]; ok {
+			if _, ok := shared[r.From]; !ok {
+				shared[r.From] = w
+			}
+			continue
+		}
+		if l, ok := limits[r.To]; ok && l.MaxConcurrency > 0 {
+			copied := make(map[string]QueueLimit, len(limits))
+			for k, v := range limits {
+				copied[k] = v
+			}
+			l.StealFrom = append([]string{r.From}, l.StealFrom...)
+			copied[r.To] = l
+			limits = copied
+		}
+	}
+	return limits
+}
+
+// QueueDrained reports whether queue has no pending, scheduled, retrying,
+// aggregating or running tasks left. Archived tasks do not count. A queue
+// that does not exist is drained.
+func (p *Processor) QueueDrained(ctx context.Context, queue string) (bool, error) {
+	queues, err := p.inspector.Queues()
+	if err != nil {
+		return false, err
+	}
+	if !slices.Contains(queues, queue) {
+		return true, nil
+	}
+	info, err := p.inspector.GetQueueInfo(queue)
+	if err != nil {
+		return false, err
+	}
+	return info.Pending+info.Active+info.Scheduled+info.Retry+info.Aggregating == 0, nil
+}
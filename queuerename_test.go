@@ -0,0 +1,76 @@
+package asyncx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestQueueRename_DualReadAndDrain(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	ctx := context.Background()
+	rename := QueueRename{From: "emails", To: "notifications"}
+
+	// A task left on the old queue by a producer that predates the rename.
+	legacy := asynq.NewClient(redis)
+	defer legacy.Close()
+	if _, err := legacy.Enqueue(asynq.NewTask("email:send", nil), asynq.Queue("emails")); err != nil {
+		t.Fatalf("legacy Enqueue: %v", err)
+	}
+
+	client := NewClient(redis, nil, ClientOptions{Queue: "emails", QueueRenames: []QueueRename{rename}})
+	defer client.Close()
+	info, err := client.Enqueue(ctx, "email:send", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if info.Queue != "notifications" {
+		t.Fatalf("default queue not renamed: %s", info.Queue)
+	}
+	info, err = client.Enqueue(ctx, "email:send", nil, asynq.Queue("emails"))
+	if err != nil || info.Queue != "notifications" {
+		t.Fatalf("explicit queue not renamed: %v, %v", info, err)
+	}
+
+	processor := NewProcessor(redis, nil, ProcessorConfig{
+		Concurrency:  1,
+		Queues:       map[string]int{"notifications": 1},
+		QueueRenames: []QueueRename{rename},
+	})
+	defer processor.Shutdown()
+	if drained, err := processor.QueueDrained(ctx, "emails"); err != nil || drained {
+		t.Fatalf("old queue reported drained before processing: %v, %v", drained, err)
+	}
+
+	var mu sync.Mutex
+	queues := map[string]int{}
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("email:send", func(ctx context.Context, tsk *asynq.Task) error {
+		q, _ := asynq.GetQueueName(ctx)
+		mu.Lock()
+		queues[q]++
+		mu.Unlock()
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	err = pollUntil(t, 5*time.Second, func() (bool, error) {
+		return processor.QueueDrained(ctx, "emails")
+	})
+	if err != nil {
+		t.Fatalf("old queue not drained: %v", err)
+	}
+	err = pollUntil(t, 5*time.Second, func() (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return queues["emails"] == 1 && queues["notifications"] == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected queues served: %v", queues)
+	}
+}
@@ -0,0 +1,170 @@
+package asyncx
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultQueue is the queue used when neither ClientOptions nor ProcessorConfig name one.
+const DefaultQueue = "default"
+
+// QueueSpec documents a queue known to the application.
+type QueueSpec struct {
+	Name        string
+	Description string
+	Weight      int    // default priority weight used by the Processor
+	Team        string // owning team, shown in topology views
+}
+
+// QueueRegistry holds the set of known queues. When passed to NewClient or
+// NewProcessor, every configured queue name must be registered.
+// It is safe for concurrent use.
+type QueueRegistry struct {
+	mu     sync.RWMutex
+	queues map[string]QueueSpec
+}
+
+func NewQueueRegistry(specs ...QueueSpec) *QueueRegistry {
+	r := &QueueRegistry{queues: make(map[string]QueueSpec, len(specs))}
+	for _, s := range specs {
+		if err := r.Register(s); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a queue. Names must be non-empty and unique.
+func (r *QueueRegistry) Register(spec QueueSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("queue name cannot be empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.queues[spec.Name]; ok {
+		return fmt.Errorf("queue %q already registered", spec.Name)
+	}
+	r.queues[spec.Name] = spec
+	return nil
+}
+
+func (r *QueueRegistry) Lookup(name string) (QueueSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.queues[name]
+	return s, ok
+}
+
+// Specs returns all registered queues sorted by name.
+func (r *QueueRegistry) Specs() []QueueSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]QueueSpec, 0, len(r.queues))
+	for _, s := range r.queues {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Weights returns the default weight of every registered queue with a positive weight,
+// in the shape expected by ProcessorConfig.Queues.
+func (r *QueueRegistry) Weights() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]int, len(r.queues))
+	for name, s := range r.queues {
+		if s.Weight > 0 {
+			out[name] = s.Weight
+		}
+	}
+	return out
+}
+
+// UnknownQueueError reports a queue name missing from the registry.
+// Suggestion holds the closest registered name, if any is close enough to be a typo.
+type UnknownQueueError struct {
+	Name       string
+	Suggestion string
+}
+
+func (e *UnknownQueueError) Error() string {
+	if e.Suggestion != "" {
+		return fmt.Sprintf("unknown queue %q (did you mean %q?)", e.Name, e.Suggestion)
+	}
+	return fmt.Sprintf("unknown queue %q", e.Name)
+}
+
+// Validate returns an *UnknownQueueError for the first name that is not registered.
+func (r *QueueRegistry) Validate(names ...string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, n := range names {
+		if _, ok := r.queues[n]; ok {
+			continue
+		}
+		e := &UnknownQueueError{Name: n}
+		best := len(n)/2 + 1
+		for known := range r.queues {
+			if d := editDistance(n, known); d < best || (d == best && known < e.Suggestion) {
+				best, e.Suggestion = d, known
+			}
+		}
+		return e
+	}
+	return nil
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// QueueLimit configures a queue in ProcessorConfig.QueueLimits. Weight
+// applies while the queue shares workers with others; a positive
+// MaxConcurrency isolates it on its own server with that many workers.
+type QueueLimit struct {
+	Weight         int
+	MaxConcurrency int
+	// StealFrom lists overflow queues, in order of preference, that the
+	// isolated workers drain whenever their own queue is empty. Stolen tasks
+	// carry TaskEvent.StolenBy and are counted in MetricStolen.
+	StealFrom []string
+}
+
+// isolatedQueues returns the queues each isolated server of limits serves,
+// weighted so that strict priority prefers the own queue, then StealFrom
+// in order.
+func isolatedQueues(limits map[string]QueueLimit) map[string]map[string]int {
+	out := make(map[string]map[string]int)
+	for name, l := range limits {
+		if l.MaxConcurrency <= 0 {
+			continue
+		}
+		qs := map[string]int{name: len(l.StealFrom) + 1}
+		for i, q := range l.StealFrom {
+			if _, ok := qs[q]; !ok {
+				qs[q] = len(l.StealFrom) - i
+			}
+		}
+		out[name] = qs
+	}
+	return out
+}
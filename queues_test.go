@@ -0,0 +1,113 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestQueueRegistry_Validate(t *testing.T) {
+	reg := NewQueueRegistry(
+		QueueSpec{Name: "critical", Description: "payments and auth", Weight: 6, Team: "payments"},
+		QueueSpec{Name: "default", Weight: 3},
+		QueueSpec{Name: "low", Weight: 1},
+	)
+	if err := reg.Validate("critical", "low"); err != nil {
+		t.Fatalf("Validate known queues: %v", err)
+	}
+	err := reg.Validate("critcal")
+	var uq *UnknownQueueError
+	if !errors.As(err, &uq) {
+		t.Fatalf("want UnknownQueueError, got %v", err)
+	}
+	if uq.Suggestion != "critical" {
+		t.Fatalf("want suggestion critical, got %q", uq.Suggestion)
+	}
+	if err := reg.Validate("reports"); err == nil || err.(*UnknownQueueError).Suggestion != "" {
+		t.Fatalf("want unknown queue without suggestion, got %v", err)
+	}
+	if err := reg.Register(QueueSpec{Name: "low"}); err == nil {
+		t.Fatalf("duplicate registration should fail")
+	}
+	w := reg.Weights()
+	if len(w) != 3 || w["critical"] != 6 {
+		t.Fatalf("unexpected weights: %v", w)
+	}
+}
+
+func TestNewClient_UnknownQueuePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected panic for unregistered queue")
+		}
+	}()
+	reg := NewQueueRegistry(QueueSpec{Name: "default"})
+	NewClient(asynq.RedisClientOpt{Addr: "127.0.0.1:0"}, nil, ClientOptions{Queue: "defualt", Registry: reg})
+}
+
+func TestQueueOf(t *testing.T) {
+	if q := queueOf([]asynq.Option{asynq.MaxRetry(1), asynq.Queue("low")}, "default"); q != "low" {
+		t.Fatalf("want low, got %s", q)
+	}
+	if q := queueOf(nil, "default"); q != "default" {
+		t.Fatalf("want default, got %s", q)
+	}
+}
+
+func TestProcessor_QueueLimitsIsolateQueues(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, nil, ProcessorConfig{
+		Concurrency: 4,
+		QueueLimits: map[string]QueueLimit{"heavy": {Weight: 3, MaxConcurrency: 1}},
+	})
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	lightDone := make(chan struct{})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("heavy:job", func(ctx context.Context, tsk *asynq.Task) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		return nil
+	})
+	mux.HandleFunc("light:job", func(ctx context.Context, tsk *asynq.Task) error {
+		close(lightDone)
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, nil, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := client.Enqueue(ctx, "heavy:job", nil, asynq.Queue("heavy")); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	if _, err := client.Enqueue(ctx, "light:job", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-lightDone:
+	case <-time.After(3 * time.Second):
+		t.Fatal("default queue starved by the heavy queue")
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	if p := peak.Load(); p != 1 {
+		t.Fatalf("heavy queue ran %d tasks at once, cap is 1", p)
+	}
+}
@@ -0,0 +1,176 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimit describes a token bucket: Rate tokens per second are refilled up to Burst.
+// A zero Rate disables limiting for the bucket.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type RateLimiterOptions struct {
+	// Limits maps a bucket key (the task type unless KeyFunc is set) to its limit.
+	Limits map[string]RateLimit
+	// Default applies to keys absent from Limits. The zero value means unlimited.
+	Default RateLimit
+	// KeyFunc derives the bucket key for a task, e.g. a tenant ID read from the payload.
+	// Defaults to the task type.
+	KeyFunc func(ctx context.Context, t *asynq.Task) string
+	// Prefix namespaces bucket keys in Redis. Defaults to "asyncx:ratelimit:".
+	Prefix string
+}
+
+// RateLimiter is a Redis-backed token bucket shared by every worker connected to the same Redis.
+type RateLimiter struct {
+	rdb  redis.UniversalClient
+	mu   sync.RWMutex // guards opts.Limits and opts.Default
+	opts RateLimiterOptions
+}
+
+func NewRateLimiter(redisOpt asynq.RedisConnOpt, opts RateLimiterOptions) *RateLimiter {
+	if opts.Prefix == "" {
+		opts.Prefix = "asyncx:ratelimit:"
+	}
+	return &RateLimiter{
+		rdb:  makeRedis(redisOpt),
+		opts: opts,
+	}
+}
+
+// ThrottledError is returned for a task whose bucket is empty.
+// The Processor retries it after RetryAfter without counting it as a failure.
+type ThrottledError struct {
+	Key        string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("rate limited: key=%s retry_after=%s", e.Key, e.RetryAfter)
+}
+
+func isThrottled(err error) bool {
+	var te *ThrottledError
+	return errors.As(err, &te)
+}
+
+// tokenBucketScript refills the bucket based on elapsed time and takes one token.
+// It returns {allowed, wait_ms}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+local elapsed = math.max(0, now - ts)
+tokens = math.min(burst, tokens + elapsed * rate / 1000)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return {allowed, wait}
+`)
+
+// Allow takes a token from the bucket for key. When the bucket is empty it
+// returns false and the time until the next token becomes available.
+func (r *RateLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	r.mu.RLock()
+	limit, ok := r.opts.Limits[key]
+	if !ok {
+		limit = r.opts.Default
+	}
+	r.mu.RUnlock()
+	if limit.Rate <= 0 {
+		return true, 0, nil
+	}
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	now := time.Now().UnixMilli()
+	res, err := tokenBucketScript.Run(ctx, r.rdb, []string{r.opts.Prefix + key}, limit.Rate, burst, now).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limiter reply: %v", res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// SetLimits replaces Limits and Default while the limiter is in use. Buckets
+// keep their tokens and refill at the new rates.
+func (r *RateLimiter) SetLimits(limits map[string]RateLimit, def RateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opts.Limits = limits
+	r.opts.Default = def
+}
+
+// check returns a *ThrottledError if t exceeds its limit. Redis errors fail open
+// so that a limiter outage does not stop processing.
+func (r *RateLimiter) check(ctx context.Context, t *asynq.Task) error {
+	key := t.Type()
+	if r.opts.KeyFunc != nil {
+		key = r.opts.KeyFunc(ctx, t)
+	}
+	ok, wait, err := r.Allow(ctx, key)
+	if err != nil || ok {
+		return nil
+	}
+	return &ThrottledError{Key: key, RetryAfter: wait}
+}
+
+// wait blocks until t's bucket has a token or ctx is done. Redis errors fail
+// open, as in check.
+func (r *RateLimiter) wait(ctx context.Context, t *asynq.Task) error {
+	for {
+		var te *ThrottledError
+		if err := r.check(ctx, t); !errors.As(err, &te) {
+			return err
+		}
+		timer := time.NewTimer(te.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Middleware rejects tasks exceeding their limit with a *ThrottledError.
+// It can be installed with asynq.ServeMux.Use; ProcessorConfig.RateLimiter
+// additionally checks the limit before the task is marked in_progress.
+func (r *RateLimiter) Middleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		if err := r.check(ctx, t); err != nil {
+			return err
+		}
+		return next.ProcessTask(ctx, t)
+	})
+}
+
+func (r *RateLimiter) Close() error {
+	return r.rdb.Close()
+}
@@ -0,0 +1,117 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+
+	rl := NewRateLimiter(asynq.RedisClientOpt{Addr: s.Addr()}, RateLimiterOptions{
+		Limits: map[string]RateLimit{"email:deliver": {Rate: 1, Burst: 2}},
+	})
+	defer rl.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		ok, _, err := rl.Allow(ctx, "email:deliver")
+		if err != nil || !ok {
+			t.Fatalf("call %d: want allowed, got ok=%v err=%v", i, ok, err)
+		}
+	}
+	ok, wait, err := rl.Allow(ctx, "email:deliver")
+	if err != nil {
+		t.Fatalf("Allow: %v", err)
+	}
+	if ok || wait <= 0 {
+		t.Fatalf("want throttled with positive wait, got ok=%v wait=%v", ok, wait)
+	}
+	if ok, _, _ := rl.Allow(ctx, "unlimited:type"); !ok {
+		t.Fatalf("keys without a limit must not be throttled")
+	}
+}
+
+func TestRateLimiter_Middleware(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+
+	rl := NewRateLimiter(asynq.RedisClientOpt{Addr: s.Addr()}, RateLimiterOptions{
+		Default: RateLimit{Rate: 0.5, Burst: 1},
+		KeyFunc: func(ctx context.Context, t *asynq.Task) string { return "tenant-a" },
+	})
+	defer rl.Close()
+
+	calls := 0
+	h := rl.Middleware(asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		calls++
+		return nil
+	}))
+	task := asynq.NewTask("report:build", nil)
+	if err := h.ProcessTask(context.Background(), task); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	err := h.ProcessTask(context.Background(), task)
+	var te *ThrottledError
+	if !errors.As(err, &te) || te.Key != "tenant-a" {
+		t.Fatalf("want ThrottledError for tenant-a, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("handler should run once, ran %d times", calls)
+	}
+}
+
+func TestClient_RateLimiter(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+
+	rl := NewRateLimiter(redis, RateLimiterOptions{
+		Limits: map[string]RateLimit{"email:send": {Rate: 0.5, Burst: 2}},
+		Prefix: "asyncx:enqueue:",
+	})
+	defer rl.Close()
+	client := NewClient(redis, nil, ClientOptions{RateLimiter: rl})
+	defer client.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := client.Enqueue(ctx, "email:send", nil); err != nil {
+			t.Fatalf("enqueue %d: %v", i, err)
+		}
+	}
+	_, err := client.Enqueue(ctx, "email:send", nil)
+	var te *ThrottledError
+	if !errors.As(err, &te) || te.Key != "email:send" {
+		t.Fatalf("want ThrottledError, got %v", err)
+	}
+	if _, err := client.Enqueue(ctx, "report:build", nil); err != nil {
+		t.Fatalf("unlimited type: %v", err)
+	}
+
+	waiting := NewClient(redis, nil, ClientOptions{RateLimiter: rl, RateLimitWait: true})
+	defer waiting.Close()
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := waiting.Enqueue(short, "email:send", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want the wait to end with the context, got %v", err)
+	}
+
+	fast := NewRateLimiter(redis, RateLimiterOptions{Default: RateLimit{Rate: 20, Burst: 1}, Prefix: "asyncx:enqueue-fast:"})
+	defer fast.Close()
+	waiting = NewClient(redis, nil, ClientOptions{RateLimiter: fast, RateLimitWait: true})
+	defer waiting.Close()
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := waiting.Enqueue(ctx, "email:send", nil); err != nil {
+			t.Fatalf("waiting enqueue %d: %v", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("three enqueues at 20/s took %v", elapsed)
+	}
+}
@@ -0,0 +1,100 @@
+package asyncx
+
+import (
+	"context"
+	"time"
+)
+
+type ReaperConfig struct {
+	// Interval between scans. Defaults to one minute.
+	Interval time.Duration
+	// Timeout is how long a task may stay in_progress before it is considered
+	// stale. Defaults to 30 minutes.
+	Timeout time.Duration
+	// Timeouts overrides Timeout per task type.
+	Timeouts map[string]time.Duration
+	// Requeue re-enqueues stale tasks as new tasks with the same type, queue
+	// and payload, using Client.Redrive so that the stale original does not
+	// also run if asynq recovers it. Requires a Client.
+	Requeue bool
+	// Leader, if set, limits scans to the elected replica.
+	Leader Leader
+}
+
+// Reaper finds tasks left in in_progress by crashed workers and marks them StatusStale.
+// A task whose last heartbeat is within its timeout is considered alive however
+// long it has been running.
+type Reaper struct {
+	store  Store
+	client *Client
+	cfg    ReaperConfig
+}
+
+// NewReaper creates a Reaper. client may be nil unless cfg.Requeue is set.
+func NewReaper(store Store, client *Client, cfg ReaperConfig) *Reaper {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Minute
+	}
+	return &Reaper{store: store, client: client, cfg: cfg}
+}
+
+// Run scans every Interval until ctx is cancelled.
+func (r *Reaper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = r.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce performs a single scan, or nothing unless r leads.
+func (r *Reaper) RunOnce(ctx context.Context) error {
+	if !leads(r.cfg.Leader) {
+		return nil
+	}
+	now := time.Now().UTC()
+	// Query with the shortest timeout and apply per-type timeouts below.
+	shortest := r.cfg.Timeout
+	for _, d := range r.cfg.Timeouts {
+		if d > 0 && d < shortest {
+			shortest = d
+		}
+	}
+	recs, err := listRecords(ctx, r.store, TaskFilter{Status: StatusInProgress, StartedBefore: now.Add(-shortest)})
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		timeout := r.timeoutFor(rec.Type)
+		if rec.StartedAt == nil || now.Sub(*rec.StartedAt) < timeout {
+			continue
+		}
+		if rec.LastHeartbeatAt != nil && now.Sub(*rec.LastHeartbeatAt) < timeout {
+			continue
+		}
+		if err := markStatus(ctx, r.store, rec.ID, StatusStale, now); err != nil {
+			return err
+		}
+		if r.cfg.Requeue && r.client != nil {
+			if _, err := r.client.Redrive(ctx, rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reaper) timeoutFor(taskType string) time.Duration {
+	if d, ok := r.cfg.Timeouts[taskType]; ok && d > 0 {
+		return d
+	}
+	return r.cfg.Timeout
+}
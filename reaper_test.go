@@ -0,0 +1,78 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestReaper_MarksStaleAndRequeues(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+
+	stuck := []struct {
+		id, typ string
+		age     time.Duration
+	}{
+		{"reap-crashed", "email:deliver", 2 * time.Hour},
+		{"reap-long", "video:transcode", 2 * time.Hour},
+		{"reap-fresh", "email:deliver", time.Minute},
+	}
+	for _, s := range stuck {
+		rec := TaskRecord{ID: s.id, Type: s.typ, Queue: "default", PayloadJSON: `{"n":1}`, CreatedAt: time.Now().UTC()}
+		if err := store.InsertCreated(ctx, rec); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		if err := store.MarkStarted(ctx, s.id, time.Now().Add(-s.age).UTC()); err != nil {
+			t.Fatalf("MarkStarted: %v", err)
+		}
+	}
+
+	// A long-running handler that keeps heart-beating is not stale.
+	alive := TaskRecord{ID: "reap-alive", Type: "email:deliver", Queue: "default", PayloadJSON: `{}`, CreatedAt: time.Now().UTC()}
+	if err := store.InsertCreated(ctx, alive); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if err := store.MarkStarted(ctx, alive.ID, time.Now().Add(-3*time.Hour).UTC()); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+	if err := store.Heartbeat(ctx, alive.ID, time.Now().UTC()); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+
+	r := NewReaper(store, client, ReaperConfig{
+		Timeout:  time.Hour,
+		Timeouts: map[string]time.Duration{"video:transcode": 6 * time.Hour},
+		Requeue:  true,
+	})
+	if err := r.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	want := map[string]Status{"reap-crashed": StatusStale, "reap-long": StatusInProgress, "reap-fresh": StatusInProgress, "reap-alive": StatusInProgress}
+	for id, status := range want {
+		got, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID(%s): %v", id, err)
+		}
+		if got.Status != status {
+			t.Fatalf("%s: want status=%s got=%s", id, status, got.Status)
+		}
+	}
+	requeued, err := store.List(ctx, TaskFilter{Type: "email:deliver", Status: StatusCreated})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(requeued) != 1 || requeued[0].PayloadJSON != `{"n":1}` {
+		t.Fatalf("want one requeued copy with the original payload, got %#v", requeued)
+	}
+}
@@ -0,0 +1,107 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// RedactionPolicy lists sensitive payload fields that must not reach
+// observability integrations. Share one policy between ClientOptions and
+// ProcessorConfig so hooks (and the logs and metrics built on them) and
+// task spans all see the same redacted view. The stored payload_json is
+// never redacted.
+type RedactionPolicy struct {
+	// Fields are JSON object keys, matched case-insensitively at any depth,
+	// and metric label or span tag keys.
+	Fields []string
+	// Replacement substitutes redacted values. Defaults to "[REDACTED]".
+	Replacement string
+}
+
+func (p *RedactionPolicy) replacement() string {
+	if p.Replacement == "" {
+		return "[REDACTED]"
+	}
+	return p.Replacement
+}
+
+func (p *RedactionPolicy) sensitive(key string) bool {
+	for _, f := range p.Fields {
+		if strings.EqualFold(f, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactJSON returns doc with the values of sensitive keys replaced.
+// Documents that are not valid JSON are replaced entirely, since they cannot
+// be inspected. A nil policy returns doc unchanged.
+func (p *RedactionPolicy) RedactJSON(doc []byte) []byte {
+	if p == nil || len(p.Fields) == 0 || len(doc) == 0 {
+		return doc
+	}
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		b, _ := json.Marshal(p.replacement())
+		return b
+	}
+	out, err := json.Marshal(p.redact(v))
+	if err != nil {
+		return doc
+	}
+	return out
+}
+
+func (p *RedactionPolicy) redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if p.sensitive(k) {
+				v[k] = p.replacement()
+			} else {
+				v[k] = p.redact(child)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = p.redact(child)
+		}
+	}
+	return v
+}
+
+// RedactLabels returns a copy of labels with sensitive keys' values replaced,
+// for metric labels and span tags. A nil policy returns labels unchanged.
+func (p *RedactionPolicy) RedactLabels(labels map[string]string) map[string]string {
+	if p == nil || len(p.Fields) == 0 {
+		return labels
+	}
+	out := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if p.sensitive(k) {
+			v = p.replacement()
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// RedactingSink wraps sink so that label values are redacted by policy.
+func RedactingSink(sink MetricsSink, policy *RedactionPolicy) MetricsSink {
+	return redactingSink{sink: sink, policy: policy}
+}
+
+type redactingSink struct {
+	sink   MetricsSink
+	policy *RedactionPolicy
+}
+
+func (s redactingSink) AddCounter(ctx context.Context, name string, delta float64, labels map[string]string) {
+	s.sink.AddCounter(ctx, name, delta, s.policy.RedactLabels(labels))
+}
+
+func (s redactingSink) RecordHistogram(ctx context.Context, name string, value float64, labels map[string]string) {
+	s.sink.RecordHistogram(ctx, name, value, s.policy.RedactLabels(labels))
+}
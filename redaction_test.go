@@ -0,0 +1,64 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRedactionPolicy(t *testing.T) {
+	p := &RedactionPolicy{Fields: []string{"password", "card_number"}}
+	got := string(p.RedactJSON([]byte(`{"user":"ann","Password":"x","cards":[{"card_number":"4111","exp":"12/30"}]}`)))
+	want := `{"Password":"[REDACTED]","cards":[{"card_number":"[REDACTED]","exp":"12/30"}],"user":"ann"}`
+	if got != want {
+		t.Fatalf("RedactJSON:\n got %s\nwant %s", got, want)
+	}
+	if got := string(p.RedactJSON([]byte(`password=x`))); got != `"[REDACTED]"` {
+		t.Fatalf("invalid JSON should be replaced entirely, got %s", got)
+	}
+	var nilPolicy *RedactionPolicy
+	if got := string(nilPolicy.RedactJSON([]byte(`{"password":"x"}`))); got != `{"password":"x"}` {
+		t.Fatalf("nil policy must not redact, got %s", got)
+	}
+	labels := p.RedactLabels(map[string]string{"type": "login", "password": "x"})
+	if labels["type"] != "login" || labels["password"] != "[REDACTED]" {
+		t.Fatalf("RedactLabels: %v", labels)
+	}
+}
+
+type payloadHooks struct {
+	NopHooks
+	payloads []string
+}
+
+func (h *payloadHooks) OnEnqueued(ctx context.Context, e TaskEvent) {
+	h.payloads = append(h.payloads, string(e.Payload))
+}
+
+func TestClient_RedactsHookPayloads(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	hooks := &payloadHooks{}
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{
+		Hooks:     hooks,
+		Redaction: &RedactionPolicy{Fields: []string{"token"}, Replacement: "***"},
+	})
+	defer client.Close()
+	ctx := context.Background()
+
+	info, err := client.Enqueue(ctx, "rd:login", map[string]string{"token": "s3cret"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if len(hooks.payloads) != 1 || hooks.payloads[0] != `{"token":"***"}` {
+		t.Fatalf("hook saw unredacted payload: %v", hooks.payloads)
+	}
+	if rec, _ := store.GetByID(ctx, info.ID); rec == nil || rec.PayloadJSON != `{"token":"s3cret"}` {
+		t.Fatalf("stored payload must stay intact: %#v", rec)
+	}
+}
@@ -0,0 +1,78 @@
+package asyncx
+
+import (
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisClient adapts a go-redis client the application already configured
+// (pool, TLS, credentials) into an asynq.RedisConnOpt, so every constructor
+// taking one shares it instead of dialing its own. asyncx never closes the
+// client; close it after everything using it.
+func RedisClient(rdb redis.UniversalClient) asynq.RedisConnOpt {
+	return sharedRedis{rdb: rdb}
+}
+
+// NewClientFromRedisClient is NewClient over an existing go-redis client.
+func NewClientFromRedisClient(rdb redis.UniversalClient, store Store, opts ClientOptions) *Client {
+	return NewClient(RedisClient(rdb), store, opts)
+}
+
+// NewProcessorFromRedisClient is NewProcessor over an existing go-redis
+// client.
+func NewProcessorFromRedisClient(rdb redis.UniversalClient, store Store, cfg ProcessorConfig) *Processor {
+	return NewProcessor(RedisClient(rdb), store, cfg)
+}
+
+type sharedRedis struct {
+	rdb redis.UniversalClient
+}
+
+func (s sharedRedis) MakeRedisClient() interface{} { return s.rdb }
+
+// uncloseable is a shared client whose Close is a no-op.
+type uncloseable struct {
+	redis.UniversalClient
+}
+
+func (uncloseable) Close() error { return nil }
+
+// makeRedis returns a client for opt that the caller closes when done.
+func makeRedis(opt asynq.RedisConnOpt) redis.UniversalClient {
+	if s, ok := opt.(sharedRedis); ok {
+		return uncloseable{s.rdb}
+	}
+	return opt.MakeRedisClient().(redis.UniversalClient)
+}
+
+// clusterClient returns rdb, or the client it shares, as a cluster client.
+func clusterClient(rdb redis.UniversalClient) (*redis.ClusterClient, bool) {
+	if u, ok := rdb.(uncloseable); ok {
+		rdb = u.UniversalClient
+	}
+	cc, ok := rdb.(*redis.ClusterClient)
+	return cc, ok
+}
+
+// The asynq constructors below leave a shared client open on Close.
+
+func newAsynqClient(opt asynq.RedisConnOpt) *asynq.Client {
+	if s, ok := opt.(sharedRedis); ok {
+		return asynq.NewClientFromRedisClient(s.rdb)
+	}
+	return asynq.NewClient(opt)
+}
+
+func newInspector(opt asynq.RedisConnOpt) *asynq.Inspector {
+	if s, ok := opt.(sharedRedis); ok {
+		return asynq.NewInspectorFromRedisClient(s.rdb)
+	}
+	return asynq.NewInspector(opt)
+}
+
+func newAsynqServer(opt asynq.RedisConnOpt, cfg asynq.Config) *asynq.Server {
+	if s, ok := opt.(sharedRedis); ok {
+		return asynq.NewServerFromRedisClient(s.rdb, cfg)
+	}
+	return asynq.NewServer(opt, cfg)
+}
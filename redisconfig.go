@@ -0,0 +1,168 @@
+package asyncx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// RedisConfig describes a Redis deployment in one place and converts it to
+// the matching asynq option with ConnOpt, checking it first.
+type RedisConfig struct {
+	// Addrs are "host:port" addresses: the server for standalone Redis, the
+	// Sentinels with MasterName, or seed nodes with Cluster.
+	Addrs []string
+	// MasterName selects Sentinel failover for the named master.
+	MasterName string
+	// Cluster selects Redis Cluster.
+	Cluster bool
+
+	Username string
+	Password string
+	// SentinelPassword authenticates with the Sentinels themselves.
+	SentinelPassword string
+	// DB is the database index. Redis Cluster only has database 0.
+	DB int
+
+	// TLS, if set, encrypts connections.
+	TLS *RedisTLS
+
+	// PoolSize is the connections per server. Defaults to go-redis's 10
+	// per CPU.
+	PoolSize     int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// RedisTLS configures TLS from files. Connections require TLS 1.2 or later.
+type RedisTLS struct {
+	// CAFile is a PEM bundle of CAs to trust instead of the system pool.
+	CAFile string
+	// CertFile and KeyFile are a PEM client certificate and key for mutual
+	// TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName is verified against the server certificate. Defaults to
+	// the host being dialed.
+	ServerName string
+	// InsecureSkipVerify disables certificate verification; for testing
+	// only.
+	InsecureSkipVerify bool
+}
+
+// Validate reports the first problem with c.
+func (c RedisConfig) Validate() error {
+	if len(c.Addrs) == 0 {
+		return errors.New("asyncx: RedisConfig needs at least one address")
+	}
+	for _, addr := range c.Addrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("asyncx: RedisConfig address %q: %v", addr, err)
+		}
+	}
+	switch {
+	case c.Cluster && c.MasterName != "":
+		return errors.New("asyncx: RedisConfig cannot set both Cluster and MasterName")
+	case !c.Cluster && c.MasterName == "" && len(c.Addrs) > 1:
+		return errors.New("asyncx: RedisConfig has several addresses but neither Cluster nor MasterName")
+	case c.DB < 0:
+		return errors.New("asyncx: RedisConfig DB is negative")
+	case c.Cluster && c.DB != 0:
+		return errors.New("asyncx: Redis Cluster only supports DB 0")
+	case c.SentinelPassword != "" && c.MasterName == "":
+		return errors.New("asyncx: RedisConfig SentinelPassword needs MasterName")
+	case c.PoolSize < 0:
+		return errors.New("asyncx: RedisConfig PoolSize is negative")
+	}
+	if t := c.TLS; t != nil && (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("asyncx: RedisTLS needs both CertFile and KeyFile")
+	}
+	return nil
+}
+
+// ConnOpt validates c, loads its TLS files and returns the asynq option for
+// its deployment, to pass to any constructor taking an asynq.RedisConnOpt.
+func (c RedisConfig) ConnOpt() (asynq.RedisConnOpt, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	tlsConfig, err := c.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.Cluster:
+		return asynq.RedisClusterClientOpt{
+			Addrs:        c.Addrs,
+			Username:     c.Username,
+			Password:     c.Password,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+			TLSConfig:    tlsConfig,
+		}, nil
+	case c.MasterName != "":
+		return asynq.RedisFailoverClientOpt{
+			MasterName:       c.MasterName,
+			SentinelAddrs:    c.Addrs,
+			SentinelPassword: c.SentinelPassword,
+			Username:         c.Username,
+			Password:         c.Password,
+			DB:               c.DB,
+			DialTimeout:      c.DialTimeout,
+			ReadTimeout:      c.ReadTimeout,
+			WriteTimeout:     c.WriteTimeout,
+			PoolSize:         c.PoolSize,
+			TLSConfig:        tlsConfig,
+		}, nil
+	default:
+		return asynq.RedisClientOpt{
+			Addr:         c.Addrs[0],
+			Username:     c.Username,
+			Password:     c.Password,
+			DB:           c.DB,
+			DialTimeout:  c.DialTimeout,
+			ReadTimeout:  c.ReadTimeout,
+			WriteTimeout: c.WriteTimeout,
+			PoolSize:     c.PoolSize,
+			TLSConfig:    tlsConfig,
+		}, nil
+	}
+}
+
+// config builds the tls.Config for t, or nil without TLS.
+func (t *RedisTLS) config() (*tls.Config, error) {
+	if t == nil {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("asyncx: RedisTLS CAFile: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("asyncx: RedisTLS CAFile %s holds no PEM certificates", t.CAFile)
+		}
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("asyncx: RedisTLS client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
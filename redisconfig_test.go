@@ -0,0 +1,56 @@
+package asyncx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRedisConfigValidate(t *testing.T) {
+	for name, cfg := range map[string]RedisConfig{
+		"no addrs":         {},
+		"no port":          {Addrs: []string{"localhost"}},
+		"cluster+sentinel": {Addrs: []string{"a:1"}, Cluster: true, MasterName: "m"},
+		"several addrs":    {Addrs: []string{"a:1", "b:1"}},
+		"cluster db":       {Addrs: []string{"a:1"}, Cluster: true, DB: 2},
+		"sentinel pass":    {Addrs: []string{"a:1"}, SentinelPassword: "p"},
+		"cert no key":      {Addrs: []string{"a:1"}, TLS: &RedisTLS{CertFile: "c.pem"}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: Validate accepted %+v", name, cfg)
+		}
+	}
+}
+
+func TestRedisConfigConnOpt(t *testing.T) {
+	opt, err := RedisConfig{Addrs: []string{"a:1", "b:1"}, MasterName: "m", DB: 3}.ConnOpt()
+	if f, ok := opt.(asynq.RedisFailoverClientOpt); err != nil || !ok || f.MasterName != "m" || f.DB != 3 || len(f.SentinelAddrs) != 2 {
+		t.Fatalf("Sentinel ConnOpt: %#v %v", opt, err)
+	}
+	opt, err = RedisConfig{Addrs: []string{"a:1"}, Cluster: true, TLS: &RedisTLS{}}.ConnOpt()
+	if c, ok := opt.(asynq.RedisClusterClientOpt); err != nil || !ok || c.TLSConfig == nil || c.TLSConfig.MinVersion == 0 {
+		t.Fatalf("Cluster ConnOpt: %#v %v", opt, err)
+	}
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(ca, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (RedisConfig{Addrs: []string{"a:1"}, TLS: &RedisTLS{CAFile: ca}}).ConnOpt(); err == nil {
+		t.Fatal("ConnOpt accepted a CA file without certificates")
+	}
+
+	s := startMiniRedis(t)
+	defer s.Close()
+	opt, err = RedisConfig{Addrs: []string{s.Addr()}, PoolSize: 2}.ConnOpt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(opt, nil, ClientOptions{})
+	defer client.Close()
+	if _, err := client.Enqueue(context.Background(), "ping", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestRedisClusterConnOpt(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClusterClientOpt{Addrs: []string{s.Addr()}}
+
+	client := NewClient(redis, nil, ClientOptions{})
+	defer client.Close()
+	processor := NewProcessor(redis, nil, ProcessorConfig{Concurrency: 1, WorkerRegistry: true})
+	defer processor.Shutdown()
+	done := make(chan struct{})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("ping", func(ctx context.Context, tsk *asynq.Task) error {
+		close(done)
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	if _, err := client.Enqueue(ctx, "ping", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not run over the cluster connection")
+	}
+	workers, err := processor.Workers(ctx)
+	if err != nil || len(workers) != 1 {
+		t.Fatalf("Workers over a cluster client: %+v %v", workers, err)
+	}
+}
+
+func TestSharedRedisClient(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	rdb := goredis.NewClient(&goredis.Options{Addr: s.Addr()})
+	defer rdb.Close()
+
+	client := NewClientFromRedisClient(rdb, nil, ClientOptions{ResultNotifications: true})
+	processor := NewProcessorFromRedisClient(rdb, nil, ProcessorConfig{Concurrency: 1, PublishResults: true})
+	done := make(chan struct{})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("ping", func(ctx context.Context, tsk *asynq.Task) error {
+		close(done)
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	if _, err := client.Enqueue(ctx, "ping", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not run over the shared client")
+	}
+	processor.Shutdown()
+	client.Close()
+	NewLeaderElector(RedisClient(rdb), LeaderElectorOptions{}).Close()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		t.Fatalf("shared client closed by asyncx: %v", err)
+	}
+}
@@ -0,0 +1,408 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStoreOptions struct {
+	// Prefix namespaces all keys. Defaults to "asyncx:store:".
+	Prefix string
+	// TTL expires every record this long after creation. Zero keeps records
+	// until TerminalTTL applies or forever.
+	TTL time.Duration
+	// TerminalTTL, if set, shortens expiry once a task completes or fails.
+	TerminalTTL time.Duration
+}
+
+// RedisStore implements Store with one Redis hash per task and sorted-set
+// indexes (scored by creation time) per status, type and queue.
+//
+// Unlike SQLStore it is not durable: records are lost on Redis eviction,
+// expiry or an unpersisted restart. Use it when losing history is acceptable.
+// All keys are assumed to live on a single Redis node (no Cluster support).
+type RedisStore struct {
+	rdb  redis.UniversalClient
+	opts RedisStoreOptions
+}
+
+func NewRedisStore(redisOpt asynq.RedisConnOpt, opts RedisStoreOptions) *RedisStore {
+	if opts.Prefix == "" {
+		opts.Prefix = "asyncx:store:"
+	}
+	return &RedisStore{rdb: makeRedis(redisOpt), opts: opts}
+}
+
+func (s *RedisStore) Close() error { return s.rdb.Close() }
+
+func (s *RedisStore) taskKey(id string) string       { return s.opts.Prefix + "task:" + id }
+func (s *RedisStore) allIdx() string                 { return s.opts.Prefix + "idx:all" }
+func (s *RedisStore) statusIdx(st Status) string     { return s.opts.Prefix + "idx:status:" + string(st) }
+func (s *RedisStore) typeIdx(taskType string) string { return s.opts.Prefix + "idx:type:" + taskType }
+func (s *RedisStore) queueIdx(queue string) string   { return s.opts.Prefix + "idx:queue:" + queue }
+func (s *RedisStore) parentIdx(id string) string     { return s.opts.Prefix + "idx:parent:" + id }
+
+func formatTime(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
+
+func (s *RedisStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
+	now := time.Now().UTC()
+	score := float64(now.UnixMilli())
+	key := s.taskKey(rec.ID)
+	_, err := s.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		fields := []any{
+			"id", rec.ID,
+			"type", rec.Type,
+			"queue", rec.Queue,
+			"payload_json", rec.PayloadJSON,
+			"status", string(insertStatus(&rec)),
+			"task_class", string(rec.Class),
+			"created_at", formatTime(now),
+		}
+		if md := encodeMetadata(rec.Metadata); md != nil {
+			fields = append(fields, "metadata_json", *md)
+		}
+		if rec.Priority != "" {
+			fields = append(fields, "priority", string(rec.Priority))
+		}
+		if rec.RequestJSON != nil {
+			fields = append(fields, "request_json", *rec.RequestJSON)
+		}
+		if rec.GuardToken != "" {
+			fields = append(fields, "guard_token", rec.GuardToken)
+		}
+		if rec.CreatedBy != "" {
+			fields = append(fields, "created_by", rec.CreatedBy)
+		}
+		if rec.Source != "" {
+			fields = append(fields, "source", rec.Source)
+		}
+		if rec.DedupKey != "" {
+			fields = append(fields, "dedup_key", rec.DedupKey)
+		}
+		if rec.WorkflowTraceparent != nil {
+			fields = append(fields, "workflow_traceparent", *rec.WorkflowTraceparent)
+		}
+		if rec.ParentTaskID != "" {
+			fields = append(fields, "parent_task_id", rec.ParentTaskID)
+		}
+		if rec.ContentType != "" {
+			fields = append(fields, "content_type", rec.ContentType)
+		}
+		if rec.ContentHash != "" {
+			fields = append(fields, "content_hash", rec.ContentHash)
+		}
+		if rec.Shard != "" {
+			fields = append(fields, "shard", rec.Shard)
+		}
+		if rec.PayloadVersion != 0 {
+			fields = append(fields, "payload_version", strconv.Itoa(rec.PayloadVersion))
+		}
+		p.HSet(ctx, key, fields...)
+		if s.opts.TTL > 0 {
+			p.Expire(ctx, key, s.opts.TTL)
+		}
+		z := redis.Z{Score: score, Member: rec.ID}
+		p.ZAdd(ctx, s.allIdx(), z)
+		p.ZAdd(ctx, s.statusIdx(insertStatus(&rec)), z)
+		p.ZAdd(ctx, s.typeIdx(rec.Type), z)
+		p.ZAdd(ctx, s.queueIdx(rec.Queue), z)
+		if rec.ParentTaskID != "" {
+			p.ZAdd(ctx, s.parentIdx(rec.ParentTaskID), z)
+		}
+		return nil
+	})
+	return err
+}
+
+// update applies fields to an existing record and moves it between status and
+// queue indexes. Updates to unknown IDs are ignored, matching SQL UPDATE semantics.
+func (s *RedisStore) update(ctx context.Context, taskID string, status Status, queue string, ttl time.Duration, fields ...any) error {
+	return s.updateFrom(ctx, taskID, func(Status) Status { return status }, queue, ttl, fields...)
+}
+
+// updateFrom is update with the new status derived from the current one; an
+// empty status leaves it unchanged.
+func (s *RedisStore) updateFrom(ctx context.Context, taskID string, next func(Status) Status, queue string, ttl time.Duration, fields ...any) error {
+	key := s.taskKey(taskID)
+	// updated_at goes first so that an explicit value in fields wins.
+	fields = append([]any{"updated_at", formatTime(time.Now())}, fields...)
+	if queue != "" {
+		fields = append(fields, "queue", queue)
+	}
+	return s.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		cur, err := tx.HMGet(ctx, key, "status", "queue").Result()
+		if err != nil {
+			return err
+		}
+		oldStatus, ok := cur[0].(string)
+		if !ok {
+			return nil
+		}
+		oldQueue, _ := cur[1].(string)
+		fields := fields
+		status := next(Status(oldStatus))
+		if status != "" {
+			fields = append(fields[:len(fields):len(fields)], "status", string(status))
+		}
+		score, err := tx.ZScore(ctx, s.allIdx(), taskID).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.HSet(ctx, key, fields...)
+			z := redis.Z{Score: score, Member: taskID}
+			if status != "" && string(status) != oldStatus {
+				p.ZRem(ctx, s.statusIdx(Status(oldStatus)), taskID)
+				p.ZAdd(ctx, s.statusIdx(status), z)
+			}
+			if queue != "" && queue != oldQueue {
+				p.ZRem(ctx, s.queueIdx(oldQueue), taskID)
+				p.ZAdd(ctx, s.queueIdx(queue), z)
+			}
+			if ttl > 0 {
+				p.Expire(ctx, key, ttl)
+			}
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (s *RedisStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+	return s.updateFrom(ctx, taskID, func(cur Status) Status {
+		if slices.Contains(unenqueuedStatuses, cur) {
+			return StatusCreated
+		}
+		return ""
+	}, queue, 0, "enqueued_at", formatTime(enqueuedAt))
+}
+
+func (s *RedisStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error {
+	w := workerFromContext(ctx)
+	pid := ""
+	if w.PID != 0 {
+		pid = strconv.Itoa(w.PID)
+	}
+	return s.update(ctx, taskID, StatusInProgress, "", 0, "started_at", formatTime(startedAt), "next_retry_at", "", "failure_kind", "",
+		"worker_id", w.ID, "hostname", w.Host, "pid", pid)
+}
+
+func (s *RedisStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error {
+	fields := []any{"finished_at", formatTime(finishedAt)}
+	if resultJSON != nil {
+		fields = append(fields, "result_json", *resultJSON)
+	}
+	return s.update(ctx, taskID, StatusCompleted, "", s.opts.TerminalTTL, fields...)
+}
+
+func (s *RedisStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.update(ctx, taskID, StatusFailed, "", s.opts.TerminalTTL, "error_msg", errorMsg, "failure_kind", string(FailureTransient), "finished_at", formatTime(finishedAt))
+}
+
+func (s *RedisStore) MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.update(ctx, taskID, StatusFailed, "", s.opts.TerminalTTL, "error_msg", errorMsg, "failure_kind", string(FailurePermanent), "finished_at", formatTime(finishedAt))
+}
+
+func (s *RedisStore) MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) error {
+	return s.update(ctx, taskID, StatusFailed, "", 0, "error_msg", errorMsg, "failure_kind", string(FailureTransient), "next_retry_at", formatTime(nextRetryAt))
+}
+
+func (s *RedisStore) MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) error {
+	return s.update(ctx, taskID, StatusTimedOut, "", 0, "error_msg", timeoutMsg(timeout),
+		"timeout_ms", strconv.FormatInt(timeout.Milliseconds(), 10), "finished_at", formatTime(at), "updated_at", formatTime(at))
+}
+
+func (s *RedisStore) AddRuntime(ctx context.Context, taskID string, d time.Duration) (time.Duration, error) {
+	key := s.taskKey(taskID)
+	// Like update, ignore unknown IDs rather than creating a partial hash.
+	if n, err := s.rdb.Exists(ctx, key).Result(); err != nil || n == 0 {
+		return 0, err
+	}
+	ms, err := s.rdb.HIncrBy(ctx, key, "runtime_ms", d.Milliseconds()).Result()
+	return time.Duration(ms) * time.Millisecond, err
+}
+
+func (s *RedisStore) MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) error {
+	return s.update(ctx, taskID, StatusFailed, "", s.opts.TerminalTTL, "error_msg", budgetMsg(budget), "failure_kind", string(FailureBudgetExhausted), "finished_at", formatTime(finishedAt))
+}
+
+func (s *RedisStore) SetErrorDetails(ctx context.Context, taskID string, details string) error {
+	return s.update(ctx, taskID, "", "", 0, "error_details", details)
+}
+
+func (s *RedisStore) Heartbeat(ctx context.Context, taskID string, at time.Time) error {
+	return s.update(ctx, taskID, "", "", 0, "last_heartbeat_at", formatTime(at))
+}
+
+func (s *RedisStore) MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) error {
+	return s.update(ctx, taskID, status, "", 0, "updated_at", formatTime(at))
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.rdb.Ping(ctx).Err()
+}
+
+func (s *RedisStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	m, err := s.rdb.HGetAll(ctx, s.taskKey(taskID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, ErrNotFound
+	}
+	return recordFromHash(m), nil
+}
+
+// List picks the most selective index for f, then filters the remaining
+// conditions in memory. Index entries whose hash has expired are pruned.
+func (s *RedisStore) List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error) {
+	idx := s.allIdx()
+	switch {
+	case f.ParentID != "":
+		idx = s.parentIdx(f.ParentID)
+	case f.Status != "":
+		idx = s.statusIdx(f.Status)
+	case f.Type != "":
+		idx = s.typeIdx(f.Type)
+	case f.Queue != "":
+		idx = s.queueIdx(f.Queue)
+	}
+	rng := &redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+	if !f.CreatedAfter.IsZero() {
+		rng.Min = strconv.FormatInt(f.CreatedAfter.UnixMilli(), 10)
+	}
+	if !f.CreatedBefore.IsZero() {
+		rng.Max = "(" + strconv.FormatInt(f.CreatedBefore.UnixMilli(), 10)
+	}
+	ids, err := s.rdb.ZRangeByScore(ctx, idx, rng).Result()
+	if err != nil {
+		return nil, err
+	}
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	_, err = s.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = p.HGetAll(ctx, s.taskKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []*TaskRecord
+	var expired []any
+	for i, cmd := range cmds {
+		m := cmd.Val()
+		if len(m) == 0 {
+			expired = append(expired, ids[i])
+			continue
+		}
+		rec := recordFromHash(m)
+		if !f.matches(rec) {
+			continue
+		}
+		out = append(out, rec)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	if len(expired) > 0 {
+		_ = s.rdb.ZRem(ctx, idx, expired...).Err()
+	}
+	return out, nil
+}
+
+// matches reports whether rec satisfies every condition in f.
+func (f TaskFilter) matches(rec *TaskRecord) bool {
+	before := func(t *time.Time, limit time.Time) bool {
+		return limit.IsZero() || (t != nil && t.Before(limit))
+	}
+	switch {
+	case f.Status != "" && rec.Status != f.Status,
+		f.Type != "" && rec.Type != f.Type,
+		f.Queue != "" && rec.Queue != f.Queue,
+		f.DedupKey != "" && rec.DedupKey != f.DedupKey,
+		f.ContentHash != "" && rec.ContentHash != f.ContentHash,
+		f.Shard != "" && rec.Shard != f.Shard,
+		f.ParentID != "" && rec.ParentTaskID != f.ParentID,
+		f.PayloadRetained && rec.PayloadPurgedAt != nil,
+		f.Enqueued && rec.EnqueuedAt.IsZero(),
+		f.RetryScheduled && rec.NextRetryAt == nil,
+		!f.CreatedAfter.IsZero() && rec.CreatedAt.Before(f.CreatedAfter),
+		!f.CreatedBefore.IsZero() && !rec.CreatedAt.Before(f.CreatedBefore),
+		!before(rec.StartedAt, f.StartedBefore),
+		!before(rec.UpdatedAt, f.UpdatedBefore),
+		!matchesMetadata(rec.Metadata, f.Metadata):
+		return false
+	}
+	return true
+}
+
+func recordFromHash(m map[string]string) *TaskRecord {
+	parse := func(field string) *time.Time {
+		v, ok := m[field]
+		if !ok || v == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+	optional := func(field string) *string {
+		v, ok := m[field]
+		if !ok {
+			return nil
+		}
+		return &v
+	}
+	rec := &TaskRecord{
+		ID:                  m["id"],
+		Type:                m["type"],
+		Queue:               m["queue"],
+		PayloadJSON:         m["payload_json"],
+		Status:              Status(m["status"]),
+		Class:               TaskClass(m["task_class"]),
+		FailureKind:         FailureKind(m["failure_kind"]),
+		Priority:            Priority(m["priority"]),
+		Metadata:            decodeMetadata(m["metadata_json"]),
+		GuardToken:          m["guard_token"],
+		CreatedBy:           m["created_by"],
+		Source:              m["source"],
+		DedupKey:            m["dedup_key"],
+		ContentHash:         m["content_hash"],
+		Shard:               m["shard"],
+		ParentTaskID:        m["parent_task_id"],
+		WorkerID:            m["worker_id"],
+		Hostname:            m["hostname"],
+		ContentType:         m["content_type"],
+		ErrorMsg:            optional("error_msg"),
+		ErrorDetails:        optional("error_details"),
+		ResultJSON:          optional("result_json"),
+		RequestJSON:         optional("request_json"),
+		WorkflowTraceparent: optional("workflow_traceparent"),
+		UpdatedAt:           parse("updated_at"),
+		StartedAt:           parse("started_at"),
+		FinishedAt:          parse("finished_at"),
+		LastHeartbeatAt:     parse("last_heartbeat_at"),
+		NextRetryAt:         parse("next_retry_at"),
+		PayloadPurgedAt:     parse("payload_purged_at"),
+	}
+	rec.TimeoutMS, _ = strconv.ParseInt(m["timeout_ms"], 10, 64)
+	rec.RuntimeMS, _ = strconv.ParseInt(m["runtime_ms"], 10, 64)
+	rec.PID, _ = strconv.Atoi(m["pid"])
+	rec.PayloadVersion, _ = strconv.Atoi(m["payload_version"])
+	if t := parse("created_at"); t != nil {
+		rec.CreatedAt = *t
+	}
+	if t := parse("enqueued_at"); t != nil {
+		rec.EnqueuedAt = *t
+	}
+	return rec
+}
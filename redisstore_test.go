@@ -0,0 +1,108 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRedisStore_Lifecycle(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	store := NewRedisStore(asynq.RedisClientOpt{Addr: s.Addr()}, RedisStoreOptions{})
+	defer store.Close()
+	ctx := context.Background()
+
+	rec := TaskRecord{ID: "r-1", Type: "email:deliver", Queue: "default", PayloadJSON: `{"user_id":1}`, Class: ClassCritical}
+	if err := store.InsertCreated(ctx, rec); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if err := store.MarkEnqueued(ctx, rec.ID, "critical", time.Now()); err != nil {
+		t.Fatalf("MarkEnqueued: %v", err)
+	}
+	if err := store.MarkStarted(ctx, rec.ID, time.Now()); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+	result := `{"ok":true}`
+	if err := store.MarkCompleted(ctx, rec.ID, &result, time.Now()); err != nil {
+		t.Fatalf("MarkCompleted: %v", err)
+	}
+
+	got, err := store.GetByID(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Status != StatusCompleted || got.Queue != "critical" || got.Class != ClassCritical {
+		t.Fatalf("unexpected record: %#v", got)
+	}
+	if got.ResultJSON == nil || *got.ResultJSON != result || got.StartedAt == nil || got.FinishedAt == nil {
+		t.Fatalf("result or timestamps missing: %#v", got)
+	}
+	if _, err := store.GetByID(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "r-2", Type: "email:deliver", Queue: "default", PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if err := store.MarkTimedOut(ctx, "r-2", time.Second, time.Now()); err != nil {
+		t.Fatalf("MarkTimedOut: %v", err)
+	}
+	if got, err := store.GetByID(ctx, "r-2"); err != nil || got.Status != StatusTimedOut || got.FinishedAt == nil {
+		t.Fatalf("timed-out record should be finished: %#v %v", got, err)
+	}
+	// Updates to unknown IDs are no-ops, like SQL UPDATE.
+	if err := store.MarkFailed(ctx, "missing", "boom", time.Now()); err != nil {
+		t.Fatalf("MarkFailed on missing record: %v", err)
+	}
+}
+
+func TestRedisStore_ListAndTTL(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	store := NewRedisStore(asynq.RedisClientOpt{Addr: s.Addr()}, RedisStoreOptions{TTL: time.Hour, TerminalTTL: time.Minute})
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, id := range []string{"l-1", "l-2", "l-3"} {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "report:build", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	if err := store.MarkFailed(ctx, "l-2", "boom", time.Now()); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+
+	created, err := store.List(ctx, TaskFilter{Status: StatusCreated})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("want 2 created records, got %d", len(created))
+	}
+	failed, _ := store.List(ctx, TaskFilter{Type: "report:build", Status: StatusFailed})
+	if len(failed) != 1 || failed[0].ID != "l-2" {
+		t.Fatalf("unexpected failed records: %#v", failed)
+	}
+
+	// The failed record expires with TerminalTTL, the others with TTL.
+	s.FastForward(2 * time.Minute)
+	all, _ := store.List(ctx, TaskFilter{})
+	if len(all) != 2 {
+		t.Fatalf("want 2 records after terminal TTL, got %d", len(all))
+	}
+	s.FastForward(time.Hour)
+	if all, _ := store.List(ctx, TaskFilter{}); len(all) != 0 {
+		t.Fatalf("want all records expired, got %d", len(all))
+	}
+}
+
+func TestRedisStore_MarkEnqueuedKeepsLaterStatus(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	store := NewRedisStore(asynq.RedisClientOpt{Addr: s.Addr()}, RedisStoreOptions{})
+	defer store.Close()
+	testMarkEnqueuedKeepsLaterStatus(t, store)
+}
@@ -0,0 +1,123 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+type ReenqueuerConfig struct {
+	// Interval between passes. Defaults to one minute.
+	Interval time.Duration
+	// Grace is how long a record may stay created without being marked
+	// enqueued before it counts as stuck, e.g. because the Client crashed
+	// between the insert and the Redis call. Defaults to one minute.
+	Grace time.Duration
+	// BatchSize is the most records retried per status and pass. Defaults
+	// to 100.
+	BatchSize int
+	// Leader, if set, limits passes to the elected replica.
+	Leader Leader
+}
+
+// Reenqueuer puts tasks whose record was written but whose Redis enqueue
+// failed or never happened back on Redis, giving at-least-once delivery
+// across Redis outages with ClientOptions.PersistBeforeEnqueue. Tasks keep
+// their ID and queue; other asynq options of the original call, such as
+// MaxRetry or ProcessIn, are not recorded and fall back to the type's
+// TaskDefaults.
+type Reenqueuer struct {
+	client *Client
+	cfg    ReenqueuerConfig
+}
+
+// NewReenqueuer panics if client has no store.
+func NewReenqueuer(client *Client, cfg ReenqueuerConfig) *Reenqueuer {
+	if client.store == nil {
+		panic("asyncx: NewReenqueuer: client has no store")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Grace <= 0 {
+		cfg.Grace = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	return &Reenqueuer{client: client, cfg: cfg}
+}
+
+// Run retries every Interval until ctx is cancelled.
+func (r *Reenqueuer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = r.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce retries stuck records once, or does nothing unless r leads. It
+// stops at the first Redis error, leaving the rest for the next pass.
+func (r *Reenqueuer) RunOnce(ctx context.Context) error {
+	if !leads(r.cfg.Leader) {
+		return nil
+	}
+	store := r.client.store
+	failed, err := listRecords(ctx, store, TaskFilter{Status: StatusEnqueueFailed, Limit: r.cfg.BatchSize})
+	if err != nil {
+		return err
+	}
+	created, err := listRecords(ctx, store, TaskFilter{Status: StatusCreated, CreatedBefore: time.Now().Add(-r.cfg.Grace), Limit: r.cfg.BatchSize})
+	if err != nil {
+		return err
+	}
+	for _, rec := range created {
+		// Fire-and-forget records are never marked enqueued; see Client.
+		if rec.EnqueuedAt.IsZero() && rec.Class != ClassFireAndForget {
+			failed = append(failed, rec)
+		}
+	}
+	for _, rec := range failed {
+		if err := r.client.reenqueue(ctx, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reenqueue puts the task of rec on Redis under its recorded ID and marks it
+// enqueued. A task already on Redis counts as enqueued.
+func (c *Client) reenqueue(ctx context.Context, rec *TaskRecord) error {
+	now := time.Now().UTC()
+	if rec.PayloadPurgedAt != nil {
+		return markPermanentFailure(ctx, c.store, rec.ID, ErrPayloadPurged.Error(), now)
+	}
+	b, err := PayloadBytes(rec)
+	if err != nil {
+		return markPermanentFailure(ctx, c.store, rec.ID, err.Error(), now)
+	}
+	onRedis, _, err := c.placePayload(ctx, rec.Type, b)
+	if err != nil {
+		return err
+	}
+	opts := append(c.defaults[rec.Type].options(), asynq.TaskID(rec.ID), asynq.Queue(rec.Queue))
+	if rec.Class == ClassFireAndForget {
+		opts = append(opts, asynq.MaxRetry(0))
+	}
+	_, err = c.client.EnqueueContext(ctx, asynq.NewTask(rec.Type, onRedis), opts...)
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		if rec.Status != StatusEnqueueFailed {
+			_ = markStatus(ctx, c.store, rec.ID, StatusEnqueueFailed, now)
+		}
+		return err
+	}
+	return c.store.MarkEnqueued(ctx, rec.ID, rec.Queue, now)
+}
@@ -0,0 +1,63 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestReenqueuer_RetriesFailedAndStuckRecords(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	client := NewClient(redis, store, ClientOptions{PersistBeforeEnqueue: true, IDGenerator: func() string { return "lost" }})
+	defer client.Close()
+
+	s.Close()
+	if _, err := client.Enqueue(ctx, "report:build", map[string]int{"id": 1}); err == nil {
+		t.Fatal("Enqueue should fail with Redis down")
+	}
+	// A record whose Client died before the Redis call.
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "stuck", Type: "report:build", Queue: DefaultQueue, PayloadJSON: `{"id":2}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	r := NewReenqueuer(client, ReenqueuerConfig{Grace: time.Millisecond})
+	if err := r.RunOnce(ctx); err == nil {
+		t.Fatal("RunOnce should fail with Redis down")
+	}
+	if rec, _ := store.GetByID(ctx, "lost"); rec.Status != StatusEnqueueFailed {
+		t.Fatalf("status %s", rec.Status)
+	}
+
+	if err := s.Restart(); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	if err := r.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	inspector := asynq.NewInspector(redis)
+	defer inspector.Close()
+	for id, payload := range map[string]string{"lost": `{"id":1}`, "stuck": `{"id":2}`} {
+		rec, err := store.GetByID(ctx, id)
+		if err != nil || rec.Status != StatusCreated || rec.EnqueuedAt.IsZero() {
+			t.Fatalf("%s: %+v %v", id, rec, err)
+		}
+		info, err := inspector.GetTaskInfo(DefaultQueue, id)
+		if err != nil || string(info.Payload) != payload {
+			t.Fatalf("%s on Redis: %+v %v", id, info, err)
+		}
+	}
+	// Already on Redis: nothing left to retry.
+	if err := r.RunOnce(ctx); err != nil {
+		t.Fatalf("second RunOnce: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package asyncx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+
+	"github.com/hibiken/asynq"
+)
+
+// ProcessorReload holds the settings Processor.Reload changes. Zero fields
+// keep their current value.
+type ProcessorReload struct {
+	// Concurrency of the shared server.
+	Concurrency int `json:"concurrency,omitempty"`
+	// Queues replaces the weights of the queues served by the shared
+	// server. Queues isolated by QueueLimits keep their own server and are
+	// ignored here.
+	Queues map[string]int `json:"queues,omitempty"`
+	// RateLimits replaces the limits of ProcessorConfig.RateLimiter, with
+	// DefaultRateLimit for unlisted keys.
+	RateLimits       map[string]RateLimit `json:"rate_limits,omitempty"`
+	DefaultRateLimit RateLimit            `json:"default_rate_limit"`
+}
+
+// sharedServer is what Reload needs to rebuild the shared asynq server.
+type sharedServer struct {
+	build       func(concurrency int, queues map[string]int) *asynq.Server
+	concurrency int
+	queues      map[string]int
+	limits      map[string]QueueLimit
+	renames     []QueueRename
+	registry    *QueueRegistry
+}
+
+// Reload applies r to a running Processor. Rate limits change in place. A
+// new Concurrency or Queues starts a replacement shared server, then shuts
+// the old one down as Shutdown would, so that its in-flight tasks get
+// GracePeriod to finish while the new server already fetches; until they do,
+// both servers' workers may run. Reload returns once the old server has
+// stopped. The worker registry is updated with the new queues.
+func (p *Processor) Reload(r ProcessorReload) error {
+	if r.RateLimits != nil && p.limiter == nil {
+		return errors.New("asyncx: Reload: no RateLimiter configured")
+	}
+	p.reload.Lock()
+	defer p.reload.Unlock()
+	next := p.shared
+	if r.Concurrency > 0 {
+		next.concurrency = r.Concurrency
+	}
+	if r.Queues != nil {
+		qs, err := next.weights(r.Queues)
+		if err != nil {
+			return err
+		}
+		next.queues = qs
+	}
+	restart := next.concurrency != p.shared.concurrency || r.Queues != nil
+	if restart {
+		p.mu.Lock()
+		h := p.serving
+		p.mu.Unlock()
+		if h == nil {
+			return errors.New("asyncx: Reload: processor is not running")
+		}
+		srv := next.build(next.concurrency, next.queues)
+		if err := srv.Start(h); err != nil {
+			return fmt.Errorf("asyncx: Reload: %w", err)
+		}
+		p.mu.Lock()
+		old := p.server
+		p.server = srv
+		p.queues = queueNames(next.queues, next.limits)
+		if p.unregister != nil {
+			p.unregister()
+			p.unregister = p.register()
+		}
+		p.mu.Unlock()
+		old.Shutdown()
+		p.shared = next
+		if p.tenants != nil {
+			p.tenants.resize(next.concurrency)
+		}
+	}
+	if r.RateLimits != nil {
+		p.limiter.SetLimits(r.RateLimits, r.DefaultRateLimit)
+	}
+	return nil
+}
+
+// weights returns the shared server's weights for queues, as NewProcessor
+// computes them.
+func (s sharedServer) weights(queues map[string]int) (map[string]int, error) {
+	qs := maps.Clone(queues)
+	for name, l := range s.limits {
+		if l.MaxConcurrency > 0 {
+			delete(qs, name)
+		}
+	}
+	if len(qs) == 0 {
+		return nil, errors.New("asyncx: Reload: no queues")
+	}
+	if s.registry != nil {
+		for name := range qs {
+			if err := s.registry.Validate(name); err != nil {
+				return nil, fmt.Errorf("asyncx: Reload: %w", err)
+			}
+		}
+	}
+	applyRenames(s.renames, qs, nil)
+	return qs, nil
+}
+
+// ReloadHandler serves Reload for an admin API: a POST with a
+// ProcessorReload as JSON answers 204 once applied, 400 otherwise.
+func (p *Processor) ReloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var reload ProcessorReload
+		if err := json.NewDecoder(r.Body).Decode(&reload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := p.Reload(reload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,109 @@
+package asyncx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_Reload(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	limiter := NewRateLimiter(redis, RateLimiterOptions{})
+	defer limiter.Close()
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, RateLimiter: limiter, GracePeriod: 5 * time.Second})
+	if err := processor.Reload(ProcessorReload{Concurrency: 2}); err == nil {
+		t.Fatal("Reload before Run should fail")
+	}
+	block := make(chan struct{})
+	started := make(chan string, 2)
+	processor.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error {
+		started <- string(tsk.Payload())
+		<-block
+		return nil
+	})
+	processor.HandleFunc("email:send", func(ctx context.Context, tsk *asynq.Task) error {
+		started <- "email"
+		return nil
+	})
+	done := make(chan error, 1)
+	go func() { done <- processor.Run(ctx, nil) }()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	slow, err := client.Enqueue(ctx, "report:build", "slow")
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not start")
+	}
+
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- processor.Reload(ProcessorReload{
+			Concurrency: 2,
+			Queues:      map[string]int{DefaultQueue: 1, "mail": 1},
+			RateLimits:  map[string]RateLimit{"email:send": {Rate: 1, Burst: 1}},
+		})
+	}()
+	// The new server serves "mail" while the old one still runs the slow task.
+	if _, err := client.Enqueue(ctx, "email:send", nil, asynq.Queue("mail")); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case got := <-started:
+		if got != "email" {
+			t.Fatalf("started %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("new queue not served after Reload")
+	}
+	select {
+	case err := <-reloaded:
+		t.Fatalf("Reload returned before in-flight task finished: %v", err)
+	default:
+	}
+	close(block)
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if rec, err := store.GetByID(ctx, slow.ID); err != nil || rec.Status != StatusCompleted {
+		t.Fatalf("in-flight task: %+v %v", rec, err)
+	}
+	if ok, _, _ := limiter.Allow(ctx, "email:send"); !ok {
+		t.Fatal("first token refused")
+	}
+	if ok, _, _ := limiter.Allow(ctx, "email:send"); ok {
+		t.Fatal("new rate limit not applied")
+	}
+
+	rec := httptest.NewRecorder()
+	processor.ReloadHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload", strings.NewReader(`{"queues":{}}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("empty queues: status %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	processor.ReloadHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reload", strings.NewReader(`{"concurrency":3}`)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reload: status %d %s", rec.Code, rec.Body)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package asyncx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// RequestInfo attributes a task to the request that enqueued it. Client.Enqueue
+// stores the RequestInfo found in its context in the request_json column.
+type RequestInfo struct {
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Tenant        string `json:"tenant,omitempty"`
+	// Subject is the authenticated principal, e.g. a user ID.
+	Subject string `json:"subject,omitempty"`
+	// TraceParent is the W3C traceparent of the request's span.
+	TraceParent string `json:"traceparent,omitempty"`
+}
+
+type requestInfoKey struct{}
+
+// WithRequestInfo returns ctx carrying info for Client.Enqueue to record.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the RequestInfo set by WithRequestInfo or
+// HTTPMiddleware.
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
+
+// HTTPContextConfig configures how HTTPMiddleware and FromRequest build a
+// RequestInfo from an incoming request.
+type HTTPContextConfig struct {
+	// CorrelationHeader carries the correlation ID. Defaults to
+	// "X-Correlation-ID", with "X-Request-ID" as a fallback. A missing ID is
+	// generated and echoed in the response header.
+	CorrelationHeader string
+	// TenantHeader carries the tenant. Defaults to "X-Tenant-ID".
+	TenantHeader string
+	// Subject, if set, extracts the authenticated principal, typically from a
+	// value stored in the request context by the auth middleware.
+	Subject func(r *http.Request) string
+}
+
+func (cfg HTTPContextConfig) withDefaults() HTTPContextConfig {
+	if cfg.CorrelationHeader == "" {
+		cfg.CorrelationHeader = "X-Correlation-ID"
+	}
+	if cfg.TenantHeader == "" {
+		cfg.TenantHeader = "X-Tenant-ID"
+	}
+	return cfg
+}
+
+// FromRequest extracts the RequestInfo of r. The trace context is taken from
+// the active OpenTelemetry span if there is one, else from the traceparent
+// header. Frameworks without net/http middleware, such as Gin, can use it
+// directly:
+//
+//	c.Request = c.Request.WithContext(asyncx.WithRequestInfo(c.Request.Context(), cfg.FromRequest(c.Request)))
+func (cfg HTTPContextConfig) FromRequest(r *http.Request) RequestInfo {
+	cfg = cfg.withDefaults()
+	info := RequestInfo{
+		CorrelationID: r.Header.Get(cfg.CorrelationHeader),
+		Tenant:        r.Header.Get(cfg.TenantHeader),
+	}
+	if info.CorrelationID == "" {
+		info.CorrelationID = r.Header.Get("X-Request-ID")
+	}
+	if info.CorrelationID == "" {
+		info.CorrelationID = newCorrelationID()
+	}
+	if cfg.Subject != nil {
+		info.Subject = cfg.Subject(r)
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(r.Context(), carrier)
+	info.TraceParent = carrier.Get("traceparent")
+	if info.TraceParent == "" {
+		info.TraceParent = r.Header.Get("traceparent")
+	}
+	return info
+}
+
+// HTTPMiddleware stores the RequestInfo of each request in its context so
+// that tasks enqueued while handling it are attributed without extra code.
+// It is standard net/http middleware: use it directly with Chi, or with
+// echo.WrapMiddleware for Echo.
+func HTTPMiddleware(cfg HTTPContextConfig) func(http.Handler) http.Handler {
+	cfg = cfg.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := cfg.FromRequest(r)
+			w.Header().Set(cfg.CorrelationHeader, info.CorrelationID)
+			next.ServeHTTP(w, r.WithContext(WithRequestInfo(r.Context(), info)))
+		})
+	}
+}
+
+func newCorrelationID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,74 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+type subjectKey struct{}
+
+func TestHTTPMiddleware_AttributesEnqueuedTasks(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+
+	var taskID string
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, err := client.Enqueue(r.Context(), "report:build", nil)
+		if err != nil {
+			t.Errorf("Enqueue: %v", err)
+			return
+		}
+		taskID = info.ID
+	})
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), subjectKey{}, "user-42")))
+		})
+	}
+	h := auth(HTTPMiddleware(HTTPContextConfig{
+		Subject: func(r *http.Request) string { s, _ := r.Context().Value(subjectKey{}).(string); return s },
+	})(app))
+
+	req := httptest.NewRequest(http.MethodPost, "/reports", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("X-Tenant-ID", "acme")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-Correlation-ID"); got != "req-1" {
+		t.Fatalf("correlation header not echoed: %q", got)
+	}
+	rec, err := store.GetByID(context.Background(), taskID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if rec.RequestJSON == nil {
+		t.Fatal("request_json not recorded")
+	}
+	var info RequestInfo
+	if err := json.Unmarshal([]byte(*rec.RequestJSON), &info); err != nil {
+		t.Fatalf("decode request_json: %v", err)
+	}
+	want := RequestInfo{CorrelationID: "req-1", Tenant: "acme", Subject: "user-42", TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	if info != want {
+		t.Fatalf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestHTTPContextConfig_GeneratesCorrelationID(t *testing.T) {
+	info := HTTPContextConfig{}.FromRequest(httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(info.CorrelationID) != 32 {
+		t.Fatalf("expected a generated correlation ID, got %q", info.CorrelationID)
+	}
+}
@@ -0,0 +1,157 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// resultChannelPrefix is the Redis pub/sub channel prefix on which the
+// Processor announces finished tasks when ProcessorConfig.PublishResults is set.
+const resultChannelPrefix = "asyncx:result:"
+
+type resultKey struct{}
+
+// resultHolder carries a handler's result from SetResult to the lifecycle middleware.
+type resultHolder struct {
+	limits JSONLimits
+	json   *string
+}
+
+// SetResult records v, JSON encoded, as the result of the task being handled
+// in ctx. It is stored in result_json when the handler returns nil.
+// Results over ProcessorConfig.ResultLimits are rejected with a *JSONLimitError.
+func SetResult(ctx context.Context, v any) error {
+	h, ok := ctx.Value(resultKey{}).(*resultHolder)
+	if !ok {
+		return errors.New("asyncx: SetResult called outside a Processor handler")
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := h.limits.Check("result_json", b); err != nil {
+		return err
+	}
+	s := string(b)
+	h.json = &s
+	return nil
+}
+
+var (
+	// ErrTaskNotFinished is returned by GetResult for tasks that have not
+	// reached a terminal status yet.
+	ErrTaskNotFinished = errors.New("asyncx: task not finished")
+	// ErrTaskFailed matches every *TaskFailedError with errors.Is.
+	ErrTaskFailed = errors.New("asyncx: task failed")
+)
+
+// TaskFailedError is returned by WaitForResult and GetResult for failed tasks.
+type TaskFailedError struct {
+	TaskID string
+	Msg    string
+}
+
+func (e *TaskFailedError) Error() string {
+	return fmt.Sprintf("task %s failed: %s", e.TaskID, e.Msg)
+}
+
+func (e *TaskFailedError) Unwrap() error { return ErrTaskFailed }
+
+func failedError(rec *TaskRecord) *TaskFailedError {
+	msg := ""
+	if rec.ErrorMsg != nil {
+		msg = *rec.ErrorMsg
+	}
+	return &TaskFailedError{TaskID: rec.ID, Msg: msg}
+}
+
+// GetResult decodes the result_json of a completed task into T. It returns
+// ErrTaskNotFinished while the task is still pending or running and a
+// *TaskFailedError if it failed. A completed task without a result yields
+// the zero T. Results kept in a BlobStore need Client.WaitForResult.
+func GetResult[T any](ctx context.Context, store Store, taskID string) (T, error) {
+	var v T
+	rec, err := store.GetByID(ctx, taskID)
+	if err != nil {
+		return v, err
+	}
+	switch rec.Status {
+	case StatusCompleted:
+		if rec.ResultJSON == nil {
+			return v, nil
+		}
+		err = json.Unmarshal([]byte(*rec.ResultJSON), &v)
+		return v, err
+	case StatusFailed:
+		return v, failedError(rec)
+	default:
+		return v, ErrTaskNotFinished
+	}
+}
+
+// WaitForResult blocks until the task completes or fails for good and returns
+// its result_json. A failure is reported as *TaskFailedError; failures that
+// asynq will still retry are waited out. The store is polled every
+// pollInterval (default 1s); with ClientOptions.ResultNotifications the
+// Processor's announcement wakes the wait early.
+func (c *Client) WaitForResult(ctx context.Context, taskID string, pollInterval time.Duration) (json.RawMessage, error) {
+	if c.store == nil {
+		return nil, errors.New("asyncx: WaitForResult requires a store")
+	}
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	var notify <-chan *redis.Message
+	if c.rdb != nil {
+		// Subscribe before the first read so an announcement cannot be missed.
+		sub := c.rdb.Subscribe(ctx, resultChannelPrefix+taskID)
+		defer sub.Close()
+		if _, err := sub.Receive(ctx); err != nil {
+			return nil, err
+		}
+		notify = sub.Channel()
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		rec, err := c.store.GetByID(ctx, taskID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if rec != nil {
+			switch rec.Status {
+			case StatusCompleted:
+				if rec.ResultJSON == nil {
+					return nil, nil
+				}
+				return resolveResult(ctx, c.blobs, json.RawMessage(*rec.ResultJSON))
+			case StatusFailed:
+				if c.finalFailure(rec) {
+					return nil, failedError(rec)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// finalFailure reports whether asynq has given up on a failed task, i.e. it
+// is archived or no longer known, rather than waiting for a retry.
+func (c *Client) finalFailure(rec *TaskRecord) bool {
+	info, err := c.inspector.GetTaskInfo(rec.Queue, rec.ID)
+	if err != nil {
+		return errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound)
+	}
+	return info.State == asynq.TaskStateArchived || info.State == asynq.TaskStateCompleted
+}
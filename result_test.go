@@ -0,0 +1,95 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_WaitForResult(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{PublishResults: true})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("rr:sum", func(ctx context.Context, tsk *asynq.Task) error {
+		return SetResult(ctx, map[string]int{"sum": 3})
+	})
+	mux.HandleFunc("rr:fail", func(ctx context.Context, tsk *asynq.Task) error {
+		return errors.New("boom")
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{ResultNotifications: true})
+	defer client.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := client.Enqueue(ctx, "rr:sum", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	res, err := client.WaitForResult(ctx, info.ID, time.Minute)
+	if err != nil {
+		t.Fatalf("WaitForResult: %v", err)
+	}
+	if string(res) != `{"sum":3}` {
+		t.Fatalf("unexpected result %s", res)
+	}
+
+	info, err = client.Enqueue(ctx, "rr:fail", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	_, err = client.WaitForResult(ctx, info.ID, 50*time.Millisecond)
+	var tfe *TaskFailedError
+	if !errors.As(err, &tfe) || tfe.Msg != "boom" {
+		t.Fatalf("want TaskFailedError, got %v", err)
+	}
+
+	if err := SetResult(context.Background(), 1); err == nil {
+		t.Fatalf("SetResult outside a handler should fail")
+	}
+}
+
+func TestGetResult(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	for _, id := range []string{"gr-ok", "gr-fail", "gr-run"} {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "rr:sum", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	result := `{"sum":3}`
+	_ = store.MarkCompleted(ctx, "gr-ok", &result, time.Now())
+	_ = store.MarkFailed(ctx, "gr-fail", "boom", time.Now())
+	_ = store.MarkStarted(ctx, "gr-run", time.Now())
+
+	type sum struct {
+		Sum int `json:"sum"`
+	}
+	got, err := GetResult[sum](ctx, store, "gr-ok")
+	if err != nil || got.Sum != 3 {
+		t.Fatalf("GetResult: %v %v", got, err)
+	}
+	if _, err := GetResult[sum](ctx, store, "gr-fail"); !errors.Is(err, ErrTaskFailed) {
+		t.Fatalf("want ErrTaskFailed, got %v", err)
+	}
+	if _, err := GetResult[sum](ctx, store, "gr-run"); !errors.Is(err, ErrTaskNotFinished) {
+		t.Fatalf("want ErrTaskNotFinished, got %v", err)
+	}
+	if _, err := GetResult[sum](ctx, store, "gr-missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+}
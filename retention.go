@@ -0,0 +1,145 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPayloadPurged is returned by Client.Redrive for a record whose payload
+// was purged.
+var ErrPayloadPurged = errors.New("asyncx: task payload was purged")
+
+// purgedPayload replaces a purged payload.
+const purgedPayload = "null"
+
+// PayloadRetention is how long payloads are kept after a task finishes.
+// Zero keeps them for as long as the record.
+type PayloadRetention struct {
+	// Completed applies to StatusCompleted records.
+	Completed time.Duration
+	// Failed applies to StatusTimedOut records and StatusFailed records
+	// asynq will not retry.
+	Failed time.Duration
+}
+
+// PayloadPurgeStore is implemented by stores that can drop a record's
+// payload while keeping the rest of it. The Janitor uses it to apply
+// JanitorConfig.PayloadRetention.
+type PayloadPurgeStore interface {
+	// PurgePayload replaces the payload with "null" and records at in
+	// payload_purged_at.
+	PurgePayload(ctx context.Context, taskID string, at time.Time) error
+}
+
+// retentionFor returns the policy for taskType.
+func (j *Janitor) retentionFor(taskType string) PayloadRetention {
+	if r, ok := j.cfg.PayloadRetentionByType[taskType]; ok {
+		return r
+	}
+	return j.cfg.PayloadRetention
+}
+
+// purgePayloads purges the payloads of finished records past their type's
+// retention.
+func (j *Janitor) purgePayloads(ctx context.Context, now time.Time) error {
+	ps, ok := storeAs[PayloadPurgeStore](j.store)
+	if !ok {
+		return nil
+	}
+	policies := []PayloadRetention{j.cfg.PayloadRetention}
+	for _, r := range j.cfg.PayloadRetentionByType {
+		policies = append(policies, r)
+	}
+	sweep := func(status Status, keep func(PayloadRetention) time.Duration) error {
+		// List once with the shortest retention in use, then check each
+		// record against its own type's.
+		var shortest time.Duration
+		for _, r := range policies {
+			if d := keep(r); d > 0 && (shortest == 0 || d < shortest) {
+				shortest = d
+			}
+		}
+		if shortest == 0 {
+			return nil
+		}
+		recs, err := listRecords(ctx, j.store, TaskFilter{Status: status, UpdatedBefore: now.Add(-shortest), PayloadRetained: true})
+		if err != nil {
+			return err
+		}
+		for _, rec := range recs {
+			d := keep(j.retentionFor(rec.Type))
+			if d <= 0 || rec.UpdatedAt == nil || rec.UpdatedAt.After(now.Add(-d)) {
+				continue
+			}
+			if rec.Status == StatusFailed && rec.NextRetryAt != nil {
+				// asynq will retry it and needs the payload.
+				continue
+			}
+			if err := j.deleteBlob(ctx, rec); err != nil {
+				return err
+			}
+			if err := ps.PurgePayload(ctx, rec.ID, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	completed := func(r PayloadRetention) time.Duration { return r.Completed }
+	failed := func(r PayloadRetention) time.Duration { return r.Failed }
+	return errors.Join(
+		sweep(StatusCompleted, completed),
+		sweep(StatusFailed, failed),
+		sweep(StatusTimedOut, failed),
+	)
+}
+
+func (s *SQLStore) PurgePayload(ctx context.Context, taskID string, at time.Time) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	var checksum *string
+	if len(s.checksumKey) > 0 {
+		// The checksum covers the payload, so it is rewritten to keep the
+		// purged record verifiable.
+		rec, err := s.GetByID(ctx, taskID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+		c := recordChecksum(s.checksumKey, rec.ID, rec.Type, purgedPayload, rec.CreatedAt)
+		checksum = &c
+	}
+	q := `UPDATE asyncx_tasks SET payload_json = ?, payload_purged_at = ?, updated_at = ?, checksum = COALESCE(?, checksum) WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET payload_json = $1, payload_purged_at = $2, updated_at = $3, checksum = COALESCE($4, checksum) WHERE id = $5`
+	return s.exec(ctx, q, qpg, purgedPayload, at.UTC(), at.UTC(), checksum, taskID)
+}
+
+func (s *RedisStore) PurgePayload(ctx context.Context, taskID string, at time.Time) error {
+	return s.update(ctx, taskID, "", "", 0, "updated_at", formatTime(at), "payload_json", purgedPayload, "payload_purged_at", formatTime(at))
+}
+
+func (s *BoltStore) PurgePayload(ctx context.Context, taskID string, at time.Time) error {
+	return s.update(taskID, func(rec *TaskRecord) {
+		t := at.UTC()
+		rec.PayloadJSON, rec.PayloadPurgedAt = purgedPayload, &t
+	})
+}
+
+// deleteBlob deletes the blob holding rec's payload, if any, before the
+// payload and with it the blob's key are purged.
+func (j *Janitor) deleteBlob(ctx context.Context, rec *TaskRecord) error {
+	if j.cfg.Blobs == nil {
+		return nil
+	}
+	b, err := PayloadBytes(rec)
+	if err != nil {
+		return nil
+	}
+	if key, ok := blobKey(b); ok {
+		return j.cfg.Blobs.DeleteBlob(ctx, key)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryStore is implemented by stores Client.RetryFailed can select from.
+// It links each retried record to its retry in the retried_as column, so a
+// record is retried at most once.
+type RetryStore interface {
+	// RetryCandidates returns up to f.BatchSize records matching f that
+	// failed for good and were not retried yet, oldest first.
+	RetryCandidates(ctx context.Context, f RetryFilter) ([]*TaskRecord, error)
+	// MarkRetried records that retryID was enqueued in place of id.
+	MarkRetried(ctx context.Context, id, retryID string) error
+}
+
+// RetryFilter selects the failed records Client.RetryFailed re-enqueues.
+// Zero fields match every record.
+type RetryFilter struct {
+	Type string
+	// ErrorContains selects records whose error message contains it.
+	ErrorContains string
+	// FailedAfter and FailedBefore bound when the records failed.
+	FailedAfter  time.Time
+	FailedBefore time.Time
+	// BatchSize is how many records are selected and re-enqueued at a
+	// time. Defaults to 100.
+	BatchSize int
+	// Concurrency caps the re-enqueues in flight. Defaults to 4.
+	Concurrency int
+}
+
+// RetryFailed re-enqueues every record that failed for good and matches f,
+// with Client.Redrive. Each retry carries the original's metadata plus a
+// "retry_of" label with its ID, and the original records the retry's ID.
+// It stops after the first batch with an error and returns how many tasks
+// it re-enqueued. The store must implement RetryStore.
+func (c *Client) RetryFailed(ctx context.Context, f RetryFilter) (int, error) {
+	rs, ok := storeAs[RetryStore](c.store)
+	if !ok {
+		return 0, errors.New("asyncx: RetryFailed: store does not implement RetryStore")
+	}
+	if f.BatchSize <= 0 {
+		f.BatchSize = 100
+	}
+	if f.Concurrency <= 0 {
+		f.Concurrency = 4
+	}
+	retried := 0
+	for {
+		batch, err := rs.RetryCandidates(ctx, f)
+		if err != nil {
+			return retried, err
+		}
+		var (
+			mu   sync.Mutex
+			errs []error
+			wg   sync.WaitGroup
+		)
+		sem := make(chan struct{}, f.Concurrency)
+		for _, rec := range batch {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				err := c.retry(ctx, rs, rec)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					retried++
+				}
+			}()
+		}
+		wg.Wait()
+		if len(errs) > 0 {
+			return retried, errors.Join(errs...)
+		}
+		if len(batch) < f.BatchSize {
+			return retried, nil
+		}
+	}
+}
+
+// retry re-enqueues rec and links it to its retry.
+func (c *Client) retry(ctx context.Context, rs RetryStore, rec *TaskRecord) error {
+	md := maps.Clone(rec.Metadata)
+	if md == nil {
+		md = make(map[string]string, 1)
+	}
+	md["retry_of"] = rec.ID
+	info, err := c.Redrive(ctx, rec, WithMetadata(md))
+	if err != nil {
+		return err
+	}
+	return rs.MarkRetried(ctx, rec.ID, info.ID)
+}
+
+func (s *SQLStore) RetryCandidates(ctx context.Context, f RetryFilter) ([]*TaskRecord, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	q := `SELECT ` + taskColumns + ` FROM asyncx_tasks WHERE status = ? AND next_retry_at IS NULL AND retried_as IS NULL`
+	args := []any{string(StatusFailed)}
+	if f.Type != "" {
+		q += ` AND type = ?`
+		args = append(args, f.Type)
+	}
+	if f.ErrorContains != "" {
+		q += ` AND error_msg LIKE ? ESCAPE '!'`
+		args = append(args, "%"+likeEscaper.Replace(f.ErrorContains)+"%")
+	}
+	if !f.FailedAfter.IsZero() {
+		q += ` AND finished_at >= ?`
+		args = append(args, f.FailedAfter.UTC())
+	}
+	if !f.FailedBefore.IsZero() {
+		q += ` AND finished_at < ?`
+		args = append(args, f.FailedBefore.UTC())
+	}
+	q += ` ORDER BY created_at LIMIT ?`
+	args = append(args, f.BatchSize)
+	var out []*TaskRecord
+	err := s.queryRows(ctx, q, args, func(rows *sql.Rows) error {
+		rec, err := scanTask(rows)
+		if err != nil {
+			return err
+		}
+		if err := s.verify(rec); err != nil {
+			return err
+		}
+		out = append(out, rec)
+		return nil
+	})
+	return out, err
+}
+
+// likeEscaper escapes LIKE wildcards with '!'.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+func (s *SQLStore) MarkRetried(ctx context.Context, id, retryID string) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET retried_as = ? WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET retried_as = $1 WHERE id = $2`
+	return s.exec(ctx, q, qpg, retryID, id)
+}
@@ -0,0 +1,79 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_RetryFailed(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_retryfailed_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+
+	now := time.Now().UTC()
+	fail := func(id, typ, msg string, at time.Time) {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: typ, Queue: "default", PayloadJSON: `{"n":1}`, Metadata: map[string]string{"tenant": "acme"}}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		if err := store.MarkFailed(ctx, id, msg, at); err != nil {
+			t.Fatalf("MarkFailed: %v", err)
+		}
+	}
+	for i := range 5 {
+		fail(fmt.Sprintf("smtp-%d", i), "email:send", "dial smtp: connection refused", now.Add(-time.Minute))
+	}
+	fail("bounce", "email:send", "550 mailbox_unavailable", now.Add(-time.Minute))
+	fail("old", "email:send", "dial smtp: connection refused", now.Add(-48*time.Hour))
+	fail("other", "report:build", "dial smtp: connection refused", now.Add(-time.Minute))
+
+	f := RetryFilter{Type: "email:send", ErrorContains: "connection refused", FailedAfter: now.Add(-time.Hour), BatchSize: 2, Concurrency: 2}
+	n, err := client.RetryFailed(ctx, f)
+	if err != nil || n != 5 {
+		t.Fatalf("RetryFailed = %d, %v; want 5", n, err)
+	}
+	retries, err := store.List(ctx, TaskFilter{Metadata: map[string]string{"retry_of": "smtp-3"}})
+	if err != nil || len(retries) != 1 {
+		t.Fatalf("retry of smtp-3: %v %v", retries, err)
+	}
+	retry := retries[0]
+	if retry.Type != "email:send" || retry.PayloadJSON != `{"n":1}` || retry.Metadata["tenant"] != "acme" {
+		t.Fatalf("retry record %+v", retry)
+	}
+	var retriedAs string
+	if err := db.QueryRowContext(ctx, `SELECT retried_as FROM asyncx_tasks WHERE id = 'smtp-3'`).Scan(&retriedAs); err != nil || retriedAs != retry.ID {
+		t.Fatalf("retried_as = %q %v, want %q", retriedAs, err, retry.ID)
+	}
+	for _, id := range []string{"bounce", "old", "other"} {
+		var v sql.NullString
+		_ = db.QueryRowContext(ctx, `SELECT retried_as FROM asyncx_tasks WHERE id = ?`, id).Scan(&v)
+		if v.Valid {
+			t.Errorf("%s must not match the filter", id)
+		}
+	}
+
+	if n, err := client.RetryFailed(ctx, f); err != nil || n != 0 {
+		t.Fatalf("second RetryFailed = %d, %v; records are retried once", n, err)
+	}
+	if n, err := client.RetryFailed(ctx, RetryFilter{ErrorContains: "550_mailbox"}); err != nil || n != 0 {
+		t.Fatalf("underscore must not be a wildcard: %d, %v", n, err)
+	}
+	if n, err := client.RetryFailed(ctx, RetryFilter{ErrorContains: "550 mailbox"}); err != nil || n != 1 {
+		t.Fatalf("RetryFailed by error = %d, %v", n, err)
+	}
+}
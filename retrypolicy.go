@@ -0,0 +1,113 @@
+package asyncx
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// RetryPolicy overrides asynq's retry behaviour for one task type; see
+// ProcessorConfig.RetryPolicies.
+type RetryPolicy struct {
+	// MaxRetries, if positive, stops retrying after this many retries even if
+	// the task was enqueued with a higher asynq.MaxRetry. It cannot raise it.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry; each further retry
+	// doubles it. Zero keeps asynq's default backoff.
+	BaseDelay time.Duration
+	// MaxDelay, if positive, caps the computed delay.
+	MaxDelay time.Duration
+	// Jitter randomises each delay by up to ±Jitter (a fraction, e.g. 0.2).
+	Jitter float64
+	// Retryable, if set, classifies errors; errors it rejects are treated as
+	// NonRetryable.
+	Retryable func(error) bool
+}
+
+// Delay returns the backoff before retry number n+1, where n is the number
+// of retries done so far.
+func (p RetryPolicy) Delay(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 0; i < n && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// retryAfterError carries the delay chosen by the lifecycle middleware to
+// retryDelay, so the delay asynq applies is the one recorded in next_retry_at.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// applyRetryPolicy decides whether a failed task will be retried and when.
+// It returns the error to hand back to asynq, wrapped with asynq.SkipRetry
+// when the policy gives up, and the retry delay if a retry will happen.
+func applyRetryPolicy(policy *RetryPolicy, ev TaskEvent, t *asynq.Task, err error) (error, time.Duration, bool) {
+	if policy != nil {
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			return NonRetryable(err), 0, false
+		}
+		if policy.MaxRetries > 0 && ev.Retried >= policy.MaxRetries {
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry), 0, false
+		}
+	}
+	if !willRetry(ev, err) {
+		return err, 0, false
+	}
+	var after time.Duration
+	if policy != nil && policy.BaseDelay > 0 {
+		after = policy.Delay(ev.Retried)
+	} else {
+		after = asynq.DefaultRetryDelayFunc(ev.Retried, err, t)
+	}
+	return &retryAfterError{err: err, after: after}, after, true
+}
+
+// isFailure reports whether asynq should count err as a failed attempt.
+// Tasks put back by the Processor itself are not.
+func isFailure(err error) bool {
+	return !isThrottled(err) && !isDeferred(err) && !isNotHandled(err) && !isCircuitOpen(err) && !isDependencyDown(err)
+}
+
+// retryDelay honours ThrottledError.RetryAfter and delays chosen by
+// applyRetryPolicy, and otherwise defers to asynq.
+func retryDelay(n int, e error, t *asynq.Task) time.Duration {
+	var te *ThrottledError
+	if errors.As(e, &te) && te.RetryAfter > 0 {
+		return te.RetryAfter
+	}
+	var ce *CircuitOpenError
+	if errors.As(e, &ce) {
+		return ce.RetryAfter
+	}
+	var dd *DependencyDownError
+	if errors.As(e, &dd) {
+		return dd.RetryAfter
+	}
+	var de *DeferredError
+	if errors.As(e, &de) {
+		return max(time.Until(de.Until), time.Second)
+	}
+	if isNotHandled(e) {
+		return notHandledDelay
+	}
+	var re *retryAfterError
+	if errors.As(e, &re) {
+		return re.after
+	}
+	return asynq.DefaultRetryDelayFunc(n, e, t)
+}
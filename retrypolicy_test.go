@@ -0,0 +1,102 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for n, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := p.Delay(n); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", n, got, want)
+		}
+	}
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if d := p.Delay(0); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("jittered delay %v out of range", d)
+		}
+	}
+}
+
+func TestApplyRetryPolicy(t *testing.T) {
+	task := asynq.NewTask("rp:test", nil)
+	boom := errors.New("boom")
+	ev := TaskEvent{Retried: 1, MaxRetry: 5}
+
+	err, after, retrying := applyRetryPolicy(nil, ev, task, boom)
+	if !retrying || after <= 0 || retryDelay(1, err, task) != after || !errors.Is(err, boom) {
+		t.Fatalf("default policy: err=%v after=%v retrying=%v", err, after, retrying)
+	}
+	policy := &RetryPolicy{BaseDelay: time.Minute, MaxRetries: 3}
+	if _, after, _ := applyRetryPolicy(policy, ev, task, boom); after != 2*time.Minute {
+		t.Fatalf("want 2m backoff, got %v", after)
+	}
+	if err, _, retrying := applyRetryPolicy(policy, TaskEvent{Retried: 3, MaxRetry: 5}, task, boom); retrying || !errors.Is(err, asynq.SkipRetry) {
+		t.Fatalf("MaxRetries should stop retrying: %v", err)
+	}
+	policy.Retryable = func(err error) bool { return !errors.Is(err, boom) }
+	if err, _, retrying := applyRetryPolicy(policy, ev, task, boom); retrying || !IsNonRetryable(err) {
+		t.Fatalf("non-retryable error should skip retry: %v", err)
+	}
+}
+
+func TestProcessor_RetryPolicyRecordsNextRetry(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	errPermanent := errors.New("bad input")
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, RetryPolicies: map[string]RetryPolicy{
+		"rp:flaky": {BaseDelay: time.Hour, Retryable: func(err error) bool { return !errors.Is(err, errPermanent) }},
+	}})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("rp:flaky", func(ctx context.Context, tsk *asynq.Task) error {
+		if string(tsk.Payload()) == `"permanent"` {
+			return errPermanent
+		}
+		return errors.New("timeout")
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	transient, err := client.Enqueue(ctx, "rp:flaky", "transient")
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	permanent, err := client.Enqueue(ctx, "rp:flaky", "permanent")
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(ctx, transient.ID)
+		return err == nil && rec.NextRetryAt != nil, nil
+	}); err != nil {
+		t.Fatalf("next_retry_at not recorded: %v", err)
+	}
+	rec, _ := store.GetByID(ctx, transient.ID)
+	if d := time.Until(*rec.NextRetryAt); d < 50*time.Minute || d > 70*time.Minute {
+		t.Fatalf("next_retry_at should follow the policy backoff, got %v from now", d)
+	}
+	if rec.FinishedAt != nil || rec.ErrorMsg == nil || *rec.ErrorMsg != "timeout" {
+		t.Fatalf("unexpected retrying record: %#v", rec)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	if _, err := client.WaitForResult(waitCtx, permanent.ID, 20*time.Millisecond); !errors.Is(err, ErrTaskFailed) {
+		t.Fatalf("non-retryable failure should be final, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package asyncx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RouteRule sends matching tasks to Queue. A rule matches a task type by
+// exactly one of Type, Prefix or Pattern (a regular expression matched
+// against the whole type), or any type if all three are empty; Metadata,
+// if set, must also be a subset of the task's metadata.
+type RouteRule struct {
+	Type     string
+	Prefix   string
+	Pattern  string
+	Metadata map[string]string
+	Queue    string
+
+	re *regexp.Regexp
+}
+
+func (r RouteRule) matches(taskType string, metadata map[string]string) bool {
+	switch {
+	case r.Type != "" && taskType != r.Type:
+		return false
+	case r.Prefix != "" && !strings.HasPrefix(taskType, r.Prefix):
+		return false
+	case r.re != nil && !r.re.MatchString(taskType):
+		return false
+	}
+	for k, v := range r.Metadata {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Router chooses the queue of each enqueued task from an ordered list of
+// rules, the first match winning, so that routing lives in one place
+// instead of in asynq.Queue options at every call site. A Client configured
+// with it routes every task not given an explicit asynq.Queue option.
+// Rules may be added while in use; asynq creates queues on first enqueue,
+// so processors only need Queues to list them. It is safe for concurrent
+// use.
+type Router struct {
+	mu    sync.RWMutex
+	rules []RouteRule
+}
+
+// NewRouter panics if a rule is invalid.
+func NewRouter(rules ...RouteRule) *Router {
+	r := &Router{}
+	for _, rule := range rules {
+		if err := r.Add(rule); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Add appends a rule, evaluated after the existing ones.
+func (r *Router) Add(rule RouteRule) error {
+	if rule.Queue == "" {
+		return fmt.Errorf("asyncx: route rule has no queue")
+	}
+	set := 0
+	for _, s := range []string{rule.Type, rule.Prefix, rule.Pattern} {
+		if s != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("asyncx: route rule to %q sets more than one of Type, Prefix and Pattern", rule.Queue)
+	}
+	if rule.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + rule.Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("asyncx: route rule to %q: %w", rule.Queue, err)
+		}
+		rule.re = re
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append(r.rules, rule)
+	return nil
+}
+
+// Route returns the queue of the first rule matching taskType and
+// metadata, and false if none does.
+func (r *Router) Route(taskType string, metadata map[string]string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rule := range r.rules {
+		if rule.matches(taskType, metadata) {
+			return rule.Queue, true
+		}
+	}
+	return "", false
+}
+
+// Queues returns the distinct queues the rules route to, in rule order,
+// for a Processor's Queues.
+func (r *Router) Queues() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []string
+	seen := make(map[string]bool)
+	for _, rule := range r.rules {
+		if !seen[rule.Queue] {
+			seen[rule.Queue] = true
+			out = append(out, rule.Queue)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestClient_Router(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	router := NewRouter(
+		RouteRule{Metadata: map[string]string{"tier": "gold"}, Queue: "vip"},
+		RouteRule{Type: "email:send", Queue: "emails"},
+		RouteRule{Prefix: "report:", Queue: "reports"},
+	)
+	if err := router.Add(RouteRule{Pattern: `image:(resize|crop)`, Queue: "images"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := router.Add(RouteRule{Type: "a", Prefix: "b", Queue: "c"}); err == nil {
+		t.Fatal("Add should reject a rule with two matchers")
+	}
+	if err := router.Add(RouteRule{Pattern: "(", Queue: "c"}); err == nil {
+		t.Fatal("Add should reject a bad pattern")
+	}
+
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{
+		Router:       router,
+		TaskDefaults: map[string]TaskDefaults{"image:blur": {Queue: "slow"}, "image:crop": {Queue: "slow"}},
+	})
+	defer client.Close()
+	for _, tc := range []struct {
+		taskType string
+		options  []asynq.Option
+		want     string
+	}{
+		{"email:send", nil, "emails"},
+		{"report:monthly", nil, "reports"},
+		{"image:crop", nil, "images"},
+		{"image:blur", nil, "slow"},
+		{"image:cropped", nil, DefaultQueue},
+		{"email:send", []asynq.Option{WithMetadata(map[string]string{"tier": "gold"})}, "vip"},
+		{"email:send", []asynq.Option{asynq.Queue("urgent")}, "urgent"},
+	} {
+		info, err := client.Enqueue(ctx, tc.taskType, nil, tc.options...)
+		if err != nil || info.Queue != tc.want {
+			t.Errorf("%s: queue %v %v, want %q", tc.taskType, info, err, tc.want)
+			continue
+		}
+		if rec, err := store.GetByID(ctx, info.ID); err != nil || rec.Queue != tc.want {
+			t.Errorf("%s: record %+v %v", tc.taskType, rec, err)
+		}
+	}
+	if got := router.Queues(); len(got) != 4 || got[0] != "vip" || got[3] != "images" {
+		t.Fatalf("Queues = %v", got)
+	}
+}
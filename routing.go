@@ -0,0 +1,226 @@
+package asyncx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/redis/go-redis/v9"
+)
+
+// NotHandledError is returned for a task whose type this Processor does not
+// handle because of ProcessorConfig.HandleOnly or Exclude. The task is put
+// back shortly without counting as a failure, so that a replica that handles
+// it picks it up; see putBack.
+type NotHandledError struct {
+	Type string
+}
+
+func (e *NotHandledError) Error() string {
+	return fmt.Sprintf("task type %s not handled by this processor", e.Type)
+}
+
+func isNotHandled(err error) bool {
+	var ne *NotHandledError
+	return errors.As(err, &ne)
+}
+
+// notHandledDelay is how long a task not handled here waits before it is
+// offered again.
+const notHandledDelay = time.Second
+
+// typeMatches reports whether taskType matches one of patterns. A pattern is
+// a task type, or a prefix followed by "*".
+func typeMatches(patterns []string, taskType string) bool {
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(taskType, prefix) {
+				return true
+			}
+		} else if p == taskType {
+			return true
+		}
+	}
+	return false
+}
+
+// handles reports whether p runs tasks of taskType.
+func (p *Processor) handles(taskType string) bool {
+	if len(p.handleOnly) > 0 && !typeMatches(p.handleOnly, taskType) {
+		return false
+	}
+	return !typeMatches(p.exclude, taskType)
+}
+
+// unroutable reports whether t goes to ProcessorConfig.NotFoundHandler
+// because no handler on the served mux matches its type.
+func (p *Processor) unroutable(t *asynq.Task) bool {
+	if p.notFound == nil || p.routes == nil {
+		return false
+	}
+	_, pattern := p.routes.Handler(t)
+	return pattern == ""
+}
+
+// validateRouting checks HandleOnly and Exclude against the handlers
+// registered on mux: every type named in HandleOnly needs a handler and must
+// not also be excluded.
+func (p *Processor) validateRouting(mux *asynq.ServeMux) error {
+	for _, typ := range p.handleOnly {
+		if strings.HasSuffix(typ, "*") {
+			continue
+		}
+		if typeMatches(p.exclude, typ) {
+			return fmt.Errorf("asyncx: task type %s is both in HandleOnly and excluded", typ)
+		}
+		if _, pattern := mux.Handler(asynq.NewTask(typ, nil)); pattern == "" {
+			return fmt.Errorf("asyncx: no handler registered for HandleOnly task type %s", typ)
+		}
+	}
+	return nil
+}
+
+// workerKeyPrefix prefixes the Redis keys of the worker registry.
+const workerKeyPrefix = "asyncx:workers:"
+
+// workerTTL is how long a registry entry outlives its last refresh.
+const workerTTL = 30 * time.Second
+
+// WorkerInfo describes a running Processor in the worker registry, including
+// its effective task routing.
+type WorkerInfo struct {
+	ID         string    `json:"id"`
+	Host       string    `json:"host"`
+	PID        int       `json:"pid"`
+	Queues     []string  `json:"queues"`
+	HandleOnly []string  `json:"handle_only,omitempty"`
+	Exclude    []string  `json:"exclude,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// newWorkerID returns an ID of the form host:pid:random.
+func newWorkerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// register records p in the worker registry and refreshes the entry until
+// the returned stop func is called, which removes it.
+func (p *Processor) register() (stop func()) {
+	info := WorkerInfo{
+		ID:         p.worker.ID,
+		Host:       p.worker.Host,
+		PID:        p.worker.PID,
+		Queues:     p.queues,
+		HandleOnly: p.handleOnly,
+		Exclude:    p.exclude,
+		StartedAt:  time.Now().UTC(),
+	}
+	data, _ := json.Marshal(info)
+	key := workerKeyPrefix + p.worker.ID
+	_ = p.redis().Set(context.Background(), key, data, workerTTL).Err()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(workerTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				_ = p.redis().Del(context.Background(), key).Err()
+				return
+			case <-ticker.C:
+				_ = p.redis().Set(context.Background(), key, data, workerTTL).Err()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// startRegistry registers p if it is listed in the registry, unless it has
+// already been shut down.
+func (p *Processor) startRegistry() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.listed && !p.stopped && p.unregister == nil {
+		p.unregister = p.register()
+	}
+}
+
+// stopRegistry removes p from the registry.
+func (p *Processor) stopRegistry() {
+	p.mu.Lock()
+	unregister := p.unregister
+	p.unregister, p.stopped = nil, true
+	p.mu.Unlock()
+	if unregister != nil {
+		unregister()
+	}
+}
+
+// queueNames returns the sorted names of every queue a processor serves.
+func queueNames(shared map[string]int, limits map[string]QueueLimit) []string {
+	var names []string
+	for name := range shared {
+		names = append(names, name)
+	}
+	for name, l := range limits {
+		if l.MaxConcurrency > 0 {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Workers lists the running Processors recorded in the worker registry,
+// sorted by ID; see ProcessorConfig.WorkerRegistry.
+func (p *Processor) Workers(ctx context.Context) ([]WorkerInfo, error) {
+	var mu sync.Mutex
+	var workers []WorkerInfo
+	scan := func(ctx context.Context, rdb redis.UniversalClient) error {
+		iter := rdb.Scan(ctx, 0, workerKeyPrefix+"*", 100).Iterator()
+		for iter.Next(ctx) {
+			data, err := rdb.Get(ctx, iter.Val()).Bytes()
+			if err != nil {
+				// Expired between SCAN and GET.
+				continue
+			}
+			var w WorkerInfo
+			if err := json.Unmarshal(data, &w); err != nil {
+				return err
+			}
+			mu.Lock()
+			workers = append(workers, w)
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+	var err error
+	if cc, ok := clusterClient(p.redis()); ok {
+		// SCAN covers one node; entries are spread over every master.
+		err = cc.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error { return scan(ctx, c) })
+	} else {
+		err = scan(ctx, p.redis())
+	}
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(workers, func(a, b WorkerInfo) int { return strings.Compare(a.ID, b.ID) })
+	return workers, nil
+}
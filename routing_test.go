@@ -0,0 +1,155 @@
+package asyncx
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_HandleOnlyRequiresHandler(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, nil, ProcessorConfig{HandleOnly: []string{"video:encode"}})
+	defer processor.Shutdown()
+	if err := processor.Run(context.Background(), asynq.NewServeMux()); err == nil {
+		t.Fatalf("Run should fail without a handler for video:encode")
+	}
+
+	both := NewProcessor(redis, nil, ProcessorConfig{HandleOnly: []string{"video:encode"}, Exclude: []string{"video:*"}})
+	defer both.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("video:encode", func(ctx context.Context, tsk *asynq.Task) error { return nil })
+	if err := both.Run(context.Background(), mux); err == nil {
+		t.Fatalf("Run should fail for a type both handled only and excluded")
+	}
+}
+
+func TestProcessor_RoutesTaskTypes(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	ranOn := map[string]string{}
+	start := func(name string, cfg ProcessorConfig) *Processor {
+		cfg.Concurrency = 1
+		p := NewProcessor(redis, nil, cfg)
+		mux := asynq.NewServeMux()
+		for _, typ := range []string{"video:encode", "email:send"} {
+			mux.HandleFunc(typ, func(ctx context.Context, tsk *asynq.Task) error {
+				mu.Lock()
+				ranOn[tsk.Type()] = name
+				mu.Unlock()
+				return nil
+			})
+		}
+		go func() { _ = p.Start(mux) }()
+		return p
+	}
+	heavy := start("heavy", ProcessorConfig{HandleOnly: []string{"video:*"}})
+	defer heavy.Shutdown()
+	light := start("light", ProcessorConfig{Exclude: []string{"video:*"}})
+	defer light.Shutdown()
+
+	client := NewClient(redis, nil, ClientOptions{})
+	defer client.Close()
+	for _, typ := range []string{"video:encode", "email:send"} {
+		if _, err := client.Enqueue(ctx, typ, nil, asynq.MaxRetry(5)); err != nil {
+			t.Fatalf("Enqueue %s: %v", typ, err)
+		}
+	}
+	err := pollUntil(t, 10*time.Second, func() (bool, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ranOn) == 2, nil
+	})
+	if err != nil {
+		t.Fatalf("tasks did not run: %v", ranOn)
+	}
+	if ranOn["video:encode"] != "heavy" || ranOn["email:send"] != "light" {
+		t.Fatalf("unexpected routing: %v", ranOn)
+	}
+
+	workers, err := light.Workers(ctx)
+	if err != nil {
+		t.Fatalf("Workers: %v", err)
+	}
+	if len(workers) != 2 {
+		t.Fatalf("want 2 registered workers, got %+v", workers)
+	}
+	for _, w := range workers {
+		if w.ID == heavy.worker.ID && !slices.Equal(w.HandleOnly, []string{"video:*"}) {
+			t.Errorf("heavy worker routing not recorded: %+v", w)
+		}
+		if w.ID == light.worker.ID && !slices.Equal(w.Exclude, []string{"video:*"}) {
+			t.Errorf("light worker routing not recorded: %+v", w)
+		}
+	}
+
+	heavy.Shutdown()
+	if workers, _ := light.Workers(ctx); len(workers) != 1 {
+		t.Fatalf("shut down worker still registered: %+v", workers)
+	}
+}
+
+func TestProcessor_NotHandledWithoutRetriesIsRescheduled(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	ctx := context.Background()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	light := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, Exclude: []string{"video:*"}})
+	defer light.Shutdown()
+	lightMux := asynq.NewServeMux()
+	lightMux.HandleFunc("video:encode", func(ctx context.Context, tsk *asynq.Task) error { return nil })
+	go func() { _ = light.Start(lightMux) }()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	info, err := client.Enqueue(ctx, "video:encode", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	err = pollUntil(t, 10*time.Second, func() (bool, error) {
+		ti, err := light.inspector.GetTaskInfo(info.Queue, info.ID)
+		return err == nil && ti.State == asynq.TaskStateScheduled, nil
+	})
+	if err != nil {
+		t.Fatalf("task was not rescheduled: %v", err)
+	}
+	if archived, _ := light.inspector.ListArchivedTasks(info.Queue); len(archived) != 0 {
+		t.Fatalf("task archived: %+v", archived)
+	}
+
+	ran := make(chan struct{}, 1)
+	heavy := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, HandleOnly: []string{"video:*"}})
+	defer heavy.Shutdown()
+	heavyMux := asynq.NewServeMux()
+	heavyMux.HandleFunc("video:encode", func(ctx context.Context, tsk *asynq.Task) error {
+		ran <- struct{}{}
+		return nil
+	})
+	light.Shutdown()
+	go func() { _ = heavy.Start(heavyMux) }()
+	select {
+	case <-ran:
+	case <-time.After(10 * time.Second):
+		t.Fatal("rescheduled task did not run on the dedicated processor")
+	}
+	err = pollUntil(t, 5*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(ctx, info.ID)
+		return err == nil && rec.Status == StatusCompleted, err
+	})
+	if err != nil {
+		t.Fatalf("record not completed: %v", err)
+	}
+}
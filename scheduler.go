@@ -0,0 +1,334 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule computes the occurrences of a recurring task. Next returns the
+// first occurrence after the given time, or the zero time if there is none.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+// Cron parses a standard five-field cron expression, optionally prefixed
+// with "CRON_TZ=<zone> " to evaluate it in that time zone.
+func Cron(expr string) (Schedule, error) {
+	return cron.ParseStandard(expr)
+}
+
+// SchedulerEntry is a task enqueued on every occurrence of Schedule.
+type SchedulerEntry struct {
+	// Name identifies the entry in recorded occurrences. It must be unique.
+	Name     string
+	Schedule Schedule
+	Type     string
+	Payload  any
+	Options  []asynq.Option
+	// MissedRuns decides what happens to occurrences that passed while no
+	// Scheduler was running. Defaults to MissedSkip.
+	MissedRuns MissedRunPolicy
+}
+
+// MissedRunPolicy selects how a SchedulerEntry catches up on missed
+// occurrences, e.g. after downtime. Catching up across restarts needs a
+// store implementing OccurrenceStore, which also flags the late runs as
+// backfills; those tasks carry "backfill" and "scheduled_for" metadata.
+type MissedRunPolicy string
+
+const (
+	// MissedSkip runs the first missed occurrence only when the Scheduler
+	// was delayed while running, and none after a restart.
+	MissedSkip MissedRunPolicy = ""
+	// MissedRunOnce runs the latest missed occurrence once.
+	MissedRunOnce MissedRunPolicy = "run_once"
+	// MissedRunAll runs every missed occurrence, up to
+	// SchedulerConfig.MaxBackfill of the latest ones.
+	MissedRunAll MissedRunPolicy = "run_all"
+)
+
+// WorkflowEntry starts a new run of Workflow on every occurrence of
+// Schedule through an Orchestrator.
+type WorkflowEntry struct {
+	// Name identifies the entry and its runs. It must be unique among all
+	// the Scheduler's entries.
+	Name     string
+	Schedule Schedule
+	Workflow Workflow
+}
+
+type SchedulerConfig struct {
+	// Interval between checks for due entries. Defaults to one second.
+	Interval time.Duration
+	// Leader, if set, limits enqueueing to the elected replica, e.g. a
+	// LeaderElector shared by every replica. Followers still advance their
+	// entries, so a new leader does not fire occurrences it did not own.
+	Leader Leader
+	// MaxBackfill caps the occurrences an entry with MissedRunAll runs at
+	// once. Defaults to 100.
+	MaxBackfill int
+}
+
+// Scheduler enqueues registered entries through a Client as they come due.
+// Each enqueue is recorded as a ScheduleOccurrence when the Client's store
+// implements OccurrenceStore.
+type Scheduler struct {
+	client *Client
+	cfg    SchedulerConfig
+
+	mu      sync.Mutex
+	entries []*scheduled
+}
+
+type scheduled struct {
+	SchedulerEntry
+	next time.Time
+	// resumed is set once next was moved back to the first occurrence
+	// missed since the last recorded one.
+	resumed bool
+	// orch and workflow are set for workflow entries.
+	orch     *Orchestrator
+	workflow *Workflow
+}
+
+func NewScheduler(client *Client, cfg SchedulerConfig) *Scheduler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.MaxBackfill <= 0 {
+		cfg.MaxBackfill = 100
+	}
+	return &Scheduler{client: client, cfg: cfg}
+}
+
+// Register adds e; its first occurrence is the next one from now.
+func (s *Scheduler) Register(e SchedulerEntry) error {
+	if e.Name == "" || e.Type == "" || e.Schedule == nil {
+		return errors.New("asyncx: scheduler entry needs a Name, Type and Schedule")
+	}
+	return s.add(&scheduled{SchedulerEntry: e})
+}
+
+// RegisterWorkflow adds a workflow entry run by orch; its first occurrence
+// is the next one from now. Each occurrence starts one workflow numbered
+// after the entry's previous runs (WorkflowState.RunNumber). Occurrences
+// are unique in asyncx_workflows, so replicas running the same entry start
+// each run once.
+func (s *Scheduler) RegisterWorkflow(orch *Orchestrator, e WorkflowEntry) error {
+	if e.Name == "" || e.Schedule == nil || orch == nil {
+		return errors.New("asyncx: workflow entry needs a Name, Schedule and Orchestrator")
+	}
+	if err := e.Workflow.validate(); err != nil {
+		return err
+	}
+	return s.add(&scheduled{SchedulerEntry: SchedulerEntry{Name: e.Name, Schedule: e.Schedule}, orch: orch, workflow: &e.Workflow})
+}
+
+func (s *Scheduler) add(e *scheduled) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, x := range s.entries {
+		if x.Name == e.Name {
+			return fmt.Errorf("asyncx: scheduler entry %q already registered", e.Name)
+		}
+	}
+	e.next = e.Schedule.Next(time.Now())
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// Run enqueues due entries every Interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = s.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce enqueues every entry whose next occurrence has passed. Occurrences
+// missed while the Scheduler was not running are handled by each entry's
+// MissedRuns policy.
+func (s *Scheduler) RunOnce(ctx context.Context) error {
+	return s.runDue(ctx, time.Now())
+}
+
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	leader := leads(s.cfg.Leader)
+	for _, e := range s.entries {
+		if leader && !e.resumed {
+			if err := s.resume(ctx, e, now); err != nil {
+				errs = append(errs, fmt.Errorf("scheduler entry %q: %w", e.Name, err))
+			}
+		}
+		if e.next.IsZero() || e.next.After(now) {
+			continue
+		}
+		if !leader {
+			e.next = e.Schedule.Next(now)
+			continue
+		}
+		for _, r := range s.dueRuns(e, now) {
+			if err := s.fire(ctx, e, r.at, r.backfill, now); err != nil {
+				errs = append(errs, fmt.Errorf("scheduler entry %q: %w", e.Name, err))
+			}
+		}
+		e.next = e.Schedule.Next(now)
+	}
+	return errors.Join(errs...)
+}
+
+// resume moves the next occurrence of a task entry that catches up on
+// missed runs back to the first one after its last recorded occurrence.
+func (s *Scheduler) resume(ctx context.Context, e *scheduled, now time.Time) error {
+	occ, ok := storeAs[OccurrenceStore](s.client.store)
+	if !ok || e.MissedRuns == MissedSkip || e.workflow != nil {
+		e.resumed = true
+		return nil
+	}
+	last, err := occ.Occurrences(ctx, e.Name, 1)
+	if err != nil {
+		return err
+	}
+	e.resumed = true
+	if len(last) == 1 {
+		if next := e.Schedule.Next(last[0].ScheduledFor); !next.IsZero() && next.Before(e.next) {
+			e.next = next
+		}
+	}
+	return nil
+}
+
+// run is one occurrence to enqueue.
+type run struct {
+	at       time.Time
+	backfill bool
+}
+
+// dueRuns returns the occurrences of e to run at now under its MissedRuns
+// policy, oldest first.
+func (s *Scheduler) dueRuns(e *scheduled, now time.Time) []run {
+	var due []time.Time
+	for t := e.next; !t.IsZero() && !t.After(now); t = e.Schedule.Next(t) {
+		due = append(due, t)
+		if len(due) > s.cfg.MaxBackfill {
+			due = due[1:]
+		}
+	}
+	switch e.MissedRuns {
+	case MissedRunAll:
+		runs := make([]run, len(due))
+		for i, t := range due {
+			runs[i] = run{at: t, backfill: i < len(due)-1}
+		}
+		return runs
+	case MissedRunOnce:
+		return []run{{at: due[len(due)-1], backfill: len(due) > 1}}
+	}
+	return []run{{at: e.next}}
+}
+
+func (s *Scheduler) fire(ctx context.Context, e *scheduled, at time.Time, backfill bool, now time.Time) error {
+	if e.workflow != nil {
+		_, err := e.orch.submit(ctx, *e.workflow, e.Name, at)
+		if errors.Is(err, errDuplicateRun) {
+			return nil
+		}
+		return err
+	}
+	opts := e.Options
+	if backfill {
+		opts = append(slices.Clip(opts), WithMetadata(map[string]string{"backfill": "true", "scheduled_for": at.UTC().Format(time.RFC3339)}))
+	}
+	info, err := s.client.Enqueue(ctx, e.Type, e.Payload, opts...)
+	if err != nil {
+		return err
+	}
+	if occ, ok := storeAs[OccurrenceStore](s.client.store); ok {
+		return occ.RecordOccurrence(ctx, ScheduleOccurrence{Entry: e.Name, ScheduledFor: at, TaskID: info.ID, EnqueuedAt: now.UTC(), Backfill: backfill})
+	}
+	return nil
+}
+
+// ScheduleOccurrence records one enqueue by the Scheduler.
+type ScheduleOccurrence struct {
+	Entry        string
+	ScheduledFor time.Time
+	TaskID       string
+	EnqueuedAt   time.Time
+	// Backfill is set for occurrences run late under a MissedRunPolicy.
+	Backfill bool
+}
+
+// OccurrenceStore is implemented by stores that keep the Scheduler's
+// occurrences for audit.
+type OccurrenceStore interface {
+	RecordOccurrence(ctx context.Context, o ScheduleOccurrence) error
+	// Occurrences returns the occurrences of entry, newest first. A limit
+	// <= 0 returns all of them.
+	Occurrences(ctx context.Context, entry string, limit int) ([]ScheduleOccurrence, error)
+}
+
+func (s *SQLStore) RecordOccurrence(ctx context.Context, o ScheduleOccurrence) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	backfill := 0
+	if o.Backfill {
+		backfill = 1
+	}
+	q := `INSERT INTO asyncx_schedule_occurrences (entry, scheduled_for, task_id, enqueued_at, backfill) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, q, o.Entry, o.ScheduledFor.UTC(), o.TaskID, o.EnqueuedAt.UTC(), backfill)
+	if err != nil {
+		qpg := `INSERT INTO asyncx_schedule_occurrences (entry, scheduled_for, task_id, enqueued_at, backfill) VALUES ($1, $2, $3, $4, $5)`
+		_, err2 := s.db.ExecContext(ctx, qpg, o.Entry, o.ScheduledFor.UTC(), o.TaskID, o.EnqueuedAt.UTC(), backfill)
+		return err2
+	}
+	return nil
+}
+
+func (s *SQLStore) Occurrences(ctx context.Context, entry string, limit int) ([]ScheduleOccurrence, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	q := `SELECT entry, scheduled_for, task_id, enqueued_at, backfill FROM asyncx_schedule_occurrences WHERE entry = ? ORDER BY scheduled_for DESC`
+	qpg := `SELECT entry, scheduled_for, task_id, enqueued_at, backfill FROM asyncx_schedule_occurrences WHERE entry = $1 ORDER BY scheduled_for DESC`
+	args := []any{entry}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		qpg += ` LIMIT $2`
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		rows, err = s.db.QueryContext(ctx, qpg, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer rows.Close()
+	var out []ScheduleOccurrence
+	for rows.Next() {
+		var o ScheduleOccurrence
+		if err := rows.Scan(&o.Entry, &o.ScheduledFor, &o.TaskID, &o.EnqueuedAt, &o.Backfill); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,208 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestScheduler_EnqueuesDueEntriesAndRecordsOccurrences(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_scheduler_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+
+	hourly, err := Cron("0 * * * *")
+	if err != nil {
+		t.Fatalf("Cron: %v", err)
+	}
+	sched := NewScheduler(client, SchedulerConfig{})
+	if err := sched.Register(SchedulerEntry{Name: "report", Schedule: hourly, Type: "report:build"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := sched.Register(SchedulerEntry{Name: "report", Schedule: hourly, Type: "report:build"}); err == nil {
+		t.Fatal("duplicate entry names must be rejected")
+	}
+
+	if err := sched.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if occ, _ := store.Occurrences(ctx, "report", 0); len(occ) != 0 {
+		t.Fatalf("nothing is due yet, got %+v", occ)
+	}
+
+	due := sched.entries[0].next
+	if err := sched.runDue(ctx, due.Add(time.Second)); err != nil {
+		t.Fatalf("runDue: %v", err)
+	}
+	occ, err := store.Occurrences(ctx, "report", 0)
+	if err != nil {
+		t.Fatalf("Occurrences: %v", err)
+	}
+	if len(occ) != 1 || !occ[0].ScheduledFor.Equal(due) {
+		t.Fatalf("want one occurrence at %v, got %+v", due, occ)
+	}
+	rec, err := store.GetByID(ctx, occ[0].TaskID)
+	if err != nil || rec.Type != "report:build" {
+		t.Fatalf("occurrence task not persisted: %+v %v", rec, err)
+	}
+	if next := sched.entries[0].next; !next.Equal(due.Add(time.Hour)) {
+		t.Fatalf("next occurrence: got %v, want %v", next, due.Add(time.Hour))
+	}
+}
+
+func TestScheduler_WorkflowEntryStartsOneRunPerOccurrence(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_scheduler_workflow_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, NewSQLStore(db), ClientOptions{})
+	defer client.Close()
+	orch := NewOrchestrator(db, DialectSQLite, client, OrchestratorConfig{})
+
+	hourly, err := Cron("0 * * * *")
+	if err != nil {
+		t.Fatalf("Cron: %v", err)
+	}
+	entry := WorkflowEntry{Name: "nightly-etl", Schedule: hourly, Workflow: Workflow{Name: "etl", Nodes: []WorkflowNode{
+		{ID: "extract", Type: "etl:extract"},
+		{ID: "load", Type: "etl:load", DependsOn: []string{"extract"}},
+	}}}
+	// Two replicas registering the same entry.
+	var replicas []*Scheduler
+	for range 2 {
+		sched := NewScheduler(client, SchedulerConfig{})
+		if err := sched.RegisterWorkflow(orch, entry); err != nil {
+			t.Fatalf("RegisterWorkflow: %v", err)
+		}
+		replicas = append(replicas, sched)
+	}
+	if err := replicas[0].Register(SchedulerEntry{Name: "nightly-etl", Schedule: hourly, Type: "x"}); err == nil {
+		t.Fatal("entry names must be unique across task and workflow entries")
+	}
+
+	due := replicas[0].entries[0].next
+	for _, at := range []time.Time{due.Add(time.Second), due.Add(time.Hour + time.Second)} {
+		for _, sched := range replicas {
+			if err := sched.runDue(ctx, at); err != nil {
+				t.Fatalf("runDue: %v", err)
+			}
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id FROM asyncx_workflows WHERE schedule_entry = ? ORDER BY run_number`, "nightly-etl")
+	if err != nil {
+		t.Fatalf("query workflows: %v", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) != 2 {
+		t.Fatalf("want one workflow per occurrence, got %d", len(ids))
+	}
+	for i, id := range ids {
+		wf, err := orch.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if wf.RunNumber != i+1 || wf.ScheduleEntry != "nightly-etl" || wf.Nodes[0].Status != NodeEnqueued {
+			t.Fatalf("unexpected run %d: %+v", i+1, wf)
+		}
+	}
+}
+
+func TestScheduler_MissedRunPolicies(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_scheduler_missed_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+
+	hourly, err := Cron("0 * * * *")
+	if err != nil {
+		t.Fatalf("Cron: %v", err)
+	}
+	// The last recorded occurrences were three hours before the next one, as
+	// if the scheduler had been down for two ticks. The latest one counts as
+	// on time, the one before it as backfill.
+	next := hourly.Next(time.Now())
+	last := next.Add(-3 * time.Hour)
+	for _, name := range []string{"all", "once", "skip"} {
+		if err := store.RecordOccurrence(ctx, ScheduleOccurrence{Entry: name, ScheduledFor: last, TaskID: name + "-0", EnqueuedAt: last}); err != nil {
+			t.Fatalf("RecordOccurrence: %v", err)
+		}
+	}
+	sched := NewScheduler(client, SchedulerConfig{})
+	for name, policy := range map[string]MissedRunPolicy{"all": MissedRunAll, "once": MissedRunOnce, "skip": MissedSkip} {
+		if err := sched.Register(SchedulerEntry{Name: name, Schedule: hourly, Type: "report:build", MissedRuns: policy}); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	if err := sched.runDue(ctx, next.Add(-time.Minute)); err != nil {
+		t.Fatalf("runDue: %v", err)
+	}
+
+	occ, err := store.Occurrences(ctx, "all", 0)
+	if err != nil {
+		t.Fatalf("Occurrences: %v", err)
+	}
+	if len(occ) != 3 || !occ[0].ScheduledFor.Equal(last.Add(2*time.Hour)) || !occ[1].ScheduledFor.Equal(last.Add(time.Hour)) {
+		t.Fatalf("run_all: want both missed occurrences, got %+v", occ)
+	}
+	if occ[0].Backfill || !occ[1].Backfill || occ[2].Backfill {
+		t.Fatalf("run_all: backfill flags %+v", occ)
+	}
+	rec, err := store.GetByID(ctx, occ[1].TaskID)
+	if err != nil || rec.Metadata["backfill"] != "true" || rec.Metadata["scheduled_for"] != last.Add(time.Hour).UTC().Format(time.RFC3339) {
+		t.Fatalf("backfilled task: %+v %v", rec, err)
+	}
+
+	occ, _ = store.Occurrences(ctx, "once", 0)
+	if len(occ) != 2 || !occ[0].ScheduledFor.Equal(last.Add(2*time.Hour)) || !occ[0].Backfill {
+		t.Fatalf("run_once: want the latest missed occurrence, got %+v", occ)
+	}
+	occ, _ = store.Occurrences(ctx, "skip", 0)
+	if len(occ) != 1 {
+		t.Fatalf("skip: want no backfill, got %+v", occ)
+	}
+	for _, e := range sched.entries {
+		if !e.next.Equal(next) {
+			t.Fatalf("entry %q: next %v, want %v", e.Name, e.next, next)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SchemaVersion is the asyncx_tasks schema this library expects: the number
+// of the latest file in migrations/.
+const SchemaVersion = 38
+
+// ErrSchemaOutdated is returned by CheckSchema when the database predates
+// SchemaVersion.
+var ErrSchemaOutdated = errors.New("asyncx: database schema is outdated")
+
+// SchemaChecker is implemented by stores with a versioned schema.
+type SchemaChecker interface {
+	CheckSchema(ctx context.Context) error
+}
+
+// SQLStoreOptions configures NewSQLStore and OpenSQLStore.
+type SQLStoreOptions struct {
+	// CheckSchema makes OpenSQLStore fail if CheckSchema fails, so a
+	// deployment against an old schema stops at startup rather than on the
+	// first write to a missing column.
+	CheckSchema bool
+	// ChecksumKey, if set, makes the store write an HMAC-SHA256 of each
+	// record's id, type, payload and created_at to the checksum column and
+	// verify it on every read, returning ErrChecksumMismatch for records
+	// altered directly in the database. created_at is then stored in
+	// whole seconds.
+	ChecksumKey []byte
+	// ChecksumSince exempts records created before it, typically those
+	// written before ChecksumKey was set, from carrying a checksum. Any
+	// other record without one fails with ErrChecksumMismatch.
+	ChecksumSince time.Time
+	// MaxOpenConns, MaxIdleConns, ConnMaxLifetime and ConnMaxIdleTime tune
+	// the *sql.DB connection pool when non-zero.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// Copy, if set, makes InsertCreatedBatch bulk-load with Postgres COPY.
+	Copy CopyFunc
+	// Dialect selects the SQL functions SearchPayload and RefreshSummaries
+	// use. It is required for those only.
+	Dialect Dialect
+	// History makes every transition append a TaskTransition to
+	// asyncx_task_events, read back with History.
+	History bool
+	// StrictTransitions makes MarkCompleted, MarkFailed, MarkRetry,
+	// MarkTimedOut and the other failure marks only apply to records in a
+	// status the outcome may follow, typically in_progress, and return a
+	// *TransitionError otherwise, so that a late duplicate worker cannot
+	// overwrite a final state. MarkStarted likewise refuses records in a
+	// final status, and the Processor then drops the task without running
+	// its handler. MarkStatus, which records statuses other than outcomes,
+	// is not checked.
+	StrictTransitions bool
+}
+
+// applyPool sets the non-zero pool options on db.
+func (o SQLStoreOptions) applyPool(db *sql.DB) {
+	if db == nil {
+		return
+	}
+	if o.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(o.MaxOpenConns)
+	}
+	if o.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(o.MaxIdleConns)
+	}
+	if o.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(o.ConnMaxLifetime)
+	}
+	if o.ConnMaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(o.ConnMaxIdleTime)
+	}
+}
+
+// CheckSchema reads asyncx_schema_version and fails if it is older than
+// SchemaVersion. A newer schema is accepted.
+func (s *SQLStore) CheckSchema(ctx context.Context) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	var version int
+	if err := s.db.QueryRowContext(ctx, `SELECT MAX(version) FROM asyncx_schema_version`).Scan(&version); err != nil {
+		return fmt.Errorf("asyncx: reading schema version (apply migrations up to %03d or call Migrate): %w", SchemaVersion, err)
+	}
+	if version < SchemaVersion {
+		return fmt.Errorf("%w: database is at version %d, library expects %d; apply the newer migrations or call Migrate", ErrSchemaOutdated, version, SchemaVersion)
+	}
+	return nil
+}
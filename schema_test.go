@@ -0,0 +1,55 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersion_MatchesLatestMigration(t *testing.T) {
+	files, err := fs.Glob(Migrations, "migrations/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("glob migrations: %v", err)
+	}
+	last := strings.TrimPrefix(files[len(files)-1], "migrations/")
+	n, err := strconv.Atoi(last[:3])
+	if err != nil {
+		t.Fatalf("migration %s has no numeric prefix", last)
+	}
+	if n != SchemaVersion {
+		t.Fatalf("SchemaVersion = %d, latest migration is %s", SchemaVersion, last)
+	}
+}
+
+func TestSQLStore_CheckSchema(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_schema_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+
+	if err := store.CheckSchema(ctx); err == nil {
+		t.Fatal("expected error without a schema version table")
+	}
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := store.CheckSchema(ctx); err != nil {
+		t.Fatalf("migrated schema should pass: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE asyncx_schema_version SET version = 9`); err != nil {
+		t.Fatalf("downgrade version: %v", err)
+	}
+	if err := store.CheckSchema(ctx); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("want ErrSchemaOutdated, got %v", err)
+	}
+	if _, err := OpenSQLStore(ctx, db, SQLStoreOptions{CheckSchema: true}); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("OpenSQLStore: want ErrSchemaOutdated, got %v", err)
+	}
+}
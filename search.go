@@ -0,0 +1,160 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PayloadSearchStore is implemented by stores that can find records by a
+// value inside their payload.
+type PayloadSearchStore interface {
+	// SearchPayload returns the records whose payload holds value at path,
+	// oldest first. path is a JSON path of keys and array indexes such as
+	// "$.user_id" or "$.items[0].sku". Strings, numbers and booleans are
+	// compared by their JSON text.
+	SearchPayload(ctx context.Context, path string, value any) ([]*TaskRecord, error)
+}
+
+// jsonPathSegment matches one ".key" or "[index]" step of a JSON path.
+var jsonPathSegment = regexp.MustCompile(`^(?:\.([A-Za-z0-9_-]+)|\[([0-9]+)\])`)
+
+// parseJSONPath splits a path such as "$.items[0].sku" into its keys and
+// indexes.
+func parseJSONPath(path string) ([]string, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok || rest == "" {
+		return nil, fmt.Errorf("asyncx: invalid JSON path %q", path)
+	}
+	var segs []string
+	for rest != "" {
+		m := jsonPathSegment.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("asyncx: invalid JSON path %q", path)
+		}
+		segs = append(segs, m[1]+m[2])
+		rest = rest[len(m[0]):]
+	}
+	return segs, nil
+}
+
+// searchText renders value as the text the SQL JSON functions return for
+// it: strings unquoted, everything else as JSON.
+func searchText(value any) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(value)
+	return string(b), err
+}
+
+func (s *SQLStore) SearchPayload(ctx context.Context, path string, value any) ([]*TaskRecord, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	text, err := searchText(value)
+	if err != nil {
+		return nil, err
+	}
+	var cond string
+	var arg any = path
+	switch s.dialect {
+	case DialectPostgres:
+		cond = `payload_json::jsonb #>> $1 = $2`
+		arg = "{" + strings.Join(segs, ",") + "}"
+	case DialectMySQL:
+		cond = `JSON_UNQUOTE(JSON_EXTRACT(payload_json, ?)) = ?`
+	case DialectSQLite:
+		// SQLite returns booleans as 1 and 0.
+		if b, ok := value.(bool); ok {
+			text = "0"
+			if b {
+				text = "1"
+			}
+		}
+		cond = `CAST(JSON_EXTRACT(payload_json, ?) AS TEXT) = ?`
+	default:
+		return nil, errors.New("asyncx: SearchPayload needs SQLStoreOptions.Dialect")
+	}
+	q := `SELECT ` + taskColumns + ` FROM asyncx_tasks WHERE ` + cond + ` ORDER BY created_at`
+	rows, err := s.db.QueryContext(ctx, q, arg, text)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []*TaskRecord
+	for rows.Next() {
+		rec, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.verify(rec); err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (s *BoltStore) SearchPayload(ctx context.Context, path string, value any) ([]*TaskRecord, error) {
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	text, err := searchText(value)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.List(ctx, TaskFilter{})
+	if err != nil {
+		return nil, err
+	}
+	var out []*TaskRecord
+	for _, rec := range all {
+		if v, ok := payloadAt(rec.PayloadJSON, segs); ok && v == text {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+// payloadAt returns the searchText of the value at segs in payload.
+func payloadAt(payload string, segs []string) (string, bool) {
+	var v any
+	d := json.NewDecoder(strings.NewReader(payload))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return "", false
+	}
+	for _, seg := range segs {
+		switch x := v.(type) {
+		case map[string]any:
+			next, ok := x[seg]
+			if !ok {
+				return "", false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i >= len(x) {
+				return "", false
+			}
+			v = x[i]
+		default:
+			return "", false
+		}
+	}
+	if v == nil {
+		return "", false
+	}
+	text, err := searchText(v)
+	return text, err == nil
+}
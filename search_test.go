@@ -0,0 +1,66 @@
+package asyncx
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSearchPayload(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "search.db"), BoltStoreOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer bolt.Close()
+	ctx := context.Background()
+
+	payloads := map[string]string{
+		"s1": `{"user_id": 123, "email": "a@example.com", "vip": true}`,
+		"s2": `{"user_id": 456, "items": [{"sku": "X-1"}]}`,
+		"s3": `{"user_id": "123"}`,
+		"s4": `null`,
+	}
+	stores := map[string]PayloadSearchStore{"sql": NewSQLStore(db, SQLStoreOptions{Dialect: DialectSQLite}), "bolt": bolt}
+	for name, ps := range stores {
+		store := ps.(Store)
+		now := time.Now().UTC()
+		for _, id := range []string{"s1", "s2", "s3", "s4"} {
+			now = now.Add(time.Second)
+			rec := TaskRecord{ID: id, Type: "user:sync", Queue: "default", PayloadJSON: payloads[id], Status: StatusCreated, CreatedAt: now}
+			if err := store.InsertCreated(ctx, rec); err != nil {
+				t.Fatalf("%s: InsertCreated: %v", name, err)
+			}
+		}
+		for _, tc := range []struct {
+			path  string
+			value any
+			want  []string
+		}{
+			{"$.user_id", 123, []string{"s1", "s3"}},
+			{"$.user_id", "456", []string{"s2"}},
+			{"$.email", "a@example.com", []string{"s1"}},
+			{"$.vip", true, []string{"s1"}},
+			{"$.items[0].sku", "X-1", []string{"s2"}},
+			{"$.missing", "x", nil},
+		} {
+			recs, err := ps.SearchPayload(ctx, tc.path, tc.value)
+			if err != nil {
+				t.Fatalf("%s: SearchPayload(%s): %v", name, tc.path, err)
+			}
+			var got []string
+			for _, r := range recs {
+				got = append(got, r.ID)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("%s: SearchPayload(%s, %v) = %v, want %v", name, tc.path, tc.value, got, tc.want)
+			}
+		}
+		if _, err := ps.SearchPayload(ctx, "user_id'; DROP TABLE x", 1); err == nil {
+			t.Errorf("%s: invalid path must be rejected", name)
+		}
+	}
+}
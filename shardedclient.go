@@ -0,0 +1,140 @@
+package asyncx
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	"github.com/hibiken/asynq"
+)
+
+type ShardedClientOptions struct {
+	// Client configures the Client of every shard.
+	Client ClientOptions
+	// Types and Queues route a task type or queue to a named shard; Types
+	// wins. The queue is the one Enqueue would use, after TaskDefaults,
+	// Router and QueueRenames.
+	Types  map[string]string
+	Queues map[string]string
+	// Default receives tasks neither map routes. If empty, they are spread
+	// across the shards by a hash of their queue.
+	Default string
+}
+
+// ShardedClient enqueues onto one of several Redis instances, each with its
+// own Client, choosing the shard by task type or queue. Records share one
+// store and name their shard in TaskRecord.Shard, so that admin tools can
+// find the broker holding a task with ClientFor. Processors connect to each
+// shard separately.
+//
+// Routes must not move a queue to another shard while it holds tasks, or
+// they will be looked up on the wrong broker.
+type ShardedClient struct {
+	clients map[string]*Client
+	names   []string // sorted, for hashing
+	opts    ShardedClientOptions
+	store   Store
+}
+
+// NewShardedClient panics if shards is empty or a route names an unknown
+// shard.
+func NewShardedClient(shards map[string]asynq.RedisConnOpt, store Store, opts ShardedClientOptions) *ShardedClient {
+	if len(shards) == 0 {
+		panic("asyncx: NewShardedClient: no shards")
+	}
+	s := &ShardedClient{clients: make(map[string]*Client, len(shards)), opts: opts, store: store}
+	for name := range shards {
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+	routes := []string{opts.Default}
+	for _, m := range []map[string]string{opts.Types, opts.Queues} {
+		for _, name := range m {
+			routes = append(routes, name)
+		}
+	}
+	for _, name := range routes {
+		if _, ok := shards[name]; name != "" && !ok {
+			panic(fmt.Sprintf("asyncx: NewShardedClient: unknown shard %q", name))
+		}
+	}
+	for _, name := range s.names {
+		c := NewClient(shards[name], store, opts.Client)
+		c.shard = name
+		s.clients[name] = c
+	}
+	return s
+}
+
+// Shard returns the name of the shard a task of taskType enqueued with
+// options goes to.
+func (s *ShardedClient) Shard(taskType string, options ...asynq.Option) string {
+	if name, ok := s.opts.Types[taskType]; ok {
+		return name
+	}
+	c := s.clients[s.names[0]]
+	options, _ = c.resolve(taskType, options)
+	queue := renamed(c.renames, queueOf(options, c.queue))
+	if name, ok := s.opts.Queues[queue]; ok {
+		return name
+	}
+	if s.opts.Default != "" {
+		return s.opts.Default
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(queue))
+	return s.names[h.Sum32()%uint32(len(s.names))]
+}
+
+// Client returns the Client of the named shard, or nil.
+func (s *ShardedClient) Client(name string) *Client {
+	return s.clients[name]
+}
+
+// Enqueue enqueues through the Client of the task's shard.
+func (s *ShardedClient) Enqueue(ctx context.Context, taskType string, payload any, options ...asynq.Option) (*asynq.TaskInfo, error) {
+	return s.clients[s.Shard(taskType, options...)].Enqueue(ctx, taskType, payload, options...)
+}
+
+// ClientFor returns the Client of the shard holding taskID, as recorded in
+// the store, so that its Redis can be inspected or the task cancelled.
+func (s *ShardedClient) ClientFor(ctx context.Context, taskID string) (*Client, error) {
+	c, _, err := s.lookup(ctx, taskID)
+	return c, err
+}
+
+// TaskInfo returns the state of taskID on its shard's Redis.
+func (s *ShardedClient) TaskInfo(ctx context.Context, taskID string) (*asynq.TaskInfo, error) {
+	c, rec, err := s.lookup(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	return c.inspector.GetTaskInfo(rec.Queue, rec.ID)
+}
+
+func (s *ShardedClient) lookup(ctx context.Context, taskID string) (*Client, *TaskRecord, error) {
+	if s.store == nil {
+		return nil, nil, fmt.Errorf("asyncx: ShardedClient has no store")
+	}
+	rec, err := s.store.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, ok := s.clients[rec.Shard]
+	if !ok {
+		return nil, nil, fmt.Errorf("asyncx: task %s was not enqueued through a known shard (%q)", taskID, rec.Shard)
+	}
+	return c, rec, nil
+}
+
+// Close closes every shard's Client.
+func (s *ShardedClient) Close() error {
+	var first error
+	for _, name := range s.names {
+		if err := s.clients[name].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
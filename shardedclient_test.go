@@ -0,0 +1,67 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestShardedClient(t *testing.T) {
+	ra, rb := startMiniRedis(t), startMiniRedis(t)
+	defer ra.Close()
+	defer rb.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	client := NewShardedClient(map[string]asynq.RedisConnOpt{
+		"a": asynq.RedisClientOpt{Addr: ra.Addr()},
+		"b": asynq.RedisClientOpt{Addr: rb.Addr()},
+	}, store, ShardedClientOptions{
+		Client: ClientOptions{TaskDefaults: map[string]TaskDefaults{"report:build": {Queue: "reports"}}},
+		Types:  map[string]string{"email:send": "b"},
+		Queues: map[string]string{"reports": "a", "mail": "a"},
+	})
+	defer client.Close()
+
+	for _, tc := range []struct {
+		taskType string
+		options  []asynq.Option
+		want     string
+	}{
+		{"email:send", []asynq.Option{asynq.Queue("mail")}, "b"},
+		{"report:build", nil, "a"},
+		{"image:crop", []asynq.Option{asynq.Queue("mail")}, "a"},
+	} {
+		info, err := client.Enqueue(ctx, tc.taskType, nil, tc.options...)
+		if err != nil {
+			t.Fatalf("Enqueue %s: %v", tc.taskType, err)
+		}
+		rec, err := store.GetByID(ctx, info.ID)
+		if err != nil || rec.Shard != tc.want {
+			t.Fatalf("%s: record %+v %v, want shard %q", tc.taskType, rec, err, tc.want)
+		}
+		if got, err := client.TaskInfo(ctx, info.ID); err != nil || got.ID != info.ID {
+			t.Fatalf("%s: TaskInfo %+v %v", tc.taskType, got, err)
+		}
+		other := map[string]string{"a": "b", "b": "a"}[tc.want]
+		if _, err := client.Client(other).inspector.GetTaskInfo(info.Queue, info.ID); err == nil {
+			t.Fatalf("%s: task also found on shard %s", tc.taskType, other)
+		}
+	}
+	if s := client.Shard("image:crop"); s != client.Shard("image:resize") {
+		t.Fatalf("tasks of one queue hashed to shards %s and %s", s, client.Shard("image:resize"))
+	}
+
+	plain := NewClient(asynq.RedisClientOpt{Addr: ra.Addr()}, store, ClientOptions{})
+	defer plain.Close()
+	info, err := plain.Enqueue(ctx, "report:build", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := client.ClientFor(ctx, info.ID); err == nil {
+		t.Fatal("ClientFor should reject a task enqueued outside the shards")
+	}
+}
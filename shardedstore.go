@@ -0,0 +1,191 @@
+package asyncx
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+type ShardedStoreOptions struct {
+	// ShardKey maps a task ID to the key that is hashed to pick a shard.
+	// Defaults to the ID itself. To co-locate a tenant's records, generate IDs
+	// that embed the tenant (e.g. "acme:<uuid>") and return the tenant part.
+	ShardKey func(taskID string) string
+}
+
+// ShardedStore spreads records across several stores (typically SQLStores on
+// separate databases) by a hash of the task ID. Single-record calls go to one
+// shard; List and Stats query every shard concurrently and merge the results.
+//
+// The shard count must not change once records exist, or existing IDs will
+// be routed to the wrong shard.
+type ShardedStore struct {
+	shards []Store
+	key    func(taskID string) string
+}
+
+func NewShardedStore(shards []Store, opts ShardedStoreOptions) *ShardedStore {
+	if len(shards) == 0 {
+		panic("asyncx: NewShardedStore: no shards")
+	}
+	key := opts.ShardKey
+	if key == nil {
+		key = func(id string) string { return id }
+	}
+	return &ShardedStore{shards: shards, key: key}
+}
+
+// ShardFor returns the shard that owns taskID.
+func (s *ShardedStore) ShardFor(taskID string) Store {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s.key(taskID)))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+func (s *ShardedStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
+	return s.ShardFor(rec.ID).InsertCreated(ctx, rec)
+}
+
+func (s *ShardedStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+	return s.ShardFor(taskID).MarkEnqueued(ctx, taskID, queue, enqueuedAt)
+}
+
+func (s *ShardedStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error {
+	return s.ShardFor(taskID).MarkStarted(ctx, taskID, startedAt)
+}
+
+func (s *ShardedStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error {
+	return s.ShardFor(taskID).MarkCompleted(ctx, taskID, resultJSON, finishedAt)
+}
+
+func (s *ShardedStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.ShardFor(taskID).MarkFailed(ctx, taskID, errorMsg, finishedAt)
+}
+
+func (s *ShardedStore) MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return markPermanentFailure(ctx, s.ShardFor(taskID), taskID, errorMsg, finishedAt)
+}
+
+func (s *ShardedStore) MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) error {
+	return markRetry(ctx, s.ShardFor(taskID), taskID, errorMsg, nextRetryAt)
+}
+
+func (s *ShardedStore) MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) error {
+	return markTimedOut(ctx, s.ShardFor(taskID), taskID, timeout, at)
+}
+
+func (s *ShardedStore) AddRuntime(ctx context.Context, taskID string, d time.Duration) (time.Duration, error) {
+	rs, ok := storeAs[RuntimeStore](s.ShardFor(taskID))
+	if !ok {
+		return 0, nil
+	}
+	return rs.AddRuntime(ctx, taskID, d)
+}
+
+func (s *ShardedStore) MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) error {
+	return markBudgetExhausted(ctx, s.ShardFor(taskID), taskID, budget, finishedAt)
+}
+
+func (s *ShardedStore) SetErrorDetails(ctx context.Context, taskID string, details string) error {
+	return setErrorDetails(ctx, s.ShardFor(taskID), taskID, details)
+}
+
+func (s *ShardedStore) Heartbeat(ctx context.Context, taskID string, at time.Time) error {
+	return heartbeat(ctx, s.ShardFor(taskID), taskID, at)
+}
+
+func (s *ShardedStore) MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) error {
+	return markStatus(ctx, s.ShardFor(taskID), taskID, status, at)
+}
+
+func (s *ShardedStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	return s.ShardFor(taskID).GetByID(ctx, taskID)
+}
+
+// Ping pings every shard.
+func (s *ShardedStore) Ping(ctx context.Context) error {
+	return s.scatter(func(i int, shard Store) error {
+		if pinger, ok := storeAs[Pinger](shard); ok {
+			return pinger.Ping(ctx)
+		}
+		return nil
+	})
+}
+
+// scatter runs fn against every shard concurrently and returns the first error.
+func (s *ShardedStore) scatter(fn func(i int, shard Store) error) error {
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func(i int, shard Store) {
+			defer wg.Done()
+			errs[i] = fn(i, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List merges every shard's results in creation order. Limit applies to the
+// merged result; each shard is asked for at most Limit records.
+func (s *ShardedStore) List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error) {
+	parts := make([][]*TaskRecord, len(s.shards))
+	err := s.scatter(func(i int, shard Store) error {
+		recs, err := listRecords(ctx, shard, f)
+		parts[i] = recs
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var out []*TaskRecord
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out, nil
+}
+
+// Stats sums per-status counts across shards.
+func (s *ShardedStore) Stats(ctx context.Context, f TaskFilter) (TaskStats, error) {
+	parts := make([]TaskStats, len(s.shards))
+	err := s.scatter(func(i int, shard Store) error {
+		st, err := CountByStatus(ctx, shard, f)
+		parts[i] = st
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	total := TaskStats{}
+	for _, p := range parts {
+		for status, n := range p {
+			total[status] += n
+		}
+	}
+	return total, nil
+}
+
+// CheckSchema checks every shard that implements SchemaChecker.
+func (s *ShardedStore) CheckSchema(ctx context.Context) error {
+	for i, shard := range s.shards {
+		if sc, ok := storeAs[SchemaChecker](shard); ok {
+			if err := sc.CheckSchema(ctx); err != nil {
+				return fmt.Errorf("shard %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestShardedStore_RoutesAndScatterGathers(t *testing.T) {
+	var shards []Store
+	for i := 0; i < 3; i++ {
+		db, err := sql.Open("sqlite", fmt.Sprintf("file:asyncx_shard%d?mode=memory&cache=shared", i))
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		defer db.Close()
+		if _, err := db.Exec(createTableSQL); err != nil {
+			t.Fatalf("create schema: %v", err)
+		}
+		shards = append(shards, NewSQLStore(db))
+	}
+	store := NewShardedStore(shards, ShardedStoreOptions{})
+	ctx := context.Background()
+
+	const n = 30
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("sh-%02d", i)
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "email:deliver", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		if i%3 == 0 {
+			if err := store.MarkFailed(ctx, id, "boom", time.Now()); err != nil {
+				t.Fatalf("MarkFailed: %v", err)
+			}
+		}
+	}
+
+	for _, shard := range shards {
+		recs, _ := listRecords(ctx, shard, TaskFilter{})
+		if len(recs) == 0 || len(recs) == n {
+			t.Fatalf("records not spread across shards: shard has %d of %d", len(recs), n)
+		}
+	}
+	got, err := store.GetByID(ctx, "sh-03")
+	if err != nil || got.Status != StatusFailed {
+		t.Fatalf("GetByID: rec=%#v err=%v", got, err)
+	}
+	all, err := store.List(ctx, TaskFilter{})
+	if err != nil || len(all) != n {
+		t.Fatalf("List: got %d records, err=%v", len(all), err)
+	}
+	if limited, _ := store.List(ctx, TaskFilter{Limit: 5}); len(limited) != 5 {
+		t.Fatalf("want 5 records with Limit, got %d", len(limited))
+	}
+	stats, err := store.Stats(ctx, TaskFilter{})
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats[StatusFailed] != 10 || stats[StatusCreated] != 20 {
+		t.Fatalf("unexpected stats: %v", stats)
+	}
+}
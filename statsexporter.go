@@ -0,0 +1,174 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type StatsExporterConfig struct {
+	// Interval between refreshes. Defaults to 30 seconds.
+	Interval time.Duration
+	// Window is how far back records are counted. Defaults to one hour.
+	Window time.Duration
+}
+
+// StatsExporter summarises task records for dashboards, so that they do not
+// query asyncx_tasks themselves. Every Interval it runs one Aggregate over
+// Window and sets these gauges on its GaugeSink:
+//
+//	asyncx_tasks{type,queue,status}                       records created within Window
+//	asyncx_task_duration_p50_seconds{type,queue,status}   handler duration percentiles
+//	asyncx_task_duration_p95_seconds{type,queue,status}
+//
+// Serve them with the sink's exporter, e.g. a Prometheus exporter on the
+// MeterProvider behind NewOTelMetrics. With a SummaryStore, such as
+// SQLStore, it also refreshes the asyncx_failures_per_hour and
+// asyncx_durations_by_type tables for SQL dashboards.
+type StatsExporter struct {
+	store Store
+	sink  GaugeSink
+	cfg   StatsExporterConfig
+
+	mu   sync.Mutex
+	seen map[AggregateRow]bool // groups set by the last refresh
+}
+
+// SummaryStore is implemented by stores that keep the summary tables
+// asyncx_failures_per_hour and asyncx_durations_by_type.
+type SummaryStore interface {
+	// RefreshSummaries recomputes the tables from the records created
+	// within window (all records if zero).
+	RefreshSummaries(ctx context.Context, window time.Duration) error
+}
+
+func NewStatsExporter(store Store, sink GaugeSink, cfg StatsExporterConfig) *StatsExporter {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Hour
+	}
+	return &StatsExporter{store: store, sink: sink, cfg: cfg}
+}
+
+// Run refreshes every Interval until ctx is cancelled.
+func (e *StatsExporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = e.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sets the gauges and refreshes the summary tables. Groups that
+// left the window are set to zero. On error the gauges keep their values.
+func (e *StatsExporter) RunOnce(ctx context.Context) error {
+	rows, err := Aggregate(ctx, e.store, []AggregateField{GroupByType, GroupByQueue, GroupByStatus}, e.cfg.Window)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	seen := make(map[AggregateRow]bool, len(rows))
+	for _, row := range rows {
+		seen[AggregateRow{Type: row.Type, Queue: row.Queue, Status: row.Status}] = true
+		e.set(ctx, row)
+	}
+	for group := range e.seen {
+		if !seen[group] {
+			e.set(ctx, group)
+		}
+	}
+	e.seen = seen
+	e.mu.Unlock()
+	if ss, ok := storeAs[SummaryStore](e.store); ok {
+		return ss.RefreshSummaries(ctx, e.cfg.Window)
+	}
+	return nil
+}
+
+func (e *StatsExporter) set(ctx context.Context, row AggregateRow) {
+	labels := map[string]string{"type": row.Type, "queue": row.Queue, "status": string(row.Status)}
+	e.sink.SetGauge(ctx, MetricRecords, float64(row.Count), labels)
+	e.sink.SetGauge(ctx, MetricRecordDurationP50, row.P50.Seconds(), labels)
+	e.sink.SetGauge(ctx, MetricRecordDurationP95, row.P95.Seconds(), labels)
+}
+
+// summarySQL holds the dialect-specific expressions RefreshSummaries uses.
+var summarySQL = map[Dialect]struct{ hour, seconds string }{
+	DialectPostgres: {
+		hour:    `date_trunc('hour', COALESCE(finished_at, updated_at))`,
+		seconds: `EXTRACT(EPOCH FROM finished_at - started_at)`,
+	},
+	DialectMySQL: {
+		hour:    `DATE_FORMAT(COALESCE(finished_at, updated_at), '%Y-%m-%d %H:00:00')`,
+		seconds: `TIMESTAMPDIFF(MICROSECOND, started_at, finished_at) / 1000000`,
+	},
+	DialectSQLite: {
+		// Durations have one second resolution.
+		hour:    `substr(COALESCE(finished_at, updated_at), 1, 13) || ':00:00'`,
+		seconds: `ROUND((julianday(substr(finished_at, 1, 19)) - julianday(substr(started_at, 1, 19))) * 86400)`,
+	},
+}
+
+// RefreshSummaries replaces the summary tables in one transaction. Final
+// failures are counted per hour of their finish. Percentiles come from
+// percentile_cont on Postgres and from Aggregate elsewhere. It needs
+// SQLStoreOptions.Dialect.
+func (s *SQLStore) RefreshSummaries(ctx context.Context, window time.Duration) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	exprs, ok := summarySQL[s.dialect]
+	if !ok {
+		return errors.New("asyncx: RefreshSummaries needs SQLStoreOptions.Dialect")
+	}
+	where, args := ``, []any{}
+	if window > 0 {
+		where, args = ` AND created_at >= ?`, []any{time.Now().Add(-window).UTC()}
+	}
+	p50, p95 := `NULL`, `NULL`
+	var percentiles []AggregateRow
+	if s.dialect == DialectPostgres {
+		p50 = `percentile_cont(0.5) WITHIN GROUP (ORDER BY ` + exprs.seconds + `)`
+		p95 = `percentile_cont(0.95) WITHIN GROUP (ORDER BY ` + exprs.seconds + `)`
+	} else {
+		var err error
+		if percentiles, err = s.Aggregate(ctx, []AggregateField{GroupByType}, window); err != nil {
+			return err
+		}
+	}
+	failures := `INSERT INTO asyncx_failures_per_hour (hour, type, queue, failures) SELECT ` + exprs.hour + `, type, queue, COUNT(*) FROM asyncx_tasks` +
+		` WHERE (status = '` + string(StatusTimedOut) + `' OR (status = '` + string(StatusFailed) + `' AND next_retry_at IS NULL))` + where + ` GROUP BY 1, 2, 3`
+	durations := `INSERT INTO asyncx_durations_by_type (type, finished, avg_seconds, max_seconds, p50_seconds, p95_seconds) SELECT type, COUNT(*), ` +
+		`AVG(` + exprs.seconds + `), MAX(` + exprs.seconds + `), ` + p50 + `, ` + p95 + ` FROM asyncx_tasks` +
+		` WHERE started_at IS NOT NULL AND finished_at IS NOT NULL` + where + ` GROUP BY type`
+	setPercentiles := `UPDATE asyncx_durations_by_type SET p50_seconds = ?, p95_seconds = ? WHERE type = ?`
+	return s.inTx(ctx, func(s *SQLStore) error {
+		for _, q := range []string{`DELETE FROM asyncx_failures_per_hour`, `DELETE FROM asyncx_durations_by_type`} {
+			if err := s.exec(ctx, q, q); err != nil {
+				return err
+			}
+		}
+		for _, q := range []string{failures, durations} {
+			if err := s.exec(ctx, q, dollarPlaceholders(q), args...); err != nil {
+				return err
+			}
+		}
+		for _, row := range percentiles {
+			if row.P95 == 0 {
+				continue
+			}
+			if err := s.exec(ctx, setPercentiles, dollarPlaceholders(setPercentiles), row.P50.Seconds(), row.P95.Seconds(), row.Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,106 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestStatsExporterAndSummaryTables(t *testing.T) {
+	db, err := sql.Open("sqlite", "file:asyncx_stats_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db, SQLStoreOptions{Dialect: DialectSQLite})
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	exporter := NewStatsExporter(store, NewOTelMetrics(provider.Meter("asyncx-test")), StatsExporterConfig{})
+
+	start := time.Now().Add(-time.Minute).UTC()
+	add := func(id, typ string, took time.Duration, fail bool) {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: typ, Queue: "default", PayloadJSON: `{}`, CreatedAt: start}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		_ = store.MarkStarted(ctx, id, start)
+		if fail {
+			_ = store.MarkFailed(ctx, id, "boom", start.Add(took))
+		} else {
+			_ = store.MarkCompleted(ctx, id, nil, start.Add(took))
+		}
+	}
+	add("a", "email:send", 2*time.Second, false)
+	add("b", "email:send", 4*time.Second, false)
+	add("c", "email:send", time.Second, true)
+	add("d", `odd"type`, time.Second, false)
+
+	if err := exporter.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	gauges := map[string]float64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			g, ok := m.Data.(metricdata.Gauge[float64])
+			if !ok {
+				t.Fatalf("%s is not a gauge: %#v", m.Name, m.Data)
+			}
+			for _, dp := range g.DataPoints {
+				typ, _ := dp.Attributes.Value(attribute.Key("type"))
+				status, _ := dp.Attributes.Value(attribute.Key("status"))
+				gauges[m.Name+" "+typ.AsString()+" "+status.AsString()] = dp.Value
+			}
+		}
+	}
+	for key, want := range map[string]float64{
+		MetricRecords + " email:send completed":           2,
+		MetricRecords + " email:send failed":              1,
+		MetricRecords + ` odd"type completed`:             1,
+		MetricRecordDurationP95 + " email:send completed": 4,
+	} {
+		if gauges[key] != want {
+			t.Errorf("%s = %v, want %v (all: %v)", key, gauges[key], want, gauges)
+		}
+	}
+
+	var hour time.Time
+	var typ string
+	var failures int
+	if err := db.QueryRowContext(ctx, `SELECT hour, type, failures FROM asyncx_failures_per_hour`).Scan(&hour, &typ, &failures); err != nil {
+		t.Fatalf("failures table: %v", err)
+	}
+	if typ != "email:send" || failures != 1 || !hour.Equal(hour.Truncate(time.Hour)) {
+		t.Errorf("failures table = %v %q %d", hour, typ, failures)
+	}
+	var finished int
+	var maxSeconds, p50, p95 float64
+	if err := db.QueryRowContext(ctx, `SELECT finished, max_seconds, p50_seconds, p95_seconds FROM asyncx_durations_by_type WHERE type = 'email:send'`).Scan(&finished, &maxSeconds, &p50, &p95); err != nil {
+		t.Fatalf("durations table: %v", err)
+	}
+	if finished != 3 || maxSeconds != 4 || p50 != 2 || p95 != 4 {
+		t.Errorf("durations table = %d %v %v %v", finished, maxSeconds, p50, p95)
+	}
+
+	// The tables are replaced on every refresh.
+	if _, err := db.ExecContext(ctx, `DELETE FROM asyncx_tasks WHERE type = 'email:send'`); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := exporter.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM asyncx_failures_per_hour`).Scan(&failures); err != nil || failures != 0 {
+		t.Errorf("failures table after refresh: %d rows, %v", failures, err)
+	}
+}
@@ -4,76 +4,412 @@ import (
 	context "context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ErrNotFound is returned by Store.GetByID for unknown task IDs.
+// SQLStore errors also match sql.ErrNoRows for backward compatibility.
+var ErrNotFound = errors.New("asyncx: task not found")
+
 // Store abstracts persistence for task lifecycle records.
-// Implementations must be safe for concurrent use.
+// Implementations must be safe for concurrent use. Further capabilities
+// are optional interfaces a store may implement, such as StatusStore,
+// ListStore, FailureStore, RuntimeStore and HeartbeatStore; SQLStore
+// implements them all.
 type Store interface {
+	// InsertCreated records a new task in StatusCreated, or in StatusParked
+	// if rec.Status is parked.
 	InsertCreated(ctx context.Context, rec TaskRecord) error
+	// MarkEnqueued records the queue and time of a Redis enqueue. It sets
+	// StatusCreated only on records still enqueue_failed, parked or held, so
+	// that it never undoes a worker that already picked the task up;
+	// CassandraStore, whose updates are blind writes, is the exception.
 	MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error
 	MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error
 	MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error
+	// MarkFailed records a final failure with FailureTransient.
 	MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error
 	GetByID(ctx context.Context, taskID string) (*TaskRecord, error)
 }
 
+// StatusStore is implemented by stores that record transitions carrying
+// no extra data, e.g. StatusThrottled. Other stores skip them.
+type StatusStore interface {
+	MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) error
+}
+
+// ListStore is implemented by stores that can query their records. The
+// features that find tasks through their records, such as the janitor, the
+// Reenqueuer, CancelWhere and dedup keys, require it.
+type ListStore interface {
+	// List returns records matching f.
+	List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error)
+}
+
+// FailureStore is implemented by stores that record how an attempt failed.
+// Other stores record each failed attempt with MarkFailed and drop the
+// error details.
+type FailureStore interface {
+	// MarkPermanentFailure records a final failure with FailurePermanent.
+	MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error
+	// MarkRetry records a failed attempt that asynq will retry at nextRetryAt.
+	MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) error
+	// MarkTimedOut records that the handler exceeded timeout.
+	MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) error
+	// SetErrorDetails attaches diagnostics, e.g. a stack trace, to the last error.
+	SetErrorDetails(ctx context.Context, taskID string, details string) error
+}
+
+// RuntimeStore is implemented by stores that sum a task's run time over its
+// attempts. ProcessorConfig.RuntimeBudgets are only enforced with one.
+type RuntimeStore interface {
+	// AddRuntime adds d to the task's cumulative run time and returns the new total.
+	AddRuntime(ctx context.Context, taskID string, d time.Duration) (time.Duration, error)
+	// MarkBudgetExhausted records a final failure because the cumulative run
+	// time reached budget.
+	MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) error
+}
+
+// HeartbeatStore is implemented by stores that record that running tasks
+// are alive; see ProcessorConfig.HeartbeatInterval.
+type HeartbeatStore interface {
+	// Heartbeat records that a running task is still alive.
+	Heartbeat(ctx context.Context, taskID string, at time.Time) error
+}
+
+// ErrListUnsupported is returned by features that find tasks through their
+// records when the store is not a ListStore.
+var ErrListUnsupported = errors.New("asyncx: store cannot list records")
+
+// storeAs returns store as a T, looking through wrappers with an Unwrap
+// method, such as BufferedStore, so that optional interfaces of the wrapped
+// store stay reachable.
+func storeAs[T any](store Store) (T, bool) {
+	for {
+		if t, ok := store.(T); ok {
+			return t, true
+		}
+		w, ok := store.(interface{ Unwrap() Store })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		store = w.Unwrap()
+	}
+}
+
+// listRecords lists the records of a ListStore.
+func listRecords(ctx context.Context, store Store, f TaskFilter) ([]*TaskRecord, error) {
+	ls, ok := storeAs[ListStore](store)
+	if !ok {
+		return nil, ErrListUnsupported
+	}
+	return ls.List(ctx, f)
+}
+
+// markStatus records status if store is a StatusStore.
+func markStatus(ctx context.Context, store Store, taskID string, status Status, at time.Time) error {
+	if ss, ok := storeAs[StatusStore](store); ok {
+		return ss.MarkStatus(ctx, taskID, status, at)
+	}
+	return nil
+}
+
+// markPermanentFailure falls back to MarkFailed for stores that are not a
+// FailureStore, as do markRetry and markTimedOut; markBudgetExhausted does
+// so for stores that are not a RuntimeStore.
+func markPermanentFailure(ctx context.Context, store Store, taskID string, errorMsg string, finishedAt time.Time) error {
+	if fs, ok := storeAs[FailureStore](store); ok {
+		return fs.MarkPermanentFailure(ctx, taskID, errorMsg, finishedAt)
+	}
+	return store.MarkFailed(ctx, taskID, errorMsg, finishedAt)
+}
+
+func markRetry(ctx context.Context, store Store, taskID string, errorMsg string, nextRetryAt time.Time) error {
+	if fs, ok := storeAs[FailureStore](store); ok {
+		return fs.MarkRetry(ctx, taskID, errorMsg, nextRetryAt)
+	}
+	return store.MarkFailed(ctx, taskID, errorMsg, time.Now().UTC())
+}
+
+func markTimedOut(ctx context.Context, store Store, taskID string, timeout time.Duration, at time.Time) error {
+	if fs, ok := storeAs[FailureStore](store); ok {
+		return fs.MarkTimedOut(ctx, taskID, timeout, at)
+	}
+	return store.MarkFailed(ctx, taskID, timeoutMsg(timeout), at)
+}
+
+// heartbeat records a heartbeat if store is a HeartbeatStore.
+func heartbeat(ctx context.Context, store Store, taskID string, at time.Time) error {
+	if hs, ok := storeAs[HeartbeatStore](store); ok {
+		return hs.Heartbeat(ctx, taskID, at)
+	}
+	return nil
+}
+
+func markBudgetExhausted(ctx context.Context, store Store, taskID string, budget time.Duration, finishedAt time.Time) error {
+	if rs, ok := storeAs[RuntimeStore](store); ok {
+		return rs.MarkBudgetExhausted(ctx, taskID, budget, finishedAt)
+	}
+	return store.MarkFailed(ctx, taskID, budgetMsg(budget), finishedAt)
+}
+
+// setErrorDetails attaches details if store is a FailureStore.
+func setErrorDetails(ctx context.Context, store Store, taskID string, details string) error {
+	if fs, ok := storeAs[FailureStore](store); ok {
+		return fs.SetErrorDetails(ctx, taskID, details)
+	}
+	return nil
+}
+
+// TaskStats counts records per status.
+type TaskStats map[Status]int
+
+// StatsStore is implemented by stores that can count records without loading
+// them. Use CountByStatus to work with any Store.
+type StatsStore interface {
+	Stats(ctx context.Context, f TaskFilter) (TaskStats, error)
+}
+
+// CountByStatus returns per-status counts for records matching f, using
+// StatsStore when available and falling back to List.
+func CountByStatus(ctx context.Context, store Store, f TaskFilter) (TaskStats, error) {
+	if ss, ok := storeAs[StatsStore](store); ok {
+		return ss.Stats(ctx, f)
+	}
+	f.Limit = 0
+	recs, err := listRecords(ctx, store, f)
+	if err != nil {
+		return nil, err
+	}
+	stats := TaskStats{}
+	for _, rec := range recs {
+		stats[rec.Status]++
+	}
+	return stats, nil
+}
+
+// TaskFilter selects records for Store.List. Zero-valued fields are ignored.
+type TaskFilter struct {
+	Status        Status
+	Type          string
+	Queue         string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	StartedBefore time.Time
+	UpdatedBefore time.Time
+	// Metadata selects records carrying all of these labels.
+	Metadata map[string]string
+	// DedupKey selects the record enqueued with this WithDedupKey key.
+	DedupKey string
+	// ContentHash selects records with this TaskRecord.ContentHash.
+	ContentHash string
+	// Shard selects records enqueued through this ShardedClient shard.
+	Shard string
+	// ParentID selects the children of a task; see EnqueueChild.
+	ParentID string
+	// PayloadRetained selects records whose payload has not been purged.
+	PayloadRetained bool
+	// Enqueued selects records whose Redis enqueue was recorded.
+	Enqueued bool
+	// RetryScheduled selects failed records that asynq will retry.
+	RetryScheduled bool
+	Limit          int
+}
+
 // SQLStore is a reference implementation backed by a relational DB (Postgres/MySQL).
 // Table schema is provided in migrations.
 type SQLStore struct {
-	db *sql.DB
+	db          *sql.DB
+	checksumKey []byte
+	checkSince  time.Time
+	copy        CopyFunc
+	dialect     Dialect
+	history     bool
+	strict      bool
+	stmts       *sync.Map // query text -> *sql.Stmt
+	tx          *sql.Tx   // set on the copies inTx hands out
 }
 
-func NewSQLStore(db *sql.DB) *SQLStore {
-	return &SQLStore{db: db}
+// NewSQLStore returns a store over db. At most one SQLStoreOptions may be
+// given; its CheckSchema is only honoured by OpenSQLStore.
+func NewSQLStore(db *sql.DB, opts ...SQLStoreOptions) *SQLStore {
+	s := &SQLStore{db: db, stmts: &sync.Map{}}
+	if len(opts) > 0 {
+		s.checksumKey = opts[0].ChecksumKey
+		s.checkSince = opts[0].ChecksumSince
+		s.copy = opts[0].Copy
+		s.dialect = opts[0].Dialect
+		s.history = opts[0].History
+		s.strict = opts[0].StrictTransitions
+		opts[0].applyPool(db)
+	}
+	return s
 }
 
-func (s *SQLStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
+// OpenSQLStore is NewSQLStore that also runs CheckSchema when
+// opts.CheckSchema is set, returning its error.
+func OpenSQLStore(ctx context.Context, db *sql.DB, opts SQLStoreOptions) (*SQLStore, error) {
+	s := NewSQLStore(db, opts)
+	if opts.CheckSchema {
+		if err := s.CheckSchema(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// stmt returns the prepared statement for q, preparing and caching it on
+// first use. Drivers that reject '?' placeholders get qpg instead, so the
+// placeholder style is only probed once per statement. Within inTx the
+// statement runs in the transaction.
+func (s *SQLStore) stmt(ctx context.Context, q, qpg string) (*sql.Stmt, error) {
+	st, err := s.prepared(ctx, q, qpg)
+	if err != nil || s.tx == nil {
+		return st, err
+	}
+	return s.tx.StmtContext(ctx, st), nil
+}
+
+// inTx runs fn with a copy of s whose statements run in one transaction,
+// committed if fn succeeds. Within a transaction, fn gets s itself.
+func (s *SQLStore) inTx(ctx context.Context, fn func(s *SQLStore) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
 	if s.db == nil {
 		return errors.New("nil db")
 	}
-	query := `INSERT INTO asyncx_tasks (id, type, queue, payload_json, status, created_at)
-		VALUES (?, ?, ?, ?, ?, ?)`
-	// Use Postgres-style placeholders if driver is postgres.
-	// We detect driver name via DB stats workaround is unreliable; keep portable by attempting Exec with '?'
-	// and fallback to '$' placeholders if needed. For simplicity, prefer '?'.
-	_, err := s.db.ExecContext(ctx, query, rec.ID, rec.Type, rec.Queue, rec.PayloadJSON, string(StatusCreated), time.Now().UTC())
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		// attempt Postgres style
-		queryPg := `INSERT INTO asyncx_tasks (id, type, queue, payload_json, status, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6)`
-		_, err2 := s.db.ExecContext(ctx, queryPg, rec.ID, rec.Type, rec.Queue, rec.PayloadJSON, string(StatusCreated), time.Now().UTC())
-		return err2
+		return err
+	}
+	c := *s
+	c.tx = tx
+	if err := fn(&c); err != nil {
+		_ = tx.Rollback()
+		return err
 	}
+	return tx.Commit()
+}
+
+// applyTx runs fn on a copy of s within one transaction; see txStore.
+func (s *SQLStore) applyTx(ctx context.Context, fn func(st Store) error) error {
+	return s.inTx(ctx, func(s *SQLStore) error { return fn(s) })
+}
+
+// prepared returns the cached statement for q; see stmt.
+func (s *SQLStore) prepared(ctx context.Context, q, qpg string) (*sql.Stmt, error) {
+	if st, ok := s.stmts.Load(q); ok {
+		return st.(*sql.Stmt), nil
+	}
+	st, err := s.db.PrepareContext(ctx, q)
+	if err != nil {
+		var err2 error
+		if st, err2 = s.db.PrepareContext(ctx, qpg); err2 != nil {
+			return nil, err2
+		}
+	}
+	if prev, loaded := s.stmts.LoadOrStore(q, st); loaded {
+		st.Close()
+		return prev.(*sql.Stmt), nil
+	}
+	return st, nil
+}
+
+// exec runs a cached statement; see stmt.
+func (s *SQLStore) exec(ctx context.Context, q, qpg string, args ...any) error {
+	st, err := s.stmt(ctx, q, qpg)
+	if err != nil {
+		return err
+	}
+	_, err = st.ExecContext(ctx, args...)
+	return err
+}
+
+// Close releases the cached prepared statements. It does not close the
+// *sql.DB, which belongs to the caller.
+func (s *SQLStore) Close() error {
+	s.stmts.Range(func(k, v any) bool {
+		v.(*sql.Stmt).Close()
+		s.stmts.Delete(k)
+		return true
+	})
 	return nil
 }
 
-func (s *SQLStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+func (s *SQLStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
 	if s.db == nil {
 		return errors.New("nil db")
 	}
-	q := `UPDATE asyncx_tasks SET status = ?, queue = ?, enqueued_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, q, string(StatusCreated), queue, enqueuedAt.UTC(), taskID)
-	if err != nil {
-		qpg := `UPDATE asyncx_tasks SET status = $1, queue = $2, enqueued_at = $3, updated_at = NOW() WHERE id = $4`
-		_, err2 := s.db.ExecContext(ctx, qpg, string(StatusCreated), queue, enqueuedAt.UTC(), taskID)
-		return err2
+	now := time.Now().UTC()
+	return s.withHistory(ctx, rec.ID, insertStatus(&rec), "", now, func(s *SQLStore) error {
+		return s.exec(ctx, insertSQL, dollarPlaceholders(insertSQL), s.insertArgs(rec, now, nil)...)
+	})
+}
+
+// optional maps "" to SQL NULL.
+func optional(v string) *string {
+	if v == "" {
+		return nil
 	}
-	return nil
+	return &v
+}
+
+// insertColumns are the columns written for a new record, in insertArgs order.
+var insertColumns = []string{"id", "type", "queue", "payload_json", "status", "task_class", "request_json", "priority", "metadata_json", "guard_token", "created_by", "source", "checksum", "dedup_key", "workflow_traceparent", "parent_task_id", "content_type", "payload_version", "content_hash", "shard", "created_at", "enqueued_at"}
+
+var insertSQL = `INSERT INTO asyncx_tasks (` + strings.Join(insertColumns, ", ") + `) VALUES (?` + strings.Repeat(", ?", len(insertColumns)-1) + `)`
+
+// insertArgs returns the insertColumns values for rec created at now.
+// With a ChecksumKey, created_at is truncated to the second so that it reads
+// back as checksummed.
+func (s *SQLStore) insertArgs(rec TaskRecord, now time.Time, enqueuedAt *time.Time) []any {
+	var checksum *string
+	if len(s.checksumKey) > 0 {
+		now = now.Truncate(time.Second)
+		c := recordChecksum(s.checksumKey, rec.ID, rec.Type, rec.PayloadJSON, now)
+		checksum = &c
+	}
+	var payloadVersion *int
+	if rec.PayloadVersion != 0 {
+		payloadVersion = &rec.PayloadVersion
+	}
+	return []any{rec.ID, rec.Type, rec.Queue, rec.PayloadJSON, string(insertStatus(&rec)), optional(string(rec.Class)), rec.RequestJSON,
+		optional(string(rec.Priority)), encodeMetadata(rec.Metadata), optional(rec.GuardToken), optional(rec.CreatedBy), optional(rec.Source),
+		checksum, optional(rec.DedupKey), rec.WorkflowTraceparent, optional(rec.ParentTaskID), optional(rec.ContentType), payloadVersion, optional(rec.ContentHash), optional(rec.Shard), now, enqueuedAt}
+}
+
+func (s *SQLStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	// Only unenqueuedStatuses are reset, in the same statement.
+	status := `status = CASE WHEN status IN (` + statusList(unenqueuedStatuses) + `) THEN '` + string(StatusCreated) + `' ELSE status END`
+	q := `UPDATE asyncx_tasks SET ` + status + `, queue = ?, enqueued_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET ` + status + `, queue = $1, enqueued_at = $2, updated_at = NOW() WHERE id = $3`
+	return s.withHistory(ctx, taskID, StatusCreated, "", enqueuedAt, func(s *SQLStore) error {
+		return s.exec(ctx, q, qpg, queue, enqueuedAt.UTC(), taskID)
+	})
 }
 
 func (s *SQLStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error {
 	if s.db == nil {
 		return errors.New("nil db")
 	}
-	q := `UPDATE asyncx_tasks SET status = ?, started_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, q, string(StatusInProgress), startedAt.UTC(), taskID)
-	if err != nil {
-		qpg := `UPDATE asyncx_tasks SET status = $1, started_at = $2, updated_at = NOW() WHERE id = $3`
-		_, err2 := s.db.ExecContext(ctx, qpg, string(StatusInProgress), startedAt.UTC(), taskID)
-		return err2
+	w := workerFromContext(ctx)
+	var pid *int
+	if w.PID != 0 {
+		pid = &w.PID
 	}
-	return nil
+	q := `UPDATE asyncx_tasks SET status = ?, started_at = ?, next_retry_at = NULL, failure_kind = NULL, worker_id = ?, hostname = ?, pid = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET status = $1, started_at = $2, next_retry_at = NULL, failure_kind = NULL, worker_id = $3, hostname = $4, pid = $5, updated_at = NOW() WHERE id = $6`
+	return s.withHistory(ctx, taskID, StatusInProgress, "", startedAt, func(s *SQLStore) error {
+		return s.transition(ctx, StatusInProgress, q, qpg, string(StatusInProgress), startedAt.UTC(), optional(w.ID), optional(w.Host), pid, taskID)
+	})
 }
 
 func (s *SQLStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error {
@@ -81,56 +417,192 @@ func (s *SQLStore) MarkCompleted(ctx context.Context, taskID string, resultJSON
 		return errors.New("nil db")
 	}
 	q := `UPDATE asyncx_tasks SET status = ?, result_json = ?, finished_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, q, string(StatusCompleted), resultJSON, finishedAt.UTC(), taskID)
-	if err != nil {
-		qpg := `UPDATE asyncx_tasks SET status = $1, result_json = $2, finished_at = $3, updated_at = NOW() WHERE id = $4`
-		_, err2 := s.db.ExecContext(ctx, qpg, string(StatusCompleted), resultJSON, finishedAt.UTC(), taskID)
-		return err2
-	}
-	return nil
+	qpg := `UPDATE asyncx_tasks SET status = $1, result_json = $2, finished_at = $3, updated_at = NOW() WHERE id = $4`
+	return s.withHistory(ctx, taskID, StatusCompleted, "", finishedAt, func(s *SQLStore) error {
+		return s.transition(ctx, StatusCompleted, q, qpg, string(StatusCompleted), resultJSON, finishedAt.UTC(), taskID)
+	})
 }
 
 func (s *SQLStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.markFailed(ctx, taskID, errorMsg, FailureTransient, finishedAt)
+}
+
+func (s *SQLStore) MarkPermanentFailure(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return s.markFailed(ctx, taskID, errorMsg, FailurePermanent, finishedAt)
+}
+
+func (s *SQLStore) markFailed(ctx context.Context, taskID string, errorMsg string, kind FailureKind, finishedAt time.Time) error {
 	if s.db == nil {
 		return errors.New("nil db")
 	}
-	q := `UPDATE asyncx_tasks SET status = ?, error_msg = ?, finished_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
-	_, err := s.db.ExecContext(ctx, q, string(StatusFailed), errorMsg, finishedAt.UTC(), taskID)
-	if err != nil {
-		qpg := `UPDATE asyncx_tasks SET status = $1, error_msg = $2, finished_at = $3, updated_at = NOW() WHERE id = $4`
-		_, err2 := s.db.ExecContext(ctx, qpg, string(StatusFailed), errorMsg, finishedAt.UTC(), taskID)
-		return err2
+	q := `UPDATE asyncx_tasks SET status = ?, error_msg = ?, failure_kind = ?, finished_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET status = $1, error_msg = $2, failure_kind = $3, finished_at = $4, updated_at = NOW() WHERE id = $5`
+	return s.withHistory(ctx, taskID, StatusFailed, errorMsg, finishedAt, func(s *SQLStore) error {
+		return s.transition(ctx, StatusFailed, q, qpg, string(StatusFailed), errorMsg, string(kind), finishedAt.UTC(), taskID)
+	})
+}
+
+func (s *SQLStore) MarkRetry(ctx context.Context, taskID string, errorMsg string, nextRetryAt time.Time) error {
+	if s.db == nil {
+		return errors.New("nil db")
 	}
-	return nil
+	q := `UPDATE asyncx_tasks SET status = ?, error_msg = ?, failure_kind = ?, next_retry_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET status = $1, error_msg = $2, failure_kind = $3, next_retry_at = $4, updated_at = NOW() WHERE id = $5`
+	return s.withHistory(ctx, taskID, StatusFailed, errorMsg, time.Now(), func(s *SQLStore) error {
+		return s.transition(ctx, StatusFailed, q, qpg, string(StatusFailed), errorMsg, string(FailureTransient), nextRetryAt.UTC(), taskID)
+	})
 }
 
-func (s *SQLStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+func (s *SQLStore) MarkTimedOut(ctx context.Context, taskID string, timeout time.Duration, at time.Time) error {
 	if s.db == nil {
-		return nil, errors.New("nil db")
+		return errors.New("nil db")
+	}
+	msg := timeoutMsg(timeout)
+	q := `UPDATE asyncx_tasks SET status = ?, error_msg = ?, timeout_ms = ?, finished_at = ?, updated_at = ? WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET status = $1, error_msg = $2, timeout_ms = $3, finished_at = $4, updated_at = $5 WHERE id = $6`
+	return s.withHistory(ctx, taskID, StatusTimedOut, msg, at, func(s *SQLStore) error {
+		return s.transition(ctx, StatusTimedOut, q, qpg, string(StatusTimedOut), msg, timeout.Milliseconds(), at.UTC(), at.UTC(), taskID)
+	})
+}
+
+// timeoutMsg is the error_msg recorded by MarkTimedOut.
+func timeoutMsg(timeout time.Duration) string {
+	return fmt.Sprintf("timed out after %s", timeout)
+}
+
+func (s *SQLStore) AddRuntime(ctx context.Context, taskID string, d time.Duration) (time.Duration, error) {
+	if s.db == nil {
+		return 0, errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET runtime_ms = COALESCE(runtime_ms, 0) + ? WHERE id = ?`
+	sel := `SELECT runtime_ms FROM asyncx_tasks WHERE id = ?`
+	if _, err := s.db.ExecContext(ctx, q, d.Milliseconds(), taskID); err != nil {
+		q = `UPDATE asyncx_tasks SET runtime_ms = COALESCE(runtime_ms, 0) + $1 WHERE id = $2`
+		sel = `SELECT runtime_ms FROM asyncx_tasks WHERE id = $1`
+		if _, err := s.db.ExecContext(ctx, q, d.Milliseconds(), taskID); err != nil {
+			return 0, err
+		}
+	}
+	var ms sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, sel, taskID).Scan(&ms); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return time.Duration(ms.Int64) * time.Millisecond, nil
+}
+
+func (s *SQLStore) MarkBudgetExhausted(ctx context.Context, taskID string, budget time.Duration, finishedAt time.Time) error {
+	return s.markFailed(ctx, taskID, budgetMsg(budget), FailureBudgetExhausted, finishedAt)
+}
+
+// budgetMsg is the error_msg recorded by MarkBudgetExhausted.
+func budgetMsg(budget time.Duration) string {
+	return fmt.Sprintf("runtime budget of %s exhausted", budget)
+}
+
+func (s *SQLStore) SetErrorDetails(ctx context.Context, taskID string, details string) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET error_details = ? WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET error_details = $1 WHERE id = $2`
+	return s.exec(ctx, q, qpg, details, taskID)
+}
+
+func (s *SQLStore) Heartbeat(ctx context.Context, taskID string, at time.Time) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET last_heartbeat_at = ? WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET last_heartbeat_at = $1 WHERE id = $2`
+	return s.exec(ctx, q, qpg, at.UTC(), taskID)
+}
+
+// MarkStatus writes status unconditionally, also under StrictTransitions.
+func (s *SQLStore) MarkStatus(ctx context.Context, taskID string, status Status, at time.Time) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET status = ?, updated_at = ? WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET status = $1, updated_at = $2 WHERE id = $3`
+	return s.withHistory(ctx, taskID, status, "", at, func(s *SQLStore) error {
+		return s.exec(ctx, q, qpg, string(status), at.UTC(), taskID)
+	})
+}
+
+func (s *SQLStore) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return errors.New("nil db")
 	}
-	q := `SELECT id, type, queue, payload_json, status, error_msg, result_json, created_at, enqueued_at, started_at, finished_at FROM asyncx_tasks WHERE id = ?`
-	row := s.db.QueryRowContext(ctx, q, taskID)
+	return s.db.PingContext(ctx)
+}
+
+// taskColumns is the column list read by scanTask.
+const taskColumns = `id, type, queue, payload_json, status, error_msg, result_json, task_class, created_at, updated_at, enqueued_at, started_at, finished_at, last_heartbeat_at, next_retry_at, failure_kind, error_details, timeout_ms, request_json, priority, runtime_ms, metadata_json, guard_token, created_by, source, checksum, dedup_key, workflow_traceparent, payload_purged_at, parent_task_id, worker_id, hostname, pid, content_type, payload_version, content_hash, shard`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row rowScanner) (*TaskRecord, error) {
 	rec := TaskRecord{}
 	var status string
-	var startedAt, finishedAt, enqueuedAt sql.NullTime
-	var errorMsg, resultJSON sql.NullString
-	if err := row.Scan(&rec.ID, &rec.Type, &rec.Queue, &rec.PayloadJSON, &status, &errorMsg, &resultJSON, &rec.CreatedAt, &enqueuedAt, &startedAt, &finishedAt); err != nil {
-		// retry with postgres placeholders if needed
-		qpg := `SELECT id, type, queue, payload_json, status, error_msg, result_json, created_at, enqueued_at, started_at, finished_at FROM asyncx_tasks WHERE id = $1`
-		row = s.db.QueryRowContext(ctx, qpg, taskID)
-		if err2 := row.Scan(&rec.ID, &rec.Type, &rec.Queue, &rec.PayloadJSON, &status, &errorMsg, &resultJSON, &rec.CreatedAt, &enqueuedAt, &startedAt, &finishedAt); err2 != nil {
-			return nil, err2
-		}
+	var startedAt, finishedAt, enqueuedAt, updatedAt, heartbeatAt, nextRetryAt, purgedAt sql.NullTime
+	var timeoutMS, runtimeMS, pid, payloadVersion sql.NullInt64
+	var errorMsg, resultJSON, class, failureKind, errorDetails, requestJSON, priority, metadataJSON, guardToken, createdBy, source, checksum, dedupKey, workflowTP, parentID, workerID, hostname, contentType, contentHash, shard sql.NullString
+	if err := row.Scan(&rec.ID, &rec.Type, &rec.Queue, &rec.PayloadJSON, &status, &errorMsg, &resultJSON, &class, &rec.CreatedAt, &updatedAt, &enqueuedAt, &startedAt, &finishedAt, &heartbeatAt, &nextRetryAt, &failureKind, &errorDetails, &timeoutMS, &requestJSON, &priority, &runtimeMS, &metadataJSON, &guardToken, &createdBy, &source, &checksum, &dedupKey, &workflowTP, &purgedAt, &parentID, &workerID, &hostname, &pid, &contentType, &payloadVersion, &contentHash, &shard); err != nil {
+		return nil, err
 	}
 	rec.Status = Status(status)
+	rec.Class = TaskClass(class.String)
+	rec.FailureKind = FailureKind(failureKind.String)
+	rec.Priority = Priority(priority.String)
+	rec.TimeoutMS = timeoutMS.Int64
+	rec.RuntimeMS = runtimeMS.Int64
+	rec.Metadata = decodeMetadata(metadataJSON.String)
+	rec.GuardToken = guardToken.String
+	rec.CreatedBy = createdBy.String
+	rec.Source = source.String
+	rec.Checksum = checksum.String
+	rec.DedupKey = dedupKey.String
+	rec.ParentTaskID = parentID.String
+	rec.WorkerID = workerID.String
+	rec.Hostname = hostname.String
+	rec.PID = int(pid.Int64)
+	rec.ContentType = contentType.String
+	rec.PayloadVersion = int(payloadVersion.Int64)
+	rec.ContentHash = contentHash.String
+	rec.Shard = shard.String
+	if purgedAt.Valid {
+		v := purgedAt.Time
+		rec.PayloadPurgedAt = &v
+	}
+	if workflowTP.Valid {
+		v := workflowTP.String
+		rec.WorkflowTraceparent = &v
+	}
 	if errorMsg.Valid {
 		v := errorMsg.String
 		rec.ErrorMsg = &v
 	}
+	if errorDetails.Valid {
+		v := errorDetails.String
+		rec.ErrorDetails = &v
+	}
 	if resultJSON.Valid {
 		v := resultJSON.String
 		rec.ResultJSON = &v
 	}
+	if requestJSON.Valid {
+		v := requestJSON.String
+		rec.RequestJSON = &v
+	}
+	if updatedAt.Valid {
+		t := updatedAt.Time
+		rec.UpdatedAt = &t
+	}
 	if startedAt.Valid {
 		t := startedAt.Time
 		rec.StartedAt = &t
@@ -142,5 +614,202 @@ func (s *SQLStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, err
 	if enqueuedAt.Valid {
 		rec.EnqueuedAt = enqueuedAt.Time
 	}
+	if heartbeatAt.Valid {
+		t := heartbeatAt.Time
+		rec.LastHeartbeatAt = &t
+	}
+	if nextRetryAt.Valid {
+		t := nextRetryAt.Time
+		rec.NextRetryAt = &t
+	}
 	return &rec, nil
 }
+
+func (s *SQLStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	q := `SELECT ` + taskColumns + ` FROM asyncx_tasks WHERE id = ?`
+	qpg := `SELECT ` + taskColumns + ` FROM asyncx_tasks WHERE id = $1`
+	st, err := s.stmt(ctx, q, qpg)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := scanTask(st.QueryRowContext(ctx, taskID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verify(rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// List returns records matching f ordered by creation time.
+func (s *SQLStore) List(ctx context.Context, f TaskFilter) ([]*TaskRecord, error) {
+	var out []*TaskRecord
+	err := s.Each(ctx, f, func(rec *TaskRecord) error {
+		out = append(out, rec)
+		return nil
+	})
+	return out, err
+}
+
+// Each calls fn for every record matching f, oldest first, reading them one
+// row at a time.
+func (s *SQLStore) Each(ctx context.Context, f TaskFilter, fn func(*TaskRecord) error) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	where, args := f.where()
+	q := `SELECT ` + taskColumns + ` FROM asyncx_tasks` + where + ` ORDER BY created_at`
+	// Metadata is matched after scanning, so the limit is applied then too.
+	if f.Limit > 0 && len(f.Metadata) == 0 {
+		q += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		var err2 error
+		rows, err2 = s.db.QueryContext(ctx, dollarPlaceholders(q), args...)
+		if err2 != nil {
+			return err2
+		}
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		rec, err := scanTask(rows)
+		if err != nil {
+			return err
+		}
+		if err := s.verify(rec); err != nil {
+			return err
+		}
+		if !matchesMetadata(rec.Metadata, f.Metadata) {
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+		n++
+		if f.Limit > 0 && n == f.Limit {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+// where renders f as a SQL WHERE clause with '?' placeholders.
+func (f TaskFilter) where() (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if f.Status != "" {
+		add("status = ?", string(f.Status))
+	}
+	if f.Type != "" {
+		add("type = ?", f.Type)
+	}
+	if f.Queue != "" {
+		add("queue = ?", f.Queue)
+	}
+	if f.DedupKey != "" {
+		add("dedup_key = ?", f.DedupKey)
+	}
+	if f.ContentHash != "" {
+		add("content_hash = ?", f.ContentHash)
+	}
+	if f.Shard != "" {
+		add("shard = ?", f.Shard)
+	}
+	if f.ParentID != "" {
+		add("parent_task_id = ?", f.ParentID)
+	}
+	if !f.CreatedAfter.IsZero() {
+		add("created_at >= ?", f.CreatedAfter.UTC())
+	}
+	if !f.CreatedBefore.IsZero() {
+		add("created_at < ?", f.CreatedBefore.UTC())
+	}
+	if !f.StartedBefore.IsZero() {
+		add("started_at < ?", f.StartedBefore.UTC())
+	}
+	if !f.UpdatedBefore.IsZero() {
+		add("updated_at < ?", f.UpdatedBefore.UTC())
+	}
+	if f.PayloadRetained {
+		conds = append(conds, "payload_purged_at IS NULL")
+	}
+	if f.Enqueued {
+		conds = append(conds, "enqueued_at IS NOT NULL")
+	}
+	if f.RetryScheduled {
+		conds = append(conds, "next_retry_at IS NOT NULL")
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return ` WHERE ` + strings.Join(conds, " AND "), args
+}
+
+// Stats counts records matching f per status with a single GROUP BY query.
+func (s *SQLStore) Stats(ctx context.Context, f TaskFilter) (TaskStats, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	if len(f.Metadata) > 0 {
+		// Metadata cannot be matched in SQL portably; count the listed records.
+		f.Limit = 0
+		recs, err := s.List(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		stats := TaskStats{}
+		for _, rec := range recs {
+			stats[rec.Status]++
+		}
+		return stats, nil
+	}
+	where, args := f.where()
+	q := `SELECT status, COUNT(*) FROM asyncx_tasks` + where + ` GROUP BY status`
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		var err2 error
+		rows, err2 = s.db.QueryContext(ctx, dollarPlaceholders(q), args...)
+		if err2 != nil {
+			return nil, err2
+		}
+	}
+	defer rows.Close()
+	stats := TaskStats{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		stats[Status(status)] = n
+	}
+	return stats, rows.Err()
+}
+
+// dollarPlaceholders rewrites '?' placeholders as $1, $2, ... for Postgres.
+func dollarPlaceholders(q string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
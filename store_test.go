@@ -4,9 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/hibiken/asynq"
 	_ "modernc.org/sqlite"
 )
 
@@ -23,7 +27,33 @@ CREATE TABLE IF NOT EXISTS asyncx_tasks (
     updated_at   DATETIME     NULL,
     enqueued_at  DATETIME     NULL,
     started_at   DATETIME     NULL,
-    finished_at  DATETIME     NULL
+    finished_at  DATETIME     NULL,
+    task_class   VARCHAR(32)  NULL,
+    last_heartbeat_at DATETIME NULL,
+    next_retry_at DATETIME NULL,
+    failure_kind VARCHAR(16) NULL,
+    error_details TEXT NULL,
+    timeout_ms BIGINT NULL,
+    request_json TEXT NULL,
+    priority VARCHAR(16) NULL,
+    runtime_ms BIGINT NULL,
+    metadata_json TEXT NULL,
+    guard_token VARCHAR(64) NULL,
+    created_by VARCHAR(255) NULL,
+    source VARCHAR(255) NULL,
+    checksum VARCHAR(64) NULL,
+    dedup_key VARCHAR(255) NULL,
+    workflow_traceparent VARCHAR(64) NULL,
+    payload_purged_at DATETIME NULL,
+    parent_task_id VARCHAR(64) NULL,
+    worker_id VARCHAR(255) NULL,
+    hostname VARCHAR(255) NULL,
+    pid INTEGER NULL,
+    content_type VARCHAR(255) NULL,
+    payload_version INTEGER NULL,
+    content_hash VARCHAR(64) NULL,
+    shard VARCHAR(64) NULL,
+    exported_at DATETIME NULL
 );
 `
 
@@ -114,3 +144,334 @@ func TestSQLStore_MarkFailed(t *testing.T) {
 		t.Fatalf("unexpected error msg: %#v", got.ErrorMsg)
 	}
 }
+
+func TestSQLStore_MarkStatus(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	rec := TaskRecord{ID: "task-throttled", Type: "email:deliver", Queue: "default", PayloadJSON: `{}`, Status: StatusCreated, CreatedAt: time.Now().UTC()}
+	if err := store.InsertCreated(ctx, rec); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if err := store.MarkStatus(ctx, rec.ID, StatusThrottled, time.Now().UTC()); err != nil {
+		t.Fatalf("MarkStatus: %v", err)
+	}
+	got, err := store.GetByID(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Status != StatusThrottled {
+		t.Fatalf("want status=%s got=%s", StatusThrottled, got.Status)
+	}
+}
+
+// baseStore hides every optional interface of the wrapped store.
+type baseStore struct{ s Store }
+
+func (b baseStore) InsertCreated(ctx context.Context, rec TaskRecord) error {
+	return b.s.InsertCreated(ctx, rec)
+}
+func (b baseStore) MarkEnqueued(ctx context.Context, taskID string, queue string, enqueuedAt time.Time) error {
+	return b.s.MarkEnqueued(ctx, taskID, queue, enqueuedAt)
+}
+func (b baseStore) MarkStarted(ctx context.Context, taskID string, startedAt time.Time) error {
+	return b.s.MarkStarted(ctx, taskID, startedAt)
+}
+func (b baseStore) MarkCompleted(ctx context.Context, taskID string, resultJSON *string, finishedAt time.Time) error {
+	return b.s.MarkCompleted(ctx, taskID, resultJSON, finishedAt)
+}
+func (b baseStore) MarkFailed(ctx context.Context, taskID string, errorMsg string, finishedAt time.Time) error {
+	return b.s.MarkFailed(ctx, taskID, errorMsg, finishedAt)
+}
+func (b baseStore) GetByID(ctx context.Context, taskID string) (*TaskRecord, error) {
+	return b.s.GetByID(ctx, taskID)
+}
+
+func TestStore_OptionalInterfacesFallBack(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := baseStore{NewSQLStore(db)}
+	ctx := context.Background()
+
+	rec := TaskRecord{ID: "task-base", Type: "email:deliver", Queue: "default", PayloadJSON: `{}`, CreatedAt: time.Now().UTC()}
+	if err := store.InsertCreated(ctx, rec); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if err := markStatus(ctx, store, rec.ID, StatusThrottled, time.Now().UTC()); err != nil {
+		t.Fatalf("markStatus: %v", err)
+	}
+	if err := markTimedOut(ctx, store, rec.ID, time.Second, time.Now().UTC()); err != nil {
+		t.Fatalf("markTimedOut: %v", err)
+	}
+	got, err := store.GetByID(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Status != StatusFailed || got.ErrorMsg == nil || *got.ErrorMsg != timeoutMsg(time.Second) {
+		t.Fatalf("want failed with the timeout message, got %s %v", got.Status, got.ErrorMsg)
+	}
+	if _, err := listRecords(ctx, store, TaskFilter{}); !errors.Is(err, ErrListUnsupported) {
+		t.Fatalf("listRecords: want ErrListUnsupported, got %v", err)
+	}
+}
+
+func TestSQLStore_MarkEnqueuedKeepsLaterStatus(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	testMarkEnqueuedKeepsLaterStatus(t, NewSQLStore(db))
+}
+
+// testMarkEnqueuedKeepsLaterStatus checks that an enqueue recorded after the
+// worker started the task leaves the task in progress, while a record whose
+// enqueue had failed goes back to created.
+func testMarkEnqueuedKeepsLaterStatus(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	for _, id := range []string{"enq-started", "enq-failed"} {
+		rec := TaskRecord{ID: id, Type: "email:deliver", Queue: "default", PayloadJSON: `{}`, Status: StatusCreated, CreatedAt: time.Now().UTC()}
+		if err := store.InsertCreated(ctx, rec); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	if err := store.MarkStarted(ctx, "enq-started", time.Now().UTC()); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+	if err := markStatus(ctx, store, "enq-failed", StatusEnqueueFailed, time.Now().UTC()); err != nil {
+		t.Fatalf("MarkStatus: %v", err)
+	}
+	for id, want := range map[string]Status{"enq-started": StatusInProgress, "enq-failed": StatusCreated} {
+		if err := store.MarkEnqueued(ctx, id, "critical", time.Now().UTC()); err != nil {
+			t.Fatalf("MarkEnqueued: %v", err)
+		}
+		got, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if got.Status != want || got.Queue != "critical" || got.EnqueuedAt.IsZero() {
+			t.Fatalf("%s: want status=%s queue=critical enqueued, got status=%s queue=%s enqueued_at=%v", id, want, got.Status, got.Queue, got.EnqueuedAt)
+		}
+	}
+}
+
+func TestSQLStore_List(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	for i, typ := range []string{"email:deliver", "email:deliver", "report:build"} {
+		rec := TaskRecord{ID: "list-" + string(rune('a'+i)), Type: typ, Queue: "default", PayloadJSON: `{}`, CreatedAt: time.Now().UTC()}
+		if err := store.InsertCreated(ctx, rec); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	if err := store.MarkStarted(ctx, "list-a", time.Now().UTC()); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+
+	got, err := store.List(ctx, TaskFilter{Type: "email:deliver"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 email records, got %d", len(got))
+	}
+	got, err = store.List(ctx, TaskFilter{Status: StatusInProgress, StartedBefore: time.Now().Add(time.Minute)})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "list-a" || got[0].StartedAt == nil {
+		t.Fatalf("unexpected in-progress records: %#v", got)
+	}
+	if got, _ := store.List(ctx, TaskFilter{Limit: 1}); len(got) != 1 {
+		t.Fatalf("Limit not applied, got %d records", len(got))
+	}
+}
+
+func TestDollarPlaceholders(t *testing.T) {
+	got := dollarPlaceholders(`UPDATE t SET a = ? WHERE id = ? AND b = ?`)
+	if want := `UPDATE t SET a = $1 WHERE id = $2 AND b = $3`; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+}
+
+func TestSQLStore_Heartbeat(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	ctx := context.Background()
+
+	rec := TaskRecord{ID: "task-hb", Type: "video:transcode", Queue: "default", PayloadJSON: `{}`, CreatedAt: time.Now().UTC()}
+	if err := store.InsertCreated(ctx, rec); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	at := time.Now().UTC().Truncate(time.Second)
+	if err := store.Heartbeat(ctx, rec.ID, at); err != nil {
+		t.Fatalf("Heartbeat: %v", err)
+	}
+	got, err := store.GetByID(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.LastHeartbeatAt == nil || !got.LastHeartbeatAt.Equal(at) {
+		t.Fatalf("want heartbeat %v got %v", at, got.LastHeartbeatAt)
+	}
+}
+
+func TestSQLStore_ChecksumDetectsTampering(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db, SQLStoreOptions{ChecksumKey: []byte("audit-key")})
+	ctx := context.Background()
+
+	rec := TaskRecord{ID: "sum-1", Type: "ledger:post", Queue: "default", PayloadJSON: `{"amount":10}`}
+	if err := store.InsertCreated(ctx, rec); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	got, err := store.GetByID(ctx, rec.ID)
+	if err != nil || got.Checksum == "" {
+		t.Fatalf("GetByID: %+v %v", got, err)
+	}
+	// Lifecycle updates do not touch the checksummed fields.
+	if err := store.MarkStarted(ctx, rec.ID, time.Now()); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+	if _, err := store.GetByID(ctx, rec.ID); err != nil {
+		t.Fatalf("GetByID after MarkStarted: %v", err)
+	}
+
+	if _, err := db.Exec(`UPDATE asyncx_tasks SET payload_json = ? WHERE id = ?`, `{"amount":10000}`, rec.ID); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if _, err := store.GetByID(ctx, rec.ID); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("GetByID: want ErrChecksumMismatch, got %v", err)
+	}
+	if _, err := store.List(ctx, TaskFilter{Type: "ledger:post"}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("List: want ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestSQLStore_ChecksumSurvivesRoundedCreatedAt(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db, SQLStoreOptions{ChecksumKey: []byte("audit-key")})
+	ctx := context.Background()
+
+	rec := TaskRecord{ID: "sum-round", Type: "ledger:post", Queue: "default", PayloadJSON: `{}`}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 700_000_000, time.UTC)
+	if err := store.exec(ctx, insertSQL, dollarPlaceholders(insertSQL), store.insertArgs(rec, at, nil)...); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	// As MySQL DATETIME does, round created_at to the nearest second.
+	got, err := store.GetByID(ctx, rec.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE asyncx_tasks SET created_at = ? WHERE id = ?`, got.CreatedAt.Round(time.Second), rec.ID); err != nil {
+		t.Fatalf("round: %v", err)
+	}
+	if _, err := store.GetByID(ctx, rec.ID); err != nil {
+		t.Fatalf("GetByID after rounding: %v", err)
+	}
+}
+
+func TestSQLStore_ChecksumRejectsStrippedChecksum(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	since := time.Now().Add(-time.Hour).UTC()
+	store := NewSQLStore(db, SQLStoreOptions{ChecksumKey: []byte("audit-key"), ChecksumSince: since})
+	ctx := context.Background()
+
+	// A record from before the key was set has no checksum.
+	legacy := TaskRecord{ID: "sum-legacy", Type: "ledger:post", Queue: "default", PayloadJSON: `{}`}
+	if err := NewSQLStore(db).InsertCreated(ctx, legacy); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE asyncx_tasks SET created_at = ? WHERE id = ?`, since.Add(-time.Hour), legacy.ID); err != nil {
+		t.Fatalf("backdate: %v", err)
+	}
+	if _, err := store.GetByID(ctx, legacy.ID); err != nil {
+		t.Fatalf("GetByID legacy: %v", err)
+	}
+
+	rec := TaskRecord{ID: "sum-2", Type: "ledger:post", Queue: "default", PayloadJSON: `{"amount":10}`}
+	if err := store.InsertCreated(ctx, rec); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE asyncx_tasks SET payload_json = ?, checksum = NULL WHERE id = ?`, `{"amount":10000}`, rec.ID); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if _, err := store.GetByID(ctx, rec.ID); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("GetByID: want ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestSQLStore_CachesPreparedStatements(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	store := NewSQLStore(db, SQLStoreOptions{MaxOpenConns: 7})
+	defer store.Close()
+	ctx := context.Background()
+	if got := db.Stats().MaxOpenConnections; got != 7 {
+		t.Fatalf("MaxOpenConns not applied: %d", got)
+	}
+
+	count := func() int {
+		n := 0
+		store.stmts.Range(func(_, _ any) bool { n++; return true })
+		return n
+	}
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("prep-%d", i)
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "t", Queue: "default", PayloadJSON: "{}"}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+		if err := store.MarkStarted(ctx, id, time.Now()); err != nil {
+			t.Fatalf("MarkStarted: %v", err)
+		}
+		if _, err := store.GetByID(ctx, id); err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+	}
+	if n := count(); n != 3 {
+		t.Fatalf("want 3 cached statements, got %d", n)
+	}
+	if err := store.Close(); err != nil || count() != 0 {
+		t.Fatalf("Close should release the statements: %v, %d left", err, count())
+	}
+}
+
+func TestMarkStarted_RecordsWorkerIdentity(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	s := startMiniRedis(t)
+	defer s.Close()
+	redisStore := NewRedisStore(asynq.RedisClientOpt{Addr: s.Addr()}, RedisStoreOptions{})
+	defer redisStore.Close()
+	bolt, err := NewBoltStore(filepath.Join(t.TempDir(), "worker.db"), BoltStoreOptions{})
+	if err != nil {
+		t.Fatalf("NewBoltStore: %v", err)
+	}
+	defer bolt.Close()
+	w := workerIdentity{ID: "host-a:42:beef", Host: "host-a", PID: 42}
+	ctx := context.WithValue(context.Background(), workerKey{}, w)
+
+	for name, store := range map[string]Store{"sql": NewSQLStore(db), "redis": redisStore, "bolt": bolt} {
+		id := "worker-" + name
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "report:build", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("%s: InsertCreated: %v", name, err)
+		}
+		if err := store.MarkStarted(ctx, id, time.Now()); err != nil {
+			t.Fatalf("%s: MarkStarted: %v", name, err)
+		}
+		rec, err := store.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("%s: GetByID: %v", name, err)
+		}
+		if rec.WorkerID != w.ID || rec.Hostname != w.Host || rec.PID != w.PID {
+			t.Errorf("%s: worker identity not recorded: %q %q %d", name, rec.WorkerID, rec.Hostname, rec.PID)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package asyncx
+
+import (
+	"context"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// RunningTask describes the task a handler is processing; see
+// TaskFromContext.
+type RunningTask struct {
+	ID    string
+	Type  string
+	Queue string
+	// RetryCount is the number of earlier attempts, MaxRetry the most asynq
+	// makes.
+	RetryCount int
+	MaxRetry   int
+	// EnqueuedAt and Metadata come from the task record; they are zero
+	// without a store or record.
+	EnqueuedAt time.Time
+	Metadata   map[string]string
+}
+
+type runningTaskKey struct{}
+
+// TaskFromContext returns the task being processed, inside handlers run by
+// a Processor. It reports false elsewhere.
+func TaskFromContext(ctx context.Context) (RunningTask, bool) {
+	rt, ok := ctx.Value(runningTaskKey{}).(RunningTask)
+	return rt, ok
+}
+
+// withRunningTask adds the RunningTask of t, described by rec if not nil,
+// to ctx.
+func withRunningTask(ctx context.Context, t *asynq.Task, rec *TaskRecord) context.Context {
+	rt := RunningTask{Type: t.Type()}
+	rt.ID, _ = getTaskID(ctx)
+	rt.Queue, _ = getQueueName(ctx)
+	rt.RetryCount, _ = getRetryCount(ctx)
+	rt.MaxRetry, _ = getMaxRetry(ctx)
+	if rec != nil {
+		rt.EnqueuedAt = rec.EnqueuedAt
+		rt.Metadata = rec.Metadata
+	}
+	return context.WithValue(ctx, runningTaskKey{}, rt)
+}
@@ -0,0 +1,53 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestTaskFromContext(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, Queues: map[string]int{"reports": 1}})
+	seen := make(chan RunningTask, 1)
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("report:build", func(ctx context.Context, tsk *asynq.Task) error {
+		rt, ok := TaskFromContext(ctx)
+		if !ok {
+			t.Error("TaskFromContext: no task")
+		}
+		seen <- rt
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{Queue: "reports"})
+	defer client.Close()
+	ctx := context.Background()
+	if _, ok := TaskFromContext(ctx); ok {
+		t.Fatal("TaskFromContext outside a handler")
+	}
+	info, err := client.Enqueue(ctx, "report:build", nil, asynq.MaxRetry(7), WithMetadata(map[string]string{"tenant": "acme"}))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	select {
+	case rt := <-seen:
+		if rt.ID != info.ID || rt.Type != "report:build" || rt.Queue != "reports" || rt.RetryCount != 0 || rt.MaxRetry != 7 ||
+			rt.EnqueuedAt.IsZero() || rt.Metadata["tenant"] != "acme" {
+			t.Fatalf("handler saw %+v", rt)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("handler did not run")
+	}
+}
@@ -0,0 +1,151 @@
+package asyncx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/hibiken/asynq"
+)
+
+// TaskTypeSpec documents a task type known to the application.
+type TaskTypeSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// PayloadSchema is a JSON Schema of the payload, for producers to read.
+	// It is not enforced.
+	PayloadSchema json.RawMessage `json:"payload_schema,omitempty"`
+	// Obsolete types are rejected by Enqueue; ReplacedBy names the type to
+	// use instead.
+	Obsolete   bool   `json:"obsolete,omitempty"`
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// Handled is set by the registry once a Processor configured with it
+	// starts with a handler for the type. Register ignores it.
+	Handled bool `json:"handled"`
+}
+
+// TaskTypeRegistry holds the set of known task types. A Client configured
+// with it rejects unknown and obsolete types on Enqueue; a Processor marks
+// the types it has handlers for. It serves its specs as JSON, for discovery
+// by producers. It is safe for concurrent use.
+type TaskTypeRegistry struct {
+	mu      sync.RWMutex
+	types   map[string]TaskTypeSpec
+	handled map[string]bool
+}
+
+func NewTaskTypeRegistry(specs ...TaskTypeSpec) *TaskTypeRegistry {
+	r := &TaskTypeRegistry{types: make(map[string]TaskTypeSpec, len(specs)), handled: make(map[string]bool)}
+	for _, s := range specs {
+		if err := r.Register(s); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a task type. Names must be non-empty and unique.
+func (r *TaskTypeRegistry) Register(spec TaskTypeSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("task type name cannot be empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.types[spec.Name]; ok {
+		return fmt.Errorf("task type %q already registered", spec.Name)
+	}
+	spec.Handled = false
+	r.types[spec.Name] = spec
+	return nil
+}
+
+func (r *TaskTypeRegistry) Lookup(name string) (TaskTypeSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.types[name]
+	s.Handled = r.handled[name]
+	return s, ok
+}
+
+// Specs returns all registered task types sorted by name.
+func (r *TaskTypeRegistry) Specs() []TaskTypeSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]TaskTypeSpec, 0, len(r.types))
+	for name, s := range r.types {
+		s.Handled = r.handled[name]
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// UnknownTaskTypeError reports a task type missing from the registry.
+// Suggestion holds the closest registered name, if any is close enough to be a typo.
+type UnknownTaskTypeError struct {
+	Name       string
+	Suggestion string
+}
+
+func (e *UnknownTaskTypeError) Error() string {
+	if e.Suggestion != "" {
+		return fmt.Sprintf("unknown task type %q (did you mean %q?)", e.Name, e.Suggestion)
+	}
+	return fmt.Sprintf("unknown task type %q", e.Name)
+}
+
+// ObsoleteTaskTypeError reports a task type registered as obsolete.
+type ObsoleteTaskTypeError struct {
+	Name       string
+	ReplacedBy string
+}
+
+func (e *ObsoleteTaskTypeError) Error() string {
+	if e.ReplacedBy != "" {
+		return fmt.Sprintf("task type %q is obsolete, use %q", e.Name, e.ReplacedBy)
+	}
+	return fmt.Sprintf("task type %q is obsolete", e.Name)
+}
+
+// Validate returns an *UnknownTaskTypeError or *ObsoleteTaskTypeError for a
+// type producers may not enqueue.
+func (r *TaskTypeRegistry) Validate(name string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if s, ok := r.types[name]; ok {
+		if s.Obsolete {
+			return &ObsoleteTaskTypeError{Name: name, ReplacedBy: s.ReplacedBy}
+		}
+		return nil
+	}
+	e := &UnknownTaskTypeError{Name: name}
+	best := len(name)/2 + 1
+	for known, s := range r.types {
+		if s.Obsolete {
+			continue
+		}
+		if d := editDistance(name, known); d < best || (d == best && known < e.Suggestion) {
+			best, e.Suggestion = d, known
+		}
+	}
+	return e
+}
+
+// markHandled records which registered types mux has a handler for.
+func (r *TaskTypeRegistry) markHandled(mux *asynq.ServeMux) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name := range r.types {
+		if _, pattern := mux.Handler(asynq.NewTask(name, nil)); pattern != "" {
+			r.handled[name] = true
+		}
+	}
+}
+
+// ServeHTTP writes Specs as JSON.
+func (r *TaskTypeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(r.Specs())
+}
@@ -0,0 +1,57 @@
+package asyncx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestTaskTypeRegistry(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	ctx := context.Background()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	types := NewTaskTypeRegistry(
+		TaskTypeSpec{Name: "email:send", Description: "Sends one email", PayloadSchema: json.RawMessage(`{"type":"object"}`)},
+		TaskTypeSpec{Name: "email:deliver", Obsolete: true, ReplacedBy: "email:send"},
+		TaskTypeSpec{Name: "report:build"},
+	)
+	if err := types.Register(TaskTypeSpec{Name: "report:build"}); err == nil {
+		t.Fatal("duplicate Register should fail")
+	}
+
+	client := NewClient(redis, nil, ClientOptions{TaskTypes: types})
+	defer client.Close()
+	if _, err := client.Enqueue(ctx, "email:send", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	var unknown *UnknownTaskTypeError
+	if _, err := client.Enqueue(ctx, "email:snd", nil); !errors.As(err, &unknown) || unknown.Suggestion != "email:send" {
+		t.Fatalf("typo: %v", err)
+	}
+	var obsolete *ObsoleteTaskTypeError
+	if _, err := client.Enqueue(ctx, "email:deliver", nil); !errors.As(err, &obsolete) || obsolete.ReplacedBy != "email:send" {
+		t.Fatalf("obsolete: %v", err)
+	}
+
+	processor := NewProcessor(redis, nil, ProcessorConfig{Concurrency: 1, TaskTypes: types})
+	processor.HandleFunc("email:send", func(ctx context.Context, t *asynq.Task) error { return nil })
+	if _, err := processor.serveMux(nil); err != nil {
+		t.Fatalf("serveMux: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	types.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/task-types", nil))
+	var specs []TaskTypeSpec
+	if err := json.Unmarshal(rec.Body.Bytes(), &specs); err != nil || len(specs) != 3 {
+		t.Fatalf("discovery: %s %v", rec.Body, err)
+	}
+	if specs[1].Name != "email:send" || !specs[1].Handled || string(specs[1].PayloadSchema) != `{"type":"object"}` || specs[2].Handled {
+		t.Fatalf("specs = %+v", specs)
+	}
+}
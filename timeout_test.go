@@ -0,0 +1,53 @@
+package asyncx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestProcessor_PerTypeTimeout(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	processor := NewProcessor(redis, store, ProcessorConfig{
+		Concurrency: 1,
+		Timeouts:    map[string]time.Duration{"to:slow": 50 * time.Millisecond},
+	})
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("to:slow", func(ctx context.Context, tsk *asynq.Task) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+
+	info, err := client.Enqueue(ctx, "to:slow", nil, asynq.MaxRetry(0))
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := pollUntil(t, 3*time.Second, func() (bool, error) {
+		rec, err := store.GetByID(ctx, info.ID)
+		return err == nil && rec.Status == StatusTimedOut, nil
+	}); err != nil {
+		t.Fatalf("task did not time out: %v", err)
+	}
+	rec, _ := store.GetByID(ctx, info.ID)
+	if rec.TimeoutMS != 50 || rec.ErrorMsg == nil || *rec.ErrorMsg != "timed out after 50ms" {
+		t.Fatalf("unexpected record: %#v", rec)
+	}
+}
@@ -0,0 +1,81 @@
+package asyncx
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingConfig enables a span per processed task; see ProcessorConfig.Tracing.
+type TracingConfig struct {
+	Tracer trace.Tracer
+	// SuccessSampleRate is the fraction (0..1) of tasks traced up front; their
+	// span is the parent of spans created in the handler. Zero keeps no
+	// successful spans. Failed tasks are always recorded: when a failure was
+	// not sampled, its span is created afterwards with the original start
+	// time and has no children.
+	SuccessSampleRate float64
+	// Tags are static attributes added to every span, e.g. service or env.
+	Tags map[string]string
+}
+
+// spanName is the name of every task span; the task type is the resource.
+const spanName = "asyncx.process"
+
+// taskSpan tracks the span of one task, which may not exist yet.
+type taskSpan struct {
+	cfg   *TracingConfig
+	span  trace.Span // nil until sampled
+	ctx   context.Context
+	begin time.Time
+	attrs []attribute.KeyValue
+	opts  []trace.SpanStartOption // kind and workflow parent or link
+}
+
+// startSpan samples the task and, if selected, starts its span and returns a
+// context carrying it.
+func startSpan(ctx context.Context, cfg *TracingConfig, redact *RedactionPolicy, t *asynq.Task, ev TaskEvent) (context.Context, *taskSpan) {
+	ctx, wopts := workflowSpanOptions(ctx)
+	opts := append([]trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindConsumer)}, wopts...)
+	ts := &taskSpan{cfg: cfg, ctx: ctx, begin: time.Now(), attrs: spanAttrs(cfg, redact, t, ev), opts: opts}
+	if cfg.SuccessSampleRate > 0 && rand.Float64() < cfg.SuccessSampleRate {
+		ctx, ts.span = cfg.Tracer.Start(ctx, spanName, append(ts.opts, trace.WithAttributes(ts.attrs...))...)
+	}
+	return ctx, ts
+}
+
+func spanAttrs(cfg *TracingConfig, redact *RedactionPolicy, t *asynq.Task, ev TaskEvent) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("resource.name", t.Type()),
+		attribute.String("asyncx.task.type", t.Type()),
+		attribute.String("asyncx.task.id", ev.TaskID),
+		attribute.String("asyncx.queue", ev.Queue),
+		attribute.Int("asyncx.retried", ev.Retried),
+	}
+	for k, v := range redact.RedactLabels(cfg.Tags) {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return attrs
+}
+
+// end finishes the span, creating it retroactively for unsampled failures.
+func (ts *taskSpan) end(err error, retrying bool) {
+	if ts.span == nil {
+		if err == nil {
+			return
+		}
+		_, ts.span = ts.cfg.Tracer.Start(ts.ctx, spanName,
+			append(ts.opts, trace.WithAttributes(ts.attrs...), trace.WithTimestamp(ts.begin))...)
+	}
+	if err != nil {
+		ts.span.RecordError(err)
+		ts.span.SetStatus(codes.Error, err.Error())
+		ts.span.SetAttributes(attribute.Bool("asyncx.will_retry", retrying))
+	}
+	ts.span.End()
+}
@@ -0,0 +1,118 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTaskSpan_Sampling(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("asyncx-test")
+	task := asynq.NewTask("report:build", nil)
+	ev := TaskEvent{TaskID: "t-1", Queue: "default"}
+	ctx := context.Background()
+
+	none := &TracingConfig{Tracer: tracer, Tags: map[string]string{"env": "test"}}
+	_, span := startSpan(ctx, none, nil, task, ev)
+	span.end(nil, false)
+	if n := len(rec.Ended()); n != 0 {
+		t.Fatalf("unsampled success must not record a span, got %d", n)
+	}
+
+	_, span = startSpan(ctx, none, nil, task, ev)
+	time.Sleep(10 * time.Millisecond)
+	span.end(errors.New("boom"), true)
+	ended := rec.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("failures must always be recorded, got %d spans", len(ended))
+	}
+	s := ended[0]
+	if s.Status().Code != codes.Error || s.EndTime().Sub(s.StartTime()) < 10*time.Millisecond {
+		t.Fatalf("retroactive span should keep status and start time: %v %v", s.Status(), s.EndTime().Sub(s.StartTime()))
+	}
+	attrs := attribute.NewSet(s.Attributes()...)
+	for k, want := range map[attribute.Key]string{"resource.name": "report:build", "asyncx.task.id": "t-1", "env": "test"} {
+		if v, ok := attrs.Value(k); !ok || v.AsString() != want {
+			t.Fatalf("attribute %s = %v, want %s", k, v, want)
+		}
+	}
+
+	all := &TracingConfig{Tracer: tracer, SuccessSampleRate: 1}
+	spanCtx, span := startSpan(ctx, all, nil, task, ev)
+	if !trace.SpanContextFromContext(spanCtx).IsValid() {
+		t.Fatalf("sampled task should expose its span to the handler")
+	}
+	span.end(nil, false)
+	if n := len(rec.Ended()); n != 2 {
+		t.Fatalf("sampled success should be recorded, got %d spans", n)
+	}
+}
+
+func TestWorkflow_StepsShareTrace(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDBIntegration(t)
+	defer db.Close()
+	store := NewSQLStore(db)
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("asyncx-test")
+
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 1, Tracing: &TracingConfig{Tracer: tracer, SuccessSampleRate: 1}})
+	done := make(chan string, 1)
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("wf:extract", func(ctx context.Context, tsk *asynq.Task) error {
+		_, err := client.Enqueue(ctx, "wf:load", nil)
+		return err
+	})
+	mux.HandleFunc("wf:load", func(ctx context.Context, tsk *asynq.Task) error {
+		id, _ := asynq.GetTaskID(ctx)
+		done <- id
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	ctx, root := StartWorkflow(context.Background(), tracer, "etl")
+	if _, err := client.Enqueue(ctx, "wf:extract", nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	root.End()
+
+	var loadID string
+	select {
+	case loadID = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("workflow did not finish")
+	}
+	want := root.SpanContext().TraceID()
+	r, err := store.GetByID(context.Background(), loadID)
+	if err != nil || r.WorkflowTraceparent == nil {
+		t.Fatalf("second step should record the workflow: %+v %v", r, err)
+	}
+	time.Sleep(100 * time.Millisecond) // let the last span end
+	steps := 0
+	for _, sp := range rec.Ended() {
+		if sp.Name() != spanName {
+			continue
+		}
+		steps++
+		if sp.SpanContext().TraceID() != want {
+			t.Fatalf("step span in trace %s, want workflow trace %s", sp.SpanContext().TraceID(), want)
+		}
+	}
+	if steps != 2 {
+		t.Fatalf("want 2 step spans, got %d", steps)
+	}
+}
@@ -0,0 +1,91 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TransitionError is returned by a store with
+// SQLStoreOptions.StrictTransitions for an outcome the record's current
+// status does not allow, e.g. a late duplicate worker completing a task
+// that another worker already failed for good. The record is unchanged.
+type TransitionError struct {
+	TaskID string
+	From   Status
+	To     Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("asyncx: task %s cannot go from %s to %s", e.TaskID, e.From, e.To)
+}
+
+// outcomeFrom lists the statuses each outcome may be recorded from under
+// StrictTransitions. Stale and needs_review tasks may still be finished by
+// the worker that was presumed lost; created, enqueue_failed and parked
+// records may be failed by a Reenqueuer or Release. A task may start from
+// any status but a final one, and from failed while a retry is scheduled.
+var outcomeFrom = map[Status][]Status{
+	StatusCompleted: {StatusInProgress, StatusAwaitingAck, StatusNeedsReview, StatusStale},
+	StatusFailed:    {StatusInProgress, StatusStale, StatusCreated, StatusEnqueueFailed, StatusParked},
+	StatusTimedOut:  {StatusInProgress, StatusStale},
+	StatusInProgress: {
+		StatusCreated, StatusInProgress, StatusThrottled, StatusInterrupted, StatusDeferred,
+		StatusStale, StatusHeld, StatusEnqueueFailed, StatusParked,
+	},
+}
+
+// transition runs the outcome update q (qpg for Postgres), whose last
+// argument is the task ID. Under StrictTransitions it only applies to
+// records in a status outcomeFrom allows for to. When no row is affected the
+// record is re-read: MySQL reports 0 for an update that changes nothing, so
+// only a status the outcome may not follow is a TransitionError.
+func (s *SQLStore) transition(ctx context.Context, to Status, q, qpg string, args ...any) error {
+	if !s.strict {
+		return s.exec(ctx, q, qpg, args...)
+	}
+	var from []string
+	for _, st := range outcomeFrom[to] {
+		from = append(from, string(st))
+	}
+	cond := `status IN ('` + strings.Join(from, `', '`) + `')`
+	if to == StatusInProgress {
+		cond = `(` + cond + ` OR (status = '` + string(StatusFailed) + `' AND next_retry_at IS NOT NULL))`
+	}
+	cond = ` AND ` + cond
+	st, err := s.stmt(ctx, q+cond, qpg+cond)
+	if err != nil {
+		return err
+	}
+	res, err := st.ExecContext(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	taskID := args[len(args)-1].(string)
+	rec, err := s.GetByID(ctx, taskID)
+	if errors.Is(err, ErrNotFound) {
+		// As without StrictTransitions, updating a missing record is a no-op.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if outcomeAllowed(rec, to) {
+		return nil
+	}
+	return &TransitionError{TaskID: taskID, From: rec.Status, To: to}
+}
+
+// outcomeAllowed reports whether rec's status may be followed by to, as
+// the condition transition adds to the update does.
+func outcomeAllowed(rec *TaskRecord, to Status) bool {
+	if to == StatusInProgress && rec.Status == StatusFailed && rec.NextRetryAt != nil {
+		return true
+	}
+	return slices.Contains(outcomeFrom[to], rec.Status)
+}
@@ -0,0 +1,88 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLStore_StrictTransitions(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db, SQLStoreOptions{StrictTransitions: true})
+	now := time.Now().UTC()
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "t1", Type: "billing:charge", Queue: DefaultQueue, PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+
+	var te *TransitionError
+	if err := store.MarkCompleted(ctx, "t1", nil, now); !errors.As(err, &te) || te.From != StatusCreated || te.To != StatusCompleted {
+		t.Fatalf("complete before start: %v", err)
+	}
+	if err := store.MarkStarted(ctx, "t1", now); err != nil {
+		t.Fatalf("MarkStarted: %v", err)
+	}
+	if err := store.MarkCompleted(ctx, "t1", nil, now); err != nil {
+		t.Fatalf("MarkCompleted: %v", err)
+	}
+	// A late duplicate worker reports a failure.
+	if err := store.MarkFailed(ctx, "t1", "boom", now); !errors.As(err, &te) || te.From != StatusCompleted {
+		t.Fatalf("fail after completion: %v", err)
+	}
+	if err := store.MarkTimedOut(ctx, "t1", time.Second, now); !errors.As(err, &te) {
+		t.Fatalf("time out after completion: %v", err)
+	}
+	// A duplicate delivery starts again.
+	if err := store.MarkStarted(ctx, "t1", now); !errors.As(err, &te) || te.To != StatusInProgress {
+		t.Fatalf("start after completion: %v", err)
+	}
+	rec, err := store.GetByID(ctx, "t1")
+	if err != nil || rec.Status != StatusCompleted || rec.ErrorMsg != nil {
+		t.Fatalf("record changed: %+v %v", rec, err)
+	}
+
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "t2", Type: "billing:charge", Queue: DefaultQueue, PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	_ = store.MarkStarted(ctx, "t2", now)
+	if err := store.MarkRetry(ctx, "t2", "boom", now.Add(time.Minute)); err != nil {
+		t.Fatalf("MarkRetry: %v", err)
+	}
+	if err := store.MarkStarted(ctx, "t2", now); err != nil {
+		t.Fatalf("start a scheduled retry: %v", err)
+	}
+	if err := store.MarkFailed(ctx, "t2", "boom", now); err != nil {
+		t.Fatalf("MarkFailed: %v", err)
+	}
+	if err := store.MarkStarted(ctx, "t2", now); !errors.As(err, &te) || te.From != StatusFailed {
+		t.Fatalf("start after final failure: %v", err)
+	}
+	if err := store.MarkCompleted(ctx, "missing", nil, now); err != nil {
+		t.Fatalf("missing record: %v", err)
+	}
+
+	// MySQL reports no affected rows for an update that changes nothing;
+	// the trigger makes SQLite do the same.
+	if _, err := db.Exec(`CREATE TRIGGER asyncx_no_change BEFORE UPDATE ON asyncx_tasks BEGIN SELECT RAISE(IGNORE); END`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+	if err := store.InsertCreated(ctx, TaskRecord{ID: "t3", Type: "billing:charge", Queue: DefaultQueue, PayloadJSON: `{}`}); err != nil {
+		t.Fatalf("InsertCreated: %v", err)
+	}
+	if err := store.MarkStarted(ctx, "t3", now); err != nil {
+		t.Fatalf("unchanged but allowed update: %v", err)
+	}
+	if err := store.MarkCompleted(ctx, "t3", nil, now); !errors.As(err, &te) || te.From != StatusCreated {
+		t.Fatalf("unchanged and refused update: %v", err)
+	}
+	if _, err := db.Exec(`DROP TRIGGER asyncx_no_change`); err != nil {
+		t.Fatalf("drop trigger: %v", err)
+	}
+
+	lax := NewSQLStore(db)
+	if err := lax.MarkFailed(ctx, "t1", "boom", now); err != nil {
+		t.Fatalf("without StrictTransitions: %v", err)
+	}
+}
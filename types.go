@@ -1,9 +1,14 @@
 package asyncx
 
-import "time"
+import (
+	"slices"
+	"strings"
+	"time"
+)
 
 // Status represents task processing status recorded in the database.
-// Valid values: created, in_progress, completed, failed.
+// Valid values: created, in_progress, completed, failed, throttled, interrupted,
+// awaiting_ack, needs_review, stale, dry_run, timed_out.
 // Kept as string for readability in SQL and flexibility.
 type Status string
 
@@ -12,20 +17,199 @@ const (
 	StatusInProgress Status = "in_progress"
 	StatusCompleted  Status = "completed"
 	StatusFailed     Status = "failed"
+	StatusThrottled  Status = "throttled"
+	// StatusInterrupted marks a task that was still running when the Processor
+	// shut down. asynq re-queues such tasks, so the status is normally transient.
+	StatusInterrupted Status = "interrupted"
+	// StatusAwaitingAck marks a task whose handler returned but whose
+	// completion still needs confirmation; see ProcessorConfig.RequireAck.
+	StatusAwaitingAck Status = "awaiting_ack"
+	// StatusNeedsReview is set by the Janitor when a confirmation never arrived.
+	StatusNeedsReview Status = "needs_review"
+	// StatusStale is set by the Reaper on tasks stuck in in_progress,
+	// typically because the worker running them crashed.
+	StatusStale Status = "stale"
+	// StatusDryRun marks a task consumed by a Processor in dry-run mode whose
+	// middleware and validation passed; see ProcessorConfig.DryRun.
+	StatusDryRun Status = "dry_run"
+	// StatusTimedOut marks a task whose handler exceeded the timeout configured
+	// for its type in ProcessorConfig.Timeouts on its last attempt. Earlier
+	// overruns are recorded as retries.
+	StatusTimedOut Status = "timed_out"
+	// StatusDeferred marks a task that arrived during a downtime window of its
+	// type, or while its CircuitBreaker was open; it runs again when the
+	// window or cool-down ends.
+	StatusDeferred Status = "deferred"
+	// StatusSuppressed marks a task that did not run because another copy of
+	// the same re-drive already had; see Client.Redrive.
+	StatusSuppressed Status = "suppressed"
+	// StatusHeld marks a task frozen with Client.Freeze; it is off Redis,
+	// kept in its record, until Client.Unfreeze.
+	StatusHeld Status = "held"
+	// StatusEnqueueFailed marks a record inserted before a Redis enqueue
+	// that failed; see ClientOptions.PersistBeforeEnqueue.
+	StatusEnqueueFailed Status = "enqueue_failed"
+	// StatusUnroutable marks a task whose type had no handler; see
+	// ProcessorConfig.NotFoundHandler.
+	StatusUnroutable Status = "unroutable"
+	// StatusParked marks a task recorded but not yet put on Redis; see
+	// Client.EnqueueParked.
+	StatusParked Status = "parked"
+	// StatusCanceled marks a task deleted before it ran; see
+	// Client.CancelWhere.
+	StatusCanceled Status = "canceled"
+	// StatusPromoted only appears in task history, for a task a
+	// PriorityAger moved to a higher queue; the record stays created.
+	StatusPromoted Status = "promoted"
+)
+
+// finalStatuses are the statuses of records whose task is done with, for
+// good or bad, unless it is re-driven; StatusFailed only once no retry is
+// scheduled (next_retry_at is NULL). Stale tasks may still be finished by
+// the worker presumed lost and enqueue_failed ones re-enqueued by a
+// Reenqueuer.
+var finalStatuses = []Status{
+	StatusCompleted, StatusFailed, StatusTimedOut, StatusSuppressed, StatusDryRun,
+	StatusCanceled, StatusUnroutable, StatusStale, StatusEnqueueFailed,
+}
+
+// isFinal reports whether rec has one of finalStatuses.
+func isFinal(rec *TaskRecord) bool {
+	if rec.Status == StatusFailed {
+		return rec.NextRetryAt == nil
+	}
+	return slices.Contains(finalStatuses, rec.Status)
+}
+
+// finalCondition is the SQL condition isFinal applies.
+func finalCondition() string {
+	return `(status IN (` + statusList(finalStatuses) + `) AND (status <> '` + string(StatusFailed) + `' OR next_retry_at IS NULL))`
+}
+
+// statusList renders statuses as a list of SQL string literals.
+func statusList(statuses []Status) string {
+	in := make([]string, len(statuses))
+	for i, st := range statuses {
+		in[i] = string(st)
+	}
+	return `'` + strings.Join(in, `', '`) + `'`
+}
+
+// unenqueuedStatuses are the statuses of records whose task is not on Redis.
+// MarkEnqueued moves only these to StatusCreated; a worker may already have
+// moved the record on by the time the enqueue is recorded.
+var unenqueuedStatuses = []Status{StatusCreated, StatusEnqueueFailed, StatusParked, StatusHeld}
+
+// insertStatus is the status InsertCreated gives rec: StatusParked for a
+// parked record, so that no Reenqueuer sees it as created first, and
+// StatusCreated otherwise.
+func insertStatus(rec *TaskRecord) Status {
+	if rec.Status == StatusParked {
+		return StatusParked
+	}
+	return StatusCreated
+}
+
+// FailureKind distinguishes failures that retrying cannot fix from ones that
+// merely ran out of retries.
+type FailureKind string
+
+const (
+	// FailureTransient failures may succeed on retry, e.g. timeouts.
+	FailureTransient FailureKind = "transient"
+	// FailurePermanent failures were marked with NonRetryable or asynq.SkipRetry.
+	FailurePermanent FailureKind = "permanent"
+	// FailureBudgetExhausted failures used up their type's runtime budget
+	// and were not retried further.
+	FailureBudgetExhausted FailureKind = "budget_exhausted"
+)
+
+// TaskClass classifies how strictly a task is tracked.
+// Metrics and retention jobs can use it to treat classes differently.
+type TaskClass string
+
+const (
+	// ClassStandard tasks use asynq's retry defaults and full lifecycle tracking.
+	ClassStandard TaskClass = "standard"
+	// ClassCritical tasks are tracked exactly and should be retained longest.
+	ClassCritical TaskClass = "critical"
+	// ClassFireAndForget tasks are never retried, only their creation and
+	// terminal state are persisted, and they are candidates for short retention.
+	ClassFireAndForget TaskClass = "fire_and_forget"
 )
 
 // TaskRecord is the persisted representation of a task lifecycle.
 // It stores the essential metadata for auditing and retries.
 type TaskRecord struct {
-	ID          string // asynq task ID
-	Type        string // asynq task type
-	Queue       string // queue name
-	PayloadJSON string // raw JSON payload as string
-	Status      Status
-	ErrorMsg    *string // last error message, if any
-	ResultJSON  *string // optional task result JSON, if handler set
-	CreatedAt   time.Time
-	EnqueuedAt  time.Time
-	StartedAt   *time.Time
-	FinishedAt  *time.Time
+	ID          string     `json:"id"`           // asynq task ID
+	Type        string     `json:"type"`         // asynq task type
+	Queue       string     `json:"queue"`        // queue name
+	PayloadJSON string     `json:"payload_json"` // raw JSON payload as string; base64 for non-JSON codecs
+	Status      Status     `json:"status"`
+	Class       TaskClass  `json:"task_class,omitempty"`  // empty for records created before classification existed
+	ErrorMsg    *string    `json:"error_msg,omitempty"`   // last error message, if any
+	ResultJSON  *string    `json:"result_json,omitempty"` // optional task result JSON, if handler set
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	EnqueuedAt  time.Time  `json:"enqueued_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// LastHeartbeatAt is refreshed while the handler runs when
+	// ProcessorConfig.HeartbeatInterval is set.
+	LastHeartbeatAt *time.Time `json:"last_heartbeat_at,omitempty"`
+	// NextRetryAt is when asynq will retry a failed task; it is cleared when
+	// the task starts again.
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	// FailureKind is set on failed records and cleared when the task starts again.
+	FailureKind FailureKind `json:"failure_kind,omitempty"`
+	// ErrorDetails holds diagnostics for the last error, such as the stack
+	// trace of a recovered panic.
+	ErrorDetails *string `json:"error_details,omitempty"`
+	// TimeoutMS is the configured timeout, in milliseconds, when the task timed out.
+	TimeoutMS int64 `json:"timeout_ms,omitempty"`
+	// RequestJSON is the RequestInfo captured from the enqueueing context, if any.
+	RequestJSON *string `json:"request_json,omitempty"`
+	// Priority is the tier of the queue the task was enqueued on, if that
+	// queue is one of the named priority tiers.
+	Priority Priority `json:"priority,omitempty"`
+	// RuntimeMS is the handler run time summed over all attempts. It is only
+	// tracked for task types with a runtime budget.
+	RuntimeMS int64 `json:"runtime_ms,omitempty"`
+	// Metadata holds the labels attached with WithMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// GuardToken ties a re-driven task to its original; see Client.Redrive.
+	GuardToken string `json:"guard_token,omitempty"`
+	// CreatedBy is the user or service account that enqueued the task: the
+	// RequestInfo.Subject of the enqueue context, else ClientOptions.CreatedBy.
+	CreatedBy string `json:"created_by,omitempty"`
+	// Source is the enqueueing service; see ClientOptions.Source.
+	Source string `json:"source,omitempty"`
+	// Checksum is the HMAC written by an SQLStore with a ChecksumKey.
+	Checksum string `json:"checksum,omitempty"`
+	// DedupKey is the key given with WithDedupKey, if any.
+	DedupKey string `json:"dedup_key,omitempty"`
+	// WorkflowTraceparent is the W3C traceparent of the StartWorkflow span
+	// the task was enqueued under, if any.
+	WorkflowTraceparent *string `json:"workflow_traceparent,omitempty"`
+	// PayloadPurgedAt is set when the Janitor purged the payload under a
+	// PayloadRetention policy; PayloadJSON is then "null".
+	PayloadPurgedAt *time.Time `json:"payload_purged_at,omitempty"`
+	// ParentTaskID is the task that spawned this one with EnqueueChild.
+	ParentTaskID string `json:"parent_task_id,omitempty"`
+	// WorkerID, Hostname and PID identify the Processor that last started
+	// the task.
+	WorkerID string `json:"worker_id,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	PID      int    `json:"pid,omitempty"`
+	// ContentType is the Codec content type of the payload. Empty means
+	// JSON.
+	ContentType string `json:"content_type,omitempty"`
+	// PayloadVersion is the payload schema version the task was enqueued
+	// with; see ClientOptions.PayloadVersions. Zero means unversioned.
+	PayloadVersion int `json:"payload_version,omitempty"`
+	// ContentHash is the hash of type and payload recorded for
+	// ClientOptions.ContentDedupWindows.
+	ContentHash string `json:"content_hash,omitempty"`
+	// Shard names the Redis instance a ShardedClient enqueued the task on.
+	Shard string `json:"shard,omitempty"`
 }
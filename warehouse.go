@@ -0,0 +1,163 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// WarehouseWriter loads finished records into an analytics store such as
+// ClickHouse or BigQuery; implement it with the warehouse's client. A batch
+// whose export could not be recorded is written again, so WriteBatch should
+// deduplicate by record ID, e.g. with a ReplacingMergeTree or a MERGE.
+type WarehouseWriter interface {
+	WriteBatch(ctx context.Context, recs []*TaskRecord) error
+}
+
+// WarehouseStore is implemented by stores a WarehouseSink can drain. It
+// tracks exports in the exported_at column.
+type WarehouseStore interface {
+	// Unexported returns up to limit finished records that were not
+	// exported yet, oldest first. Finished records are those in one of the
+	// final statuses (completed, failed for good, timed_out, suppressed,
+	// dry_run, canceled, unroutable, stale and enqueue_failed).
+	Unexported(ctx context.Context, limit int) ([]*TaskRecord, error)
+	MarkExported(ctx context.Context, ids []string, at time.Time) error
+	// DeleteExported deletes records exported before cutoff and returns
+	// how many it deleted.
+	DeleteExported(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type WarehouseSinkConfig struct {
+	// Interval between passes. Defaults to one minute.
+	Interval time.Duration
+	// BatchSize is the most records written per WriteBatch. Defaults to 500.
+	BatchSize int
+	// Leader, if set, limits passes to the elected replica.
+	Leader Leader
+}
+
+// WarehouseSink streams finished records to a WarehouseWriter for long-term
+// analytics. Pair it with JanitorConfig.DeleteExportedAfter to keep
+// asyncx_tasks small.
+type WarehouseSink struct {
+	store  WarehouseStore
+	writer WarehouseWriter
+	cfg    WarehouseSinkConfig
+}
+
+// NewWarehouseSink panics if store does not implement WarehouseStore.
+func NewWarehouseSink(store Store, w WarehouseWriter, cfg WarehouseSinkConfig) *WarehouseSink {
+	ws, ok := storeAs[WarehouseStore](store)
+	if !ok {
+		panic("asyncx: NewWarehouseSink: store does not implement WarehouseStore")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	return &WarehouseSink{store: ws, writer: w, cfg: cfg}
+}
+
+// Run exports every Interval until ctx is cancelled.
+func (s *WarehouseSink) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = s.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce exports batches until no finished record is left unexported, or
+// does nothing unless s leads.
+func (s *WarehouseSink) RunOnce(ctx context.Context) error {
+	if !leads(s.cfg.Leader) {
+		return nil
+	}
+	for {
+		recs, err := s.store.Unexported(ctx, s.cfg.BatchSize)
+		if err != nil || len(recs) == 0 {
+			return err
+		}
+		if err := s.writer.WriteBatch(ctx, recs); err != nil {
+			return err
+		}
+		ids := make([]string, len(recs))
+		for i, rec := range recs {
+			ids[i] = rec.ID
+		}
+		if err := s.store.MarkExported(ctx, ids, time.Now().UTC()); err != nil {
+			return err
+		}
+		if len(recs) < s.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// deleteExported deletes records exported more than DeleteExportedAfter ago.
+func (j *Janitor) deleteExported(ctx context.Context, now time.Time) error {
+	ws, ok := storeAs[WarehouseStore](j.store)
+	if !ok || j.cfg.DeleteExportedAfter <= 0 {
+		return nil
+	}
+	_, err := ws.DeleteExported(ctx, now.Add(-j.cfg.DeleteExportedAfter))
+	return err
+}
+
+func (s *SQLStore) Unexported(ctx context.Context, limit int) ([]*TaskRecord, error) {
+	if s.db == nil {
+		return nil, errors.New("nil db")
+	}
+	q := `SELECT ` + taskColumns + ` FROM asyncx_tasks WHERE exported_at IS NULL AND ` + finalCondition() + ` ORDER BY created_at LIMIT ?`
+	var out []*TaskRecord
+	err := s.queryRows(ctx, q, []any{limit}, func(rows *sql.Rows) error {
+		rec, err := scanTask(rows)
+		if err != nil {
+			return err
+		}
+		if err := s.verify(rec); err != nil {
+			return err
+		}
+		out = append(out, rec)
+		return nil
+	})
+	return out, err
+}
+
+func (s *SQLStore) MarkExported(ctx context.Context, ids []string, at time.Time) error {
+	if s.db == nil {
+		return errors.New("nil db")
+	}
+	q := `UPDATE asyncx_tasks SET exported_at = ? WHERE id = ?`
+	qpg := `UPDATE asyncx_tasks SET exported_at = $1 WHERE id = $2`
+	for _, id := range ids {
+		if err := s.exec(ctx, q, qpg, at.UTC(), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) DeleteExported(ctx context.Context, cutoff time.Time) (int64, error) {
+	if s.db == nil {
+		return 0, errors.New("nil db")
+	}
+	st, err := s.stmt(ctx, `DELETE FROM asyncx_tasks WHERE exported_at < ?`, `DELETE FROM asyncx_tasks WHERE exported_at < $1`)
+	if err != nil {
+		return 0, err
+	}
+	res, err := st.ExecContext(ctx, cutoff.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
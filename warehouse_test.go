@@ -0,0 +1,66 @@
+package asyncx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeWarehouse struct {
+	batches [][]string
+}
+
+func (w *fakeWarehouse) WriteBatch(ctx context.Context, recs []*TaskRecord) error {
+	var ids []string
+	for _, rec := range recs {
+		ids = append(ids, rec.ID)
+	}
+	w.batches = append(w.batches, ids)
+	return nil
+}
+
+func TestWarehouseSink(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewSQLStore(db)
+	now := time.Now()
+	for _, id := range []string{"done-1", "done-2", "done-3", "timed-out", "canceled", "retrying", "running"} {
+		if err := store.InsertCreated(ctx, TaskRecord{ID: id, Type: "report:build", Queue: "default", PayloadJSON: `{}`}); err != nil {
+			t.Fatalf("InsertCreated: %v", err)
+		}
+	}
+	for _, id := range []string{"done-1", "done-2"} {
+		_ = store.MarkCompleted(ctx, id, nil, now)
+	}
+	_ = store.MarkPermanentFailure(ctx, "done-3", "boom", now)
+	_ = store.MarkTimedOut(ctx, "timed-out", time.Second, now)
+	_ = store.MarkStatus(ctx, "canceled", StatusCanceled, now)
+	_ = store.MarkRetry(ctx, "retrying", "flaky", now.Add(time.Minute))
+	_ = store.MarkStarted(ctx, "running", now)
+
+	w := &fakeWarehouse{}
+	sink := NewWarehouseSink(store, w, WarehouseSinkConfig{BatchSize: 2})
+	if err := sink.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	// Every final status is exported, not only completed and failed.
+	if len(w.batches) != 3 || len(w.batches[0]) != 2 || len(w.batches[1]) != 2 || len(w.batches[2]) != 1 {
+		t.Fatalf("batches %v", w.batches)
+	}
+	if err := sink.RunOnce(ctx); err != nil || len(w.batches) != 3 {
+		t.Fatalf("second pass should export nothing: %v %v", w.batches, err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := NewJanitor(store, JanitorConfig{DeleteExportedAfter: time.Millisecond}).RunOnce(ctx); err != nil {
+		t.Fatalf("Janitor: %v", err)
+	}
+	if _, err := store.GetByID(ctx, "done-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("exported record should be deleted, got %v", err)
+	}
+	if recs, _ := store.List(ctx, TaskFilter{}); len(recs) != 2 {
+		t.Fatalf("unexported records should remain, got %d", len(recs))
+	}
+}
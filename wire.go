@@ -0,0 +1,12 @@
+package asyncx
+
+import "github.com/google/wire"
+
+// WireSet provides Store, *Client, *Processor and *asynq.ServeMux from a
+// Config. The injector supplies the Config and runs the Processor:
+//
+//	func InitProcessor(cfg asyncx.Config) (*asyncx.Processor, error) {
+//		wire.Build(asyncx.WireSet)
+//		return nil, nil
+//	}
+var WireSet = wire.NewSet(ProvideStore, ProvideClient, ProvideProcessor, ProvideServeMux)
@@ -0,0 +1,450 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// ErrWorkflowNotFound is returned by Orchestrator.Get for unknown workflows.
+var ErrWorkflowNotFound = errors.New("asyncx: workflow not found")
+
+// WorkflowStatus is the state of a workflow in asyncx_workflows.
+type WorkflowStatus string
+
+const (
+	WorkflowRunning   WorkflowStatus = "running"
+	WorkflowCompleted WorkflowStatus = "completed"
+	WorkflowFailed    WorkflowStatus = "failed"
+)
+
+// NodeStatus is the state of a workflow node in asyncx_workflow_nodes.
+type NodeStatus string
+
+const (
+	NodePending   NodeStatus = "pending"
+	NodeEnqueued  NodeStatus = "enqueued"
+	NodeCompleted NodeStatus = "completed"
+	NodeFailed    NodeStatus = "failed"
+	// NodeSkipped marks a node that never ran because the workflow failed.
+	NodeSkipped NodeStatus = "skipped"
+)
+
+// Workflow is a DAG of tasks submitted to an Orchestrator.
+type Workflow struct {
+	Name  string
+	Nodes []WorkflowNode
+}
+
+// WorkflowNode is one task of a Workflow. It is enqueued once every node in
+// DependsOn has completed.
+type WorkflowNode struct {
+	// ID names the node within its workflow.
+	ID      string
+	Type    string
+	Payload any
+	// Queue overrides the Client's queue.
+	Queue     string
+	DependsOn []string
+}
+
+// WorkflowState is a workflow as persisted, with its nodes in submission
+// order.
+type WorkflowState struct {
+	ID        string
+	Name      string
+	Status    WorkflowStatus
+	CreatedAt time.Time
+	// ScheduleEntry and RunNumber are set for workflows started by a
+	// Scheduler: the entry's name and the run's 1-based number.
+	ScheduleEntry string
+	RunNumber     int
+	Nodes         []NodeState
+}
+
+// NodeState is a workflow node as persisted. TaskID is set once the node is
+// enqueued.
+type NodeState struct {
+	ID        string
+	Type      string
+	DependsOn []string
+	Status    NodeStatus
+	TaskID    string
+}
+
+type OrchestratorConfig struct {
+	// Interval between passes over running workflows. Defaults to one second.
+	Interval time.Duration
+	// OnError, if set, is called with the error of every failed pass of Run.
+	OnError func(error)
+}
+
+// Orchestrator runs Workflows stored in asyncx_workflows and
+// asyncx_workflow_nodes. It follows each enqueued node through the Client's
+// store and enqueues nodes as their dependencies complete. A node whose task
+// ends in any other final status than completed, dry_run or suppressed (e.g.
+// failed for good, timed out, canceled, unroutable or stale) fails the
+// workflow; its pending nodes are skipped.
+type Orchestrator struct {
+	db      *sql.DB
+	dialect Dialect
+	client  *Client
+	cfg     OrchestratorConfig
+}
+
+// NewOrchestrator returns an Orchestrator for the workflow tables in db.
+// The client must have a store, which is where node outcomes are read.
+func NewOrchestrator(db *sql.DB, dialect Dialect, client *Client, cfg OrchestratorConfig) *Orchestrator {
+	if client.store == nil {
+		panic("asyncx: NewOrchestrator requires a Client with a store")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	return &Orchestrator{db: db, dialect: dialect, client: client, cfg: cfg}
+}
+
+// validate checks that node IDs are unique, dependencies exist and the
+// graph has no cycle.
+func (wf Workflow) validate() error {
+	if len(wf.Nodes) == 0 {
+		return errors.New("asyncx: workflow has no nodes")
+	}
+	deps := make(map[string][]string, len(wf.Nodes))
+	for _, n := range wf.Nodes {
+		if n.ID == "" {
+			return errors.New("asyncx: workflow node without ID")
+		}
+		if _, dup := deps[n.ID]; dup {
+			return fmt.Errorf("asyncx: duplicate workflow node %s", n.ID)
+		}
+		deps[n.ID] = n.DependsOn
+	}
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("asyncx: workflow has a cycle through node %s", id)
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		for _, d := range deps[id] {
+			if _, ok := deps[d]; !ok {
+				return fmt.Errorf("asyncx: workflow node %s depends on unknown node %s", id, d)
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		return nil
+	}
+	for _, n := range wf.Nodes {
+		if err := visit(n.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Submit validates and persists wf, enqueues the nodes without
+// dependencies and returns the workflow ID.
+func (o *Orchestrator) Submit(ctx context.Context, wf Workflow) (string, error) {
+	return o.submit(ctx, wf, "", time.Time{})
+}
+
+// errDuplicateRun is returned by submit when the occurrence of a schedule
+// entry already started a workflow.
+var errDuplicateRun = errors.New("asyncx: workflow run already started")
+
+// submit persists and starts wf. A non-empty entry records the workflow as
+// the run of that Scheduler entry for scheduledFor, numbered after the
+// entry's previous runs.
+func (o *Orchestrator) submit(ctx context.Context, wf Workflow, entry string, scheduledFor time.Time) (string, error) {
+	if err := wf.validate(); err != nil {
+		return "", err
+	}
+	if entry != "" {
+		started, err := o.runStarted(ctx, entry, scheduledFor)
+		if err != nil {
+			return "", err
+		}
+		if started {
+			return "", errDuplicateRun
+		}
+	}
+	id := randomID()
+	now := time.Now().UTC()
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	q := `INSERT INTO asyncx_workflows (id, name, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`
+	args := []any{id, wf.Name, string(WorkflowRunning), now, now}
+	if entry != "" {
+		var last sql.NullInt64
+		qr := `SELECT MAX(run_number) FROM asyncx_workflows WHERE schedule_entry = ?`
+		if err := tx.QueryRowContext(ctx, bind(o.dialect, qr), entry).Scan(&last); err != nil {
+			return "", err
+		}
+		q = `INSERT INTO asyncx_workflows (id, name, status, created_at, updated_at, schedule_entry, scheduled_for, run_number) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		args = append(args, entry, scheduledFor.UTC(), last.Int64+1)
+	}
+	if _, err := tx.ExecContext(ctx, bind(o.dialect, q), args...); err != nil {
+		if entry != "" {
+			// Lost the race for the unique index to another replica.
+			if started, _ := o.runStarted(ctx, entry, scheduledFor); started {
+				return "", errDuplicateRun
+			}
+		}
+		return "", err
+	}
+	qn := `INSERT INTO asyncx_workflow_nodes (workflow_id, node_id, position, type, queue, payload_json, depends_on, status, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	for i, n := range wf.Nodes {
+		payload, err := json.Marshal(n.Payload)
+		if err != nil {
+			return "", err
+		}
+		deps, _ := json.Marshal(n.DependsOn)
+		if _, err := tx.ExecContext(ctx, bind(o.dialect, qn), id, n.ID, i, n.Type, optional(n.Queue), string(payload), string(deps), string(NodePending), now); err != nil {
+			return "", err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		if entry != "" {
+			if started, _ := o.runStarted(ctx, entry, scheduledFor); started {
+				return "", errDuplicateRun
+			}
+		}
+		return "", err
+	}
+	return id, o.advance(ctx, id)
+}
+
+// runStarted reports whether the occurrence of entry at scheduledFor has a
+// workflow.
+func (o *Orchestrator) runStarted(ctx context.Context, entry string, scheduledFor time.Time) (bool, error) {
+	var n int
+	q := `SELECT COUNT(*) FROM asyncx_workflows WHERE schedule_entry = ? AND scheduled_for = ?`
+	err := o.db.QueryRowContext(ctx, bind(o.dialect, q), entry, scheduledFor.UTC()).Scan(&n)
+	return n > 0, err
+}
+
+// Get returns the persisted state of a workflow.
+func (o *Orchestrator) Get(ctx context.Context, id string) (*WorkflowState, error) {
+	w := &WorkflowState{ID: id}
+	var status string
+	var entry sql.NullString
+	var run sql.NullInt64
+	q := `SELECT name, status, created_at, schedule_entry, run_number FROM asyncx_workflows WHERE id = ?`
+	err := o.db.QueryRowContext(ctx, bind(o.dialect, q), id).Scan(&w.Name, &status, &w.CreatedAt, &entry, &run)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkflowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.Status, w.ScheduleEntry, w.RunNumber = WorkflowStatus(status), entry.String, int(run.Int64)
+	nodes, err := o.nodes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		w.Nodes = append(w.Nodes, n.NodeState)
+	}
+	return w, nil
+}
+
+// storedNode is a node row with what is needed to enqueue it.
+type storedNode struct {
+	NodeState
+	queue   sql.NullString
+	payload string
+}
+
+func (o *Orchestrator) nodes(ctx context.Context, id string) ([]storedNode, error) {
+	q := `SELECT node_id, type, queue, payload_json, depends_on, status, task_id FROM asyncx_workflow_nodes WHERE workflow_id = ? ORDER BY position`
+	rows, err := o.db.QueryContext(ctx, bind(o.dialect, q), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var nodes []storedNode
+	for rows.Next() {
+		var n storedNode
+		var deps, taskID sql.NullString
+		var status string
+		if err := rows.Scan(&n.ID, &n.Type, &n.queue, &n.payload, &deps, &status, &taskID); err != nil {
+			return nil, err
+		}
+		if deps.Valid {
+			_ = json.Unmarshal([]byte(deps.String), &n.DependsOn)
+		}
+		n.Status, n.TaskID = NodeStatus(status), taskID.String
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+// Run advances running workflows every Interval until ctx is cancelled.
+func (o *Orchestrator) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := o.RunOnce(ctx); err != nil && ctx.Err() == nil && o.cfg.OnError != nil {
+			o.cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce advances every running workflow once.
+func (o *Orchestrator) RunOnce(ctx context.Context) error {
+	q := `SELECT id FROM asyncx_workflows WHERE status = ?`
+	rows, err := o.db.QueryContext(ctx, bind(o.dialect, q), string(WorkflowRunning))
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	var errs []error
+	for _, id := range ids {
+		errs = append(errs, o.advance(ctx, id))
+	}
+	return errors.Join(errs...)
+}
+
+// advance records the outcome of enqueued nodes, then either finishes the
+// workflow or enqueues the pending nodes whose dependencies completed.
+func (o *Orchestrator) advance(ctx context.Context, id string) error {
+	nodes, err := o.nodes(ctx, id)
+	if err != nil {
+		return err
+	}
+	status := map[string]NodeStatus{}
+	failed := false
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Status == NodeEnqueued {
+			rec, err := o.client.store.GetByID(ctx, n.TaskID)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			switch {
+			case rec == nil, !isFinal(rec), rec.Status == StatusEnqueueFailed:
+				// A Reenqueuer puts enqueue_failed tasks back on Redis.
+			case rec.Status == StatusCompleted, rec.Status == StatusDryRun, rec.Status == StatusSuppressed:
+				// A suppressed task's work was done by another copy.
+				n.Status = NodeCompleted
+			default:
+				n.Status = NodeFailed
+			}
+			if n.Status != NodeEnqueued {
+				if err := o.setNode(ctx, id, n.ID, n.Status, n.TaskID); err != nil {
+					return err
+				}
+			}
+		}
+		status[n.ID] = n.Status
+		failed = failed || n.Status == NodeFailed
+	}
+	if failed {
+		for _, n := range nodes {
+			if n.Status == NodePending {
+				if err := o.setNode(ctx, id, n.ID, NodeSkipped, ""); err != nil {
+					return err
+				}
+			}
+		}
+		return o.setWorkflow(ctx, id, WorkflowFailed)
+	}
+	completed := true
+	for _, n := range nodes {
+		completed = completed && n.Status == NodeCompleted
+		if n.Status != NodePending || !depsCompleted(n.DependsOn, status) {
+			continue
+		}
+		taskID, err := o.enqueue(ctx, id, n)
+		if err != nil {
+			return err
+		}
+		if err := o.setNode(ctx, id, n.ID, NodeEnqueued, taskID); err != nil {
+			return err
+		}
+	}
+	if completed {
+		return o.setWorkflow(ctx, id, WorkflowCompleted)
+	}
+	return nil
+}
+
+func depsCompleted(deps []string, status map[string]NodeStatus) bool {
+	for _, d := range deps {
+		if status[d] != NodeCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueue enqueues a node as task "<workflow>:<node>", with a long node ID
+// shortened by boundedTaskID. The fixed task ID and dedup key make
+// enqueueing a node again after a crash a no-op.
+func (o *Orchestrator) enqueue(ctx context.Context, id string, n storedNode) (string, error) {
+	taskID := boundedTaskID(id + ":" + n.ID)
+	opts := []asynq.Option{
+		asynq.TaskID(taskID),
+		WithDedupKey("workflow:" + taskID),
+		WithMetadata(map[string]string{"workflow_id": id, "workflow_node": n.ID}),
+	}
+	if n.queue.Valid {
+		opts = append(opts, asynq.Queue(n.queue.String))
+	}
+	payload, err := jsonPayload(o.client.codec, []byte(n.payload))
+	if err == nil {
+		_, err = o.client.Enqueue(ctx, n.Type, payload, opts...)
+	}
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) && !errors.Is(err, ErrDuplicateTask) {
+		return "", err
+	}
+	return taskID, nil
+}
+
+func (o *Orchestrator) setNode(ctx context.Context, id, node string, status NodeStatus, taskID string) error {
+	q := `UPDATE asyncx_workflow_nodes SET status = ?, task_id = COALESCE(?, task_id), updated_at = ? WHERE workflow_id = ? AND node_id = ?`
+	_, err := o.db.ExecContext(ctx, bind(o.dialect, q), string(status), optional(taskID), time.Now().UTC(), id, node)
+	return err
+}
+
+func (o *Orchestrator) setWorkflow(ctx context.Context, id string, status WorkflowStatus) error {
+	q := `UPDATE asyncx_workflows SET status = ?, updated_at = ? WHERE id = ?`
+	_, err := o.db.ExecContext(ctx, bind(o.dialect, q), string(status), time.Now().UTC(), id)
+	return err
+}
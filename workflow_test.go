@@ -0,0 +1,189 @@
+package asyncx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+func TestWorkflow_ValidateRejectsBadGraphs(t *testing.T) {
+	for name, wf := range map[string]Workflow{
+		"empty":     {},
+		"duplicate": {Nodes: []WorkflowNode{{ID: "a"}, {ID: "a"}}},
+		"unknown":   {Nodes: []WorkflowNode{{ID: "a", DependsOn: []string{"b"}}}},
+		"cycle":     {Nodes: []WorkflowNode{{ID: "a", DependsOn: []string{"b"}}, {ID: "b", DependsOn: []string{"a"}}}},
+	} {
+		if err := wf.validate(); err == nil {
+			t.Errorf("%s: validate should fail", name)
+		}
+	}
+}
+
+func TestOrchestrator_RunReportsErrors(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db := openTestDB(t)
+	defer db.Close()
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, NewSQLStore(db), ClientOptions{})
+	defer client.Close()
+
+	// openTestDB has no workflow tables, so every pass fails.
+	errs := make(chan error, 1)
+	o := NewOrchestrator(db, DialectSQLite, client, OrchestratorConfig{
+		Interval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = o.Run(ctx) }()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("OnError called with nil")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not report the failed pass")
+	}
+}
+
+func TestOrchestrator_RunsDAG(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_workflow_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	store := NewSQLStore(db)
+	client := NewClient(redis, store, ClientOptions{})
+	defer client.Close()
+
+	var mu sync.Mutex
+	var ran []string
+	processor := NewProcessor(redis, store, ProcessorConfig{Concurrency: 2})
+	defer processor.Shutdown()
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("step", func(ctx context.Context, tsk *asynq.Task) error {
+		mu.Lock()
+		ran = append(ran, MetadataFromContext(ctx)["workflow_node"])
+		mu.Unlock()
+		return nil
+	})
+	mux.HandleFunc("broken", func(ctx context.Context, tsk *asynq.Task) error {
+		return NonRetryable(errors.New("boom"))
+	})
+	go func() { _ = processor.Start(mux) }()
+
+	orch := NewOrchestrator(db, DialectSQLite, client, OrchestratorConfig{})
+	wait := func(id string, want WorkflowStatus) *WorkflowState {
+		t.Helper()
+		var wf *WorkflowState
+		err := pollUntil(t, 15*time.Second, func() (bool, error) {
+			if err := orch.RunOnce(ctx); err != nil {
+				return false, err
+			}
+			var err error
+			wf, err = orch.Get(ctx, id)
+			return err == nil && wf.Status == want, err
+		})
+		if err != nil {
+			t.Fatalf("workflow %s not %s: %v %+v", id, want, err, wf)
+		}
+		return wf
+	}
+
+	diamond, err := orch.Submit(ctx, Workflow{Name: "diamond", Nodes: []WorkflowNode{
+		{ID: "a", Type: "step"},
+		{ID: "b", Type: "step", DependsOn: []string{"a"}},
+		{ID: "c", Type: "step", DependsOn: []string{"a"}},
+		{ID: "d", Type: "step", DependsOn: []string{"b", "c"}},
+	}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	wf := wait(diamond, WorkflowCompleted)
+	for _, n := range wf.Nodes {
+		if n.Status != NodeCompleted || n.TaskID == "" {
+			t.Errorf("node not completed: %+v", n)
+		}
+	}
+	mu.Lock()
+	order := slices.Clone(ran)
+	mu.Unlock()
+	if len(order) != 4 || order[0] != "a" || order[3] != "d" {
+		t.Fatalf("unexpected run order %v", order)
+	}
+
+	failing, err := orch.Submit(ctx, Workflow{Name: "failing", Nodes: []WorkflowNode{
+		{ID: "x", Type: "broken"},
+		{ID: "y", Type: "step", DependsOn: []string{"x"}},
+	}})
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	wf = wait(failing, WorkflowFailed)
+	if wf.Nodes[0].Status != NodeFailed || wf.Nodes[1].Status != NodeSkipped {
+		t.Fatalf("unexpected node states %+v", wf.Nodes)
+	}
+	if _, err := orch.Get(ctx, "missing"); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Fatalf("Get missing: %v", err)
+	}
+}
+
+func TestOrchestrator_MapsFinalStatuses(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	db, err := sql.Open("sqlite", "file:asyncx_workflow_final_test?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := Migrate(ctx, db, DialectSQLite); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	store := NewSQLStore(db)
+	client := NewClient(asynq.RedisClientOpt{Addr: s.Addr()}, store, ClientOptions{})
+	defer client.Close()
+	orch := NewOrchestrator(db, DialectSQLite, client, OrchestratorConfig{})
+
+	for status, want := range map[Status]WorkflowStatus{
+		StatusTimedOut:      WorkflowFailed,
+		StatusCanceled:      WorkflowFailed,
+		StatusUnroutable:    WorkflowFailed,
+		StatusStale:         WorkflowFailed,
+		StatusSuppressed:    WorkflowCompleted,
+		StatusEnqueueFailed: WorkflowRunning,
+	} {
+		id, err := orch.Submit(ctx, Workflow{Name: string(status), Nodes: []WorkflowNode{{ID: "only", Type: "step"}}})
+		if err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+		wf, _ := orch.Get(ctx, id)
+		if err := store.MarkStatus(ctx, wf.Nodes[0].TaskID, status, time.Now().UTC()); err != nil {
+			t.Fatalf("MarkStatus: %v", err)
+		}
+		if err := orch.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+		if wf, _ = orch.Get(ctx, id); wf.Status != want {
+			t.Errorf("node %s: workflow %s, want %s", status, wf.Status, want)
+		}
+	}
+}
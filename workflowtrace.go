@@ -0,0 +1,73 @@
+package asyncx
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// workflowSpanName is the name of the root span started by StartWorkflow.
+const workflowSpanName = "asyncx.workflow"
+
+type workflowKey struct{}
+
+// StartWorkflow starts the root span of a multi-task pipeline and returns a
+// context carrying it. Tasks enqueued with that context record the span in
+// workflow_traceparent, and their handlers' contexts carry it on, so steps
+// enqueued by handlers belong to the same workflow. With
+// ProcessorConfig.Tracing, each task span joins the workflow's trace, or
+// links to the workflow span when the task context already has a parent.
+// End the returned span once the pipeline has been enqueued.
+func StartWorkflow(ctx context.Context, tracer trace.Tracer, name string) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, workflowSpanName, trace.WithAttributes(attribute.String("asyncx.workflow.name", name)))
+	return context.WithValue(ctx, workflowKey{}, span.SpanContext()), span
+}
+
+// WorkflowTraceID returns the trace ID of the workflow ctx belongs to, if any.
+func WorkflowTraceID(ctx context.Context) (trace.TraceID, bool) {
+	sc, ok := workflowFromContext(ctx)
+	return sc.TraceID(), ok
+}
+
+func workflowFromContext(ctx context.Context) (trace.SpanContext, bool) {
+	sc, ok := ctx.Value(workflowKey{}).(trace.SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// encodeWorkflow returns the workflow span of ctx as a traceparent, or nil.
+func encodeWorkflow(ctx context.Context) *string {
+	sc, ok := workflowFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), sc), carrier)
+	tp := carrier.Get("traceparent")
+	return &tp
+}
+
+// withWorkflow puts the workflow recorded as traceparent tp into ctx.
+func withWorkflow(ctx context.Context, tp string) context.Context {
+	carrier := propagation.MapCarrier{"traceparent": tp}
+	sc := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(context.Background(), carrier))
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, workflowKey{}, sc)
+}
+
+// workflowSpanOptions makes a task span part of the workflow in ctx: a child
+// of the workflow span when ctx has no span of its own, otherwise linked to it.
+func workflowSpanOptions(ctx context.Context) (context.Context, []trace.SpanStartOption) {
+	sc, ok := workflowFromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	opts := []trace.SpanStartOption{trace.WithAttributes(attribute.String("asyncx.workflow.trace_id", sc.TraceID().String()))}
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, append(opts, trace.WithLinks(trace.Link{SpanContext: sc}))
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, sc), opts
+}
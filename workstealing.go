@@ -0,0 +1,32 @@
+package asyncx
+
+import (
+	"context"
+
+	"github.com/hibiken/asynq"
+)
+
+// isolatedServer runs one queue of ProcessorConfig.QueueLimits, plus the
+// queues it steals from.
+type isolatedServer struct {
+	queue  string
+	server *asynq.Server
+}
+
+type workerQueueKey struct{}
+
+// withWorkerQueue tags tasks run by h with the queue its workers belong to,
+// so that tasks taken from other queues can be told apart as stolen.
+func withWorkerQueue(h asynq.Handler, queue string) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		return h.ProcessTask(context.WithValue(ctx, workerQueueKey{}, queue), t)
+	})
+}
+
+// stolenBy returns the worker queue that stole the running task, or "".
+func stolenBy(ctx context.Context, queue string) string {
+	if wq, ok := ctx.Value(workerQueueKey{}).(string); ok && wq != queue {
+		return wq
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package asyncx
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// counterSink records counter totals by name.
+type counterSink struct {
+	mu       sync.Mutex
+	counters map[string]float64
+}
+
+func (s *counterSink) AddCounter(ctx context.Context, name string, delta float64, labels map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counters == nil {
+		s.counters = map[string]float64{}
+	}
+	s.counters[name+"/"+labels["stolen_by"]] += delta
+}
+
+func (s *counterSink) RecordHistogram(ctx context.Context, name string, value float64, labels map[string]string) {
+}
+
+func (s *counterSink) get(key string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters[key]
+}
+
+func TestProcessor_WorkStealing(t *testing.T) {
+	s := startMiniRedis(t)
+	defer s.Close()
+	redis := asynq.RedisClientOpt{Addr: s.Addr()}
+	sink := &counterSink{}
+	processor := NewProcessor(redis, nil, ProcessorConfig{
+		Queues:      map[string]int{DefaultQueue: 1},
+		QueueLimits: map[string]QueueLimit{"email": {MaxConcurrency: 1, StealFrom: []string{"bulk"}}},
+		Hooks:       MetricsHooks(sink),
+	})
+	done := make(chan string, 2)
+	mux := asynq.NewServeMux()
+	mux.HandleFunc("job", func(ctx context.Context, tsk *asynq.Task) error {
+		q, _ := asynq.GetQueueName(ctx)
+		done <- q
+		return nil
+	})
+	go func() { _ = processor.Start(mux) }()
+	defer processor.Shutdown()
+
+	client := NewClient(redis, nil, ClientOptions{})
+	defer client.Close()
+	ctx := context.Background()
+	// Only the email workers serve bulk, so they must steal it.
+	for _, q := range []string{"email", "bulk"} {
+		if _, err := client.Enqueue(ctx, "job", nil, asynq.Queue(q)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(3 * time.Second):
+			t.Fatal("tasks were not processed")
+		}
+	}
+	if err := pollUntil(t, time.Second, func() (bool, error) {
+		return sink.get(MetricStolen+"/email") == 1, nil
+	}); err != nil {
+		t.Fatalf("want one stolen task counted, got %v", sink.counters)
+	}
+}